@@ -19,6 +19,7 @@ func (c *Cli) newRootCmd() *cobra.Command {
 	cmd.AddCommand(
 		c.newServeCmd(),
 		c.newScanCmd(),
+		c.newRotateKeysCmd(),
 	)
 
 	return &cmd
@@ -0,0 +1,60 @@
+package apidepot
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/secrets"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+func (c *Cli) newRotateKeysCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:   "rotate-keys",
+		Short: "Re-encrypt the secrets with the primary key of the key file",
+		Long: `Re-encrypt the secrets with the primary key of the key file
+
+Add a new key to the key file and make it primary before running. the old key can be removed from the key file after.`,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			cfg, err := c.getServerConfig(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			if cfg.Secrets.KeyFile == "" {
+				return errors.New("secrets.keyFile is required")
+			}
+
+			ctx := cmd.Context()
+			container := digo.NewContainer(ctx, digo.EnvProd, cfg)
+			db, err := digo.Get[*gorm.DB](container, services.ServiceKeyDB)
+			if err != nil {
+				return err
+			}
+
+			count, err := secrets.ReencryptSecrets(db.WithContext(ctx))
+			if err != nil {
+				return err
+			}
+
+			logger.Info("re-encrypted secrets", "count", count, "keyId", secrets.GetKeyProvider().PrimaryKeyId())
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.String("secrets.keyFile", "", "Path to the key file encrypting the secrets")
+	f.String("db.seoul.host", "localhost", "Database host")
+	f.Int("db.seoul.port", 6543, "Database port")
+	f.String("db.seoul.user", "postgres", "Database user")
+	f.String("db.seoul.password", "postgres", "Database password")
+	f.String("db.seoul.name", "postgres", "Database name")
+	f.String("db.pingTimeout", "5s", "Database ping timeout")
+	f.Bool("db.autoMigration", true, "Auto migration")
+	f.Int("db.maxIdleConns", 10, "Max idle connections")
+	f.Int("db.maxOpenConns", 100, "Max open connections")
+	f.String("db.connMaxLifetime", "1h", "Connection max lifetime")
+
+	return &cmd
+}
@@ -3,6 +3,7 @@ package apidepot
 import (
 	"context"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/billing"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/habiliai/apidepot/pkg/internal/histories"
@@ -33,13 +34,18 @@ func (c *Cli) newScanCmd() *cobra.Command {
 				return err
 			}
 
+			billingService, err := digo.Get[billing.Service](container, billing.ServiceKey)
+			if err != nil {
+				return err
+			}
+
 			db, err := digo.Get[*gorm.DB](container, services.ServiceKeyDB)
 			if err != nil {
 				return err
 			}
 
-			return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-				ctx = helpers.WithTx(ctx, tx)
+			if err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				ctx := helpers.WithTx(ctx, tx)
 
 				var eg errgroup.Group
 				eg.Go(func() error {
@@ -50,7 +56,14 @@ func (c *Cli) newScanCmd() *cobra.Command {
 				})
 
 				return eg.Wait()
-			})
+			}); err != nil {
+				return err
+			}
+
+			// close the previous month. invoices are generated only once since billed histories are skipped
+			lastMonth := billing.PeriodOf(time.Now()).AddDate(0, 0, -1)
+			_, err = billingService.GenerateInvoices(helpers.WithTx(ctx, db.WithContext(ctx)), lastMonth)
+			return err
 		},
 	}
 
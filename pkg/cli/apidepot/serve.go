@@ -2,18 +2,26 @@ package apidepot
 
 import (
 	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/instance"
 	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
 	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	"gorm.io/gorm"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 )
 
 func (c *Cli) newServeCmd() *cobra.Command {
@@ -30,6 +38,10 @@ func (c *Cli) newServeCmd() *cobra.Command {
 				return err
 			}
 
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
 			container := digo.NewContainer(cmd.Context(), digo.EnvProd, cfg)
 			grpcServer, err := digo.Get[*grpc.Server](container, proto.ServiceKeyGrpcServer)
 			if err != nil {
@@ -41,7 +53,39 @@ func (c *Cli) newServeCmd() *cobra.Command {
 				grpcServer.GracefulStop()
 			}()
 
+			instanceService, err := digo.Get[instance.Service](container, instance.ServiceKey)
+			if err != nil {
+				return err
+			}
+
+			stackService, err := digo.Get[stack.Service](container, stack.ServiceKey)
+			if err != nil {
+				return err
+			}
+
+			db, err := digo.Get[*gorm.DB](container, services.ServiceKeyDB)
+			if err != nil {
+				return err
+			}
+
+			githookService, err := digo.Get[githook.Service](container, githook.ServiceKey)
+			if err != nil {
+				return err
+			}
+
 			eg := errgroup.Group{}
+			eg.Go(func() error {
+				return instanceService.RunDeploymentWorkers(ctx, cfg.Deployment.Workers)
+			})
+			eg.Go(func() error {
+				return stackService.RunBackupScheduler(helpers.WithTx(ctx, db.WithContext(ctx)))
+			})
+			eg.Go(func() error {
+				return instanceService.RunJWTSecretRetirement(ctx)
+			})
+			eg.Go(func() error {
+				return stackService.RunRequestUsageFlusher(helpers.WithTx(ctx, db))
+			})
 			eg.Go(func() error {
 				address := fmt.Sprintf("%s:%d", cfg.Address, cfg.Port)
 				listener, err := new(net.ListenConfig).Listen(ctx, "tcp", address)
@@ -58,7 +102,13 @@ func (c *Cli) newServeCmd() *cobra.Command {
 				return true
 			}))
 
-			httpServer := http.Server{Handler: grpcWebServer}
+			mux := http.NewServeMux()
+			mux.Handle(constants.PathApiKeyVerify, proto.NewApiKeyVerifyHandler(stackService, db, cfg.Stack.ApiKeyVerifySecret))
+			githubWebhookHandler := proto.NewGithubWebhookHandler(ctx, githookService, db, cfg.Github.WebhookSecret)
+			mux.Handle(constants.PathGithubWebhook, githubWebhookHandler)
+			mux.Handle("/", grpcWebServer)
+
+			httpServer := http.Server{Handler: mux}
 			defer httpServer.Close()
 			go func() {
 				<-ctx.Done()
@@ -81,7 +131,13 @@ func (c *Cli) newServeCmd() *cobra.Command {
 				return nil
 			})
 
-			return eg.Wait()
+			err = eg.Wait()
+
+			// interrupt the github deliveries being handled, which are handled again on redelivery
+			cancel()
+			githubWebhookHandler.Wait()
+
+			return err
 		},
 	}
 
@@ -127,7 +183,15 @@ func (c *Cli) newServeCmd() *cobra.Command {
 	f.String("stack.seoul.domain", "local.shaple.io", "Domain for stack in seoul")
 	f.String("stack.singapore.scheme", "http", "Scheme for stack in singapore")
 	f.String("stack.singapore.domain", "local.shaple.io", "Domain for stack in singapore")
+	f.Int("deployment.workers", 4, "Number of workers running the queued deployments")
 	f.Bool("stack.skipHealthCheck", false, "Skip health check for stack when checking service availability")
+	f.String("stack.backup.bucket", "", "S3 bucket storing the database backups of stacks. backups are disabled if empty")
+	f.Duration("stack.backup.interval", 24*time.Hour, "Interval of the scheduled database backups of stacks")
+	f.Int("stack.backup.retention", 7, "Number of the scheduled database backups kept for each stack")
+	f.Duration("stack.keyRotationGracePeriod", 24*time.Hour, "Default period the previous jwt secret of a stack is accepted after its keys are rotated")
+	f.String("stack.apiKeyVerifyUrl", "", "URL of this server the ingresses of stacks verify the stack api keys and count the requests against the monthly quotas with, e.g. http://apidepot.apidepot.svc:8081/_internal/api-keys/verify. required, since neither is enforced at the ingresses and the api keys cannot be revoked without it")
+	f.String("stack.apiKeyVerifySecret", "", "Secret signing the stack ids the ingresses of stacks call stack.apiKeyVerifyUrl with, so that nothing else can call it")
+	f.String("stack.globalDomain", "", "Domain of the global endpoints of multi-region stacks, whose dns steers the requests to the nearest healthy zone. stacks cannot be multi-region if empty")
 	f.String("stoa.url", "http://apidepot.local.shaple.io", "Stoacloud stack url")
 	f.String("stoa.anonKey", localAnonKey, "Stoacloud stack anon key")
 	f.String("stoa.adminKey", localAdminKey, "Stoacloud stack admin key")
@@ -135,6 +199,8 @@ func (c *Cli) newServeCmd() *cobra.Command {
 	f.String("github.clientSecret", "", "Github client secret")
 	f.String("github.appId", "1068010", "Github app id")
 	f.String("github.appPrivateKey", "", "Github app private key (base64)")
+	f.String("github.webhookSecret", "", "Secret of the github webhook served at "+constants.PathGithubWebhook+" on the web port, which registers vapi releases and redeploys stacks on pushes. the webhook is disabled if empty")
+	f.String("secrets.keyFile", "", "Path to the key file encrypting the secrets. secrets are stored in plaintext if empty")
 	f.String("s3.accessKey", "minioadmin", "Access key for s3")
 	f.String("s3.secretKey", "minioadmin", "Secret key for s3")
 	f.String("s3.seoul.endpoint", "http://minio.local.shaple.io", "Regional endpoint for s3 in seoul")
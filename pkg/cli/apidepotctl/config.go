@@ -147,8 +147,9 @@ type (
 			} `yaml:"database,omitempty"`
 
 			Env []struct {
-				Name  string `yaml:"name,omitempty"`
-				Value string `yaml:"value,omitempty"`
+				Name   string `yaml:"name,omitempty"`
+				Value  string `yaml:"value,omitempty"`
+				Secret bool   `yaml:"secret,omitempty"`
 			} `yaml:"env,omitempty"`
 		} `yaml:"stack,omitempty"`
 	}
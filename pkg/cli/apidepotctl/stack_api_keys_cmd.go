@@ -0,0 +1,183 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *Cli) newStackApiKeysCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "api-keys",
+		Short:   "Manage stack's custom api keys",
+		Aliases: []string{"api-key"},
+	}
+
+	cmd.AddCommand(
+		c.newCreateStackApiKeyCmd(),
+		c.newListStackApiKeysCmd(),
+		c.newRevokeStackApiKeyCmd(),
+	)
+
+	return &cmd
+}
+
+func (c *Cli) newCreateStackApiKeyCmd() *cobra.Command {
+	var (
+		role      string
+		vapis     []string
+		expiresIn time.Duration
+	)
+
+	cmd := cobra.Command{
+		Use:   "create NAME",
+		Short: "Create stack's custom api key",
+		Long: `Create stack's custom api key
+
+the key is printed only once, so keep it safe.
+vapis are given by their slugs, e.g. "sns/v2". the key can call every vapi if not given.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			req := &proto.CreateStackApiKeyRequest{
+				StackId:      st.Id,
+				Name:         args[0],
+				Role:         role,
+				AllowedVapis: vapis,
+			}
+			if expiresIn > 0 {
+				req.ExpiresAt = tspb.New(time.Now().Add(expiresIn))
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			resp, err := tcc.CreateStackApiKey(ctx, req)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			fmt.Printf("created api key '%s'(id=%d)\n", resp.ApiKey.Name, resp.ApiKey.Id)
+			fmt.Println(resp.Token)
+
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&role, "role", "", "Role claim of the api key")
+	f.StringSliceVar(&vapis, "vapi", nil, "Slug of the vapi the api key can call. can be given multiple times")
+	f.DurationVar(&expiresIn, "expires-in", 0, "Period until the api key expires, e.g. 720h. the api key never expires if not given")
+	_ = cmd.MarkFlagRequired("role")
+
+	return &cmd
+}
+
+func (c *Cli) newListStackApiKeysCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List stack's custom api keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			resp, err := tcc.ListStackApiKeys(ctx, &proto.StackId{Id: st.Id})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, apiKey := range resp.ApiKeys {
+				vapis := "*"
+				if len(apiKey.AllowedVapis) > 0 {
+					vapis = strings.Join(apiKey.AllowedVapis, ",")
+				}
+
+				status := "active"
+				if apiKey.Revoked {
+					status = "revoked"
+				} else if apiKey.ExpiresAt != nil && apiKey.ExpiresAt.AsTime().Before(time.Now()) {
+					status = "expired"
+				} else if apiKey.ExpiresAt != nil {
+					status = "expires at " + apiKey.ExpiresAt.AsTime().Local().Format(time.DateTime)
+				}
+
+				fmt.Printf("%d\t%s\trole=%s\tvapis=%s\t%s\n", apiKey.Id, apiKey.Name, apiKey.Role, vapis, status)
+			}
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newRevokeStackApiKeyCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "revoke ID",
+		Short: "Revoke stack's custom api key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			apiKeyId, err := strconv.ParseInt(args[0], 10, 32)
+			if err != nil {
+				return errors.Wrapf(err, "invalid api key id: %s", args[0])
+			}
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.RevokeStackApiKey(ctx, &proto.RevokeStackApiKeyRequest{
+				StackId:  st.Id,
+				ApiKeyId: int32(apiKeyId),
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,68 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+func (s *ApiDepotCtlTestSuite) TestCreateStackApiKeyCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("CreateStackApiKey", authTokenMatcher, mock.MatchedBy(func(req *proto.CreateStackApiKeyRequest) bool {
+		s.Equal(int32(1), req.StackId)
+		s.Equal("partner", req.Name)
+		s.Equal("partner_role", req.Role)
+		s.Equal([]string{"sns/v2", "storage"}, req.AllowedVapis)
+		if s.NotNil(req.ExpiresAt) {
+			s.WithinDuration(time.Now().Add(720*time.Hour), req.ExpiresAt.AsTime(), time.Minute)
+		}
+
+		return true
+	})).Return(&proto.CreateStackApiKeyResponse{
+		ApiKey: &proto.StackApiKey{
+			Id:   1,
+			Name: "partner",
+		},
+		Token: "token",
+	}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "api-keys", "create", "partner",
+		"--role", "partner_role",
+		"--vapi", "sns/v2", "--vapi", "storage",
+		"--expires-in", "720h",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
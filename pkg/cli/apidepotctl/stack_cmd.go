@@ -24,7 +24,13 @@ func (c *Cli) newStackCmd() *cobra.Command {
 		c.newUpdateStackCmd(),
 		c.newStackVapiCmd(),
 		c.newStackEnvCmd(),
+		c.newStackApiKeysCmd(),
+		c.newStackRateLimitsCmd(),
+		c.newStackDomainsCmd(),
 		c.newStackCustomVapiCmd(),
+		c.newStackLogsCmd(),
+		c.newStackStatusCmd(),
+		c.newStackPreviewsCmd(),
 	)
 
 	return &cmd
@@ -216,8 +222,9 @@ func (c *Cli) newUpdateStackCmd() *cobra.Command {
 				var envVars []*proto.SetStackEnvRequest_EnvVar
 				for _, env := range c.args.Stack.Env {
 					envVars = append(envVars, &proto.SetStackEnvRequest_EnvVar{
-						Name:  env.Name,
-						Value: env.Value,
+						Name:   env.Name,
+						Value:  env.Value,
+						Secret: env.Secret,
 					})
 				}
 				if _, err = tcc.SetStackEnv(ctx, &proto.SetStackEnvRequest{
@@ -0,0 +1,249 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"strings"
+)
+
+func (c *Cli) newStackDomainsCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "domains",
+		Short:   "Manage stack's custom domains",
+		Aliases: []string{"domain"},
+	}
+
+	cmd.AddCommand(
+		c.newAddStackDomainCmd(),
+		c.newVerifyStackDomainCmd(),
+		c.newListStackDomainsCmd(),
+		c.newRemoveStackDomainCmd(),
+		c.newSetStackPrimaryDomainCmd(),
+	)
+
+	return &cmd
+}
+
+func (c *Cli) newAddStackDomainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "add HOSTNAME",
+		Short: "Add custom domain to the stack",
+		Long: `Add custom domain to the stack
+
+add the printed TXT record to the dns of the domain, and verify the domain with "stack domains verify HOSTNAME".
+point the domain to the stack with a CNAME record as well.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			customDomain, err := tcc.AddStackCustomDomain(ctx, &proto.AddStackCustomDomainRequest{
+				StackId:  st.Id,
+				Hostname: args[0],
+			})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			fmt.Printf("added custom domain '%s'. add the following dns records to verify it:\n", customDomain.Hostname)
+			fmt.Printf("%s\tTXT\t%s\n", customDomain.VerificationRecordName, customDomain.VerificationRecordValue)
+			fmt.Printf("%s\tCNAME\t%s\n", customDomain.Hostname, st.Domain)
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newVerifyStackDomainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify HOSTNAME",
+		Short: "Verify the ownership of stack's custom domain",
+		Long: `Verify the ownership of stack's custom domain
+
+the stack is served from the domain on the next deployment once it is verified.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			customDomain, err := findStackCustomDomain(st, args[0])
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.VerifyStackCustomDomain(ctx, &proto.StackCustomDomainId{
+				StackId:        st.Id,
+				CustomDomainId: customDomain.Id,
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			fmt.Printf("verified custom domain '%s'\n", customDomain.Hostname)
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newListStackDomainsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List stack's domains",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			printDomain := func(hostname string, status string) {
+				if hostname == st.PrimaryDomain || (st.PrimaryDomain == "" && hostname == st.Domain) {
+					status += ",primary"
+				}
+				fmt.Printf("%s\t%s\n", hostname, status)
+			}
+
+			printDomain(st.Domain, "generated")
+			for _, customDomain := range st.CustomDomains {
+				if customDomain.Verified {
+					printDomain(customDomain.Hostname, "verified")
+				} else {
+					printDomain(customDomain.Hostname, "unverified")
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newRemoveStackDomainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove HOSTNAME",
+		Short: "Remove custom domain from the stack",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			customDomain, err := findStackCustomDomain(st, args[0])
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.RemoveStackCustomDomain(ctx, &proto.StackCustomDomainId{
+				StackId:        st.Id,
+				CustomDomainId: customDomain.Id,
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newSetStackPrimaryDomainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set-primary HOSTNAME",
+		Short: "Set the domain the endpoint of the stack is on",
+		Long: `Set the domain the endpoint of the stack is on
+
+the domain is the generated domain or a verified custom domain of the stack.
+the endpoint is changed on the next deployment.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.SetStackPrimaryDomain(ctx, &proto.SetStackPrimaryDomainRequest{
+				StackId:  st.Id,
+				Hostname: args[0],
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+}
+
+func findStackCustomDomain(st *proto.Stack, hostname string) (*proto.StackCustomDomain, error) {
+	hostname = strings.TrimSuffix(strings.ToLower(hostname), ".")
+	for _, customDomain := range st.CustomDomains {
+		if customDomain.Hostname == hostname {
+			return customDomain, nil
+		}
+	}
+
+	return nil, errors.Errorf("custom domain '%s' is not added to the stack", hostname)
+}
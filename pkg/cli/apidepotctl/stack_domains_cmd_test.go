@@ -0,0 +1,56 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *ApiDepotCtlTestSuite) TestVerifyStackDomainCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+				CustomDomains: []*proto.StackCustomDomain{
+					{Id: 3, StackId: 1, Hostname: "api.example.com"},
+				},
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("VerifyStackCustomDomain", authTokenMatcher, mock.MatchedBy(func(req *proto.StackCustomDomainId) bool {
+		s.Equal(int32(1), req.StackId)
+		s.Equal(int32(3), req.CustomDomainId)
+
+		return true
+	})).Return(&proto.StackCustomDomain{Id: 3, Verified: true}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "domains", "verify", "API.example.com",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
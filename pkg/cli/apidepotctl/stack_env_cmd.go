@@ -1,10 +1,12 @@
 package apidepotctl
 
 import (
+	"fmt"
 	"github.com/habiliai/apidepot/pkg/internal/proto"
 	"github.com/habiliai/apidepot/pkg/internal/util"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 func (c *Cli) newStackEnvCmd() *cobra.Command {
@@ -14,6 +16,7 @@ func (c *Cli) newStackEnvCmd() *cobra.Command {
 	}
 
 	cmd.AddCommand(
+		c.newListStackEnvCmd(),
 		c.newSetStackEnvCmd(),
 		c.newUnsetStackEnvCmd(),
 	)
@@ -21,13 +24,54 @@ func (c *Cli) newStackEnvCmd() *cobra.Command {
 	return &cmd
 }
 
-func (c *Cli) newSetStackEnvCmd() *cobra.Command {
+func (c *Cli) newListStackEnvCmd() *cobra.Command {
 	return &cobra.Command{
+		Use:   "list",
+		Short: "List stack vapi's environment variables",
+		Long: `List stack vapi's environment variables
+
+the values of secret environment variables are masked.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			for _, envVar := range st.EnvVars {
+				if envVar.Secret {
+					fmt.Printf("%s=%s (secret, updated at %s)\n", envVar.Name, envVar.MaskedValue, envVar.UpdatedAt.AsTime().Local().Format(time.DateTime))
+				} else {
+					fmt.Printf("%s=%s\n", envVar.Name, envVar.Value)
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+func (c *Cli) newSetStackEnvCmd() *cobra.Command {
+	var secret bool
+
+	cmd := cobra.Command{
 		Use:   "set KEY=VALUE [...KEY=VALUE]",
 		Short: "Set stack vapi's environment variable",
 		Long: `Set stack vapi's environment variable
 
-key has prefix for VAPI name. e.g. "user-management.MAX_USERS=1000"`,
+key has prefix for VAPI name. e.g. "user-management.MAX_USERS=1000"
+the value of a secret environment variable can't be read back once it is set.`,
 		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) (err error) {
 			defer c.close()
@@ -50,8 +94,9 @@ key has prefix for VAPI name. e.g. "user-management.MAX_USERS=1000"`,
 					return errors.Errorf("invalid key-value pair: %s", arg)
 				}
 				userEnvVars = append(userEnvVars, &proto.SetStackEnvRequest_EnvVar{
-					Name:  key,
-					Value: value,
+					Name:   key,
+					Value:  value,
+					Secret: secret,
 				})
 			}
 
@@ -71,6 +116,10 @@ key has prefix for VAPI name. e.g. "user-management.MAX_USERS=1000"`,
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&secret, "secret", false, "Set as secret environment variables")
+
+	return &cmd
 }
 
 func (c *Cli) newUnsetStackEnvCmd() *cobra.Command {
@@ -0,0 +1,93 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"io"
+)
+
+func (c *Cli) newStackLogsCmd() *cobra.Command {
+	var (
+		instanceId int32
+		since      string
+		tail       int64
+		follow     bool
+	)
+
+	cmd := cobra.Command{
+		Use:   "logs [COMPONENT]",
+		Short: "Print the logs of the stack",
+		Long: `Print the logs of the stack
+
+component is one of auth, storage, postgrest or the name of a vapi. every component is printed if not given.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if instanceId == 0 {
+				resp, err := tcc.GetStackInstances(ctx, &proto.StackId{Id: st.Id})
+				if err != nil {
+					return errors.WithStack(err)
+				} else if len(resp.Instances) == 0 {
+					return errors.New("no instance found")
+				}
+				instanceId = resp.Instances[0].Id
+			}
+
+			req := proto.StreamInstanceLogsRequest{
+				InstanceId: instanceId,
+				Follow:     follow,
+			}
+			if len(args) > 0 {
+				req.Component = &args[0]
+			}
+			if since != "" {
+				req.Since = &since
+			}
+			if tail >= 0 {
+				req.Tail = &tail
+			}
+
+			stream, err := tcc.StreamInstanceLogs(ctx, &req)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			for {
+				line, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return errors.WithStack(err)
+				}
+
+				fmt.Printf("[%s] %s\n", line.Pod, line.Line)
+			}
+		},
+	}
+
+	f := cmd.Flags()
+	f.Int32Var(&instanceId, "instance", 0, "Specify instance id. the first instance of the stack if not given")
+	f.StringVar(&since, "since", "", "Only print the logs newer than the duration like 10m")
+	f.Int64Var(&tail, "tail", -1, "Only print the last lines of each pod. every line if negative")
+	f.BoolVar(&follow, "follow", false, "Keep streaming the logs")
+
+	return &cmd
+}
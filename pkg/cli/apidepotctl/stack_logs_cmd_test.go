@@ -0,0 +1,73 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *ApiDepotCtlTestSuite) TestStackLogsCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStackInstances", authTokenMatcher, mock.MatchedBy(func(req *proto.StackId) bool {
+		s.Equal(int32(1), req.Id)
+		return true
+	})).Return(&proto.GetStackInstancesResponse{
+		Instances: []*proto.Instance{
+			{
+				Id: 2,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("StreamInstanceLogs", mock.MatchedBy(func(req *proto.StreamInstanceLogsRequest) bool {
+		s.Equal(int32(2), req.InstanceId)
+		s.Equal("auth", req.GetComponent())
+		s.Equal("10m", req.GetSince())
+		s.Nil(req.Tail)
+		s.True(req.Follow)
+		return true
+	}), mock.Anything).Run(func(args mock.Arguments) {
+		stream := args.Get(1).(proto.ApiDepot_StreamInstanceLogsServer)
+		s.NoError(stream.Send(&proto.InstanceLogLine{
+			Pod:  "auth-1",
+			Line: "started",
+		}))
+	}).Return(nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "logs", "auth",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+		"--since", "10m",
+		"--follow",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
@@ -0,0 +1,109 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+func (c *Cli) newStackPreviewsCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "previews",
+		Short:   "Manage preview stacks of pull requests",
+		Aliases: []string{"preview"},
+	}
+
+	cmd.AddCommand(
+		c.newListStackPreviewsCmd(),
+		c.newSetStackPreviewsEnabledCmd("enable", "Create a preview stack for each pull request on the stack's git branch", true),
+		c.newSetStackPreviewsEnabledCmd("disable", "Stop creating preview stacks for new pull requests", false),
+	)
+
+	return &cmd
+}
+
+func (c *Cli) newListStackPreviewsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List preview stacks of the stack",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			resp, err := tcc.GetStackPreviews(ctx, &proto.StackId{Id: st.Id})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PULL REQUEST\tSTACK\tENDPOINT\tCREATED")
+			for _, preview := range resp.Previews {
+				fmt.Fprintf(
+					w,
+					"%s#%d\t%s\t%s\t%s\n",
+					preview.GitRepo,
+					preview.PullRequestNumber,
+					preview.StackName,
+					preview.Endpoint,
+					preview.CreatedAt.AsTime().Local().Format(time.RFC3339),
+				)
+			}
+
+			return errors.WithStack(w.Flush())
+		},
+	}
+}
+
+func (c *Cli) newSetStackPreviewsEnabledCmd(use string, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:   use,
+		Short: short,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err = tcc.UpdateStack(ctx, &proto.UpdateStackRequest{
+				StackId:         st.Id,
+				PreviewsEnabled: &enabled,
+			}); err != nil {
+				return errors.Wrapf(err, "failed to update stack")
+			}
+
+			return nil
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ApiDepotCtlTestSuite) TestStackPreviewsCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Twice()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Twice()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Twice()
+	s.cloudServer.On("UpdateStack", authTokenMatcher, mock.MatchedBy(func(req *proto.UpdateStackRequest) bool {
+		s.Equal(int32(1), req.StackId)
+		s.Require().NotNil(req.PreviewsEnabled)
+		s.True(*req.PreviewsEnabled)
+		return true
+	})).Return(&emptypb.Empty{}, nil).Once()
+	s.cloudServer.On("GetStackPreviews", authTokenMatcher, mock.MatchedBy(func(req *proto.StackId) bool {
+		s.Equal(int32(1), req.Id)
+		return true
+	})).Return(&proto.GetStackPreviewsResponse{
+		Previews: []*proto.StackPreview{
+			{
+				Id:                1,
+				StackId:           2,
+				ParentStackId:     1,
+				GitRepo:           "habiliai/test-stack",
+				PullRequestNumber: 7,
+				StackName:         "test-stack-pr-7",
+				Endpoint:          "https://test-stack-pr-7.apidepot.local",
+				CreatedAt:         timestamppb.Now(),
+			},
+		},
+	}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "previews", "enable",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+	s.Require().NoError(cmd.Execute())
+
+	cmd = s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "previews", "list",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+	s.Require().NoError(cmd.Execute())
+}
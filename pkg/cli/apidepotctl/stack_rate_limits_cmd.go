@@ -0,0 +1,203 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"slices"
+)
+
+func (c *Cli) newStackRateLimitsCmd() *cobra.Command {
+	cmd := cobra.Command{
+		Use:     "rate-limits",
+		Short:   "Manage stack's rate limits and request usage",
+		Aliases: []string{"rate-limit"},
+	}
+
+	cmd.AddCommand(
+		c.newGetStackRateLimitsCmd(),
+		c.newSetStackRateLimitCmd(),
+		c.newUnsetStackRateLimitCmd(),
+	)
+
+	return &cmd
+}
+
+func (c *Cli) newGetStackRateLimitsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get",
+		Short: "Get stack's rate limits and request usage of this month",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			usage, err := tcc.GetStackRequestUsage(ctx, &proto.StackId{Id: st.Id})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			limits := st.GetRateLimits()
+			printRateLimit("*", limits.GetGlobal())
+			slugs := make([]string, 0, len(limits.GetVapis()))
+			for slug := range limits.GetVapis() {
+				slugs = append(slugs, slug)
+			}
+			slices.Sort(slugs)
+			for _, slug := range slugs {
+				printRateLimit(slug, limits.Vapis[slug])
+			}
+			fmt.Printf("requests in %s: %d of the stack, %d/%d of the owner\n", usage.Month, usage.StackRequests, usage.OwnerRequests, usage.Quota)
+
+			return nil
+		},
+	}
+}
+
+func printRateLimit(target string, limit *proto.StackRateLimit) {
+	if limit == nil {
+		fmt.Printf("%s\tunlimited\n", target)
+		return
+	}
+
+	fmt.Printf("%s\t%d req/s, burst %d", target, limit.RequestsPerSecond, limit.Burst)
+	if limit.MaxInFlight > 0 {
+		fmt.Printf(", max %d in flight", limit.MaxInFlight)
+	}
+	fmt.Println()
+}
+
+func (c *Cli) newSetStackRateLimitCmd() *cobra.Command {
+	var (
+		vapi  string
+		limit proto.StackRateLimit
+	)
+
+	cmd := cobra.Command{
+		Use:   "set",
+		Short: "Set stack's rate limit",
+		Long: `Set stack's rate limit
+
+the limit applies to the whole stack, or to a vapi if --vapi is given. a vapi is given by its slug, e.g. "sns/v2",
+and a custom vapi by its name. the limits are applied on the next deployment.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			limits := st.GetRateLimits()
+			if limits == nil {
+				limits = &proto.StackRateLimits{}
+			}
+			if vapi == "" {
+				limits.Global = &limit
+			} else {
+				if limits.Vapis == nil {
+					limits.Vapis = map[string]*proto.StackRateLimit{}
+				}
+				limits.Vapis[vapi] = &limit
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.SetStackRateLimits(ctx, &proto.SetStackRateLimitsRequest{
+				StackId:    st.Id,
+				RateLimits: limits,
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+
+	f := cmd.Flags()
+	f.StringVar(&vapi, "vapi", "", "Slug of the vapi to limit. the whole stack is limited if not given")
+	f.Int64Var(&limit.RequestsPerSecond, "rps", 0, "Average requests per second")
+	f.Int64Var(&limit.Burst, "burst", 0, "Max requests going through at once above the average")
+	f.Int64Var(&limit.MaxInFlight, "max-in-flight", 0, "Max requests served at once. unlimited if not given")
+	_ = cmd.MarkFlagRequired("rps")
+
+	return &cmd
+}
+
+func (c *Cli) newUnsetStackRateLimitCmd() *cobra.Command {
+	var vapi string
+
+	cmd := cobra.Command{
+		Use:   "unset",
+		Short: "Unset stack's rate limit",
+		Long: `Unset stack's rate limit
+
+the limit of the whole stack is unset, or that of a vapi if --vapi is given.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			limits := st.GetRateLimits()
+			if limits == nil {
+				return nil
+			}
+			if vapi == "" {
+				limits.Global = nil
+			} else {
+				delete(limits.Vapis, vapi)
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if _, err := tcc.SetStackRateLimits(ctx, &proto.SetStackRateLimitsRequest{
+				StackId:    st.Id,
+				RateLimits: limits,
+			}); err != nil {
+				return errors.WithStack(err)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&vapi, "vapi", "", "Slug of the vapi to unset the limit of")
+
+	return &cmd
+}
@@ -0,0 +1,65 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *ApiDepotCtlTestSuite) TestSetStackVapiRateLimitCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+				RateLimits: &proto.StackRateLimits{
+					Global: &proto.StackRateLimit{RequestsPerSecond: 100, Burst: 50},
+				},
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("SetStackRateLimits", authTokenMatcher, mock.MatchedBy(func(req *proto.SetStackRateLimitsRequest) bool {
+		s.Equal(int32(1), req.StackId)
+		if s.NotNil(req.RateLimits.Global) {
+			s.Equal(int64(100), req.RateLimits.Global.RequestsPerSecond)
+		}
+		if s.Contains(req.RateLimits.Vapis, "sns/v2") {
+			s.Equal(int64(10), req.RateLimits.Vapis["sns/v2"].RequestsPerSecond)
+			s.Equal(int64(20), req.RateLimits.Vapis["sns/v2"].Burst)
+			s.Equal(int64(5), req.RateLimits.Vapis["sns/v2"].MaxInFlight)
+		}
+
+		return true
+	})).Return(&proto.Stack{Id: 1}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "rate-limits", "set",
+		"--vapi", "sns/v2",
+		"--rps", "10", "--burst", "20", "--max-in-flight", "5",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
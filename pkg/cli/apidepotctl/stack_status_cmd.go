@@ -0,0 +1,79 @@
+package apidepotctl
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"os"
+	"text/tabwriter"
+)
+
+func (c *Cli) newStackStatusCmd() *cobra.Command {
+	var instanceId int32
+
+	cmd := cobra.Command{
+		Use:   "status",
+		Short: "Print the health of each component of the stack",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			if instanceId == 0 {
+				resp, err := tcc.GetStackInstances(ctx, &proto.StackId{Id: st.Id})
+				if err != nil {
+					return errors.WithStack(err)
+				} else if len(resp.Instances) == 0 {
+					return errors.New("no instance found")
+				}
+				instanceId = resp.Instances[0].Id
+			}
+
+			status, err := tcc.GetInstanceStatus(ctx, &proto.InstanceId{Id: instanceId})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "COMPONENT\tTYPE\tREADY\tRESTARTS\tHEALTH\tLAST ERROR")
+			for _, component := range status.Components {
+				health := "unhealthy"
+				if component.Healthy {
+					health = "healthy"
+				}
+				fmt.Fprintf(
+					w,
+					"%s\t%s\t%d/%d\t%d\t%s\t%s\n",
+					component.Name,
+					component.Component,
+					component.ReadyPods,
+					component.TotalPods,
+					component.Restarts,
+					health,
+					component.LastError,
+				)
+			}
+
+			return errors.WithStack(w.Flush())
+		},
+	}
+
+	cmd.Flags().Int32Var(&instanceId, "instance", 0, "Specify instance id. the first instance of the stack if not given")
+
+	return &cmd
+}
@@ -0,0 +1,70 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *ApiDepotCtlTestSuite) TestStackStatusCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetInstanceStatus", authTokenMatcher, mock.MatchedBy(func(req *proto.InstanceId) bool {
+		s.Equal(int32(3), req.Id)
+		return true
+	})).Return(&proto.InstanceStatus{
+		InstanceId: 3,
+		Components: []*proto.InstanceComponentStatus{
+			{
+				Name:      "auth",
+				Component: "auth",
+				ReadyPods: 1,
+				TotalPods: 1,
+				Healthy:   true,
+			},
+			{
+				Name:      "sns",
+				Component: "vapi",
+				TotalPods: 1,
+				Restarts:  4,
+				LastError: "vapi-1-v1: CrashLoopBackOff",
+			},
+		},
+	}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "status",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+		"--instance", "3",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
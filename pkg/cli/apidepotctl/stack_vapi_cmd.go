@@ -2,9 +2,11 @@ package apidepotctl
 
 import (
 	"context"
+	"fmt"
 	"github.com/habiliai/apidepot/pkg/internal/proto"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"time"
 )
 
 func (c *Cli) newStackVapiCmd() *cobra.Command {
@@ -19,6 +21,7 @@ func (c *Cli) newStackVapiCmd() *cobra.Command {
 	cmd.AddCommand(
 		c.newInstallStackVapiCmd(),
 		c.newUninstallStackVapiCmd(),
+		c.newGetStackVapiNoticesCmd(),
 	)
 
 	return &cmd
@@ -63,6 +66,10 @@ func (c *Cli) newInstallStackVapiCmd() *cobra.Command {
 				return errors.WithStack(err)
 			}
 
+			if vapiRelease.Deprecated {
+				fmt.Printf("warning: %s@%s is deprecated: %s\n", c.args.StackVapi.Name, vapiRelease.Version, vapiRelease.DeprecationMessage)
+			}
+
 			return nil
 		},
 	}
@@ -119,6 +126,50 @@ func (c *Cli) newUninstallStackVapiCmd() *cobra.Command {
 	return &cmd
 }
 
+func (c *Cli) newGetStackVapiNoticesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "notices",
+		Short: "List state changes of the vapi releases installed on the stack",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			defer c.close()
+			ctx := cmd.Context()
+
+			if err = c.readConfig(cmd.Flags()); err != nil {
+				return
+			} else if err = c.connectApiDepot(); err != nil {
+				return
+			} else if ctx, err = c.verifyCli(ctx); err != nil {
+				return
+			}
+
+			st, err := c.getStack(ctx)
+			if err != nil {
+				return err
+			}
+
+			tcc := proto.NewApiDepotClient(c.conn)
+			resp, err := tcc.GetStackVapiNotices(ctx, &proto.StackId{Id: st.Id})
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			for _, notice := range resp.Notices {
+				fmt.Printf(
+					"%s\t%s@%s\t%s\t%s\n",
+					notice.CreatedAt.AsTime().Local().Format(time.DateTime),
+					notice.PackageName,
+					notice.Vapi.GetVersion(),
+					notice.Event,
+					notice.Message,
+				)
+			}
+
+			return nil
+		},
+	}
+}
+
 func (c *Cli) getVapiRelease(
 	ctx context.Context,
 	name, version string,
@@ -0,0 +1,64 @@
+package apidepotctl_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/stretchr/testify/mock"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ApiDepotCtlTestSuite) TestGetStackVapiNoticesCmd() {
+	s.Require().NoError(util.CopyFile("./testdata/stack_cmd_test.orig.yaml", "./testdata/stack_cmd_test.yaml", true))
+
+	authTokenMatcher := mock.MatchedBy(func(ctx context.Context) bool {
+		token := helpers.GetAuthToken(ctx)
+		s.NotEmpty(token)
+		return true
+	})
+	s.cloudServer.On("VerifyCliApp", mock.Anything, mock.Anything).Return(&proto.VerifyCliAppResponse{
+		AccessToken: s.session.AccessToken,
+	}, nil).Once()
+	s.cloudServer.On("GetProjects", authTokenMatcher, mock.Anything).Return(&proto.GetProjectsResponse{
+		Projects: []*proto.Project{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStacks", authTokenMatcher, mock.Anything).Return(&proto.GetStacksResponse{
+		Stacks: []*proto.Stack{
+			{
+				Id: 1,
+			},
+		},
+	}, nil).Once()
+	s.cloudServer.On("GetStackVapiNotices", authTokenMatcher, mock.MatchedBy(func(req *proto.StackId) bool {
+		s.Equal(int32(1), req.Id)
+		return true
+	})).Return(&proto.GetStackVapiNoticesResponse{
+		Notices: []*proto.StackVapiNotice{
+			{
+				Id:          1,
+				StackId:     1,
+				PackageName: "sns",
+				Vapi:        &proto.VapiRelease{Version: "1.2.0"},
+				Event:       "deprecated",
+				Message:     "use sns 2.x",
+				CreatedAt:   tspb.Now(),
+			},
+		},
+	}, nil).Once()
+	defer s.cloudServer.AssertExpectations(s.T())
+
+	cmd := s.cli.NewRootCmd()
+	cmd.SetArgs([]string{
+		"stack", "vapi", "notices",
+		"-f", "./testdata/stack_cmd_test.yaml",
+		"--stack.name", "test-stack",
+	})
+
+	err := cmd.Execute()
+	s.NoError(err)
+}
@@ -22,11 +22,32 @@ type (
 		Domain string
 	}
 
+	StackBackupConfig struct {
+		// Bucket is the s3 bucket of each region storing the backups. Backups are disabled if empty.
+		Bucket    string
+		Interval  time.Duration
+		Retention int
+	}
+
 	StackConfig struct {
 		ForceDelete     bool
 		SkipHealthCheck bool
-		Seoul           RegionalStackConfig
-		Singapore       RegionalStackConfig
+		Backup          StackBackupConfig
+		// KeyRotationGracePeriod is how long the previous jwt secret is accepted after rotating the keys of a stack
+		KeyRotationGracePeriod time.Duration
+		// ApiKeyVerifyURL is the url of the api depot server the ingresses of the stacks verify the stack api keys and
+		// count the requests against the monthly quotas with. it is required to serve, since neither is enforced at the
+		// ingresses and the api keys cannot be revoked without it.
+		ApiKeyVerifyURL string
+		// ApiKeyVerifySecret signs the stack ids in the addresses the ingresses call ApiKeyVerifyURL with, so that
+		// nothing else can verify api keys or count requests through it.
+		ApiKeyVerifySecret string
+		// GlobalDomain is the domain of the global endpoints of the multi-region stacks. its dns is expected to steer
+		// the requests to the nearest healthy zone, e.g. by geolocation steering health-checking the postgrest live
+		// path of each zone. stacks cannot be multi-region if empty.
+		GlobalDomain string
+		Seoul        RegionalStackConfig
+		Singapore    RegionalStackConfig
 	}
 
 	RegionalS3Config struct {
@@ -84,6 +105,15 @@ type (
 			Timeout time.Duration
 		}
 
+		Deployment struct {
+			Workers int
+		}
+
+		Secrets struct {
+			// KeyFile is the local key file of the master keys encrypting the secrets. Secrets are stored in plaintext if empty.
+			KeyFile string
+		}
+
 		DB   DBConfig
 		Stoa struct {
 			URL      string
@@ -95,6 +125,8 @@ type (
 			ClientId      string
 			ClientSecret  string
 			AppPrivateKey string
+			// WebhookSecret signs the deliveries of the github webhook. The webhook is disabled if empty.
+			WebhookSecret string
 		}
 
 		K8s KubernetesConfig
@@ -133,6 +165,13 @@ func (c *ApiDepotServerConfig) Validate() error {
 		return errors.New("s3.accessKey is required")
 	}
 
+	// every owner has a monthly request quota, which is counted only by the ingresses verifying the api keys
+	if c.Stack.ApiKeyVerifyURL == "" {
+		return errors.New("stack.apiKeyVerifyUrl is required to enforce the monthly request quotas")
+	} else if c.Stack.ApiKeyVerifySecret == "" {
+		return errors.New("stack.apiKeyVerifySecret is required with stack.apiKeyVerifyUrl")
+	}
+
 	return nil
 }
 
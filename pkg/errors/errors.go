@@ -12,4 +12,5 @@ var (
 	ErrPreconditionRequired = errors.New("precondition required")
 	ErrTimeout              = errors.New("timeout")
 	ErrUnauthorized         = errors.New("unauthorized")
+	ErrQuotaExceeded        = errors.New("quota exceeded")
 )
@@ -0,0 +1,127 @@
+package billing
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"slices"
+	"time"
+)
+
+func (s *service) GenerateInvoices(
+	ctx context.Context,
+	period time.Time,
+) ([]domain.Invoice, error) {
+	begin := PeriodOf(period)
+	end := begin.AddDate(0, 1, 0)
+	if end.After(time.Now()) {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "period %s is not closed yet", begin.Format("2006-01"))
+	}
+
+	var invoices []domain.Invoice
+	if err := helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		usages, err := collectUsage(tx, nil, begin, end)
+		if err != nil {
+			return err
+		}
+
+		userIds := make([]uint, 0, len(usages))
+		for userId := range usages {
+			userIds = append(userIds, userId)
+		}
+		slices.Sort(userIds)
+
+		for _, userId := range userIds {
+			u := usages[userId]
+
+			invoice := domain.Invoice{
+				UserID: userId,
+				Period: begin,
+			}
+			if err := tx.
+				Where("user_id = ? AND period = ?", userId, begin).
+				FirstOrCreate(&invoice).
+				Error; err != nil {
+				return errors.Wrapf(err, "failed to get invoice")
+			}
+
+			items := slices.DeleteFunc(u.items, func(item domain.InvoiceItem) bool {
+				return item.RunningSeconds == 0 && item.StorageByteDays == 0
+			})
+			for i := range items {
+				items[i].InvoiceID = invoice.ID
+			}
+			if len(items) > 0 {
+				if err := tx.Omit(clause.Associations).Create(&items).Error; err != nil {
+					return errors.Wrapf(err, "failed to create invoice items")
+				}
+			}
+
+			if err := markBilled(tx, &domain.InstanceHistory{}, u.instanceHistories); err != nil {
+				return err
+			}
+			if err := markBilled(tx, &domain.StackHistory{}, u.stackHistories); err != nil {
+				return err
+			}
+
+			logger.Info("invoice generated", "userId", userId, "period", begin, "numItems", len(items))
+			invoice.Items = append(invoice.Items, items...)
+			invoices = append(invoices, invoice)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return invoices, nil
+}
+
+func markBilled(tx *gorm.DB, model any, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return errors.Wrapf(
+		tx.Model(model).Where("id IN ?", ids).Update("billed", true).Error,
+		"failed to mark histories as billed",
+	)
+}
+
+func (s *service) GetInvoices(ctx context.Context) ([]domain.Invoice, error) {
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.FindInvoicesByUserID(helpers.GetTx(ctx), user.ID)
+}
+
+func (s *service) GetCurrentUsage(ctx context.Context) (*domain.Invoice, error) {
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	begin := PeriodOf(now)
+	usages, err := collectUsage(helpers.GetTx(ctx), &user.ID, begin, now)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := domain.Invoice{
+		UserID: user.ID,
+		User:   *user,
+		Period: begin,
+	}
+	if u, ok := usages[user.ID]; ok {
+		invoice.Items = u.items
+	}
+
+	return &invoice, nil
+}
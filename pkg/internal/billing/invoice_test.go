@@ -0,0 +1,189 @@
+package billing_test
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/billing"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+func (s *BillingTestSuite) TestGivenLastMonthHistoriesWhenGenerateInvoicesTwiceThenShouldBillOnce() {
+	// Given
+	owner := domain.User{
+		Name:       "test",
+		AuthUserId: "test",
+	}
+	s.Require().NoError(owner.Save(s.db))
+
+	stack := domain.Stack{
+		Name: "test-stack",
+		Project: domain.Project{
+			Name:  "test-project",
+			Owner: owner,
+		},
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	instance := domain.Instance{
+		Name:  "test-instance",
+		State: domain.InstanceStateRunning,
+		Stack: stack,
+	}
+	s.Require().NoError(instance.Save(s.db))
+
+	lastMonth := billing.PeriodOf(time.Now()).AddDate(0, -1, 0)
+	for i := 0; i < 3; i++ {
+		at := lastMonth.Add(time.Duration(i) * time.Hour)
+
+		instanceHistory := domain.InstanceHistory{
+			InstanceID: instance.ID,
+			Running:    true,
+		}
+		instanceHistory.CreatedAt = at
+		s.Require().NoError(instanceHistory.Save(s.db))
+
+		stackHistory := domain.StackHistory{
+			StackID:     stack.ID,
+			StorageSize: 1024,
+		}
+		stackHistory.CreatedAt = at
+		s.Require().NoError(stackHistory.Save(s.db))
+	}
+
+	// When
+	invoices, err := s.service.GenerateInvoices(s, lastMonth)
+	s.Require().NoError(err)
+	_, err = s.service.GenerateInvoices(s, lastMonth)
+	s.Require().NoError(err)
+
+	// Then
+	s.Require().Len(invoices, 1)
+	s.Require().Len(invoices[0].Items, 2)
+	s.Equal(domain.InvoiceItemKindInstance, invoices[0].Items[0].Kind)
+	s.Equal(int64(2*60*60), invoices[0].Items[0].RunningSeconds)
+	s.Equal(domain.InvoiceItemKindStorage, invoices[0].Items[1].Kind)
+	s.InDelta(1024.0/12, invoices[0].Items[1].StorageByteDays, 0.001)
+
+	s.userService.On("GetUser", mock.Anything).Return(&owner, nil)
+	saved, err := s.service.GetInvoices(s)
+	s.Require().NoError(err)
+	s.Require().Len(saved, 1)
+	s.Require().Len(saved[0].Items, 2)
+	s.Equal(stack.Name, saved[0].Items[0].Stack.Name)
+
+	var numUnbilled int64
+	s.Require().NoError(s.db.Model(&domain.InstanceHistory{}).Where("billed = false").Count(&numUnbilled).Error)
+	s.Zero(numUnbilled)
+}
+
+func (s *BillingTestSuite) TestGivenStoppedInstanceHistoriesWhenGenerateInvoicesThenShouldNotBillStoppedTime() {
+	// Given
+	owner := domain.User{
+		Name:       "test",
+		AuthUserId: "test",
+	}
+	s.Require().NoError(owner.Save(s.db))
+
+	stack := domain.Stack{
+		Name: "test-stack",
+		Project: domain.Project{
+			Name:  "test-project",
+			Owner: owner,
+		},
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	instance := domain.Instance{
+		Name:  "test-instance",
+		State: domain.InstanceStateRunning,
+		Stack: stack,
+	}
+	s.Require().NoError(instance.Save(s.db))
+
+	lastMonth := billing.PeriodOf(time.Now()).AddDate(0, -1, 0)
+	for i, running := range []bool{true, false, false, true, true} {
+		instanceHistory := domain.InstanceHistory{
+			InstanceID: instance.ID,
+			Running:    running,
+		}
+		instanceHistory.CreatedAt = lastMonth.Add(time.Duration(i) * time.Hour)
+		s.Require().NoError(instanceHistory.Save(s.db))
+	}
+
+	// When
+	invoices, err := s.service.GenerateInvoices(s, lastMonth)
+	s.Require().NoError(err)
+
+	// Then
+	s.Require().Len(invoices, 1)
+	s.Require().Len(invoices[0].Items, 1)
+	s.Equal(int64(2*60*60), invoices[0].Items[0].RunningSeconds)
+
+	var numUnbilled int64
+	s.Require().NoError(s.db.Model(&domain.InstanceHistory{}).Where("billed = false").Count(&numUnbilled).Error)
+	s.Zero(numUnbilled)
+}
+
+func (s *BillingTestSuite) TestGivenIntervalCrossingMonthStartWhenGenerateInvoicesThenShouldSplitIt() {
+	// Given
+	owner := domain.User{
+		Name:       "test",
+		AuthUserId: "test",
+	}
+	s.Require().NoError(owner.Save(s.db))
+
+	stack := domain.Stack{
+		Name: "test-stack",
+		Project: domain.Project{
+			Name:  "test-project",
+			Owner: owner,
+		},
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	instance := domain.Instance{
+		Name:  "test-instance",
+		State: domain.InstanceStateRunning,
+		Stack: stack,
+	}
+	s.Require().NoError(instance.Save(s.db))
+
+	lastMonth := billing.PeriodOf(time.Now()).AddDate(0, -1, 0)
+	twoMonthsAgo := lastMonth.AddDate(0, -1, 0)
+	for _, at := range []time.Time{
+		lastMonth.Add(-30 * time.Minute),
+		lastMonth.Add(time.Hour),
+		lastMonth.AddDate(0, 1, 0).Add(-time.Hour),
+		lastMonth.AddDate(0, 1, 0).Add(20 * time.Minute),
+	} {
+		instanceHistory := domain.InstanceHistory{
+			InstanceID: instance.ID,
+			Running:    true,
+		}
+		instanceHistory.CreatedAt = at
+		s.Require().NoError(instanceHistory.Save(s.db))
+	}
+
+	// When
+	previousInvoices, err := s.service.GenerateInvoices(s, twoMonthsAgo)
+	s.Require().NoError(err)
+	invoices, err := s.service.GenerateInvoices(s, lastMonth)
+	s.Require().NoError(err)
+
+	// Then
+	s.Require().Len(previousInvoices, 1)
+	s.Require().Len(previousInvoices[0].Items, 1)
+	s.Equal(int64(30*60), previousInvoices[0].Items[0].RunningSeconds)
+
+	s.Require().Len(invoices, 1)
+	s.Require().Len(invoices[0].Items, 1)
+	s.Equal(lastMonth.AddDate(0, 1, 0).Sub(lastMonth).Milliseconds()/1000, invoices[0].Items[0].RunningSeconds)
+}
+
+func (s *BillingTestSuite) TestGivenCurrentMonthWhenGenerateInvoicesThenShouldFail() {
+	// When
+	_, err := s.service.GenerateInvoices(s, time.Now())
+
+	// Then
+	s.Require().Error(err)
+}
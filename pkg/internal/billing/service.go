@@ -0,0 +1,46 @@
+package billing
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	tclog "github.com/habiliai/apidepot/pkg/internal/log"
+	"github.com/habiliai/apidepot/pkg/internal/user"
+	"time"
+)
+
+type (
+	Service interface {
+		// GenerateInvoices bills the unbilled histories of the month containing period. It is safe to run repeatedly.
+		GenerateInvoices(ctx context.Context, period time.Time) ([]domain.Invoice, error)
+		GetInvoices(ctx context.Context) ([]domain.Invoice, error)
+		// GetCurrentUsage returns the not yet billed usage of the current month as an unsaved invoice.
+		GetCurrentUsage(ctx context.Context) (*domain.Invoice, error)
+	}
+
+	service struct {
+		users user.Service
+	}
+)
+
+const (
+	ServiceKey digo.ObjectKey = "billingService"
+)
+
+var (
+	_      Service = (*service)(nil)
+	logger         = tclog.GetLogger()
+)
+
+func init() {
+	digo.ProvideService(ServiceKey, func(container *digo.Container) (any, error) {
+		users, err := digo.Get[user.Service](container, user.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &service{
+			users: users,
+		}, nil
+	})
+}
@@ -0,0 +1,39 @@
+package billing_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/billing"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/habiliai/apidepot/pkg/internal/user"
+	usertest "github.com/habiliai/apidepot/pkg/internal/user/test"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"testing"
+)
+
+type BillingTestSuite struct {
+	suite.Suite
+	context.Context
+
+	db          *gorm.DB
+	service     billing.Service
+	userService *usertest.Service
+}
+
+func (s *BillingTestSuite) SetupTest() {
+	s.Context = context.TODO()
+
+	container := digo.NewContainer(s, digo.EnvTest, nil)
+	s.db = digo.MustGet[*gorm.DB](container, services.ServiceKeyDB)
+	s.Context = helpers.WithTx(s.Context, s.db)
+
+	s.userService = usertest.NewService()
+	digo.Set(container, user.ServiceKey, s.userService)
+	s.service = digo.MustGet[billing.Service](container, billing.ServiceKey)
+}
+
+func TestBillingService(t *testing.T) {
+	suite.Run(t, new(BillingTestSuite))
+}
@@ -0,0 +1,246 @@
+package billing
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"slices"
+	"time"
+)
+
+type usage struct {
+	items             []domain.InvoiceItem
+	instanceHistories []uint
+	stackHistories    []uint
+}
+
+// PeriodOf returns the first moment of the month containing t in UTC.
+func PeriodOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// collectUsage aggregates the unbilled histories written in [begin, end) per project owner.
+// If userId is given, only the usage of the user is collected.
+func collectUsage(
+	tx *gorm.DB,
+	userId *uint,
+	begin time.Time,
+	end time.Time,
+) (map[uint]*usage, error) {
+	// deleted instances and stacks are still billed for the time they were alive
+	tx = tx.Unscoped().Session(&gorm.Session{})
+
+	var instanceHistories []domain.InstanceHistory
+	stmt := tx.
+		InnerJoins("Instance").
+		InnerJoins("Instance.Stack").
+		InnerJoins("Instance.Stack.Project")
+	if userId != nil {
+		// projects are the only joined table having owner_id
+		stmt = stmt.Where("owner_id = ?", *userId)
+	}
+	if err := stmt.
+		// the stopped samples close the running intervals before them
+		Where("instance_histories.deleted_at = 0 AND instance_histories.billed = false").
+		Where("instance_histories.created_at >= ? AND instance_histories.created_at < ?", begin, end).
+		Order("instance_histories.created_at ASC").
+		Find(&instanceHistories).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find instance histories")
+	}
+
+	var stackHistories []domain.StackHistory
+	stmt = tx.
+		InnerJoins("Stack").
+		InnerJoins("Stack.Project")
+	if userId != nil {
+		stmt = stmt.Where("owner_id = ?", *userId)
+	}
+	if err := stmt.
+		Where("stack_histories.billed = false").
+		Where("stack_histories.created_at >= ? AND stack_histories.created_at < ?", begin, end).
+		Order("stack_histories.created_at ASC").
+		Find(&stackHistories).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack histories")
+	}
+
+	usages := map[uint]*usage{}
+	getUsage := func(ownerId uint) *usage {
+		u, ok := usages[ownerId]
+		if !ok {
+			u = &usage{}
+			usages[ownerId] = u
+		}
+		return u
+	}
+
+	instanceIds := make([]uint, 0, len(instanceHistories))
+	for _, hist := range instanceHistories {
+		if !slices.Contains(instanceIds, hist.InstanceID) {
+			instanceIds = append(instanceIds, hist.InstanceID)
+		}
+	}
+	previous, err := findBoundaryInstanceHistories(tx, instanceIds, "created_at < ?", begin, "created_at DESC")
+	if err != nil {
+		return nil, err
+	}
+	next, err := findBoundaryInstanceHistories(tx, instanceIds, "created_at >= ?", end, "created_at ASC")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range aggregateInstanceHistories(instanceHistories, previous, next, begin, end) {
+		u := getUsage(item.Stack.Project.OwnerID)
+		u.items = append(u.items, item)
+	}
+	for _, hist := range instanceHistories {
+		u := getUsage(hist.Instance.Stack.Project.OwnerID)
+		u.instanceHistories = append(u.instanceHistories, hist.ID)
+	}
+
+	for _, item := range aggregateStackHistories(stackHistories) {
+		u := getUsage(item.Stack.Project.OwnerID)
+		u.items = append(u.items, item)
+	}
+	for _, hist := range stackHistories {
+		u := getUsage(hist.Stack.Project.OwnerID)
+		u.stackHistories = append(u.stackHistories, hist.ID)
+	}
+
+	return usages, nil
+}
+
+// findBoundaryInstanceHistories returns the first history of each instance matching the condition in the order,
+// whether it has been billed or not.
+func findBoundaryInstanceHistories(
+	tx *gorm.DB,
+	instanceIds []uint,
+	cond string,
+	at time.Time,
+	order string,
+) (map[uint]domain.InstanceHistory, error) {
+	if len(instanceIds) == 0 {
+		return nil, nil
+	}
+
+	var histories []domain.InstanceHistory
+	if err := tx.
+		Select("DISTINCT ON (instance_id) *").
+		Where("deleted_at = 0 AND instance_id IN ?", instanceIds).
+		Where(cond, at).
+		Order("instance_id ASC, " + order).
+		Find(&histories).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find boundary instance histories")
+	}
+
+	results := make(map[uint]domain.InstanceHistory, len(histories))
+	for _, hist := range histories {
+		results[hist.InstanceID] = hist
+	}
+
+	return results, nil
+}
+
+// aggregateInstanceHistories sums the time from each running sample of each instance to its next sample, so that the
+// time after the instance has been stopped is not billed. The intervals crossing the boundaries of [begin, end) are
+// split at them with the samples before and after the period, previous and next, so that each part is billed in its
+// own period. The histories must be sorted by their creation time.
+func aggregateInstanceHistories(
+	histories []domain.InstanceHistory,
+	previous map[uint]domain.InstanceHistory,
+	next map[uint]domain.InstanceHistory,
+	begin time.Time,
+	end time.Time,
+) []domain.InvoiceItem {
+	var (
+		items []domain.InvoiceItem
+		index = map[uint]int{}
+		last  = map[uint]domain.InstanceHistory{}
+	)
+	bill := func(hist domain.InstanceHistory, running time.Duration) {
+		i, ok := index[hist.InstanceID]
+		if !ok {
+			i = len(items)
+			index[hist.InstanceID] = i
+			items = append(items, domain.InvoiceItem{
+				StackID:    hist.Instance.StackID,
+				Stack:      hist.Instance.Stack,
+				Kind:       domain.InvoiceItemKindInstance,
+				InstanceID: &hist.InstanceID,
+				Zone:       hist.Instance.Zone,
+			})
+		}
+		items[i].RunningSeconds += int64(running.Seconds())
+	}
+
+	for _, hist := range histories {
+		prev, ok := last[hist.InstanceID]
+		since := prev.CreatedAt
+		if !ok {
+			prev, ok = previous[hist.InstanceID]
+			since = begin
+		}
+
+		if ok && prev.Running {
+			bill(hist, hist.CreatedAt.Sub(since))
+		} else if hist.Running {
+			bill(hist, 0)
+		}
+		last[hist.InstanceID] = hist
+	}
+
+	// the time until the next sample is not known yet for the instances without one
+	for _, hist := range histories {
+		if _, ok := next[hist.InstanceID]; ok && hist.Running && last[hist.InstanceID].ID == hist.ID {
+			bill(hist, end.Sub(hist.CreatedAt))
+		}
+	}
+
+	return sortItems(items)
+}
+
+// aggregateStackHistories integrates the sampled storage size of each stack over time.
+// The histories must be sorted by their creation time.
+func aggregateStackHistories(histories []domain.StackHistory) []domain.InvoiceItem {
+	var (
+		items    []domain.InvoiceItem
+		index    = map[uint]int{}
+		previous = map[uint]domain.StackHistory{}
+	)
+	for _, hist := range histories {
+		i, ok := index[hist.StackID]
+		if !ok {
+			i = len(items)
+			index[hist.StackID] = i
+			items = append(items, domain.InvoiceItem{
+				StackID: hist.StackID,
+				Stack:   hist.Stack,
+				Kind:    domain.InvoiceItemKindStorage,
+			})
+		} else {
+			prev := previous[hist.StackID]
+			days := hist.CreatedAt.Sub(prev.CreatedAt).Hours() / 24
+			items[i].StorageByteDays += float64(prev.StorageSize) * days
+		}
+		previous[hist.StackID] = hist
+	}
+
+	return sortItems(items)
+}
+
+func sortItems(items []domain.InvoiceItem) []domain.InvoiceItem {
+	slices.SortStableFunc(items, func(lhs, rhs domain.InvoiceItem) int {
+		if lhs.StackID != rhs.StackID {
+			return int(lhs.StackID) - int(rhs.StackID)
+		}
+		if lhs.InstanceID == nil || rhs.InstanceID == nil {
+			return 0
+		}
+		return int(*lhs.InstanceID) - int(*rhs.InstanceID)
+	})
+
+	return items
+}
@@ -0,0 +1,5 @@
+package constants
+
+const (
+	MaxInstanceReplicas = 10
+)
@@ -5,8 +5,12 @@ const (
 	PathPostgrest      = "/postgrest/v1"
 	PathAuth           = "/auth/v1"
 	PathVapis          = "/vapis/v1"
+	PathCustomVapis    = "/custom-vapis/v1"
 	PathPostgrestReady = PathPostgrest + "/ready"
 	PathPostgrestLive  = PathPostgrest + "/live"
 	PathStorageHealth  = PathStorage + "/health"
 	PathAuthHealth     = PathAuth + "/health"
+	PathPreview        = "/_preview"
+	PathApiKeyVerify   = "/_internal/api-keys/verify"
+	PathGithubWebhook  = "/_webhooks/github"
 )
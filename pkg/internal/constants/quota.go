@@ -0,0 +1,7 @@
+package constants
+
+const (
+	// DefaultMonthlyRequestQuota is the number of the requests a month to all the stacks of a user
+	// who doesn't have their own quota.
+	DefaultMonthlyRequestQuota = 1_000_000
+)
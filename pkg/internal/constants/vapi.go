@@ -8,4 +8,22 @@ const (
 	VapiYamlFileName      = "apidepot.yml"
 	VapiHealthPath        = "/_internal/health"
 	CustomVapiBucketId    = "custom-vapis"
+
+	DefaultVapiCPURequest         = "50m"
+	DefaultVapiMemoryRequest      = "128Mi"
+	DefaultVapiMemoryLimit        = "128Mi"
+	DefaultVapiEdgeRuntimeVersion = "v1.33.5"
+
+	// DefaultVapiMilliCPUQuota and DefaultVapiMemoryQuota bound the resources of a single vapi container
+	// for users who don't have their own quota.
+	DefaultVapiMilliCPUQuota = 1000
+	DefaultVapiMemoryQuota   = 1 * units.GiB
+)
+
+var (
+	VapiEdgeRuntimeVersions = []string{
+		"v1.33.5",
+		"v1.45.2",
+		"v1.58.3",
+	}
 )
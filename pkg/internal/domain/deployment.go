@@ -0,0 +1,242 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+type (
+	DeploymentStatus string
+	DeploymentPhase  string
+)
+
+const (
+	DeploymentStatusQueued    DeploymentStatus = "queued"
+	DeploymentStatusRunning   DeploymentStatus = "running"
+	DeploymentStatusSucceeded DeploymentStatus = "succeeded"
+	DeploymentStatusFailed    DeploymentStatus = "failed"
+	DeploymentStatusCanceled  DeploymentStatus = "canceled"
+
+	DeploymentPhaseNone      DeploymentPhase = ""
+	DeploymentPhaseRender    DeploymentPhase = "render"
+	DeploymentPhaseApply     DeploymentPhase = "apply"
+	DeploymentPhasePodsReady DeploymentPhase = "pods-ready"
+	DeploymentPhaseHealth    DeploymentPhase = "health"
+	DeploymentPhaseMigrate   DeploymentPhase = "migrate"
+)
+
+// Deployment is a queued request to deploy the stack of an instance, run by the deployment workers.
+type Deployment struct {
+	Model
+
+	InstanceID uint     `gorm:"index"`
+	Instance   Instance `gorm:"foreignKey:InstanceID"`
+
+	RequestedByID uint
+	RequestedBy   User `gorm:"foreignKey:RequestedByID"`
+
+	Strategy string
+	Timeout  string
+
+	Status DeploymentStatus `gorm:"index"`
+	Phase  DeploymentPhase
+	Error  string
+
+	// CancelRequested is set to let the worker running the deployment stop it.
+	CancelRequested bool
+	// HeartbeatAt is refreshed by the worker while running, so that deployments of a lost worker can be failed.
+	HeartbeatAt *time.Time
+	StartedAt   *time.Time
+	FinishedAt  *time.Time
+}
+
+// DeploymentEvent is emitted whenever a deployment enters a phase or finishes.
+type DeploymentEvent struct {
+	Model
+
+	DeploymentID uint       `gorm:"index"`
+	Deployment   Deployment `gorm:"foreignKey:DeploymentID"`
+
+	Phase   DeploymentPhase
+	Status  DeploymentStatus
+	Message string
+}
+
+func (d *Deployment) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(d).Error, "failed to save deployment")
+}
+
+// Enqueue saves the deployment as queued for the workers.
+func (d *Deployment) Enqueue(db *gorm.DB) error {
+	d.Status = DeploymentStatusQueued
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(d).Error; err != nil {
+			return errors.Wrapf(err, "failed to create deployment")
+		}
+
+		return d.Emit(tx, "queued")
+	})
+}
+
+func (d *Deployment) IsFinished() bool {
+	switch d.Status {
+	case DeploymentStatusSucceeded, DeploymentStatusFailed, DeploymentStatusCanceled:
+		return true
+	default:
+		return false
+	}
+}
+
+// Emit saves the phase or the status the deployment has changed to and records it as an event.
+// The cancel request and the heartbeat are left as they are, since they are updated by others while running.
+func (d *Deployment) Emit(db *gorm.DB, message string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(d).
+			Select("Status", "Phase", "Error", "StartedAt", "FinishedAt").
+			Updates(d).Error; err != nil {
+			return errors.Wrapf(err, "failed to update deployment")
+		}
+
+		event := DeploymentEvent{
+			DeploymentID: d.ID,
+			Phase:        d.Phase,
+			Status:       d.Status,
+			Message:      message,
+		}
+		return errors.Wrapf(tx.Create(&event).Error, "failed to create deployment event")
+	})
+}
+
+// Heartbeat tells that the worker running the deployment is alive and returns whether it has been asked to cancel.
+func (d *Deployment) Heartbeat(db *gorm.DB) (bool, error) {
+	if err := db.Model(d).UpdateColumn("heartbeat_at", time.Now()).Error; err != nil {
+		return false, errors.Wrapf(err, "failed to update deployment heartbeat")
+	}
+
+	var cancelRequested bool
+	if err := db.Model(&Deployment{}).
+		Where("id = ?", d.ID).
+		Select("cancel_requested").
+		Scan(&cancelRequested).Error; err != nil {
+		return false, errors.Wrapf(err, "failed to find deployment cancel request")
+	}
+
+	return cancelRequested, nil
+}
+
+func (d *Deployment) RequestCancel(db *gorm.DB) error {
+	d.CancelRequested = true
+	return errors.Wrapf(db.Model(d).UpdateColumn("cancel_requested", true).Error, "failed to request deployment cancel")
+}
+
+// Finish moves the deployment to the given final status, recording err as the reason if any.
+func (d *Deployment) Finish(db *gorm.DB, status DeploymentStatus, err error) error {
+	d.Status = status
+	d.FinishedAt = gog.PtrOf(time.Now())
+	message := string(status)
+	if err != nil {
+		d.Error = err.Error()
+		message = d.Error
+	}
+
+	return d.Emit(db, message)
+}
+
+func FindDeploymentById(db *gorm.DB, id uint, options ...FindOptions) (*Deployment, error) {
+	opt := MergeFindOptions(options...)
+	if opt.locking != nil {
+		db = db.Clauses(*opt.locking)
+	}
+
+	var d Deployment
+	if err := db.First(&d, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.Wrapf(tclerrors.ErrNotFound, "deployment not found")
+		}
+		return nil, errors.Wrapf(err, "failed to find deployment")
+	}
+
+	return &d, nil
+}
+
+// FindActiveDeploymentByInstanceId returns the queued or running deployment of the instance.
+func FindActiveDeploymentByInstanceId(db *gorm.DB, instanceId uint) (*Deployment, error) {
+	var d Deployment
+	if err := db.
+		Where("instance_id = ? AND status IN ?", instanceId, []DeploymentStatus{DeploymentStatusQueued, DeploymentStatusRunning}).
+		Order("id").
+		Limit(1).
+		Find(&d).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find active deployment")
+	} else if d.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "instance has no active deployment")
+	}
+
+	return &d, nil
+}
+
+// ClaimQueuedDeployment locks the oldest queued deployment and marks it as running.
+// Deployments locked by other workers are skipped, so it is safe to call concurrently.
+func ClaimQueuedDeployment(db *gorm.DB) (*Deployment, error) {
+	var d Deployment
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ?", DeploymentStatusQueued).
+			Order("id").
+			Limit(1).
+			Find(&d).Error; err != nil {
+			return errors.Wrapf(err, "failed to find queued deployment")
+		} else if d.ID == 0 {
+			return errors.Wrapf(tclerrors.ErrNotFound, "no queued deployment")
+		}
+
+		d.Status = DeploymentStatusRunning
+		d.StartedAt = gog.PtrOf(time.Now())
+		d.HeartbeatAt = d.StartedAt
+		if err := tx.Model(&d).UpdateColumn("heartbeat_at", d.HeartbeatAt).Error; err != nil {
+			return errors.Wrapf(err, "failed to update deployment heartbeat")
+		}
+
+		return d.Emit(tx, "started")
+	}); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// FailStaleDeployments fails the running deployments whose worker hasn't sent a heartbeat since the given time.
+func FailStaleDeployments(db *gorm.DB, since time.Time) error {
+	var deployments []Deployment
+	if err := db.
+		Where("status = ? AND heartbeat_at < ?", DeploymentStatusRunning, since).
+		Find(&deployments).Error; err != nil {
+		return errors.Wrapf(err, "failed to find stale deployments")
+	}
+
+	for _, d := range deployments {
+		if err := d.Finish(db, DeploymentStatusFailed, errors.New("deployment worker has been lost")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FindDeploymentEvents returns the events of the deployment emitted after the event with the given id, oldest first.
+func FindDeploymentEvents(db *gorm.DB, deploymentId uint, afterId uint) ([]DeploymentEvent, error) {
+	var events []DeploymentEvent
+	if err := db.
+		Where("deployment_id = ? AND id > ?", deploymentId, afterId).
+		Order("id").
+		Find(&events).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find deployment events")
+	}
+
+	return events, nil
+}
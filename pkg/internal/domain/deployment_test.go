@@ -0,0 +1,113 @@
+package domain_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *DomainTestSuite) TestGivenQueuedDeploymentsWhenClaimThenShouldRunOldestAndEmitEvents() {
+	// Given
+	user := domain.User{
+		Name: "test-user",
+	}
+	s.Require().NoError(user.Save(s.db))
+	project := domain.Project{
+		Name:  "project-1",
+		Owner: user,
+	}
+	s.Require().NoError(project.Save(s.db))
+	stack := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "stack-1",
+	}
+	s.Require().NoError(stack.Save(s.db))
+	instance := domain.Instance{StackID: stack.ID, Zone: "zone-1"}
+	s.Require().NoError(instance.Save(s.db))
+
+	deployments := []domain.Deployment{
+		{InstanceID: instance.ID, RequestedByID: user.ID, Strategy: "rolling", Timeout: "30s"},
+		{InstanceID: instance.ID, RequestedByID: user.ID, Strategy: "blue-green", Timeout: "30s"},
+	}
+	for i := range deployments {
+		s.Require().NoError(deployments[i].Enqueue(s.db))
+	}
+
+	// When
+	claimed, err := domain.ClaimQueuedDeployment(s.db)
+	s.Require().NoError(err)
+	claimed.Phase = domain.DeploymentPhaseApply
+	s.Require().NoError(claimed.Emit(s.db, ""))
+	s.Require().NoError(claimed.Finish(s.db, domain.DeploymentStatusFailed, errors.New("boom")))
+
+	// Then
+	s.Equal(deployments[0].ID, claimed.ID)
+
+	active, err := domain.FindActiveDeploymentByInstanceId(s.db, instance.ID)
+	s.Require().NoError(err)
+	s.Equal(deployments[1].ID, active.ID)
+
+	deployment, err := domain.FindDeploymentById(s.db, claimed.ID)
+	s.Require().NoError(err)
+	s.Equal(domain.DeploymentStatusFailed, deployment.Status)
+	s.Equal("boom", deployment.Error)
+	s.True(deployment.IsFinished())
+
+	events, err := domain.FindDeploymentEvents(s.db, claimed.ID, 0)
+	s.Require().NoError(err)
+	s.Require().Len(events, 4)
+	s.Equal(domain.DeploymentStatusQueued, events[0].Status)
+	s.Equal(domain.DeploymentStatusRunning, events[1].Status)
+	s.Equal(domain.DeploymentPhaseApply, events[2].Phase)
+	s.Equal(domain.DeploymentStatusFailed, events[3].Status)
+
+	events, err = domain.FindDeploymentEvents(s.db, claimed.ID, events[2].ID)
+	s.Require().NoError(err)
+	s.Len(events, 1)
+}
+
+func (s *DomainTestSuite) TestGivenRunningDeploymentWithoutHeartbeatWhenFailStaleDeploymentsThenShouldBeFailed() {
+	// Given
+	user := domain.User{
+		Name: "test-user",
+	}
+	s.Require().NoError(user.Save(s.db))
+	project := domain.Project{
+		Name:  "project-1",
+		Owner: user,
+	}
+	s.Require().NoError(project.Save(s.db))
+	stack := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "stack-1",
+	}
+	s.Require().NoError(stack.Save(s.db))
+	instance := domain.Instance{StackID: stack.ID, Zone: "zone-1"}
+	s.Require().NoError(instance.Save(s.db))
+
+	deployment := domain.Deployment{InstanceID: instance.ID, RequestedByID: user.ID, Timeout: "30s"}
+	s.Require().NoError(deployment.Enqueue(s.db))
+	_, err := domain.ClaimQueuedDeployment(s.db)
+	s.Require().NoError(err)
+
+	cancelRequested, err := deployment.Heartbeat(s.db)
+	s.Require().NoError(err)
+	s.False(cancelRequested)
+	s.Require().NoError(deployment.RequestCancel(s.db))
+	cancelRequested, err = deployment.Heartbeat(s.db)
+	s.Require().NoError(err)
+	s.True(cancelRequested)
+
+	// When
+	s.Require().NoError(domain.FailStaleDeployments(s.db, time.Now().Add(time.Minute)))
+
+	// Then
+	_, err = domain.FindActiveDeploymentByInstanceId(s.db, instance.ID)
+	s.ErrorIs(err, tclerrors.ErrNotFound)
+
+	found, err := domain.FindDeploymentById(s.db, deployment.ID)
+	s.Require().NoError(err)
+	s.Equal(domain.DeploymentStatusFailed, found.Status)
+	s.NotNil(found.FinishedAt)
+}
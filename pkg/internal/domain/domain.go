@@ -46,6 +46,22 @@ $$ LANGUAGE plpgsql;
 			&CustomVapi{},
 			&TelegramMiniappPromotion{},
 			&TelegramMiniappPromotionView{},
+			&StackVapiLock{},
+			&OrganizationMember{},
+			&Invoice{},
+			&InvoiceItem{},
+			&InstanceRevision{},
+			&Deployment{},
+			&DeploymentEvent{},
+			&StackBackup{},
+			&StackApiKey{},
+			&StackRequestUsage{},
+			&StackCustomDomain{},
+			&VapiPackageBorrower{},
+			&StackVapiNotice{},
+			&GithubDelivery{},
+			&GithubDeliveryAction{},
+			&StackPreview{},
 		), "failed to auto migrate"); err != nil {
 		return err
 	}
@@ -61,6 +77,22 @@ $$ LANGUAGE plpgsql;
 
 func DropAll(db *gorm.DB) error {
 	return db.Migrator().DropTable(
+		&StackPreview{},
+		&GithubDeliveryAction{},
+		&GithubDelivery{},
+		&StackVapiNotice{},
+		&VapiPackageBorrower{},
+		&StackCustomDomain{},
+		&StackRequestUsage{},
+		&StackApiKey{},
+		&StackBackup{},
+		&DeploymentEvent{},
+		&Deployment{},
+		&InstanceRevision{},
+		&InvoiceItem{},
+		&Invoice{},
+		&OrganizationMember{},
+		&StackVapiLock{},
 		&TelegramMiniappPromotionView{},
 		&TelegramMiniappPromotion{},
 		&CustomVapi{},
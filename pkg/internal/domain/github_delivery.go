@@ -0,0 +1,162 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type (
+	GithubDeliveryStatus     string
+	GithubDeliveryActionKind string
+)
+
+const (
+	GithubDeliveryStatusPending   GithubDeliveryStatus = "pending"
+	GithubDeliveryStatusProcessed GithubDeliveryStatus = "processed"
+	GithubDeliveryStatusIgnored   GithubDeliveryStatus = "ignored"
+	GithubDeliveryStatusFailed    GithubDeliveryStatus = "failed"
+
+	GithubDeliveryActionRegisterVapi       GithubDeliveryActionKind = "register-vapi"
+	GithubDeliveryActionRebuildCustomVapis GithubDeliveryActionKind = "rebuild-custom-vapis"
+	GithubDeliveryActionDeploy             GithubDeliveryActionKind = "deploy"
+	GithubDeliveryActionCreatePreview      GithubDeliveryActionKind = "create-preview"
+	GithubDeliveryActionCommentPreview     GithubDeliveryActionKind = "comment-preview"
+	GithubDeliveryActionDeletePreview      GithubDeliveryActionKind = "delete-preview"
+)
+
+// GithubDelivery is a push to a git repository or a pull request event delivered by the github webhook, kept with what
+// it has triggered.
+type GithubDelivery struct {
+	Model
+
+	// GUID is the X-GitHub-Delivery header, which is kept on redeliveries
+	GUID string `gorm:"uniqueIndex"`
+	// Event is push or pull_request suffixed with the action, e.g. pull_request.opened
+	Event          string
+	Repo           string `gorm:"index"`
+	Ref            string
+	CommitHash     string
+	InstallationID int64
+
+	Status GithubDeliveryStatus
+	Error  string
+
+	Actions []GithubDeliveryAction `gorm:"foreignKey:DeliveryID"`
+}
+
+// GithubDeliveryAction is a registration, a rebuild, a deployment or a change of a preview triggered by a github
+// delivery.
+type GithubDeliveryAction struct {
+	Model
+
+	DeliveryID uint           `gorm:"index"`
+	Delivery   GithubDelivery `gorm:"foreignKey:DeliveryID"`
+
+	Kind          GithubDeliveryActionKind
+	VapiPackageID *uint `gorm:"index"`
+	StackID       *uint `gorm:"index"`
+	InstanceID    *uint
+
+	// Result tells what has been done, e.g. the registered version or the queued deployment
+	Result string
+	Error  string
+}
+
+func (d *GithubDelivery) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Omit("Actions").Save(d).Error, "failed to save github delivery")
+}
+
+// Finish saves the status of the delivery with the actions it has triggered.
+func (d *GithubDelivery) Finish(db *gorm.DB, status GithubDeliveryStatus, actions []GithubDeliveryAction, err error) error {
+	d.Status = status
+	d.Error = ""
+	if err != nil {
+		d.Error = err.Error()
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("delivery_id = ?", d.ID).Delete(&GithubDeliveryAction{}).Error; err != nil {
+			return errors.Wrapf(err, "failed to delete github delivery actions")
+		}
+
+		for i := range actions {
+			actions[i].DeliveryID = d.ID
+		}
+		if len(actions) > 0 {
+			if err := tx.Omit("Delivery").Create(&actions).Error; err != nil {
+				return errors.Wrapf(err, "failed to create github delivery actions")
+			}
+		}
+		d.Actions = actions
+
+		return d.Save(tx)
+	})
+}
+
+// CreateGithubDeliveryIfNotExists creates an empty delivery of the guid unless there is one already, so that the
+// delivery can be locked even if it is delivered for the first time.
+func CreateGithubDeliveryIfNotExists(db *gorm.DB, guid string) error {
+	return errors.Wrapf(
+		db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "guid"}},
+			DoNothing: true,
+		}).Omit("Actions").Create(&GithubDelivery{GUID: guid}).Error,
+		"failed to create github delivery",
+	)
+}
+
+func FindGithubDeliveryByGUID(db *gorm.DB, guid string, options ...FindOptions) (*GithubDelivery, error) {
+	opt := MergeFindOptions(options...)
+	if opt.locking != nil {
+		db = db.Clauses(*opt.locking)
+	}
+
+	var d GithubDelivery
+	if err := db.Limit(1).Find(&d, "guid = ?", guid).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find github delivery")
+	} else if d.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "github delivery not found. guid=%s", guid)
+	}
+
+	return &d, nil
+}
+
+// FindGithubDeliveriesByStackId returns the latest deliveries having triggered actions on the stack, newest first.
+func FindGithubDeliveriesByStackId(db *gorm.DB, stackId uint, limit int) ([]GithubDelivery, error) {
+	return findGithubDeliveriesByAction(db, "stack_id = ?", stackId, limit)
+}
+
+// FindGithubDeliveriesByVapiPackageId returns the latest deliveries having triggered actions on the package, newest first.
+func FindGithubDeliveriesByVapiPackageId(db *gorm.DB, packageId uint, limit int) ([]GithubDelivery, error) {
+	return findGithubDeliveriesByAction(db, "vapi_package_id = ?", packageId, limit)
+}
+
+func findGithubDeliveriesByAction(db *gorm.DB, query string, id uint, limit int) ([]GithubDelivery, error) {
+	deliveryIds := db.
+		Session(&gorm.Session{NewDB: true}).
+		Model(&GithubDeliveryAction{}).
+		Select("delivery_id").
+		Where(query, id)
+
+	var deliveries []GithubDelivery
+	if err := db.
+		Preload("Actions", query, id).
+		Where("id IN (?)", deliveryIds).
+		Order("id DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find github deliveries")
+	}
+
+	return deliveries, nil
+}
+
+func DeleteGithubDeliveryActionsByStackId(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&GithubDeliveryAction{}).Error,
+		"failed to delete github delivery actions of the stack",
+	)
+}
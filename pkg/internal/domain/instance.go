@@ -3,6 +3,7 @@ package domain
 import (
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/pkg/errors"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/plugin/soft_delete"
 )
@@ -21,10 +22,55 @@ type (
 		State       InstanceState         `gorm:"default:0"`
 		Name        string
 
-		AppliedK8sYaml string
+		// ScalingTargets holds the autoscaling targets keyed by component, e.g. "auth" or "vapi".
+		ScalingTargets datatypes.JSONType[map[string]InstanceScalingTarget]
+
+		// AppliedK8sYaml and PreviousK8sYaml render the secrets of the stack, so they are encrypted at rest
+		AppliedK8sYaml string `gorm:"serializer:encrypted"`
+
+		// Color is the blue/green color receiving traffic. PreviousK8sYaml is the set of the other color,
+		// kept running after a blue/green deployment so that it can be rolled back to instantly.
+		Color           InstanceColor
+		PreviousColor   InstanceColor
+		PreviousK8sYaml string `gorm:"serializer:encrypted"`
+
+		// AutoDeploy subscribes the instance to the pushes to the git branch of its stack, which redeploy it.
+		AutoDeploy bool
 	}
 
 	InstanceState uint
+	InstanceColor string
+
+	InstanceScalingTarget struct {
+		CPUUtilization    *int32 `json:"cpu_utilization,omitempty"`
+		MemoryUtilization *int32 `json:"memory_utilization,omitempty"`
+	}
+)
+
+const (
+	InstanceComponentAuth       = "auth"
+	InstanceComponentStorage    = "storage"
+	InstanceComponentPostgrest  = "postgrest"
+	InstanceComponentVapi       = "vapi"
+	InstanceComponentCustomVapi = "custom-vapi"
+
+	DefaultCPUUtilization int32 = 80
+)
+
+var (
+	InstanceComponents = []string{
+		InstanceComponentAuth,
+		InstanceComponentStorage,
+		InstanceComponentPostgrest,
+		InstanceComponentVapi,
+		InstanceComponentCustomVapi,
+	}
+)
+
+const (
+	InstanceColorNone  InstanceColor = ""
+	InstanceColorBlue  InstanceColor = "blue"
+	InstanceColorGreen InstanceColor = "green"
 )
 
 const (
@@ -51,6 +97,40 @@ func (i *Instance) Delete(tx *gorm.DB) error {
 	return errors.Wrapf(tx.Delete(i).Error, "failed to delete instance")
 }
 
+// DesiredReplicas is the number of replicas each component runs with, and the lower bound while autoscaling.
+func (i *Instance) DesiredReplicas() uint {
+	return max(i.NumReplicas, 1)
+}
+
+// MaxDesiredReplicas is the upper bound of autoscaling. Autoscaling is disabled if it equals DesiredReplicas.
+func (i *Instance) MaxDesiredReplicas() uint {
+	return max(i.MaxReplicas, i.DesiredReplicas())
+}
+
+// ScalingTarget returns the autoscaling target of the component, falling back to the default CPU utilization.
+func (i *Instance) ScalingTarget(component string) InstanceScalingTarget {
+	target, ok := i.ScalingTargets.Data()[component]
+	if !ok || (target.CPUUtilization == nil && target.MemoryUtilization == nil) {
+		cpu := DefaultCPUUtilization
+		target.CPUUtilization = &cpu
+	}
+
+	return target
+}
+
+// NextColor is the color the next blue/green deployment is rendered with.
+func (i *Instance) NextColor() InstanceColor {
+	if i.Color == InstanceColorBlue {
+		return InstanceColorGreen
+	}
+
+	return InstanceColorBlue
+}
+
+func (i *Instance) CanRollback() bool {
+	return i.PreviousK8sYaml != ""
+}
+
 func (i *Instance) updateState(tx *gorm.DB, state InstanceState) error {
 	if state == InstanceStateNone {
 		return errors.Errorf("invalid state: %v", state)
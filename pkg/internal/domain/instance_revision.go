@@ -0,0 +1,105 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// InstanceRevision is a snapshot of what has been applied to an instance by a deployment or a rollback.
+type InstanceRevision struct {
+	Model
+
+	InstanceID uint     `gorm:"uniqueIndex:instance_revisions_idx_uniq"`
+	Instance   Instance `gorm:"foreignKey:InstanceID"`
+	Revision   uint     `gorm:"uniqueIndex:instance_revisions_idx_uniq"`
+
+	// K8sYaml and VapiEnvVars hold secrets, so they are encrypted at rest
+	K8sYaml        string `gorm:"serializer:encrypted"`
+	Color          InstanceColor
+	VapiReleaseIDs datatypes.JSONSlice[uint]
+	VapiEnvVars    datatypes.JSONSlice[StackVapiEnvVar] `gorm:"serializer:encrypted"`
+
+	DeployedByID uint
+	DeployedBy   User `gorm:"foreignKey:DeployedByID"`
+
+	// RollbackOf is the revision which has been re-applied to create this revision, if any.
+	RollbackOf *uint
+}
+
+func (r *InstanceRevision) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(r).Error, "failed to save instance revision")
+}
+
+// Create numbers the revision after the latest one of the instance and saves it.
+func (r *InstanceRevision) Create(db *gorm.DB) error {
+	var latest uint
+	if err := db.Model(&InstanceRevision{}).
+		Where("instance_id = ?", r.InstanceID).
+		Select("COALESCE(MAX(revision), 0)").
+		Scan(&latest).Error; err != nil {
+		return errors.Wrapf(err, "failed to find latest instance revision")
+	}
+
+	r.Revision = latest + 1
+	return errors.Wrapf(db.Create(r).Error, "failed to create instance revision")
+}
+
+func FindInstanceRevision(db *gorm.DB, instanceId uint, revision uint) (*InstanceRevision, error) {
+	var r InstanceRevision
+	if err := db.
+		Preload("DeployedBy").
+		Where("instance_id = ? AND revision = ?", instanceId, revision).
+		Find(&r).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find instance revision")
+	} else if r.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "revision %d not found", revision)
+	}
+
+	return &r, nil
+}
+
+func FindLatestInstanceRevision(db *gorm.DB, instanceId uint) (*InstanceRevision, error) {
+	var r InstanceRevision
+	if err := db.
+		Where("instance_id = ?", instanceId).
+		Order("revision DESC").
+		Limit(1).
+		Find(&r).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find latest instance revision")
+	} else if r.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "instance has no revision")
+	}
+
+	return &r, nil
+}
+
+// FindLatestInstanceRevisionByColor returns the latest revision which applied the given color.
+func FindLatestInstanceRevisionByColor(db *gorm.DB, instanceId uint, color InstanceColor) (*InstanceRevision, error) {
+	var r InstanceRevision
+	if err := db.
+		Where("instance_id = ? AND color = ?", instanceId, color).
+		Order("revision DESC").
+		Limit(1).
+		Find(&r).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find instance revision")
+	} else if r.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "revision not found")
+	}
+
+	return &r, nil
+}
+
+func FindInstanceRevisions(db *gorm.DB, instanceId uint) ([]InstanceRevision, error) {
+	var revisions []InstanceRevision
+	if err := db.
+		Preload("DeployedBy").
+		Where("instance_id = ?", instanceId).
+		Order("revision DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find instance revisions")
+	}
+
+	return revisions, nil
+}
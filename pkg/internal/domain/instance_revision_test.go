@@ -0,0 +1,60 @@
+package domain_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+)
+
+func (s *DomainTestSuite) TestGivenInstanceRevisionsWhenCreateThenShouldBeNumberedPerInstance() {
+	// Given
+	user := domain.User{
+		Name: "test-user",
+	}
+	s.Require().NoError(user.Save(s.db))
+	project := domain.Project{
+		Name:  "project-1",
+		Owner: user,
+	}
+	s.Require().NoError(project.Save(s.db))
+	stack := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "stack-1",
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	instances := []domain.Instance{
+		{StackID: stack.ID, Zone: "zone-1"},
+		{StackID: stack.ID, Zone: "zone-2"},
+	}
+	for i := range instances {
+		s.Require().NoError(instances[i].Save(s.db))
+	}
+
+	// When
+	for _, k8sYaml := range []string{"first", "second"} {
+		revision := domain.InstanceRevision{
+			InstanceID:   instances[0].ID,
+			K8sYaml:      k8sYaml,
+			DeployedByID: user.ID,
+		}
+		s.Require().NoError(revision.Create(s.db))
+	}
+	other := domain.InstanceRevision{
+		InstanceID:   instances[1].ID,
+		K8sYaml:      "other",
+		DeployedByID: user.ID,
+	}
+	s.Require().NoError(other.Create(s.db))
+
+	// Then
+	revisions, err := domain.FindInstanceRevisions(s.db, instances[0].ID)
+	s.Require().NoError(err)
+	s.Require().Len(revisions, 2)
+	s.Equal(uint(2), revisions[0].Revision)
+	s.Equal("second", revisions[0].K8sYaml)
+	s.Equal(user.Name, revisions[0].DeployedBy.Name)
+	s.Equal(uint(1), other.Revision)
+
+	_, err = domain.FindInstanceRevision(s.db, instances[1].ID, 2)
+	s.ErrorIs(err, tclerrors.ErrNotFound)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type (
+	InvoiceItemKind string
+
+	// Invoice collects the billed usage of a user for a calendar month.
+	Invoice struct {
+		Model
+
+		UserID uint `gorm:"uniqueIndex:invoices_user_period_idx_uniq"`
+		User   User `gorm:"foreignKey:UserID"`
+
+		// Period is the first day of the billed month in UTC.
+		Period time.Time `gorm:"uniqueIndex:invoices_user_period_idx_uniq"`
+
+		Items []InvoiceItem `gorm:"foreignKey:InvoiceID"`
+	}
+
+	InvoiceItem struct {
+		Model
+
+		InvoiceID uint
+		Invoice   Invoice `gorm:"foreignKey:InvoiceID"`
+
+		StackID uint
+		Stack   Stack `gorm:"foreignKey:StackID"`
+
+		Kind       InvoiceItemKind
+		InstanceID *uint
+		Zone       tcltypes.InstanceZone
+
+		RunningSeconds  int64
+		StorageByteDays float64
+	}
+)
+
+const (
+	InvoiceItemKindInstance InvoiceItemKind = "instance"
+	InvoiceItemKindStorage  InvoiceItemKind = "storage"
+)
+
+func (i *Invoice) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(i).Error, "failed to save invoice")
+}
+
+func (i *InvoiceItem) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(i).Error, "failed to save invoice item")
+}
+
+func FindInvoicesByUserID(db *gorm.DB, userId uint) ([]Invoice, error) {
+	var invoices []Invoice
+	if err := db.
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+		Preload("Items.Stack").
+		Where("user_id = ?", userId).
+		Order("period DESC").
+		Find(&invoices).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find invoices")
+	}
+
+	return invoices, nil
+}
@@ -2,17 +2,66 @@ package domain
 
 import (
 	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
 	"gorm.io/plugin/soft_delete"
 	"strings"
 )
 
-type Organization struct {
-	Model
-	soft_delete.DeletedAt
+type (
+	OrganizationRole string
 
-	Name string `gorm:"index:organizations_name_idx,unique,where:deleted_at=0"`
+	Organization struct {
+		Model
+		soft_delete.DeletedAt
+
+		Name string `gorm:"index:organizations_name_idx,unique,where:deleted_at=0"`
+
+		Members []OrganizationMember `gorm:"foreignKey:OrganizationID"`
+	}
+
+	OrganizationMember struct {
+		Model
+
+		OrganizationID uint         `gorm:"uniqueIndex:organization_members_idx_uniq"`
+		Organization   Organization `gorm:"foreignKey:OrganizationID"`
+		UserID         uint         `gorm:"uniqueIndex:organization_members_idx_uniq"`
+		User           User         `gorm:"foreignKey:UserID"`
+
+		Role OrganizationRole
+	}
+)
+
+const (
+	OrganizationRoleOwner     OrganizationRole = "owner"
+	OrganizationRoleAdmin     OrganizationRole = "admin"
+	OrganizationRoleDeveloper OrganizationRole = "developer"
+	OrganizationRoleViewer    OrganizationRole = "viewer"
+)
+
+func (r OrganizationRole) level() int {
+	switch r {
+	case OrganizationRoleOwner:
+		return 4
+	case OrganizationRoleAdmin:
+		return 3
+	case OrganizationRoleDeveloper:
+		return 2
+	case OrganizationRoleViewer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (r OrganizationRole) IsValid() bool {
+	return r.level() > 0
+}
+
+// Includes reports whether the role grants everything the other role is allowed to do.
+func (r OrganizationRole) Includes(other OrganizationRole) bool {
+	return r.level() >= other.level()
 }
 
 func (o *Organization) String() string {
@@ -31,19 +80,73 @@ func (o *Organization) Save(db *gorm.DB) error {
 }
 
 func (o *Organization) IsMember(user User) bool {
-	//if user.SelfOrganizationId == o.ID {
-	//	return true
-	//}
-	//
-	//for _, member := range o.Members {
-	//	if member.ID == user.ID {
-	//		return true
-	//	}
-	//}
+	for _, member := range o.Members {
+		if member.UserID == user.ID {
+			return true
+		}
+	}
 
 	return false
 }
 
+func (m *OrganizationMember) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(m).Error, "failed to save organization member")
+}
+
+func (m *OrganizationMember) Delete(db *gorm.DB) error {
+	return errors.Wrapf(db.Delete(m).Error, "failed to delete organization member")
+}
+
+func FindOrganizationMember(db *gorm.DB, orgId uint, userId uint) (*OrganizationMember, error) {
+	var member OrganizationMember
+	if r := db.
+		Preload("User").
+		Find(&member, "organization_id = ? AND user_id = ?", orgId, userId); r.Error != nil {
+		return nil, errors.Wrapf(r.Error, "failed to find organization member")
+	} else if r.RowsAffected == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "user is not a member of the organization")
+	}
+
+	return &member, nil
+}
+
+func FindOrganizationMembers(db *gorm.DB, orgId uint) ([]OrganizationMember, error) {
+	var members []OrganizationMember
+	if err := db.
+		Preload("User").
+		Order("id ASC").
+		Find(&members, "organization_id = ?", orgId).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find organization members")
+	}
+
+	return members, nil
+}
+
+// CheckOrganizationRole returns ErrForbidden unless the user is a superuser or a member of the organization having the role.
+func CheckOrganizationRole(db *gorm.DB, orgId uint, user *User, role OrganizationRole) error {
+	if user == nil {
+		return errors.Wrapf(tclerrors.ErrForbidden, "you are not authorized")
+	}
+
+	if user.IsSuperuser() {
+		return nil
+	}
+
+	member, err := FindOrganizationMember(db, orgId, user.ID)
+	if errors.Is(err, tclerrors.ErrNotFound) {
+		return errors.Wrapf(tclerrors.ErrForbidden, "you are not a member of the organization")
+	} else if err != nil {
+		return err
+	}
+
+	if !member.Role.Includes(role) {
+		return errors.Wrapf(tclerrors.ErrForbidden, "'%s' role is required, but you are '%s'", role, member.Role)
+	}
+
+	return nil
+}
+
 func GetOrganizationById(tx *gorm.DB, id uint) (organization Organization, err error) {
 	err = errors.Wrapf(tx.First(&organization, id).Error, "failed to find organization by id %d", id)
 	return
@@ -14,6 +14,9 @@ type Project struct {
 	OwnerID uint
 	Owner   User `gorm:"foreignKey:OwnerID"`
 
+	OrganizationID *uint
+	Organization   *Organization `gorm:"foreignKey:OrganizationID"`
+
 	Name        string
 	Description string
 
@@ -28,6 +31,23 @@ func (p *Project) Delete(db *gorm.DB) error {
 	return errors.Wrapf(db.Delete(p).Error, "failed to delete project")
 }
 
+// CheckPermission allows the project owner and, for projects owned by an organization, the members having the role.
+func (p *Project) CheckPermission(db *gorm.DB, user *User, role OrganizationRole) error {
+	if user == nil {
+		return errors.Wrapf(tclerrors.ErrForbidden, "you are not authorized")
+	}
+
+	if p.OwnerID == user.ID || user.IsSuperuser() {
+		return nil
+	}
+
+	if p.OrganizationID == nil {
+		return errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this project")
+	}
+
+	return CheckOrganizationRole(db, *p.OrganizationID, user, role)
+}
+
 func GetProjectByID(db *gorm.DB, id uint) (Project, error) {
 	var project Project
 	if err := db.
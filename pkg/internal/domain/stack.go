@@ -5,12 +5,15 @@ import (
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/habiliai/apidepot/pkg/internal/util/stringbuilder"
+	"github.com/mokiat/gog"
 	"github.com/pkg/errors"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/plugin/soft_delete"
+	"slices"
 	"strings"
+	"time"
 )
 
 type DB struct {
@@ -31,6 +34,9 @@ type Postgrest struct {
 type StackVapiEnvVar struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+	// Secret values are rendered only into kubernetes secrets and never returned by the api
+	Secret    bool      `json:"secret,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 type Stack struct {
@@ -42,12 +48,26 @@ type Stack struct {
 
 	GitRepo   string
 	GitBranch string
+	// PreviewsEnabled stacks get a preview stack for each pull request opened on their git branch
+	PreviewsEnabled bool
+	// Preview is set if the stack previews a pull request
+	Preview *StackPreview `gorm:"foreignKey:StackID"`
 
 	Name    string `gorm:"uniqueIndex:stacks_name_idx_uniq,where:deleted_at=0"`
 	Hash    string `gorm:"uniqueIndex:stacks_hash_idx_uniq,where:deleted_at=0"`
 	Domain  string
 	Scheme  string
 	SiteURL string
+	// PrimaryDomain is the verified custom domain the stack is served from primarily. empty means Domain.
+	PrimaryDomain string
+	CustomDomains []StackCustomDomain
+	// MultiRegion stacks have an instance in every zone, writing to the database in DefaultRegion and reading from
+	// its replica in their own zone.
+	MultiRegion bool
+	// GlobalDomain is the domain routing to the nearest healthy instance of a multi-region stack
+	GlobalDomain string
+	// ReplicaSchemaHash is the hash of the database schema the replicas were last synchronized with
+	ReplicaSchemaHash string
 
 	Description       string
 	LogoImageUrl      string
@@ -55,12 +75,15 @@ type Stack struct {
 	ServiceTemplate   *ServiceTemplate `gorm:"foreignKey:ServiceTemplateID"`
 	DefaultRegion     tcltypes.InstanceZone
 
-	DB datatypes.JSONType[DB]
+	// DB, Auth, AdminApiKey and VapiEnvVars hold secrets, so they are encrypted at rest
+	DB datatypes.JSONType[DB] `gorm:"serializer:encrypted"`
 
 	AuthEnabled bool
-	Auth        datatypes.JSONType[Auth]
+	Auth        datatypes.JSONType[Auth] `gorm:"serializer:encrypted"`
+	// PreviousJWTSecretExpiresAt is when the previous jwt secret of Auth is retired, if it is still accepted
+	PreviousJWTSecretExpiresAt *time.Time `gorm:"index"`
 
-	AdminApiKey string
+	AdminApiKey string `gorm:"serializer:encrypted"`
 	AnonApiKey  string
 
 	StorageEnabled bool
@@ -72,7 +95,8 @@ type Stack struct {
 	Vapis     []StackVapi
 	Instances []Instance
 
-	VapiEnvVars datatypes.JSONSlice[StackVapiEnvVar]
+	VapiEnvVars datatypes.JSONSlice[StackVapiEnvVar] `gorm:"serializer:encrypted"`
+	RateLimits  datatypes.JSONType[StackRateLimits]
 
 	CustomVapis              []CustomVapi
 	TelegramMiniappPromotion *TelegramMiniappPromotion `gorm:"foreignKey:StackID"`
@@ -83,6 +107,9 @@ type StackVapi struct {
 	Vapi    VapiRelease `gorm:"foreignKey:VapiID"`
 	StackID uint        `gorm:"primarykey"`
 	Stack   Stack       `gorm:"foreignKey:StackID"`
+
+	// Resources overrides the resources declared by the vapi release on this stack.
+	Resources datatypes.JSONType[VapiResources]
 }
 
 type StackHistory struct {
@@ -92,6 +119,7 @@ type StackHistory struct {
 	Stack   Stack `gorm:"foreignKey:StackID"`
 
 	StorageSize int
+	Billed      bool
 }
 
 func (d DB) PostgresURI(host string, port int) string {
@@ -118,7 +146,7 @@ func (s Stack) Namespace() string {
 }
 
 func (s Stack) Endpoint() string {
-	return fmt.Sprintf("%s://%s", s.Scheme, s.Domain)
+	return fmt.Sprintf("%s://%s", s.Scheme, s.Host())
 }
 
 func (s Stack) ServicePath(subPath string) string {
@@ -142,6 +170,9 @@ func (s *Stack) String() string {
 		AddField("Name", s.Name).
 		AddField("ProjectID", s.ProjectID).
 		AddField("Domain", s.Domain).
+		AddField("PrimaryDomain", s.PrimaryDomain).
+		AddField("MultiRegion", s.MultiRegion).
+		AddField("GlobalDomain", s.GlobalDomain).
 		AddField("Scheme", s.Scheme).
 		AddField("SiteURL", s.SiteURL).
 		AddField("Hash", s.Hash).
@@ -168,33 +199,79 @@ func (s *StackVapi) Delete(db *gorm.DB) error {
 	return errors.Wrapf(db.Delete(s).Error, "failed to delete stack vapi")
 }
 
+// RotateJWTSecret replaces the jwt secret, keeping the current one accepted for the grace period.
+func (s *Stack) RotateJWTSecret(secret string, gracePeriod time.Duration) {
+	auth := s.Auth.Data()
+	auth.PreviousJWTSecret = auth.JWTSecret
+	auth.JWTSecret = secret
+
+	s.Auth = datatypes.NewJSONType(auth)
+	s.PreviousJWTSecretExpiresAt = gog.PtrOf(time.Now().Add(gracePeriod))
+}
+
+func (s *Stack) RetirePreviousJWTSecret() {
+	auth := s.Auth.Data()
+	auth.PreviousJWTSecret = ""
+
+	s.Auth = datatypes.NewJSONType(auth)
+	s.PreviousJWTSecretExpiresAt = nil
+}
+
 func (s Stack) ToViewModel() Stack {
 	auth := s.Auth.Data()
 	auth.JWTSecret = ""
+	auth.PreviousJWTSecret = ""
 
 	s.Auth = datatypes.NewJSONType(auth)
 	return s
 }
 
-func (s *Stack) GetVapiEnvVarsMap() map[string]string {
-	envVars := make(map[string]string, len(s.VapiEnvVars))
-	for _, envVar := range s.VapiEnvVars {
-		envVars[envVar.Name] = envVar.Value
+// MaskedValue previews the value without revealing it: only its last characters are kept if it is long enough.
+func (v StackVapiEnvVar) MaskedValue() string {
+	const (
+		mask          = "********"
+		revealedChars = 4
+	)
+	if len(v.Value) < 4*revealedChars {
+		return mask
 	}
 
-	return envVars
+	return mask + v.Value[len(v.Value)-revealedChars:]
 }
 
-func (s *Stack) SetVapiEnvVarsMap(envVars map[string]string) {
-	vapiEnvVars := make([]StackVapiEnvVar, 0, len(envVars))
-	for key, envVar := range envVars {
-		vapiEnvVars = append(vapiEnvVars, StackVapiEnvVar{
-			Name:  key,
-			Value: envVar,
+// SetVapiEnvVar adds or updates the env var. Once an env var is secret, it stays secret until it is unset.
+func (s *Stack) SetVapiEnvVar(envVar StackVapiEnvVar) {
+	envVars := slices.Clone(s.VapiEnvVars)
+	idx := slices.IndexFunc(envVars, func(v StackVapiEnvVar) bool {
+		return v.Name == envVar.Name
+	})
+	if idx < 0 {
+		envVar.UpdatedAt = time.Now()
+		envVars = append(envVars, envVar)
+		slices.SortFunc(envVars, func(a, b StackVapiEnvVar) int {
+			return strings.Compare(a.Name, b.Name)
 		})
+	} else if old := envVars[idx]; old.Value != envVar.Value || !old.Secret && envVar.Secret {
+		old.Value = envVar.Value
+		old.Secret = old.Secret || envVar.Secret
+		old.UpdatedAt = time.Now()
+		envVars[idx] = old
+	}
+
+	s.VapiEnvVars = datatypes.NewJSONSlice(envVars)
+}
+
+// UnsetVapiEnvVar removes the env var, returning false if there is no env var of the name.
+func (s *Stack) UnsetVapiEnvVar(name string) bool {
+	envVars := slices.DeleteFunc(slices.Clone(s.VapiEnvVars), func(v StackVapiEnvVar) bool {
+		return v.Name == name
+	})
+	if len(envVars) == len(s.VapiEnvVars) {
+		return false
 	}
 
-	s.VapiEnvVars = datatypes.NewJSONSlice(vapiEnvVars)
+	s.VapiEnvVars = datatypes.NewJSONSlice(envVars)
+	return true
 }
 
 func (h *StackHistory) Save(db *gorm.DB) error {
@@ -225,6 +302,19 @@ func FindStackByID(
 	return &stack, nil
 }
 
+// FindStackIdsWithExpiredJWTSecret returns the stacks whose previous jwt secret should be retired by now.
+func FindStackIdsWithExpiredJWTSecret(db *gorm.DB, now time.Time) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&Stack{}).
+		Where("previous_jwt_secret_expires_at <= ?", now).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stacks with expired jwt secret")
+	}
+
+	return ids, nil
+}
+
 func FindStacks(db *gorm.DB) ([]Stack, error) {
 	var stacks []Stack
 	if err := db.
@@ -237,6 +327,38 @@ func FindStacks(db *gorm.DB) ([]Stack, error) {
 	return stacks, nil
 }
 
+// FindStackIdsByGitBranch returns the stacks building their custom vapis from the branch of the repository like owner/name.
+func FindStackIdsByGitBranch(db *gorm.DB, gitRepo string, gitBranch string) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&Stack{}).
+		Where("LOWER(git_repo) = LOWER(?) AND git_branch = ?", gitRepo, gitBranch).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stacks by git branch")
+	}
+
+	return ids, nil
+}
+
+// FindPreviewableStackIdsByGitBranch returns the stacks enabling previews for the pull requests on the branch of the
+// repository like owner/name. Previews do not have previews of their own.
+func FindPreviewableStackIdsByGitBranch(db *gorm.DB, gitRepo string, gitBranch string) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&Stack{}).
+		Where("LOWER(git_repo) = LOWER(?) AND git_branch = ? AND previews_enabled", gitRepo, gitBranch).
+		Where("NOT EXISTS (?)", db.
+			Session(&gorm.Session{NewDB: true}).
+			Model(&StackPreview{}).
+			Select("1").
+			Where("stack_previews.stack_id = stacks.id")).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find previewable stacks by git branch")
+	}
+
+	return ids, nil
+}
+
 func DeleteStackByID(db *gorm.DB, id uint) error {
 	return errors.Wrapf(db.Delete(&Stack{}, id).Error, "failed to delete project")
 }
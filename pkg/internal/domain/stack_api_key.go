@@ -0,0 +1,88 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"slices"
+	"time"
+)
+
+// StackApiKey is a scoped api key of a stack, signed with the jwt secret of the stack.
+// The key itself is not stored, only its id which is the jti claim of the key.
+type StackApiKey struct {
+	Model
+
+	StackID uint  `gorm:"index"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	KeyID string `gorm:"uniqueIndex"`
+	Name  string
+	Role  string
+	// AllowedVapis are the slugs of the vapis the key can call. Every vapi is allowed if empty.
+	AllowedVapis datatypes.JSONSlice[string]
+	ExpiresAt    *time.Time
+
+	Revoked   bool
+	RevokedAt *time.Time
+
+	CreatedByID uint
+	CreatedBy   User `gorm:"foreignKey:CreatedByID"`
+}
+
+func (k *StackApiKey) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(k).Error, "failed to save stack api key")
+}
+
+func (k *StackApiKey) Revoke(db *gorm.DB) error {
+	k.Revoked = true
+	k.RevokedAt = gog.PtrOf(time.Now())
+
+	return k.Save(db)
+}
+
+// IsValid returns whether the key is neither revoked nor expired.
+func (k *StackApiKey) IsValid(now time.Time) bool {
+	return !k.Revoked && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+func (k *StackApiKey) AllowsVapi(slug string) bool {
+	return len(k.AllowedVapis) == 0 || slices.Contains(k.AllowedVapis, slug)
+}
+
+func FindStackApiKeyById(db *gorm.DB, id uint) (*StackApiKey, error) {
+	var k StackApiKey
+	if err := db.Limit(1).Find(&k, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack api key")
+	} else if k.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack api key not found. id=%d", id)
+	}
+
+	return &k, nil
+}
+
+func FindStackApiKeyByKeyId(db *gorm.DB, keyId string) (*StackApiKey, error) {
+	var k StackApiKey
+	if err := db.Where("key_id = ?", keyId).Limit(1).Find(&k).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack api key")
+	} else if k.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack api key not found")
+	}
+
+	return &k, nil
+}
+
+// FindStackApiKeysByStackId returns the api keys of the stack from the newest one.
+func FindStackApiKeysByStackId(db *gorm.DB, stackId uint) ([]StackApiKey, error) {
+	var keys []StackApiKey
+	if err := db.
+		Where("stack_id = ?", stackId).
+		Order("id DESC").
+		Find(&keys).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack api keys")
+	}
+
+	return keys, nil
+}
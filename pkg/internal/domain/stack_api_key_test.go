@@ -0,0 +1,30 @@
+package domain_test
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	"gorm.io/datatypes"
+	"time"
+)
+
+func (s *DomainTestSuite) TestGivenStackApiKeyWhenRevokeOrExpireThenShouldBeInvalid() {
+	// Given
+	now := time.Now()
+	key := domain.StackApiKey{
+		AllowedVapis: datatypes.NewJSONSlice([]string{"sns/v2"}),
+		ExpiresAt:    gog.PtrOf(now.Add(time.Hour)),
+	}
+
+	// Then
+	s.True(key.IsValid(now))
+	s.False(key.IsValid(now.Add(2 * time.Hour)))
+	s.True(key.AllowsVapi("sns/v2"))
+	s.False(key.AllowsVapi("sns"))
+
+	key.Revoked = true
+	s.False(key.IsValid(now))
+
+	unscoped := domain.StackApiKey{}
+	s.True(unscoped.IsValid(now))
+	s.True(unscoped.AllowsVapi("sns"))
+}
@@ -16,7 +16,9 @@ type AuthExternalOAuthProvider struct {
 }
 
 type Auth struct {
-	JWTSecret                        string                      `json:"jwt_secret,omitempty"`
+	JWTSecret string `json:"jwt_secret,omitempty"`
+	// PreviousJWTSecret is still accepted after the secret is rotated, until Stack.PreviousJWTSecretExpiresAt
+	PreviousJWTSecret                string                      `json:"previous_jwt_secret,omitempty"`
 	JWTExp                           time.Duration               `json:"jwt_exp,omitempty"`
 	SMTPSenderName                   string                      `json:"smtp_sender_name,omitempty"`
 	MailerAutoConfirm                bool                        `json:"mailer_auto_confirm,omitempty"`
@@ -0,0 +1,106 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+type (
+	StackBackupKind   string
+	StackBackupStatus string
+)
+
+const (
+	StackBackupKindScheduled  StackBackupKind = "scheduled"
+	StackBackupKindManual     StackBackupKind = "manual"
+	StackBackupKindFinal      StackBackupKind = "final"
+	StackBackupKindPreRestore StackBackupKind = "pre-restore"
+
+	StackBackupStatusRunning   StackBackupStatus = "running"
+	StackBackupStatusSucceeded StackBackupStatus = "succeeded"
+	StackBackupStatusFailed    StackBackupStatus = "failed"
+)
+
+// StackBackup is a logical dump of the database of a stack stored in the s3 bucket of its region.
+// Backups are kept after the stack is deleted, so that its final snapshot can be recovered.
+type StackBackup struct {
+	Model
+
+	StackID uint  `gorm:"index"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	Kind       StackBackupKind
+	Status     StackBackupStatus `gorm:"index"`
+	Region     tcltypes.InstanceZone
+	ObjectPath string
+	Size       int64
+	Error      string
+	FinishedAt *time.Time
+}
+
+func (b *StackBackup) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(b).Error, "failed to save stack backup")
+}
+
+func (b *StackBackup) Delete(db *gorm.DB) error {
+	return errors.Wrapf(db.Delete(b).Error, "failed to delete stack backup")
+}
+
+// Finish records the size of the uploaded dump, or err as the reason the backup failed.
+func (b *StackBackup) Finish(db *gorm.DB, size int64, err error) error {
+	b.FinishedAt = gog.PtrOf(time.Now())
+	if err != nil {
+		b.Status = StackBackupStatusFailed
+		b.Error = err.Error()
+	} else {
+		b.Status = StackBackupStatusSucceeded
+		b.Size = size
+	}
+
+	return b.Save(db)
+}
+
+func FindStackBackupById(db *gorm.DB, id uint) (*StackBackup, error) {
+	var b StackBackup
+	if err := db.Limit(1).Find(&b, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack backup")
+	} else if b.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack backup not found. id=%d", id)
+	}
+
+	return &b, nil
+}
+
+// FindStackBackupsByStackId returns the backups of the stack from the newest one.
+func FindStackBackupsByStackId(db *gorm.DB, stackId uint) ([]StackBackup, error) {
+	var backups []StackBackup
+	if err := db.
+		Where("stack_id = ?", stackId).
+		Order("id DESC").
+		Find(&backups).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack backups")
+	}
+
+	return backups, nil
+}
+
+// FindStackIdsDueForBackup returns the stacks which have neither a running nor a succeeded backup since the given time.
+func FindStackIdsDueForBackup(db *gorm.DB, since time.Time) ([]uint, error) {
+	var ids []uint
+	if err := db.Model(&Stack{}).
+		Where(
+			"NOT EXISTS (SELECT 1 FROM stack_backups WHERE stack_backups.stack_id = stacks.id AND stack_backups.status <> ? AND stack_backups.created_at > ?)",
+			StackBackupStatusFailed,
+			since,
+		).
+		Order("id").
+		Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stacks due for backup")
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,60 @@
+package domain_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/pkg/errors"
+	"time"
+)
+
+func (s *DomainTestSuite) TestGivenStacksWithBackupsWhenFindStackIdsDueForBackupThenShouldReturnStacksWithoutRecentBackup() {
+	// Given
+	user := domain.User{
+		Name: "test-user",
+	}
+	s.Require().NoError(user.Save(s.db))
+	project := domain.Project{
+		Name:  "project-1",
+		Owner: user,
+	}
+	s.Require().NoError(project.Save(s.db))
+
+	stacks := []domain.Stack{
+		{ProjectID: project.ID, Name: "backed-up", Hash: "hash-1"},
+		{ProjectID: project.ID, Name: "failed", Hash: "hash-2"},
+		{ProjectID: project.ID, Name: "never-backed-up", Hash: "hash-3"},
+	}
+	for i := range stacks {
+		s.Require().NoError(stacks[i].Save(s.db))
+	}
+
+	backedUp := domain.StackBackup{StackID: stacks[0].ID, Kind: domain.StackBackupKindScheduled, Status: domain.StackBackupStatusRunning}
+	s.Require().NoError(backedUp.Save(s.db))
+	s.Require().NoError(backedUp.Finish(s.db, 1024, nil))
+
+	failed := domain.StackBackup{StackID: stacks[1].ID, Kind: domain.StackBackupKindScheduled, Status: domain.StackBackupStatusRunning}
+	s.Require().NoError(failed.Save(s.db))
+	s.Require().NoError(failed.Finish(s.db, 0, errors.New("boom")))
+
+	// When
+	ids, err := domain.FindStackIdsDueForBackup(s.db, time.Now().Add(-time.Hour))
+
+	// Then
+	s.Require().NoError(err)
+	s.Equal([]uint{stacks[1].ID, stacks[2].ID}, ids)
+
+	backups, err := domain.FindStackBackupsByStackId(s.db, stacks[1].ID)
+	s.Require().NoError(err)
+	s.Require().Len(backups, 1)
+	s.Equal(domain.StackBackupStatusFailed, backups[0].Status)
+	s.Equal("boom", backups[0].Error)
+	s.NotNil(backups[0].FinishedAt)
+}
+
+func (s *DomainTestSuite) TestGivenNoBackupWhenFindStackBackupByIdThenShouldReturnNotFound() {
+	// When
+	_, err := domain.FindStackBackupById(s.db, 1<<30)
+
+	// Then
+	s.ErrorIs(err, tclerrors.ErrNotFound)
+}
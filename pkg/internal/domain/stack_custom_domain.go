@@ -0,0 +1,130 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+// StackCustomDomain is a hostname of the user the stack is served from, once its ownership is verified
+// by a DNS TXT record. Any stack can claim a hostname, but only one can have it verified.
+type StackCustomDomain struct {
+	Model
+
+	StackID uint  `gorm:"uniqueIndex:stack_custom_domains_idx_uniq"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	Hostname          string `gorm:"uniqueIndex:stack_custom_domains_idx_uniq;uniqueIndex:stack_custom_domains_verified_idx_uniq,where:verified_at IS NOT NULL"`
+	VerificationToken string
+	VerifiedAt        *time.Time
+}
+
+func (d *StackCustomDomain) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(d).Error, "failed to save stack custom domain")
+}
+
+func (d *StackCustomDomain) Delete(db *gorm.DB) error {
+	return errors.Wrapf(db.Delete(d).Error, "failed to delete stack custom domain")
+}
+
+func (d StackCustomDomain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// VerificationRecordName is the name of the TXT record proving the ownership of the hostname.
+func (d StackCustomDomain) VerificationRecordName() string {
+	return "_apidepot-challenge." + d.Hostname
+}
+
+// VerificationRecordValue is the value of the TXT record proving the ownership of the hostname.
+func (d StackCustomDomain) VerificationRecordValue() string {
+	return "apidepot-verification=" + d.VerificationToken
+}
+
+// Host returns the host name the stack is served from primarily.
+func (s Stack) Host() string {
+	if s.PrimaryDomain != "" {
+		return s.PrimaryDomain
+	} else if s.GlobalDomain != "" {
+		return s.GlobalDomain
+	}
+
+	return s.Domain
+}
+
+// Hosts returns every host name the stack is served from: the generated domain, the global domain and the verified
+// custom domains.
+func (s Stack) Hosts() []string {
+	hosts := []string{s.Domain}
+	if s.GlobalDomain != "" {
+		hosts = append(hosts, s.GlobalDomain)
+	}
+	for _, customDomain := range s.VerifiedCustomDomains() {
+		hosts = append(hosts, customDomain.Hostname)
+	}
+
+	return hosts
+}
+
+func (s Stack) VerifiedCustomDomains() []StackCustomDomain {
+	var customDomains []StackCustomDomain
+	for _, customDomain := range s.CustomDomains {
+		if customDomain.Verified() {
+			customDomains = append(customDomains, customDomain)
+		}
+	}
+
+	return customDomains
+}
+
+func FindStackCustomDomainById(db *gorm.DB, id uint) (*StackCustomDomain, error) {
+	var d StackCustomDomain
+	if err := db.Limit(1).Find(&d, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack custom domain")
+	} else if d.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack custom domain not found. id=%d", id)
+	}
+
+	return &d, nil
+}
+
+func ExistsVerifiedStackCustomDomainByHostname(db *gorm.DB, hostname string) (bool, error) {
+	var count int64
+	if err := db.Model(&StackCustomDomain{}).
+		Where("hostname = ? AND verified_at IS NOT NULL", hostname).
+		Count(&count).Error; err != nil {
+		return false, errors.Wrapf(err, "failed to count stack custom domains")
+	}
+
+	return count > 0, nil
+}
+
+// DeleteUnverifiedStackCustomDomainsByHostname removes the claims of the other stacks on the hostname, once it has been
+// verified by the custom domain of the given id.
+func DeleteUnverifiedStackCustomDomainsByHostname(db *gorm.DB, hostname string, verifiedId uint) error {
+	return errors.Wrapf(
+		db.Where("hostname = ? AND verified_at IS NULL AND id <> ?", hostname, verifiedId).Delete(&StackCustomDomain{}).Error,
+		"failed to delete unverified stack custom domains",
+	)
+}
+
+func DeleteStackCustomDomainsByStackId(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&StackCustomDomain{}).Error,
+		"failed to delete stack custom domains",
+	)
+}
+
+func init() {
+	functionsOnAfterMigration = append(functionsOnAfterMigration, func(db *gorm.DB) error {
+		// hostnames used to be unique whether verified or not, which let unverified claims keep them from their owners
+		if db.Migrator().HasIndex(&StackCustomDomain{}, "idx_stack_custom_domains_hostname") {
+			if err := db.Migrator().DropIndex(&StackCustomDomain{}, "idx_stack_custom_domains_hostname"); err != nil {
+				return errors.Wrapf(err, "failed to drop index of stack custom domains")
+			}
+		}
+
+		return nil
+	})
+}
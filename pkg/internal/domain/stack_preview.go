@@ -0,0 +1,87 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+)
+
+// StackPreview is an ephemeral stack previewing a pull request on the git branch of its parent stack. It is torn
+// down when the pull request is closed.
+type StackPreview struct {
+	Model
+	DeletedAt soft_delete.DeletedAt
+
+	StackID uint  `gorm:"uniqueIndex:stack_previews_stack_idx_uniq,where:deleted_at=0"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	ParentStackID uint  `gorm:"uniqueIndex:stack_previews_pull_request_idx_uniq,where:deleted_at=0"`
+	ParentStack   Stack `gorm:"foreignKey:ParentStackID"`
+
+	// GitRepo is the repository the pull request is opened on, like owner/name
+	GitRepo           string `gorm:"uniqueIndex:stack_previews_pull_request_idx_uniq,where:deleted_at=0"`
+	PullRequestNumber int    `gorm:"uniqueIndex:stack_previews_pull_request_idx_uniq,where:deleted_at=0"`
+	// CommentID is the comment on the pull request telling the preview url. 0 if it has not been posted.
+	CommentID int64
+}
+
+func (p *StackPreview) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(p).Error, "failed to save stack preview")
+}
+
+func (p *StackPreview) Delete(db *gorm.DB) error {
+	return errors.Wrapf(db.Delete(p).Error, "failed to delete stack preview")
+}
+
+func FindStackPreviewByParentStackIdAndPullRequest(
+	db *gorm.DB,
+	parentStackId uint,
+	gitRepo string,
+	pullRequestNumber int,
+) (*StackPreview, error) {
+	var preview StackPreview
+	if err := db.
+		Where("parent_stack_id = ? AND LOWER(git_repo) = LOWER(?) AND pull_request_number = ?", parentStackId, gitRepo, pullRequestNumber).
+		First(&preview).Error; errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack preview not found")
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack preview")
+	}
+
+	return &preview, nil
+}
+
+// FindStackPreviewsByPullRequest returns the previews of the pull request, one for each parent stack.
+func FindStackPreviewsByPullRequest(db *gorm.DB, gitRepo string, pullRequestNumber int) ([]StackPreview, error) {
+	var previews []StackPreview
+	if err := db.
+		Preload("Stack").
+		Where("LOWER(git_repo) = LOWER(?) AND pull_request_number = ?", gitRepo, pullRequestNumber).
+		Order("id ASC").
+		Find(&previews).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack previews")
+	}
+
+	return previews, nil
+}
+
+func FindStackPreviewsByParentStackId(db *gorm.DB, parentStackId uint) ([]StackPreview, error) {
+	var previews []StackPreview
+	if err := db.
+		Preload("Stack").
+		Where("parent_stack_id = ?", parentStackId).
+		Order("id DESC").
+		Find(&previews).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack previews")
+	}
+
+	return previews, nil
+}
+
+func DeleteStackPreviewsByStackId(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&StackPreview{}).Error,
+		"failed to delete stack previews",
+	)
+}
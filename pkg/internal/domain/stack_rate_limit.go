@@ -0,0 +1,147 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// RateLimit bounds the requests to a stack at its ingress.
+type RateLimit struct {
+	RequestsPerSecond int64 `json:"requests_per_second"`
+	Burst             int64 `json:"burst"`
+	// MaxInFlight is the max number of the requests served at once. zero means unlimited.
+	MaxInFlight int64 `json:"max_in_flight,omitempty"`
+}
+
+type StackRateLimits struct {
+	// Global applies to every path of the stack but the vapis having their own limit. nil means unlimited.
+	Global *RateLimit `json:"global,omitempty"`
+	// Vapis are the limits of the vapis by their slugs, e.g. "sns/v2". custom vapis are given by their names.
+	Vapis map[string]RateLimit `json:"vapis,omitempty"`
+}
+
+// StackRequestUsage is the number of the requests to a stack in a month, counted at its ingress.
+type StackRequestUsage struct {
+	StackID uint   `gorm:"primarykey"`
+	Month   string `gorm:"primarykey"`
+
+	Requests  int64
+	UpdatedAt time.Time
+}
+
+func (l RateLimit) Validate() error {
+	if l.RequestsPerSecond <= 0 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "requests per second must be positive")
+	} else if l.Burst < 0 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "burst must not be negative")
+	} else if l.MaxInFlight < 0 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "max in flight must not be negative")
+	}
+
+	return nil
+}
+
+// Validate checks the limits, and that every vapi of the limits is installed on the stack.
+func (l StackRateLimits) Validate(stack *Stack) error {
+	if l.Global != nil {
+		if err := l.Global.Validate(); err != nil {
+			return err
+		}
+	}
+
+	for slug, limit := range l.Vapis {
+		if err := limit.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid rate limit of vapi '%s'", slug)
+		}
+		if !stack.HasVapiSlug(slug) {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "vapi '%s' is not installed on the stack", slug)
+		}
+	}
+
+	return nil
+}
+
+// HasVapiSlug returns whether the vapi of the slug or the custom vapi of the name is installed on the stack.
+func (s *Stack) HasVapiSlug(slug string) bool {
+	for _, vapi := range s.Vapis {
+		if vapi.Vapi.Slug() == slug {
+			return true
+		}
+	}
+	for _, customVapi := range s.CustomVapis {
+		if customVapi.Name == slug {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequestUsageMonth returns the month of the request usages at t, e.g. "2024-10".
+func RequestUsageMonth(t time.Time) string {
+	return t.UTC().Format("2006-01")
+}
+
+func AddStackRequestUsage(db *gorm.DB, stackId uint, month string, requests int64) error {
+	usage := StackRequestUsage{
+		StackID:  stackId,
+		Month:    month,
+		Requests: requests,
+	}
+
+	return errors.Wrapf(db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "stack_id"}, {Name: "month"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"requests":   gorm.Expr("stack_request_usages.requests + ?", requests),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(&usage).Error, "failed to add stack request usage")
+}
+
+// SumRequestUsagesOfOwner returns the number of the requests in the month to the stacks of the projects owned by the user,
+// including the deleted stacks.
+func SumRequestUsagesOfOwner(db *gorm.DB, ownerId uint, month string) (int64, error) {
+	var requests int64
+	if err := db.Model(&StackRequestUsage{}).
+		Joins("JOIN stacks ON stacks.id = stack_request_usages.stack_id").
+		Joins("JOIN projects ON projects.id = stacks.project_id").
+		Where("projects.owner_id = ? AND stack_request_usages.month = ?", ownerId, month).
+		Select("COALESCE(SUM(stack_request_usages.requests), 0)").
+		Scan(&requests).Error; err != nil {
+		return 0, errors.Wrapf(err, "failed to sum request usages")
+	}
+
+	return requests, nil
+}
+
+func FindStackRequestUsage(db *gorm.DB, stackId uint, month string) (int64, error) {
+	var requests int64
+	if err := db.Model(&StackRequestUsage{}).
+		Where("stack_id = ? AND month = ?", stackId, month).
+		Select("COALESCE(SUM(requests), 0)").
+		Scan(&requests).Error; err != nil {
+		return 0, errors.Wrapf(err, "failed to find stack request usage")
+	}
+
+	return requests, nil
+}
+
+// FindStackOwner returns the owner of the project of the stack.
+func FindStackOwner(db *gorm.DB, stackId uint) (*User, error) {
+	var user User
+	if err := db.
+		Joins("JOIN projects ON projects.owner_id = users.id").
+		Joins("JOIN stacks ON stacks.project_id = projects.id").
+		Where("stacks.id = ?", stackId).
+		Limit(1).
+		Find(&user).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack owner")
+	} else if user.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "stack not found. id=%d", stackId)
+	}
+
+	return &user, nil
+}
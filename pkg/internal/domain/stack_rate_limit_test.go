@@ -0,0 +1,68 @@
+package domain_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"time"
+)
+
+func (s *DomainTestSuite) TestGivenStackRateLimitsWhenValidateThenVapisShouldBeInstalled() {
+	// Given
+	stack := domain.Stack{
+		CustomVapis: []domain.CustomVapi{{Name: "my-custom-vapi"}},
+	}
+
+	// Then
+	s.NoError(domain.StackRateLimits{
+		Global: &domain.RateLimit{RequestsPerSecond: 100, Burst: 50},
+		Vapis:  map[string]domain.RateLimit{"my-custom-vapi": {RequestsPerSecond: 10}},
+	}.Validate(&stack))
+	s.ErrorIs(domain.StackRateLimits{
+		Vapis: map[string]domain.RateLimit{"sns/v2": {RequestsPerSecond: 10}},
+	}.Validate(&stack), tclerrors.ErrBadRequest)
+	s.ErrorIs(domain.StackRateLimits{
+		Global: &domain.RateLimit{Burst: 10},
+	}.Validate(&stack), tclerrors.ErrBadRequest)
+}
+
+func (s *DomainTestSuite) TestGivenStacksOfOwnerWhenAddStackRequestUsageThenShouldBeSummedByOwner() {
+	// Given
+	user := domain.User{
+		Name: "test-user",
+	}
+	s.Require().NoError(user.Save(s.db))
+	project := domain.Project{
+		Name:  "project-1",
+		Owner: user,
+	}
+	s.Require().NoError(project.Save(s.db))
+
+	stacks := []domain.Stack{
+		{ProjectID: project.ID, Name: "stack-1", Hash: "hash-1"},
+		{ProjectID: project.ID, Name: "stack-2", Hash: "hash-2"},
+	}
+	for i := range stacks {
+		s.Require().NoError(stacks[i].Save(s.db))
+	}
+	month := domain.RequestUsageMonth(time.Now())
+
+	// When
+	s.Require().NoError(domain.AddStackRequestUsage(s.db, stacks[0].ID, month, 10))
+	s.Require().NoError(domain.AddStackRequestUsage(s.db, stacks[0].ID, month, 5))
+	s.Require().NoError(domain.AddStackRequestUsage(s.db, stacks[1].ID, month, 7))
+	s.Require().NoError(domain.AddStackRequestUsage(s.db, stacks[1].ID, "2000-01", 100))
+
+	// Then
+	requests, err := domain.FindStackRequestUsage(s.db, stacks[0].ID, month)
+	s.Require().NoError(err)
+	s.Equal(int64(15), requests)
+
+	requests, err = domain.SumRequestUsagesOfOwner(s.db, user.ID, month)
+	s.Require().NoError(err)
+	s.Equal(int64(22), requests)
+
+	owner, err := domain.FindStackOwner(s.db, stacks[1].ID)
+	s.Require().NoError(err)
+	s.Equal(user.ID, owner.ID)
+	s.Equal(int64(1_000_000), owner.RequestQuota())
+}
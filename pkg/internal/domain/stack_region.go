@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"fmt"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+)
+
+// Zones returns the zones the stack runs in, DefaultRegion first.
+func (s Stack) Zones() []tcltypes.InstanceZone {
+	return append([]tcltypes.InstanceZone{s.DefaultRegion}, s.ReplicaZones()...)
+}
+
+// ReplicaZones returns the zones having a replica of the database of the stack.
+func (s Stack) ReplicaZones() []tcltypes.InstanceZone {
+	if !s.MultiRegion {
+		return nil
+	}
+
+	var zones []tcltypes.InstanceZone
+	for _, zone := range tcltypes.InstanceZones {
+		if zone != s.DefaultRegion {
+			zones = append(zones, zone)
+		}
+	}
+
+	return zones
+}
+
+// RegionalDomain returns the generated domain of the stack in the zone, given the domain of the stacks in the zone.
+func (s Stack) RegionalDomain(zone tcltypes.InstanceZone, regionalDomain string) string {
+	if zone == s.DefaultRegion {
+		return s.Domain
+	}
+
+	return s.Hash + "." + regionalDomain
+}
+
+// ZoneHost returns the host name the instance of the stack in the zone is reached at directly. It is the host of the
+// stack unless the instance is of a multi-region stack or out of DefaultRegion, which is reached at its regional domain.
+func (s Stack) ZoneHost(zone tcltypes.InstanceZone, regionalDomain string) string {
+	if zone == s.DefaultRegion && !s.MultiRegion {
+		return s.Host()
+	}
+
+	return s.RegionalDomain(zone, regionalDomain)
+}
+
+func (s Stack) ZoneEndpoint(zone tcltypes.InstanceZone, regionalDomain string) string {
+	return fmt.Sprintf("%s://%s", s.Scheme, s.ZoneHost(zone, regionalDomain))
+}
@@ -0,0 +1,28 @@
+package domain_test
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+)
+
+func (s *DomainTestSuite) TestGivenMultiRegionStackWhenGetZonesThenReplicasShouldBeOutOfDefaultRegion() {
+	stack := domain.Stack{
+		Hash:          "abc",
+		Scheme:        "https",
+		Domain:        "abc.seoul.shaple.io",
+		GlobalDomain:  "abc.global.shaple.io",
+		DefaultRegion: tcltypes.InstanceZoneOciApSeoul,
+		MultiRegion:   true,
+	}
+
+	s.Equal([]tcltypes.InstanceZone{tcltypes.InstanceZoneOciSingapore}, stack.ReplicaZones())
+	s.Equal([]tcltypes.InstanceZone{tcltypes.InstanceZoneOciApSeoul, tcltypes.InstanceZoneOciSingapore}, stack.Zones())
+	s.Equal("https://abc.global.shaple.io", stack.Endpoint())
+	s.Equal("https://abc.seoul.shaple.io", stack.ZoneEndpoint(tcltypes.InstanceZoneOciApSeoul, "seoul.shaple.io"))
+	s.Equal("https://abc.singapore.shaple.io", stack.ZoneEndpoint(tcltypes.InstanceZoneOciSingapore, "singapore.shaple.io"))
+
+	stack.MultiRegion = false
+	stack.GlobalDomain = ""
+	s.Empty(stack.ReplicaZones())
+	s.Equal("https://abc.seoul.shaple.io", stack.ZoneEndpoint(tcltypes.InstanceZoneOciApSeoul, "seoul.shaple.io"))
+}
@@ -79,3 +79,23 @@ func (s *DomainTestSuite) TestFindStackVapiByStackIDAndVapiID() {
 	s.Equal(vapiPackage.ID, result.Vapi.Package.ID)
 	s.T().Logf("%v", result)
 }
+
+func (s *DomainTestSuite) TestGivenSecretEnvVarWhenSetWithoutSecretThenShouldStaySecret() {
+	// Given
+	stack := domain.Stack{}
+	stack.SetVapiEnvVar(domain.StackVapiEnvVar{Name: "sns.API_KEY", Value: "sk-0123456789abcdef", Secret: true})
+	stack.SetVapiEnvVar(domain.StackVapiEnvVar{Name: "sns.MAX_USERS", Value: "1000"})
+
+	// When
+	stack.SetVapiEnvVar(domain.StackVapiEnvVar{Name: "sns.API_KEY", Value: "sk-fedcba9876543210"})
+
+	// Then
+	s.Require().Len(stack.VapiEnvVars, 2)
+	s.True(stack.VapiEnvVars[0].Secret)
+	s.Equal("sk-fedcba9876543210", stack.VapiEnvVars[0].Value)
+	s.Equal("********3210", stack.VapiEnvVars[0].MaskedValue())
+	s.False(stack.VapiEnvVars[0].UpdatedAt.IsZero())
+	s.True(stack.UnsetVapiEnvVar("sns.API_KEY"))
+	s.False(stack.UnsetVapiEnvVar("sns.API_KEY"))
+	s.Len(stack.VapiEnvVars, 1)
+}
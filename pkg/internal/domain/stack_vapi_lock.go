@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// StackVapiLock pins a vapi release resolved for a stack, so that every deployment uses exactly the same set.
+type StackVapiLock struct {
+	Model
+
+	StackID uint  `gorm:"uniqueIndex:stack_vapi_locks_idx_uniq"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	VapiID uint        `gorm:"uniqueIndex:stack_vapi_locks_idx_uniq"`
+	Vapi   VapiRelease `gorm:"foreignKey:VapiID"`
+
+	// Seq orders the locked releases so that dependencies come before their dependents.
+	Seq    int
+	Direct bool
+}
+
+func (l *StackVapiLock) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(l).Error, "failed to save stack vapi lock")
+}
+
+func FindStackVapiLocksByStackID(db *gorm.DB, stackId uint) ([]StackVapiLock, error) {
+	var locks []StackVapiLock
+	if err := db.
+		Preload("Vapi").
+		Preload("Vapi.Package").
+		Where("stack_id = ?", stackId).
+		Order("seq ASC").
+		Find(&locks).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack vapi locks")
+	}
+
+	return locks, nil
+}
+
+// FindLockedVapiReleasesByStackID returns the releases locked for the stack, ordered so that dependencies come before
+// their dependents.
+func FindLockedVapiReleasesByStackID(db *gorm.DB, stackId uint) ([]VapiRelease, error) {
+	locks, err := FindStackVapiLocksByStackID(db, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	releases := make([]VapiRelease, 0, len(locks))
+	for _, lock := range locks {
+		releases = append(releases, lock.Vapi)
+	}
+
+	return releases, nil
+}
+
+// FindStackIdsWithoutVapiLocks returns the stacks having vapis installed but none locked, i.e. whose vapis were
+// installed before locking was introduced.
+func FindStackIdsWithoutVapiLocks(db *gorm.DB) ([]uint, error) {
+	var stackIds []uint
+	if err := db.
+		Model(&StackVapi{}).
+		Distinct("stack_id").
+		Where("stack_id IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&Stack{}).Select("id")).
+		Where("stack_id NOT IN (?)", db.Session(&gorm.Session{NewDB: true}).Model(&StackVapiLock{}).Select("stack_id")).
+		Order("stack_id ASC").
+		Pluck("stack_id", &stackIds).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stacks without vapi locks")
+	}
+
+	return stackIds, nil
+}
+
+func DeleteStackVapiLocksByStackID(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&StackVapiLock{}).Error,
+		"failed to delete stack vapi locks",
+	)
+}
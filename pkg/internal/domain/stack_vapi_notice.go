@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type VapiReleaseEvent string
+
+const (
+	VapiReleaseEventDeprecated  VapiReleaseEvent = "deprecated"
+	VapiReleaseEventSuspended   VapiReleaseEvent = "suspended"
+	VapiReleaseEventYanked      VapiReleaseEvent = "yanked"
+	VapiReleaseEventUnpublished VapiReleaseEvent = "unpublished"
+	VapiReleaseEventPublished   VapiReleaseEvent = "published"
+	VapiReleaseEventRestored    VapiReleaseEvent = "restored"
+)
+
+// StackVapiNotice warns the stack owners that a vapi release installed on the stack has changed its state.
+type StackVapiNotice struct {
+	Model
+
+	StackID uint  `gorm:"index"`
+	Stack   Stack `gorm:"foreignKey:StackID"`
+
+	VapiReleaseID uint        `gorm:"index"`
+	VapiRelease   VapiRelease `gorm:"foreignKey:VapiReleaseID"`
+
+	Event   VapiReleaseEvent
+	Message string
+}
+
+// CreateStackVapiNotices notifies the event of the release to every stack having locked it, and returns the ids of the stacks.
+func CreateStackVapiNotices(db *gorm.DB, rel *VapiRelease, event VapiReleaseEvent, message string) ([]uint, error) {
+	var stackIds []uint
+	if err := db.
+		Model(&StackVapiLock{}).
+		Distinct("stack_id").
+		Where("vapi_id = ?", rel.ID).
+		Pluck("stack_id", &stackIds).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stacks having locked the vapi release")
+	}
+
+	if len(stackIds) == 0 {
+		return nil, nil
+	}
+
+	notices := make([]StackVapiNotice, 0, len(stackIds))
+	for _, stackId := range stackIds {
+		notices = append(notices, StackVapiNotice{
+			StackID:       stackId,
+			VapiReleaseID: rel.ID,
+			Event:         event,
+			Message:       message,
+		})
+	}
+
+	if err := db.Omit("Stack", "VapiRelease").Create(&notices).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to create stack vapi notices")
+	}
+
+	return stackIds, nil
+}
+
+// FindStackVapiNoticesByStackId returns the notices of the stack from the newest one.
+func FindStackVapiNoticesByStackId(db *gorm.DB, stackId uint) ([]StackVapiNotice, error) {
+	var notices []StackVapiNotice
+	if err := db.
+		Preload("VapiRelease").
+		Preload("VapiRelease.Package").
+		Where("stack_id = ?", stackId).
+		Order("id DESC").
+		Find(&notices).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack vapi notices")
+	}
+
+	return notices, nil
+}
+
+func DeleteStackVapiNoticesByStackId(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&StackVapiNotice{}).Error,
+		"failed to delete stack vapi notices",
+	)
+}
+
+// CountStacksLockingVapiReleases counts the stacks having locked any of the releases.
+func CountStacksLockingVapiReleases(db *gorm.DB, releaseIds []uint) (int64, error) {
+	var count int64
+	if err := db.
+		Model(&StackVapiLock{}).
+		Distinct("stack_id").
+		Where("vapi_id IN ?", releaseIds).
+		Count(&count).
+		Error; err != nil {
+		return 0, errors.Wrapf(err, "failed to count stacks having locked the vapi releases")
+	}
+
+	return count, nil
+}
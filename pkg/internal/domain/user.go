@@ -3,6 +3,7 @@ package domain
 import (
 	"context"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -31,7 +32,7 @@ type User struct {
 	GithubInstallationId int64
 	// TODO: 현재는 access token 이 만료되지 않도록 github app 이 구성되어있음
 	// 추후 만료되도록 설정시 access token 을 refresh 하도록 구현 필요
-	GithubAccessToken string
+	GithubAccessToken string `gorm:"serializer:encrypted"`
 	MediumLink        string
 	AvatarUrl         string
 	// TODO: 사용자가 많아질 경우, github api rate limit 초과 방지를 위해 installation access token 을 store 하기(GithubInstallationAccessToken 은 1시간 동안 유효)
@@ -39,6 +40,13 @@ type User struct {
 	// GithubInstallationAccessTokenExpiresAt time.Time
 
 	StorageSizeLimit int
+
+	// VapiMilliCPUQuota and VapiMemoryQuota(bytes) bound the resources of a single vapi container. zero means the default.
+	VapiMilliCPUQuota int64
+	VapiMemoryQuota   int64
+
+	// MonthlyRequestQuota is the number of the requests a month to all the stacks of the user. zero means the default.
+	MonthlyRequestQuota int64
 }
 
 func (u *User) Save(db *gorm.DB) error {
@@ -53,6 +61,26 @@ func (u *User) IsSuperuser() bool {
 	return u.Role == UserRoleAdmin
 }
 
+func (u *User) VapiQuota() (milliCPU int64, memory int64) {
+	milliCPU, memory = u.VapiMilliCPUQuota, u.VapiMemoryQuota
+	if milliCPU == 0 {
+		milliCPU = constants.DefaultVapiMilliCPUQuota
+	}
+	if memory == 0 {
+		memory = constants.DefaultVapiMemoryQuota
+	}
+
+	return
+}
+
+func (u *User) RequestQuota() int64 {
+	if u.MonthlyRequestQuota == 0 {
+		return constants.DefaultMonthlyRequestQuota
+	}
+
+	return u.MonthlyRequestQuota
+}
+
 func (u *User) GetGithubAccessToken(ctx context.Context) (string, error) {
 	accessToken := u.GithubAccessToken
 	if accessToken != "" {
@@ -8,6 +8,7 @@ import (
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"gorm.io/plugin/soft_delete"
+	"slices"
 )
 
 type (
@@ -25,8 +26,14 @@ type (
 		OwnerId uint `json:"owner_id"`
 		Owner   User `gorm:"foreignKey:OwnerId" json:"owner"`
 
+		OrganizationID *uint         `json:"organization_id"`
+		Organization   *Organization `gorm:"foreignKey:OrganizationID" json:"-"`
+
 		Releases   []VapiRelease `gorm:"foreignKey:PackageID" json:"releases"`
 		VapiPoolId string
+
+		Access    VapiPackageAccess     `gorm:"default:public" json:"access"`
+		Borrowers []VapiPackageBorrower `gorm:"foreignKey:VapiPackageID" json:"-"`
 	}
 
 	VapiEnvVar struct {
@@ -49,12 +56,28 @@ type (
 		Domains     datatypes.JSONSlice[string]
 		Homepage    string
 
+		// Yanked releases are skipped on resolving dependencies, but the stacks having locked them keep working.
+		Yanked bool `json:"yanked"`
+		// DeprecationMessage is shown on installing or searching the deprecated release.
+		DeprecationMessage string `json:"deprecation_message"`
+		SuspensionReason   string `json:"suspension_reason"`
+		YankReason         string `json:"yank_reason"`
+
 		Dependencies []VapiRelease `gorm:"many2many:vapi_releases_dependencies" json:"-"`
 
 		PackageID uint        `gorm:"index:release_version_idx,unique,where:deleted_at=0" json:"package_id"`
 		Package   VapiPackage `gorm:"foreignKey:PackageID" json:"package"`
 
-		EnvVars datatypes.JSONSlice[VapiEnvVar]
+		EnvVars              datatypes.JSONSlice[VapiEnvVar]
+		ResolvedDependencies datatypes.JSONSlice[VapiReleaseDependency] `json:"resolved_dependencies"`
+		Resources            datatypes.JSONType[VapiResources]          `json:"resources"`
+	}
+
+	// VapiReleaseDependency records which version a dependency constraint in apidepot.yml resolved to on registration.
+	VapiReleaseDependency struct {
+		Name       string `json:"name"`
+		Constraint string `json:"constraint"`
+		Version    string `json:"version"`
 	}
 
 	dfsFunc func(rel VapiRelease, parent *VapiRelease) error
@@ -75,6 +98,20 @@ func (v VapiRelease) MajorVersion() string {
 	return semver.Major("v" + v.Version)
 }
 
+// CheckInstallable returns ErrPreconditionFailed if the release cannot be newly installed on a stack.
+// Deprecated releases are still installable.
+func (v VapiRelease) CheckInstallable() error {
+	if v.Suspended {
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "vapi release %s@%s is suspended: %s", v.Package.Name, v.Version, v.SuspensionReason)
+	} else if v.Yanked {
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "vapi release %s@%s is yanked: %s", v.Package.Name, v.Version, v.YankReason)
+	} else if !v.Published {
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "vapi release %s@%s is not published", v.Package.Name, v.Version)
+	}
+
+	return nil
+}
+
 func (v *VapiPackage) Save(tx *gorm.DB) error {
 	return errors.WithStack(tx.Save(v).Error)
 }
@@ -134,16 +171,26 @@ func (v *VapiRelease) DFS(
 	return v.dfs(tx, dfsFunc, visited, nil)
 }
 
-func (v *VapiPackage) IsPermittedToEdit(user *User) error {
+func (v *VapiPackage) IsPermittedToEdit(db *gorm.DB, user *User) error {
+	return v.CheckPermission(db, user, OrganizationRoleDeveloper)
+}
+
+// CheckPermission allows the package owner, superusers and, for packages owned by an organization, the members having
+// the role.
+func (v *VapiPackage) CheckPermission(db *gorm.DB, user *User, role OrganizationRole) error {
 	if user == nil {
 		return errors.Wrapf(tclerrors.ErrForbidden, "you are not authorized")
 	}
 
-	if user.ID != v.OwnerId {
+	if user.ID == v.OwnerId || user.IsSuperuser() {
+		return nil
+	}
+
+	if v.OrganizationID == nil {
 		return errors.Wrapf(tclerrors.ErrForbidden, "you are not owned this package")
 	}
 
-	return nil
+	return CheckOrganizationRole(db, *v.OrganizationID, user, role)
 }
 
 func GetVapiReleaseByID(tx *gorm.DB, id uint) (*VapiRelease, error) {
@@ -202,13 +249,14 @@ func FindVapiReleaseByPackageNameAndVersion(
 	return &vapi, nil
 }
 
+// FindLatestVapiReleaseByPackageID returns the release of the highest version which is not yanked.
 func FindLatestVapiReleaseByPackageID(
 	db *gorm.DB,
 	packageId uint,
 ) (*VapiRelease, error) {
 	var vapi VapiRelease
 	if err := db.
-		Where("package_id = ?", packageId).
+		Where("package_id = ? AND yanked = ?", packageId, false).
 		Order("apidepot.version_to_int(version) DESC").
 		First(&vapi).
 		Error; err != nil {
@@ -266,6 +314,45 @@ func FindVapiReleasesByPackageID(
 	return vapis, nil
 }
 
+func FindVapiReleasesByPackageName(
+	db *gorm.DB,
+	name string,
+) ([]VapiRelease, error) {
+	var vapis []VapiRelease
+	if err := db.
+		Preload("Package").
+		Where("package_id IN (?)", db.Model(&VapiPackage{}).Select("id").Where("name = ?", name)).
+		Find(&vapis).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapis by package name")
+	}
+
+	return vapis, nil
+}
+
+// FindVapiReleasesByIDs returns the releases in the order of the given ids.
+func FindVapiReleasesByIDs(db *gorm.DB, ids []uint) ([]VapiRelease, error) {
+	var vapis []VapiRelease
+	if err := db.
+		Preload("Package").
+		Where("id IN ?", ids).
+		Find(&vapis).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapis by ids")
+	}
+
+	releases := make([]VapiRelease, 0, len(ids))
+	for _, id := range ids {
+		i := slices.IndexFunc(vapis, func(v VapiRelease) bool { return v.ID == id })
+		if i < 0 {
+			return nil, errors.Wrapf(tclerrors.ErrNotFound, "vapi release %d not found", id)
+		}
+		releases = append(releases, vapis[i])
+	}
+
+	return releases, nil
+}
+
 func FindVapiReleases(db *gorm.DB) ([]VapiRelease, error) {
 	var vapis []VapiRelease
 	if err := db.Preload("Package").Find(&vapis).Error; err != nil {
@@ -287,6 +374,20 @@ func FindVapiPackagesByProjectID(db *gorm.DB, projectId uint) ([]VapiPackage, er
 	return vapis, nil
 }
 
+// FindVapiPackagesByGitRepo returns the packages registered from the repository like owner/name.
+func FindVapiPackagesByGitRepo(db *gorm.DB, gitRepo string) ([]VapiPackage, error) {
+	var vapis []VapiPackage
+	if err := db.
+		Where("LOWER(git_repo) = LOWER(?)", gitRepo).
+		Order("id ASC").
+		Find(&vapis).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapis by git repo")
+	}
+
+	return vapis, nil
+}
+
 func FindVapiPackages(db *gorm.DB) ([]VapiPackage, error) {
 	var vapis []VapiPackage
 	if err := db.Find(&vapis).Error; err != nil {
@@ -0,0 +1,180 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+type (
+	VapiPackageAccess string
+
+	// VapiPackageBorrower grants a private vapi package to a stack, an organization or a user. Exactly one of them is set.
+	VapiPackageBorrower struct {
+		Model
+
+		VapiPackageID uint        `gorm:"index"`
+		VapiPackage   VapiPackage `gorm:"foreignKey:VapiPackageID"`
+
+		StackID        *uint         `gorm:"index"`
+		Stack          *Stack        `gorm:"foreignKey:StackID"`
+		OrganizationID *uint         `gorm:"index"`
+		Organization   *Organization `gorm:"foreignKey:OrganizationID"`
+		UserID         *uint         `gorm:"index"`
+		User           *User         `gorm:"foreignKey:UserID"`
+
+		CreatedByID uint
+		CreatedBy   User `gorm:"foreignKey:CreatedByID"`
+	}
+)
+
+const (
+	// VapiPackageAccessPublic packages are listed in the search and can be enabled on every stack.
+	VapiPackageAccessPublic VapiPackageAccess = "public"
+	// VapiPackageAccessUnlisted packages can be enabled on every stack, but are listed only to whom they are granted.
+	VapiPackageAccessUnlisted VapiPackageAccess = "unlisted"
+	// VapiPackageAccessPrivate packages are listed and can be enabled only by whom they are granted.
+	VapiPackageAccessPrivate VapiPackageAccess = "private"
+)
+
+func (a VapiPackageAccess) IsValid() bool {
+	switch a {
+	case VapiPackageAccessPublic, VapiPackageAccessUnlisted, VapiPackageAccessPrivate:
+		return true
+	default:
+		return false
+	}
+}
+
+func (VapiPackageBorrower) TableName() string {
+	return "vapi_packages_borrowers"
+}
+
+func (b *VapiPackageBorrower) Save(db *gorm.DB) error {
+	return errors.Wrapf(db.Save(b).Error, "failed to save vapi package borrower")
+}
+
+func (b *VapiPackageBorrower) Delete(db *gorm.DB) error {
+	return errors.Wrapf(db.Delete(b).Error, "failed to delete vapi package borrower")
+}
+
+// IsGrantedTo returns ErrForbidden unless the package is not private, the user can view the package, or the package is
+// granted to the stack, the organization of the stack, the user or an organization of the user. stack and user are optional.
+func (v *VapiPackage) IsGrantedTo(db *gorm.DB, stack *Stack, user *User) error {
+	if v.Access != VapiPackageAccessPrivate {
+		return nil
+	}
+
+	if user != nil && (user.IsSuperuser() || v.CheckPermission(db, user, OrganizationRoleViewer) == nil) {
+		return nil
+	}
+
+	tx := db.Session(&gorm.Session{NewDB: true})
+	grantees := tx.Where("false")
+	if stack != nil {
+		grantees = grantees.Or("stack_id = ?", stack.ID)
+		if stack.Project.OrganizationID != nil {
+			grantees = grantees.Or("organization_id = ?", *stack.Project.OrganizationID)
+		}
+	}
+	if user != nil {
+		grantees = grantees.
+			Or("user_id = ?", user.ID).
+			Or("organization_id IN (?)", organizationIdsOfUser(tx, user.ID))
+	}
+
+	var count int64
+	if err := db.
+		Model(&VapiPackageBorrower{}).
+		Where("vapi_package_id = ?", v.ID).
+		Where(grantees).
+		Count(&count).
+		Error; err != nil {
+		return errors.Wrapf(err, "failed to count vapi package borrowers")
+	} else if count == 0 {
+		return errors.Wrapf(tclerrors.ErrForbidden, "vapi package '%s' is private", v.Name)
+	}
+
+	return nil
+}
+
+// ListedVapiPackages scopes vapi packages to the public ones and the ones granted to the user or to the stacks the user
+// can access. Only the public ones are listed if user is nil.
+func ListedVapiPackages(user *User) func(db *gorm.DB) *gorm.DB {
+	return vapiPackagesGrantedTo(user, VapiPackageAccessPublic)
+}
+
+// GrantedVapiPackages scopes vapi packages to the ones not private and the ones granted to the user or to the stacks
+// the user can access.
+func GrantedVapiPackages(user *User) func(db *gorm.DB) *gorm.DB {
+	return vapiPackagesGrantedTo(user, VapiPackageAccessPublic, VapiPackageAccessUnlisted)
+}
+
+func vapiPackagesGrantedTo(user *User, openAccesses ...VapiPackageAccess) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if user == nil {
+			return db.Where("vapi_packages.access IN ?", openAccesses)
+		} else if user.IsSuperuser() {
+			return db
+		}
+
+		tx := db.Session(&gorm.Session{NewDB: true})
+		orgIds := organizationIdsOfUser(tx, user.ID)
+		stackIds := tx.
+			Model(&Stack{}).
+			Select("stacks.id").
+			Joins("JOIN projects ON projects.id = stacks.project_id").
+			Where("projects.deleted_at = 0").
+			Where("projects.owner_id = ? OR projects.organization_id IN (?)", user.ID, orgIds)
+		borrowedIds := tx.
+			Model(&VapiPackageBorrower{}).
+			Select("vapi_package_id").
+			Where("user_id = ? OR organization_id IN (?) OR stack_id IN (?)", user.ID, orgIds, stackIds)
+
+		return db.Where(
+			"vapi_packages.access IN ? OR vapi_packages.owner_id = ? OR vapi_packages.organization_id IN (?) OR vapi_packages.id IN (?)",
+			openAccesses,
+			user.ID,
+			orgIds,
+			borrowedIds,
+		)
+	}
+}
+
+func organizationIdsOfUser(db *gorm.DB, userId uint) *gorm.DB {
+	return db.Model(&OrganizationMember{}).Select("organization_id").Where("user_id = ?", userId)
+}
+
+func FindVapiPackageBorrowerById(db *gorm.DB, id uint) (*VapiPackageBorrower, error) {
+	var b VapiPackageBorrower
+	if err := db.Limit(1).Find(&b, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapi package borrower")
+	} else if b.ID == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "vapi package borrower not found. id=%d", id)
+	}
+
+	return &b, nil
+}
+
+func FindVapiPackageBorrowersByPackageId(db *gorm.DB, packageId uint) ([]VapiPackageBorrower, error) {
+	var borrowers []VapiPackageBorrower
+	if err := db.
+		Preload("Stack").
+		Preload("Organization").
+		Preload("User").
+		Where("vapi_package_id = ?", packageId).
+		Order("id ASC").
+		Find(&borrowers).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapi package borrowers")
+	}
+
+	return borrowers, nil
+}
+
+func DeleteVapiPackageBorrowersByStackId(db *gorm.DB, stackId uint) error {
+	return errors.Wrapf(
+		db.Where("stack_id = ?", stackId).Delete(&VapiPackageBorrower{}).Error,
+		"failed to delete vapi package borrowers of the stack",
+	)
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"slices"
+)
+
+// VapiResources is the `resources` section of apidepot.yml. Empty fields fall back to the defaults.
+type VapiResources struct {
+	CPURequest         string `json:"cpu_request,omitempty" yaml:"cpu_request"`
+	CPULimit           string `json:"cpu_limit,omitempty" yaml:"cpu_limit"`
+	MemoryRequest      string `json:"memory_request,omitempty" yaml:"memory_request"`
+	MemoryLimit        string `json:"memory_limit,omitempty" yaml:"memory_limit"`
+	EdgeRuntimeVersion string `json:"edge_runtime_version,omitempty" yaml:"edge_runtime_version"`
+}
+
+func DefaultVapiResources() VapiResources {
+	return VapiResources{
+		CPURequest:         constants.DefaultVapiCPURequest,
+		MemoryRequest:      constants.DefaultVapiMemoryRequest,
+		MemoryLimit:        constants.DefaultVapiMemoryLimit,
+		EdgeRuntimeVersion: constants.DefaultVapiEdgeRuntimeVersion,
+	}
+}
+
+// Override returns r with the non-empty fields of o applied on top of it.
+func (r VapiResources) Override(o VapiResources) VapiResources {
+	if o.CPURequest != "" {
+		r.CPURequest = o.CPURequest
+	}
+	if o.CPULimit != "" {
+		r.CPULimit = o.CPULimit
+	}
+	if o.MemoryRequest != "" {
+		r.MemoryRequest = o.MemoryRequest
+	}
+	if o.MemoryLimit != "" {
+		r.MemoryLimit = o.MemoryLimit
+	}
+	if o.EdgeRuntimeVersion != "" {
+		r.EdgeRuntimeVersion = o.EdgeRuntimeVersion
+	}
+
+	return r
+}
+
+func parseQuantity(name string, value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "invalid %s '%s'", name, value)
+	}
+	if q.Sign() <= 0 {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "%s must be positive", name)
+	}
+
+	return &q, nil
+}
+
+// Validate checks that the quantities are well-formed, every request fits in its limit
+// and the edge runtime version is one we provide.
+func (r VapiResources) Validate() error {
+	if r.EdgeRuntimeVersion != "" && !slices.Contains(constants.VapiEdgeRuntimeVersions, r.EdgeRuntimeVersion) {
+		return errors.Wrapf(
+			tclerrors.ErrBadRequest,
+			"edge runtime version '%s' is not supported. must be one of %v",
+			r.EdgeRuntimeVersion,
+			constants.VapiEdgeRuntimeVersions,
+		)
+	}
+
+	for _, pair := range [][4]string{
+		{"cpu_request", r.CPURequest, "cpu_limit", r.CPULimit},
+		{"memory_request", r.MemoryRequest, "memory_limit", r.MemoryLimit},
+	} {
+		request, err := parseQuantity(pair[0], pair[1])
+		if err != nil {
+			return err
+		}
+		limit, err := parseQuantity(pair[2], pair[3])
+		if err != nil {
+			return err
+		}
+
+		if request != nil && limit != nil && request.Cmp(*limit) > 0 {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "%s must be less than or equal to %s", pair[0], pair[2])
+		}
+	}
+
+	return nil
+}
+
+// CheckQuota verifies that neither the requests nor the limits exceed the quota of the user.
+func (r VapiResources) CheckQuota(user *User) error {
+	milliCPUQuota, memoryQuota := user.VapiQuota()
+
+	for _, value := range []string{r.CPURequest, r.CPULimit} {
+		q, err := parseQuantity("cpu", value)
+		if err != nil {
+			return err
+		}
+		if q != nil && q.MilliValue() > milliCPUQuota {
+			return errors.Wrapf(tclerrors.ErrForbidden, "cpu '%s' exceeds the quota of %dm", value, milliCPUQuota)
+		}
+	}
+
+	for _, value := range []string{r.MemoryRequest, r.MemoryLimit} {
+		q, err := parseQuantity("memory", value)
+		if err != nil {
+			return err
+		}
+		if q != nil && q.Value() > memoryQuota {
+			return errors.Wrapf(tclerrors.ErrForbidden, "memory '%s' exceeds the quota of %d bytes", value, memoryQuota)
+		}
+	}
+
+	return nil
+}
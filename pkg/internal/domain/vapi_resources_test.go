@@ -0,0 +1,63 @@
+package domain_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+)
+
+func (s *DomainTestSuite) TestGivenResourcesOverMemoryLimitWhenValidateThenShouldBeBadRequest() {
+	// Given
+	resources := domain.DefaultVapiResources().Override(domain.VapiResources{
+		MemoryRequest: "512Mi",
+	})
+
+	// When
+	err := resources.Validate()
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrBadRequest)
+}
+
+func (s *DomainTestSuite) TestGivenUnknownEdgeRuntimeVersionWhenValidateThenShouldBeBadRequest() {
+	// Given
+	resources := domain.VapiResources{
+		EdgeRuntimeVersion: "v0.0.1",
+	}
+
+	// When
+	err := resources.Validate()
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrBadRequest)
+}
+
+func (s *DomainTestSuite) TestGivenResourcesWhenCheckQuotaThenShouldBeBoundedByUserQuota() {
+	// Given
+	resources := domain.DefaultVapiResources().Override(domain.VapiResources{
+		CPULimit:    "2",
+		MemoryLimit: "512Mi",
+	})
+
+	// When
+	errDefault := resources.CheckQuota(&domain.User{})
+	errRaised := resources.CheckQuota(&domain.User{VapiMilliCPUQuota: 2000})
+
+	// Then
+	s.Require().ErrorIs(errDefault, tclerrors.ErrForbidden)
+	s.Require().NoError(errRaised)
+}
+
+func (s *DomainTestSuite) TestGivenStackOverrideWhenOverrideThenShouldKeepUnsetFields() {
+	// Given
+	release := domain.VapiResources{MemoryLimit: "256Mi", EdgeRuntimeVersion: "v1.45.2"}
+	override := domain.VapiResources{MemoryLimit: "512Mi"}
+
+	// When
+	resources := domain.DefaultVapiResources().Override(release).Override(override)
+
+	// Then
+	s.Equal("512Mi", resources.MemoryLimit)
+	s.Equal("v1.45.2", resources.EdgeRuntimeVersion)
+	s.Equal("50m", resources.CPURequest)
+	s.NoError(resources.Validate())
+}
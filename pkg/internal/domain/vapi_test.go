@@ -75,3 +75,16 @@ func (s *DomainTestSuite) TestVapiRelease_DFS() {
 	s.Contains(deps[vapiRelease2.ID], vapiRelease4.ID)
 	s.Contains(deps[vapiRelease3.ID], vapiRelease4.ID)
 }
+
+func (s *DomainTestSuite) TestGivenSuperuserWhenCheckPermissionOfOrganizationPackageThenShouldAllow() {
+	// Given
+	orgId := uint(1)
+	pkg := domain.VapiPackage{Name: "org-package", OwnerId: 2, OrganizationID: &orgId}
+	superuser := domain.User{Model: domain.Model{ID: 3}, Role: domain.UserRoleAdmin}
+
+	// When
+	err := pkg.CheckPermission(s.db, &superuser, domain.OrganizationRoleOwner)
+
+	// Then
+	s.NoError(err)
+}
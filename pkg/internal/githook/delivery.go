@@ -0,0 +1,113 @@
+package githook
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+// deliveryStaleTimeout is how long a delivery may be pending before it is handled again on redelivery. It is longer
+// than the webhook handles a delivery, so a pending delivery older than it has been interrupted, e.g. by a restart.
+const deliveryStaleTimeout = 15 * time.Minute
+
+// handleDelivery records the delivery with the actions triggered by handle. A delivery already handled is not handled
+// again unless it has failed or has been pending for too long.
+func (s *service) handleDelivery(
+	ctx context.Context,
+	input domain.GithubDelivery,
+	handle func() ([]domain.GithubDeliveryAction, error),
+) (*domain.GithubDelivery, error) {
+	tx := helpers.GetTx(ctx)
+
+	delivery, claimed, err := claimDelivery(tx, input)
+	if err != nil {
+		return nil, err
+	} else if !claimed {
+		logger.Info("skip github delivery handled already", "guid", input.GUID, "status", delivery.Status)
+		return delivery, nil
+	}
+
+	actions, err := handle()
+	status := domain.GithubDeliveryStatusProcessed
+	if err != nil {
+		status = domain.GithubDeliveryStatusFailed
+	} else if len(actions) == 0 {
+		status = domain.GithubDeliveryStatusIgnored
+	} else if numFailed := countFailedActions(actions); numFailed > 0 {
+		status = domain.GithubDeliveryStatusFailed
+		err = errors.Errorf("%d of %d actions failed", numFailed, len(actions))
+	}
+
+	logger.Info("handled github delivery", "guid", input.GUID, "event", input.Event, "repo", input.Repo, "ref", input.Ref, "status", status)
+	if err := delivery.Finish(tx, status, actions, err); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// claimDelivery saves the delivery as pending if it is to be handled. The delivery is locked while it is claimed, so
+// that concurrent redeliveries of the same guid are handled once.
+func claimDelivery(db *gorm.DB, input domain.GithubDelivery) (delivery *domain.GithubDelivery, claimed bool, err error) {
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := domain.CreateGithubDeliveryIfNotExists(tx, input.GUID); err != nil {
+			return err
+		}
+
+		delivery, err = domain.FindGithubDeliveryByGUID(tx, input.GUID, domain.Locking(clause.Locking{Strength: "UPDATE"}))
+		if err != nil {
+			return err
+		}
+
+		switch delivery.Status {
+		case "", domain.GithubDeliveryStatusFailed:
+		case domain.GithubDeliveryStatusPending:
+			if time.Since(delivery.UpdatedAt) < deliveryStaleTimeout {
+				return nil
+			}
+			logger.Warn("retry stale github delivery", "guid", input.GUID, "updatedAt", delivery.UpdatedAt)
+		default:
+			return nil
+		}
+
+		delivery.Event = input.Event
+		delivery.Repo = input.Repo
+		delivery.Ref = input.Ref
+		delivery.CommitHash = input.CommitHash
+		delivery.InstallationID = input.InstallationID
+		delivery.Status = domain.GithubDeliveryStatusPending
+		if err := delivery.Save(tx); err != nil {
+			return err
+		}
+
+		claimed = true
+		return nil
+	})
+
+	return
+}
+
+// getInstallationAccessToken returns the token of the installation of the github app. It returns an empty token if the
+// app is not installed on the repository, so that public repositories are cloned without it.
+func (s *service) getInstallationAccessToken(ctx context.Context, installationId int64) (string, error) {
+	if installationId == 0 {
+		return "", nil
+	}
+
+	return s.githubClient.GenerateInstallationAccessToken(ctx, installationId)
+}
+
+func countFailedActions(actions []domain.GithubDeliveryAction) int {
+	count := 0
+	for _, action := range actions {
+		if action.Error != "" {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,187 @@
+package githook
+
+import (
+	"context"
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/mokiat/gog"
+	"gorm.io/gorm/clause"
+	"strings"
+)
+
+type (
+	// PullRequestInput is a pull_request event of the github webhook whose signature has been verified.
+	PullRequestInput struct {
+		GUID string
+		// Action is opened, reopened, closed, synchronize, etc.
+		Action         string
+		Repo           string
+		Number         int
+		BaseBranch     string
+		HeadRepo       string
+		HeadBranch     string
+		HeadCommitHash string
+		InstallationID int64
+	}
+)
+
+// HandlePullRequest creates a preview stack of the pull request for every stack enabling previews on the base branch
+// once the pull request is opened, and posts the url of the preview on the pull request. The previews are torn down
+// when the pull request is closed. Pull requests from forks are not previewed. Pushes to the head branch rebuild the
+// custom vapis of the previews as pushes to any other branch do.
+// The delivery is recorded with the actions it has triggered.
+func (s *service) HandlePullRequest(
+	ctx context.Context,
+	input PullRequestInput,
+) (*domain.GithubDelivery, error) {
+	return s.handleDelivery(ctx, domain.GithubDelivery{
+		GUID:           input.GUID,
+		Event:          "pull_request." + input.Action,
+		Repo:           input.Repo,
+		Ref:            fmt.Sprintf("refs/pull/%d/head", input.Number),
+		CommitHash:     input.HeadCommitHash,
+		InstallationID: input.InstallationID,
+	}, func() ([]domain.GithubDeliveryAction, error) {
+		switch input.Action {
+		case "opened", "reopened":
+			return s.createPreviews(ctx, input)
+		case "closed":
+			return s.deletePreviews(ctx, input)
+		default:
+			return nil, nil
+		}
+	})
+}
+
+func (s *service) createPreviews(ctx context.Context, input PullRequestInput) ([]domain.GithubDeliveryAction, error) {
+	// anyone can open a pull request from a fork, whose code is not to be run with the schema and the settings of
+	// the parent
+	if !strings.EqualFold(input.HeadRepo, input.Repo) {
+		logger.Info("skip preview of pull request from fork", "repo", input.Repo, "headRepo", input.HeadRepo, "number", input.Number)
+		return nil, nil
+	}
+
+	tx := helpers.GetTx(ctx)
+	stackIds, err := domain.FindPreviewableStackIdsByGitBranch(tx, input.Repo, input.BaseBranch)
+	if err != nil {
+		return nil, err
+	} else if len(stackIds) == 0 {
+		return nil, nil
+	}
+
+	accessToken, err := s.getInstallationAccessToken(ctx, input.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []domain.GithubDeliveryAction
+	for _, stackId := range stackIds {
+		action := domain.GithubDeliveryAction{
+			Kind:    domain.GithubDeliveryActionCreatePreview,
+			StackID: gog.PtrOf(stackId),
+		}
+		preview, err := s.stacks.CreatePreviewStack(ctx, stackId, stack.CreatePreviewStackInput{
+			GitRepo:           input.Repo,
+			PullRequestNumber: input.Number,
+			HeadGitRepo:       input.HeadRepo,
+			HeadGitBranch:     input.HeadBranch,
+			AccessToken:       accessToken,
+		})
+		if err != nil {
+			action.Error = err.Error()
+			actions = append(actions, action)
+			continue
+		}
+		action.Result = fmt.Sprintf("created preview %s at %s", preview.Name, preview.Endpoint())
+		actions = append(actions, action)
+
+		action = domain.GithubDeliveryAction{
+			Kind:    domain.GithubDeliveryActionDeploy,
+			StackID: gog.PtrOf(preview.ID),
+		}
+		if deployment, err := s.instances.DeployPreview(ctx, preview.ID); err != nil {
+			action.Error = err.Error()
+		} else {
+			action.InstanceID = gog.PtrOf(deployment.InstanceID)
+			action.Result = fmt.Sprintf("queued deployment %d", deployment.ID)
+		}
+		actions = append(actions, action)
+
+		// the url can't be posted without the installation of the github app
+		if accessToken == "" {
+			continue
+		}
+
+		action = domain.GithubDeliveryAction{
+			Kind:    domain.GithubDeliveryActionCommentPreview,
+			StackID: gog.PtrOf(preview.ID),
+		}
+		commentId, err := s.githubClient.CreateIssueComment(ctx, accessToken, input.Repo, input.Number, newPreviewComment(preview, input))
+		if err != nil {
+			action.Error = err.Error()
+		} else {
+			preview.Preview.CommentID = commentId
+			if err := preview.Preview.Save(tx.Omit(clause.Associations)); err != nil {
+				return nil, err
+			}
+			action.Result = fmt.Sprintf("posted comment %d", commentId)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+func (s *service) deletePreviews(ctx context.Context, input PullRequestInput) ([]domain.GithubDeliveryAction, error) {
+	previews, err := domain.FindStackPreviewsByPullRequest(helpers.GetTx(ctx), input.Repo, input.Number)
+	if err != nil {
+		return nil, err
+	} else if len(previews) == 0 {
+		return nil, nil
+	}
+
+	accessToken, err := s.getInstallationAccessToken(ctx, input.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []domain.GithubDeliveryAction
+	for _, preview := range previews {
+		action := domain.GithubDeliveryAction{
+			Kind:    domain.GithubDeliveryActionDeletePreview,
+			StackID: gog.PtrOf(preview.ParentStackID),
+		}
+		if err := s.instances.DeletePreview(ctx, preview.StackID); err != nil {
+			action.Error = err.Error()
+			actions = append(actions, action)
+			continue
+		}
+		action.Result = fmt.Sprintf("deleted preview %s", preview.Stack.Name)
+		actions = append(actions, action)
+
+		if preview.CommentID == 0 || accessToken == "" {
+			continue
+		}
+
+		// the comment is kept but tells the url is gone
+		body := fmt.Sprintf("The preview `%s` of this pull request has been torn down.", preview.Stack.Name)
+		if err := s.githubClient.EditIssueComment(ctx, accessToken, input.Repo, preview.CommentID, body); err != nil {
+			logger.Warn("failed to edit preview comment", "commentId", preview.CommentID, "err", err)
+		}
+	}
+
+	return actions, nil
+}
+
+func newPreviewComment(preview *domain.Stack, input PullRequestInput) string {
+	return fmt.Sprintf(
+		"The preview `%s` of this pull request is being deployed to %s from `%s:%s`.\n\n"+
+			"Its custom vapis are rebuilt on pushes to the branch, and it is torn down when the pull request is closed.",
+		preview.Name,
+		preview.Endpoint(),
+		input.HeadRepo,
+		input.HeadBranch,
+	)
+}
@@ -0,0 +1,176 @@
+package githook
+
+import (
+	"context"
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/mokiat/gog"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxPushCommits is the number of the commits a push payload lists at most. the changed paths of a larger push
+	// are not known.
+	maxPushCommits = 2048
+)
+
+type (
+	// PushInput is a push event of the github webhook whose signature has been verified.
+	PushInput struct {
+		GUID           string
+		Repo           string
+		Ref            string
+		CommitHash     string
+		InstallationID int64
+		Created        bool
+		Deleted        bool
+		Forced         bool
+		// Commits are the paths changed by each pushed commit
+		Commits [][]string
+	}
+)
+
+// HandlePush registers a release of every package registered from the pushed branch, or from the repository if a tag
+// is pushed, on behalf of the owner of the package. On a push to a branch, it also rebuilds the custom vapis of the
+// stacks building them from the branch, and redeploys their instances subscribing to auto deployments.
+// The delivery is recorded with the actions it has triggered. A delivery already handled is not handled again
+// unless it has failed or has been pending for too long.
+func (s *service) HandlePush(
+	ctx context.Context,
+	input PushInput,
+) (*domain.GithubDelivery, error) {
+	return s.handleDelivery(ctx, domain.GithubDelivery{
+		GUID:           input.GUID,
+		Event:          "push",
+		Repo:           input.Repo,
+		Ref:            input.Ref,
+		CommitHash:     input.CommitHash,
+		InstallationID: input.InstallationID,
+	}, func() ([]domain.GithubDeliveryAction, error) {
+		return s.handlePush(ctx, input)
+	})
+}
+
+func (s *service) handlePush(ctx context.Context, input PushInput) ([]domain.GithubDeliveryAction, error) {
+	if input.Deleted {
+		return nil, nil
+	}
+
+	branch, isBranch := strings.CutPrefix(input.Ref, "refs/heads/")
+	_, isTag := strings.CutPrefix(input.Ref, "refs/tags/")
+	if !isBranch && !isTag {
+		return nil, nil
+	}
+
+	tx := helpers.GetTx(ctx)
+	pkgs, err := domain.FindVapiPackagesByGitRepo(tx, input.Repo)
+	if err != nil {
+		return nil, err
+	}
+	pkgs = slices.DeleteFunc(pkgs, func(pkg domain.VapiPackage) bool {
+		return isBranch && pkg.GitBranch != branch
+	})
+
+	var stackIds []uint
+	if isBranch {
+		stackIds, err = domain.FindStackIdsByGitBranch(tx, input.Repo, branch)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(pkgs) == 0 && len(stackIds) == 0 {
+		return nil, nil
+	}
+
+	accessToken, err := s.getInstallationAccessToken(ctx, input.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []domain.GithubDeliveryAction
+	for _, pkg := range pkgs {
+		action := domain.GithubDeliveryAction{
+			Kind:          domain.GithubDeliveryActionRegisterVapi,
+			VapiPackageID: gog.PtrOf(pkg.ID),
+		}
+		if rel, err := s.vapis.RegisterPushedRef(ctx, pkg.ID, input.Ref, accessToken); err != nil {
+			action.Error = err.Error()
+		} else {
+			action.Result = fmt.Sprintf("registered %s@%s", pkg.Name, rel.Version)
+		}
+		actions = append(actions, action)
+	}
+
+	// stacks are redeployed only to pick up their rebuilt custom vapis, since the rest of them is not kept in git
+	customVapiNames := getChangedCustomVapiNames(input)
+	for _, stackId := range stackIds {
+		if customVapiNames != nil && len(customVapiNames) == 0 {
+			break
+		}
+
+		action := domain.GithubDeliveryAction{
+			Kind:    domain.GithubDeliveryActionRebuildCustomVapis,
+			StackID: gog.PtrOf(stackId),
+		}
+		customVapis, err := s.stacks.RebuildCustomVapis(ctx, stackId, customVapiNames, accessToken)
+		if err != nil {
+			action.Error = err.Error()
+			actions = append(actions, action)
+			continue
+		} else if len(customVapis) == 0 {
+			continue
+		}
+		action.Result = "rebuilt " + strings.Join(gog.Map(customVapis, func(v domain.CustomVapi) string {
+			return v.Name
+		}), ", ")
+		actions = append(actions, action)
+
+		deployments, err := s.instances.EnqueueAutoDeployments(ctx, stackId)
+		if err != nil {
+			actions = append(actions, domain.GithubDeliveryAction{
+				Kind:    domain.GithubDeliveryActionDeploy,
+				StackID: gog.PtrOf(stackId),
+				Error:   err.Error(),
+			})
+			continue
+		}
+		for _, deployment := range deployments {
+			actions = append(actions, domain.GithubDeliveryAction{
+				Kind:       domain.GithubDeliveryActionDeploy,
+				StackID:    gog.PtrOf(stackId),
+				InstanceID: gog.PtrOf(deployment.InstanceID),
+				Result:     fmt.Sprintf("queued deployment %d", deployment.ID),
+			})
+		}
+	}
+
+	return actions, nil
+}
+
+// getChangedCustomVapiNames returns the names of the custom vapis under /vapis changed by the push. It returns nil if
+// the changed paths are not known, e.g. for a new or a force-pushed branch, so that every custom vapi is rebuilt.
+func getChangedCustomVapiNames(input PushInput) []string {
+	if input.Created || input.Forced || len(input.Commits) == 0 || len(input.Commits) >= maxPushCommits {
+		return nil
+	}
+
+	names := []string{}
+	for _, paths := range input.Commits {
+		for _, path := range paths {
+			rest, ok := strings.CutPrefix(path, "vapis/")
+			if !ok {
+				continue
+			}
+
+			name, _, isDir := strings.Cut(rest, "/")
+			if isDir && name != "" && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+
+	return names
+}
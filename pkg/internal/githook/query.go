@@ -0,0 +1,47 @@
+package githook
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+)
+
+const (
+	maxDeliveries = 50
+)
+
+// GetStackDeliveries returns the latest deliveries having triggered actions on the stack, newest first.
+func (s *service) GetStackDeliveries(
+	ctx context.Context,
+	stackId uint,
+) ([]domain.GithubDelivery, error) {
+	st, err := s.stacks.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.FindGithubDeliveriesByStackId(helpers.GetTx(ctx), st.ID, maxDeliveries)
+}
+
+// GetVapiPackageDeliveries returns the latest deliveries having triggered actions on the package, newest first.
+func (s *service) GetVapiPackageDeliveries(
+	ctx context.Context,
+	packageId uint,
+) ([]domain.GithubDelivery, error) {
+	tx := helpers.GetTx(ctx)
+	pkg, err := domain.FindVapiPackageByID(tx, packageId)
+	if err != nil {
+		return nil, err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return domain.FindGithubDeliveriesByVapiPackageId(tx, pkg.ID, maxDeliveries)
+}
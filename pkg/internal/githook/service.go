@@ -0,0 +1,88 @@
+package githook
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/instance"
+	tclog "github.com/habiliai/apidepot/pkg/internal/log"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/habiliai/apidepot/pkg/internal/user"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+)
+
+type (
+	Service interface {
+		HandlePush(
+			ctx context.Context,
+			input PushInput,
+		) (*domain.GithubDelivery, error)
+		HandlePullRequest(
+			ctx context.Context,
+			input PullRequestInput,
+		) (*domain.GithubDelivery, error)
+		GetStackDeliveries(
+			ctx context.Context,
+			stackId uint,
+		) ([]domain.GithubDelivery, error)
+		GetVapiPackageDeliveries(
+			ctx context.Context,
+			packageId uint,
+		) ([]domain.GithubDelivery, error)
+	}
+
+	service struct {
+		users        user.Service
+		vapis        vapi.Service
+		stacks       stack.Service
+		instances    instance.Service
+		githubClient services.GithubClient
+	}
+)
+
+const (
+	ServiceKey = "githookService"
+)
+
+var (
+	_      Service = (*service)(nil)
+	logger         = tclog.GetLogger()
+)
+
+func init() {
+	digo.ProvideService(ServiceKey, func(ctx *digo.Container) (any, error) {
+		users, err := digo.Get[user.Service](ctx, user.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		vapis, err := digo.Get[vapi.Service](ctx, vapi.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		stacks, err := digo.Get[stack.Service](ctx, stack.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		instances, err := digo.Get[instance.Service](ctx, instance.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		githubClient, err := digo.Get[services.GithubClient](ctx, services.ServiceKeyGithubClient)
+		if err != nil {
+			return nil, err
+		}
+
+		return &service{
+			users:        users,
+			vapis:        vapis,
+			stacks:       stacks,
+			instances:    instances,
+			githubClient: githubClient,
+		}, nil
+	})
+}
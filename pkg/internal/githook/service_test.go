@@ -0,0 +1,362 @@
+package githook_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/instance"
+	instancetest "github.com/habiliai/apidepot/pkg/internal/instance/test"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	servicestest "github.com/habiliai/apidepot/pkg/internal/services/test"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	stacktest "github.com/habiliai/apidepot/pkg/internal/stack/test"
+	"github.com/habiliai/apidepot/pkg/internal/user"
+	usertest "github.com/habiliai/apidepot/pkg/internal/user/test"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+	vapitest "github.com/habiliai/apidepot/pkg/internal/vapi/test"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+type GithookTestSuite struct {
+	suite.Suite
+
+	db        *gorm.DB
+	githooks  githook.Service
+	vapis     *vapitest.ServiceMock
+	stacks    *stacktest.ServiceMock
+	instances *instancetest.ServiceMock
+	github    *servicestest.MockGithubClient
+
+	user domain.User
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func (s *GithookTestSuite) Context() context.Context {
+	return s.ctx
+}
+
+func (s *GithookTestSuite) SetupTest() {
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	container := digo.NewContainer(s.Context(), digo.EnvTest, nil)
+	s.vapis = &vapitest.ServiceMock{}
+	digo.Set(container, vapi.ServiceKey, s.vapis)
+	s.stacks = &stacktest.ServiceMock{}
+	digo.Set(container, stack.ServiceKey, s.stacks)
+	s.instances = &instancetest.ServiceMock{}
+	digo.Set(container, instance.ServiceKey, s.instances)
+	s.github = &servicestest.MockGithubClient{}
+	digo.Set(container, services.ServiceKeyGithubClient, s.github)
+	digo.Set(container, user.ServiceKey, usertest.NewService())
+
+	s.db = digo.MustGet[*gorm.DB](container, services.ServiceKeyDB)
+	s.ctx = helpers.WithTx(s.Context(), s.db)
+	s.githooks = digo.MustGet[githook.Service](container, githook.ServiceKey)
+
+	s.user = domain.User{GithubInstallationId: 1}
+	s.Require().NoError(s.user.Save(s.db))
+}
+
+func (s *GithookTestSuite) TearDownTest() {
+	defer s.cancel()
+
+	s.vapis.AssertExpectations(s.T())
+	s.stacks.AssertExpectations(s.T())
+	s.instances.AssertExpectations(s.T())
+	s.github.AssertExpectations(s.T())
+}
+
+func TestGithookService(t *testing.T) {
+	suite.Run(t, new(GithookTestSuite))
+}
+
+func (s *GithookTestSuite) TestGivenPackageOnBranchWhenPushThenRegisterRelease() {
+	pkg := domain.VapiPackage{
+		Name:      "vapi-githook",
+		GitRepo:   "habiliai/vapi-githook",
+		GitBranch: "main",
+		OwnerId:   s.user.ID,
+	}
+	s.Require().NoError(pkg.Save(s.db))
+
+	s.github.On("GenerateInstallationAccessToken", mock.Anything, int64(1)).Return("token", nil).Once()
+	s.vapis.On("RegisterPushedRef", mock.Anything, pkg.ID, "refs/heads/main", "token").
+		Return(&domain.VapiRelease{Version: "0.1.0", PackageID: pkg.ID}, nil).Once()
+
+	delivery, err := s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID:           "push-1",
+		Repo:           "HabiliAI/vapi-githook",
+		Ref:            "refs/heads/main",
+		CommitHash:     "abcdef",
+		InstallationID: 1,
+		Commits:        [][]string{{"README.md"}},
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusProcessed, delivery.Status)
+
+	deliveries, err := domain.FindGithubDeliveriesByVapiPackageId(s.db, pkg.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	s.Require().Len(deliveries[0].Actions, 1)
+	s.Equal(domain.GithubDeliveryActionRegisterVapi, deliveries[0].Actions[0].Kind)
+	s.Equal("registered vapi-githook@0.1.0", deliveries[0].Actions[0].Result)
+
+	// redelivery is not handled again
+	delivery, err = s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID: "push-1",
+		Repo: "habiliai/vapi-githook",
+		Ref:  "refs/heads/main",
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusProcessed, delivery.Status)
+}
+
+func (s *GithookTestSuite) TestGivenStackWhenPushCustomVapiThenRebuildAndDeploy() {
+	project := domain.Project{Name: "githook-project", OwnerID: s.user.ID}
+	s.Require().NoError(s.db.Save(&project).Error)
+	stack := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "githook-stack",
+		Hash:      "githook-stack",
+		GitRepo:   "habiliai/githook-stack",
+		GitBranch: "main",
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	s.stacks.On("RebuildCustomVapis", mock.Anything, stack.ID, []string{"hello"}, "").
+		Return([]domain.CustomVapi{{Name: "hello"}}, nil).Once()
+	s.instances.On("EnqueueAutoDeployments", mock.Anything, stack.ID).
+		Return([]domain.Deployment{{Model: domain.Model{ID: 3}, InstanceID: 2}}, nil).Once()
+
+	delivery, err := s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID:       "push-2",
+		Repo:       "habiliai/githook-stack",
+		Ref:        "refs/heads/main",
+		CommitHash: "abcdef",
+		Commits:    [][]string{{"vapis/hello/index.ts", "README.md"}, {"vapis/hello/deno.json"}},
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusProcessed, delivery.Status)
+
+	deliveries, err := domain.FindGithubDeliveriesByStackId(s.db, stack.ID, 10)
+	s.Require().NoError(err)
+	s.Require().Len(deliveries, 1)
+	s.Require().Len(deliveries[0].Actions, 2)
+	s.Equal(domain.GithubDeliveryActionRebuildCustomVapis, deliveries[0].Actions[0].Kind)
+	s.Equal(domain.GithubDeliveryActionDeploy, deliveries[0].Actions[1].Kind)
+	s.Equal(uint(2), *deliveries[0].Actions[1].InstanceID)
+}
+
+func (s *GithookTestSuite) TestGivenStackWhenPushWithoutCustomVapiChangesThenIgnore() {
+	project := domain.Project{Name: "githook-project", OwnerID: s.user.ID}
+	s.Require().NoError(s.db.Save(&project).Error)
+	stack := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "githook-stack",
+		Hash:      "githook-stack",
+		GitRepo:   "habiliai/githook-stack",
+		GitBranch: "main",
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	delivery, err := s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID:    "push-3",
+		Repo:    "habiliai/githook-stack",
+		Ref:     "refs/heads/main",
+		Commits: [][]string{{"README.md"}},
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusIgnored, delivery.Status)
+}
+
+func (s *GithookTestSuite) TestGivenStalePendingDeliveryWhenRedeliveredThenHandleAgain() {
+	// Given
+	delivery := domain.GithubDelivery{GUID: "push-4", Status: domain.GithubDeliveryStatusPending}
+	s.Require().NoError(delivery.Save(s.db))
+	s.Require().NoError(s.db.Model(&delivery).UpdateColumn("updated_at", time.Now().Add(-time.Hour)).Error)
+
+	recent := domain.GithubDelivery{GUID: "push-5", Status: domain.GithubDeliveryStatusPending}
+	s.Require().NoError(recent.Save(s.db))
+
+	// When
+	stale, err := s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID: "push-4",
+		Repo: "habiliai/githook-unknown",
+		Ref:  "refs/heads/main",
+	})
+	s.Require().NoError(err)
+	pending, err := s.githooks.HandlePush(s.Context(), githook.PushInput{
+		GUID: "push-5",
+		Repo: "habiliai/githook-unknown",
+		Ref:  "refs/heads/main",
+	})
+	s.Require().NoError(err)
+
+	// Then
+	s.Equal(domain.GithubDeliveryStatusIgnored, stale.Status)
+	s.Equal(domain.GithubDeliveryStatusPending, pending.Status)
+}
+
+func (s *GithookTestSuite) TestGivenPreviewsEnabledWhenPullRequestOpenedThenCreatePreview() {
+	project := domain.Project{Name: "githook-project", OwnerID: s.user.ID}
+	s.Require().NoError(s.db.Save(&project).Error)
+	parent := domain.Stack{
+		ProjectID:       project.ID,
+		Name:            "githook-stack",
+		Hash:            "githook-stack",
+		GitRepo:         "habiliai/githook-stack",
+		GitBranch:       "main",
+		PreviewsEnabled: true,
+	}
+	s.Require().NoError(parent.Save(s.db))
+	preview := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "githook-stack-pr-7",
+		Hash:      "githook-stack-pr-7",
+		GitRepo:   "habiliai/githook-stack",
+		GitBranch: "feature",
+	}
+	s.Require().NoError(preview.Save(s.db))
+	preview.Preview = &domain.StackPreview{
+		StackID:           preview.ID,
+		ParentStackID:     parent.ID,
+		GitRepo:           "habiliai/githook-stack",
+		PullRequestNumber: 7,
+	}
+	s.Require().NoError(preview.Preview.Save(s.db))
+
+	s.github.On("GenerateInstallationAccessToken", mock.Anything, int64(1)).Return("token", nil).Once()
+	s.stacks.On("CreatePreviewStack", mock.Anything, parent.ID, stack.CreatePreviewStackInput{
+		GitRepo:           "habiliai/githook-stack",
+		PullRequestNumber: 7,
+		HeadGitRepo:       "habiliai/githook-stack",
+		HeadGitBranch:     "feature",
+		AccessToken:       "token",
+	}).Return(&preview, nil).Once()
+	s.instances.On("DeployPreview", mock.Anything, preview.ID).
+		Return(&domain.Deployment{Model: domain.Model{ID: 5}, InstanceID: 4}, nil).Once()
+	s.github.On("CreateIssueComment", mock.Anything, "token", "habiliai/githook-stack", 7, mock.Anything).
+		Return(int64(42), nil).Once()
+
+	delivery, err := s.githooks.HandlePullRequest(s.Context(), githook.PullRequestInput{
+		GUID:           "pull-request-1",
+		Action:         "opened",
+		Repo:           "habiliai/githook-stack",
+		Number:         7,
+		BaseBranch:     "main",
+		HeadRepo:       "habiliai/githook-stack",
+		HeadBranch:     "feature",
+		HeadCommitHash: "abcdef",
+		InstallationID: 1,
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusProcessed, delivery.Status)
+	s.Equal("pull_request.opened", delivery.Event)
+	s.Require().Len(delivery.Actions, 3)
+	s.Equal(domain.GithubDeliveryActionCreatePreview, delivery.Actions[0].Kind)
+	s.Equal(domain.GithubDeliveryActionDeploy, delivery.Actions[1].Kind)
+	s.Equal(uint(4), *delivery.Actions[1].InstanceID)
+	s.Equal(domain.GithubDeliveryActionCommentPreview, delivery.Actions[2].Kind)
+
+	saved, err := domain.FindStackPreviewByParentStackIdAndPullRequest(s.db, parent.ID, "habiliai/githook-stack", 7)
+	s.Require().NoError(err)
+	s.Equal(int64(42), saved.CommentID)
+}
+
+func (s *GithookTestSuite) TestGivenPreviewsEnabledWhenPullRequestFromForkOpenedThenIgnore() {
+	project := domain.Project{Name: "githook-project", OwnerID: s.user.ID}
+	s.Require().NoError(s.db.Save(&project).Error)
+	parent := domain.Stack{
+		ProjectID:       project.ID,
+		Name:            "githook-stack",
+		Hash:            "githook-stack",
+		GitRepo:         "habiliai/githook-stack",
+		GitBranch:       "main",
+		PreviewsEnabled: true,
+	}
+	s.Require().NoError(parent.Save(s.db))
+
+	delivery, err := s.githooks.HandlePullRequest(s.Context(), githook.PullRequestInput{
+		GUID:           "pull-request-fork",
+		Action:         "opened",
+		Repo:           "habiliai/githook-stack",
+		Number:         8,
+		BaseBranch:     "main",
+		HeadRepo:       "someone/githook-stack",
+		HeadBranch:     "main",
+		HeadCommitHash: "abcdef",
+		InstallationID: 1,
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusIgnored, delivery.Status)
+}
+
+func (s *GithookTestSuite) TestGivenPreviewWhenPullRequestClosedThenDeletePreview() {
+	project := domain.Project{Name: "githook-project", OwnerID: s.user.ID}
+	s.Require().NoError(s.db.Save(&project).Error)
+	parent := domain.Stack{
+		ProjectID:       project.ID,
+		Name:            "githook-stack",
+		Hash:            "githook-stack",
+		GitRepo:         "habiliai/githook-stack",
+		GitBranch:       "main",
+		PreviewsEnabled: true,
+	}
+	s.Require().NoError(parent.Save(s.db))
+	preview := domain.Stack{
+		ProjectID: project.ID,
+		Name:      "githook-stack-pr-8",
+		Hash:      "githook-stack-pr-8",
+		GitRepo:   "habiliai/githook-stack",
+		GitBranch: "feature",
+	}
+	s.Require().NoError(preview.Save(s.db))
+	stackPreview := domain.StackPreview{
+		StackID:           preview.ID,
+		ParentStackID:     parent.ID,
+		GitRepo:           "habiliai/githook-stack",
+		PullRequestNumber: 8,
+		CommentID:         43,
+	}
+	s.Require().NoError(stackPreview.Save(s.db))
+
+	s.github.On("GenerateInstallationAccessToken", mock.Anything, int64(1)).Return("token", nil).Once()
+	s.instances.On("DeletePreview", mock.Anything, preview.ID).Return(nil).Once()
+	s.github.On("EditIssueComment", mock.Anything, "token", "habiliai/githook-stack", int64(43), mock.Anything).
+		Return(nil).Once()
+
+	delivery, err := s.githooks.HandlePullRequest(s.Context(), githook.PullRequestInput{
+		GUID:           "pull-request-2",
+		Action:         "closed",
+		Repo:           "habiliai/githook-stack",
+		Number:         8,
+		BaseBranch:     "main",
+		HeadRepo:       "habiliai/githook-stack",
+		HeadBranch:     "feature",
+		InstallationID: 1,
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusProcessed, delivery.Status)
+	s.Require().Len(delivery.Actions, 1)
+	s.Equal(domain.GithubDeliveryActionDeletePreview, delivery.Actions[0].Kind)
+	s.Equal(parent.ID, *delivery.Actions[0].StackID)
+}
+
+func (s *GithookTestSuite) TestGivenPullRequestWhenSynchronizeThenIgnore() {
+	delivery, err := s.githooks.HandlePullRequest(s.Context(), githook.PullRequestInput{
+		GUID:   "pull-request-3",
+		Action: "synchronize",
+		Repo:   "habiliai/githook-stack",
+		Number: 9,
+	})
+	s.Require().NoError(err)
+	s.Equal(domain.GithubDeliveryStatusIgnored, delivery.Status)
+}
@@ -0,0 +1,34 @@
+package githooktest
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
+	"github.com/stretchr/testify/mock"
+)
+
+type ServiceMock struct {
+	mock.Mock
+}
+
+var _ githook.Service = (*ServiceMock)(nil)
+
+func (s *ServiceMock) HandlePush(ctx context.Context, input githook.PushInput) (*domain.GithubDelivery, error) {
+	args := s.Called(ctx, input)
+	return args.Get(0).(*domain.GithubDelivery), args.Error(1)
+}
+
+func (s *ServiceMock) HandlePullRequest(ctx context.Context, input githook.PullRequestInput) (*domain.GithubDelivery, error) {
+	args := s.Called(ctx, input)
+	return args.Get(0).(*domain.GithubDelivery), args.Error(1)
+}
+
+func (s *ServiceMock) GetStackDeliveries(ctx context.Context, stackId uint) ([]domain.GithubDelivery, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.GithubDelivery), args.Error(1)
+}
+
+func (s *ServiceMock) GetVapiPackageDeliveries(ctx context.Context, packageId uint) ([]domain.GithubDelivery, error) {
+	args := s.Called(ctx, packageId)
+	return args.Get(0).([]domain.GithubDelivery), args.Error(1)
+}
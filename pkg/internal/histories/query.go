@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/pkg/errors"
 	"time"
 )
@@ -11,6 +12,8 @@ import (
 type (
 	InstanceRunningTime struct {
 		InstanceId uint
+		StackId    uint
+		Zone       tcltypes.InstanceZone
 		Duration   time.Duration
 	}
 )
@@ -58,9 +61,17 @@ func (s *service) GetTotalRunningTimeForUser(
 		maxTimes[hist.InstanceID] = maxTime
 	}
 
+	seen := map[uint]bool{}
 	for _, hist := range histories {
+		if seen[hist.InstanceID] {
+			continue
+		}
+		seen[hist.InstanceID] = true
+
 		instanceRunningTimes = append(instanceRunningTimes, InstanceRunningTime{
 			InstanceId: hist.InstanceID,
+			StackId:    hist.Instance.StackID,
+			Zone:       hist.Instance.Zone,
 			Duration:   maxTimes[hist.InstanceID].Sub(minTimes[hist.InstanceID]),
 		})
 	}
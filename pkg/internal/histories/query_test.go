@@ -52,7 +52,7 @@ func (s *HistoriesTestSuite) TestGetTotalRunningTimeForMyInstancesIn() {
 
 	// then
 	s.Require().NoError(err)
-	s.Require().Len(runningTimes, 9)
+	s.Require().Len(runningTimes, 3)
 	var totalTime time.Duration
 	for _, rt := range runningTimes {
 		totalTime += rt.Duration
@@ -60,5 +60,5 @@ func (s *HistoriesTestSuite) TestGetTotalRunningTimeForMyInstancesIn() {
 	s.Require().NotZero(totalTime)
 
 	s.T().Logf("totalTime: %s", totalTime)
-	s.Require().Equal(90, int(totalTime.Seconds()))
+	s.Require().Equal(30, int(totalTime.Seconds()))
 }
@@ -9,6 +9,8 @@ import (
 	"gorm.io/gorm"
 )
 
+// WriteInstanceHistoriesAt samples whether each instance is running. The instances not running are sampled as well,
+// so that their samples close the running intervals billed before them.
 func (s *service) WriteInstanceHistoriesAt(
 	ctx context.Context,
 ) (err error) {
@@ -23,7 +25,7 @@ func (s *service) WriteInstanceHistoriesAt(
 			if err = tx.
 				Order("id ASC").
 				Limit(250).
-				Find(&instances, "state = ? AND id > ?", domain.InstanceStateRunning, cursor).
+				Find(&instances, "id > ?", cursor).
 				Error; err != nil {
 				return errors.Wrapf(err, "failed to find instances")
 			}
@@ -57,6 +57,36 @@ func (s *HistoriesTestSuite) TestScanRunningAllInstances() {
 	s.Require().True(instanceHistory.Running)
 }
 
+func (s *HistoriesTestSuite) TestGivenStoppedInstanceWhenWriteInstanceHistoriesThenShouldSampleNotRunning() {
+	// Given
+	st := domain.Stack{
+		Name: "test-stack",
+		Project: domain.Project{
+			Name: "test-project",
+			Owner: domain.User{
+				Name: "test",
+			},
+		},
+	}
+	s.Require().NoError(st.Save(s.db))
+	instance := domain.Instance{
+		Name:  "test-instance",
+		State: domain.InstanceStateReady,
+		Stack: st,
+	}
+	s.Require().NoError(instance.Save(s.db))
+
+	// When
+	err := s.service.WriteInstanceHistoriesAt(s)
+
+	// Then
+	s.Require().NoError(err)
+
+	var instanceHistory domain.InstanceHistory
+	s.Require().NoError(s.db.First(&instanceHistory, "instance_id = ?", instance.ID).Error)
+	s.False(instanceHistory.Running)
+}
+
 func (s *HistoriesTestSuite) TestWriteStackHistoriesAt() {
 	// given
 	project := domain.Project{
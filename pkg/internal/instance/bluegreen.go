@@ -0,0 +1,284 @@
+package instance
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
+	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
+	"github.com/habiliai/apidepot/pkg/internal/util/functx/v2"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"time"
+)
+
+func objectKey(obj *unstructured.Unstructured) k8syaml.K8sObjectDiffKey {
+	return k8syaml.K8sObjectDiffKey{
+		GVK:  obj.GroupVersionKind(),
+		Name: obj.GetName(),
+	}
+}
+
+func objectKeys(objects []unstructured.Unstructured) map[k8syaml.K8sObjectDiffKey]struct{} {
+	keys := make(map[k8syaml.K8sObjectDiffKey]struct{}, len(objects))
+	for i := range objects {
+		keys[objectKey(&objects[i])] = struct{}{}
+	}
+
+	return keys
+}
+
+// isRoutingObject reports whether the object routes traffic to the deployments, i.e. the ingress and its middlewares.
+func isRoutingObject(obj *unstructured.Unstructured) bool {
+	kind := obj.GetKind()
+	return kind == "Ingress" || kind == "Middleware"
+}
+
+func filterObjects(objects []unstructured.Unstructured, fn func(obj *unstructured.Unstructured) bool) []unstructured.Unstructured {
+	result := make([]unstructured.Unstructured, 0, len(objects))
+	for i := range objects {
+		if fn(&objects[i]) {
+			result = append(result, objects[i])
+		}
+	}
+
+	return result
+}
+
+// restoreObjects puts the objects back to their state in the first snapshot containing them,
+// and deletes the ones which aren't in any snapshot.
+func restoreObjects(
+	ctx context.Context,
+	k8sClient k8s.Client,
+	objects []unstructured.Unstructured,
+	snapshots ...[]unstructured.Unstructured,
+) {
+	restoreTargets := make([]unstructured.Unstructured, 0, len(objects))
+	deleteTargets := make([]unstructured.Unstructured, 0, len(objects))
+
+	for i := range objects {
+		key := objectKey(&objects[i])
+
+		restored := false
+		for _, snapshot := range snapshots {
+			for j := range snapshot {
+				if objectKey(&snapshot[j]) == key {
+					restoreTargets = append(restoreTargets, snapshot[j])
+					restored = true
+					break
+				}
+			}
+			if restored {
+				break
+			}
+		}
+
+		if !restored {
+			deleteTargets = append(deleteTargets, objects[i])
+		}
+	}
+
+	if err := k8sClient.Apply(ctx, restoreTargets); err != nil {
+		logger.Warn("failed to restore objects", "err", err)
+	}
+	if err := k8sClient.Delete(ctx, deleteTargets, false); err != nil {
+		logger.Warn("failed to delete objects", "err", err)
+	}
+}
+
+// applyK8sBlueGreen renders the stack with the next color next to the live one, health-checks it through
+// the preview ingress and then switches the ingress to it. Config maps and secrets are colored as well, so that the
+// live color keeps running with its own config until the switch.
+// The live color is kept as the previous objects of the instance, so that switchToPreviousColor can switch back to it.
+func (s *service) applyK8sBlueGreen(
+	ctx context.Context,
+	instance *domain.Instance,
+	timeout time.Duration,
+) error {
+	tx := helpers.GetTx(ctx)
+	ctx, fDone := functx.WithFuncTx(ctx)
+	defer fDone(ctx, true)
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return err
+	}
+
+	reportPhase(ctx, domain.DeploymentPhaseRender)
+	color := instance.NextColor()
+	values, k8sYamlFiles, err := s.newK8sYamlValues(ctx, instance)
+	if err != nil {
+		return err
+	}
+
+	previewK8sYaml, err := s.k8sYamlService.RenderYaml(k8sYamlFiles, values.WithColor(color).WithPreviewIngress())
+	if err != nil {
+		return err
+	}
+	newK8sYaml, err := s.k8sYamlService.RenderYaml(k8sYamlFiles, values.WithColor(color))
+	if err != nil {
+		return err
+	}
+
+	liveObjects, err := k8syaml.ParseK8sYaml(instance.AppliedK8sYaml)
+	if err != nil {
+		return err
+	}
+	standbyObjects, err := k8syaml.ParseK8sYaml(instance.PreviousK8sYaml)
+	if err != nil {
+		return err
+	}
+	previewObjects, err := k8syaml.ParseK8sYaml(previewK8sYaml)
+	if err != nil {
+		return err
+	}
+	newObjects, err := k8syaml.ParseK8sYaml(newK8sYaml)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, tclerrors.ErrTimeout)
+	defer cancel()
+
+	if err := s.applyNamespace(ctx, k8sClient, &instance.Stack); err != nil {
+		return err
+	}
+
+	reportPhase(ctx, domain.DeploymentPhaseApply)
+	logger.Info("deploy", "color", color, "namespace", instance.Stack.Namespace())
+	functx.AddRollback(ctx, func(ctx context.Context) {
+		// live traffic never reached the new color, so putting the objects back is enough
+		restoreObjects(ctx, k8sClient, previewObjects, liveObjects, standbyObjects)
+	})
+	if err := k8sClient.Apply(ctx, previewObjects); err != nil {
+		return err
+	}
+
+	if err := s.waitUntilAvailable(ctx, k8sClient, instance, color, constants.PathPreview); err != nil {
+		return err
+	}
+
+	// switch the traffic by updating the ingress in place
+	if err := k8sClient.Apply(ctx, filterObjects(newObjects, isRoutingObject)); err != nil {
+		return err
+	}
+
+	newKeys := objectKeys(newObjects)
+	liveKeys := objectKeys(liveObjects)
+	if err := k8sClient.Delete(ctx, filterObjects(previewObjects, func(obj *unstructured.Unstructured) bool {
+		_, ok := newKeys[objectKey(obj)]
+		return !ok
+	}), false); err != nil {
+		logger.Warn("failed to delete preview objects", "err", err)
+	}
+
+	// the standby objects of components removed since then are not replaced by the new color
+	if err := k8sClient.Delete(ctx, filterObjects(standbyObjects, func(obj *unstructured.Unstructured) bool {
+		_, inNew := newKeys[objectKey(obj)]
+		_, inLive := liveKeys[objectKey(obj)]
+		return !inNew && !inLive
+	}), false); err != nil {
+		logger.Warn("failed to delete stale objects", "err", err)
+	}
+
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		instance.PreviousK8sYaml = instance.AppliedK8sYaml
+		instance.PreviousColor = instance.Color
+		instance.AppliedK8sYaml = newK8sYaml
+		instance.Color = color
+		return instance.Save(tx)
+	}); err != nil {
+		return err
+	}
+
+	fDone(ctx, false)
+	return nil
+}
+
+// discardPreviousObjects deletes the previous objects which are not part of the applied ones and forgets them.
+func (s *service) discardPreviousObjects(
+	ctx context.Context,
+	k8sClient k8s.Client,
+	instance *domain.Instance,
+	appliedObjects []unstructured.Unstructured,
+) error {
+	if !instance.CanRollback() {
+		return nil
+	}
+
+	previousObjects, err := k8syaml.ParseK8sYaml(instance.PreviousK8sYaml)
+	if err != nil {
+		return err
+	}
+
+	appliedKeys := objectKeys(appliedObjects)
+	if err := k8sClient.Delete(ctx, filterObjects(previousObjects, func(obj *unstructured.Unstructured) bool {
+		_, ok := appliedKeys[objectKey(obj)]
+		return !ok
+	}), false); err != nil {
+		return err
+	}
+
+	instance.PreviousK8sYaml = ""
+	instance.PreviousColor = domain.InstanceColorNone
+	return nil
+}
+
+// switchToPreviousColor switches the ingress back to the color which was live before the last blue/green deployment.
+func (s *service) switchToPreviousColor(
+	ctx context.Context,
+	instanceId uint,
+) error {
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
+	if err != nil {
+		return err
+	}
+
+	if !instance.CanRollback() {
+		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "instance has nothing to roll back to")
+	}
+
+	if instance.State != domain.InstanceStateRunning {
+		return errors.Wrapf(tclerrors.ErrForbidden, "instance is not running")
+	}
+
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return err
+	}
+
+	previousObjects, err := k8syaml.ParseK8sYaml(instance.PreviousK8sYaml)
+	if err != nil {
+		return err
+	}
+
+	// the previous color is still running with its own config maps and secrets, so re-applying it only switches the
+	// ingress back.
+	logger.Info("rollback", "color", instance.PreviousColor, "namespace", instance.Stack.Namespace())
+	if err := k8sClient.Apply(ctx, previousObjects); err != nil {
+		return err
+	}
+
+	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		instance.AppliedK8sYaml, instance.PreviousK8sYaml = instance.PreviousK8sYaml, instance.AppliedK8sYaml
+		instance.Color, instance.PreviousColor = instance.PreviousColor, instance.Color
+		if err := instance.Save(tx); err != nil {
+			return err
+		}
+
+		// the yaml is encrypted, so the revision of the previous color is found by its color
+		source, err := domain.FindLatestInstanceRevisionByColor(tx, instance.ID, instance.Color)
+		if err != nil && !errors.Is(err, tclerrors.ErrNotFound) {
+			return err
+		}
+
+		return s.recordRevision(helpers.WithTx(ctx, tx), instance, source, user.ID)
+	})
+}
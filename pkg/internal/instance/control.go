@@ -12,18 +12,28 @@ import (
 )
 
 type (
+	DeployStrategy string
+
 	DeployStackInput struct {
-		Timeout *string `json:"timeout"`
+		Timeout  *string        `json:"timeout"`
+		Strategy DeployStrategy `json:"strategy"`
 	}
 )
 
+const (
+	// DeployStrategyRolling updates the objects of the instance in place.
+	DeployStrategyRolling DeployStrategy = "rolling"
+	// DeployStrategyBlueGreen deploys next to the live objects and switches the ingress once they are healthy.
+	DeployStrategyBlueGreen DeployStrategy = "blue-green"
+)
+
 func (s *service) LaunchInstance(
 	ctx context.Context,
 	instanceId uint,
 ) error {
 	tx := helpers.GetTx(ctx)
 
-	instance, err := s.GetInstance(ctx, instanceId)
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
 	if err != nil {
 		return err
 	}
@@ -75,7 +85,7 @@ func (s *service) StopInstance(
 ) error {
 	tx := helpers.GetTx(ctx)
 
-	instance, err := s.GetInstance(ctx, instanceId)
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
 	if err != nil {
 		return err
 	}
@@ -90,6 +100,11 @@ func (s *service) StopInstance(
 	}
 
 	return tx.Transaction(func(tx *gorm.DB) (error error) {
+		// a stopped instance has nothing to roll back to
+		if err := s.discardPreviousObjects(ctx, k8sClient, instance, nil); err != nil {
+			return err
+		}
+
 		instance.State = domain.InstanceStateReady
 		if err := instance.Save(tx); err != nil {
 			return err
@@ -16,29 +16,73 @@ import (
 	"time"
 )
 
+func parseDeployStackInput(input DeployStackInput) (time.Duration, DeployStrategy, error) {
+	if input.Timeout == nil || *input.Timeout == "" {
+		input.Timeout = gog.PtrOf("30s")
+	}
+
+	timeout, err := time.ParseDuration(*input.Timeout)
+	if err != nil {
+		return 0, "", errors.Wrapf(tclerrors.ErrBadRequest, "failed to parse timeout: %v", err)
+	}
+
+	switch input.Strategy {
+	case "":
+		return timeout, DeployStrategyRolling, nil
+	case DeployStrategyRolling, DeployStrategyBlueGreen:
+		return timeout, input.Strategy, nil
+	default:
+		return 0, "", errors.Wrapf(tclerrors.ErrBadRequest, "unknown deploy strategy '%s'", input.Strategy)
+	}
+}
+
+// DeployStack deploys the stack of the instance right away. Deployments requested through the API are
+// queued by EnqueueDeployment instead, so that the caller isn't blocked.
 func (s *service) DeployStack(
 	ctx context.Context,
 	instanceId uint,
 	input DeployStackInput,
 ) error {
-	if input.Timeout == nil || *input.Timeout == "" {
-		input.Timeout = gog.PtrOf("30s")
+	timeout, strategy, err := parseDeployStackInput(input)
+	if err != nil {
+		return err
 	}
 
-	timeout, err := time.ParseDuration(*input.Timeout)
+	user, err := s.users.GetUser(ctx)
 	if err != nil {
-		return errors.Wrapf(tclerrors.ErrBadRequest, "failed to parse timeout: %v", err)
+		return err
 	}
 
-	tx := helpers.GetTx(ctx)
-	ctx, fDone := functx.WithFuncTx(ctx)
-	defer fDone(ctx, true)
-
-	instance, err := s.GetInstance(ctx, instanceId)
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
 	if err != nil {
 		return err
 	}
 
+	return s.deploy(ctx, instance, timeout, strategy, user.ID)
+}
+
+// deploy applies the stack of the instance and migrates its database. Every step saves the instance in its
+// own short transaction, so that no transaction is held open while waiting for kubernetes.
+func (s *service) deploy(
+	ctx context.Context,
+	instance *domain.Instance,
+	timeout time.Duration,
+	strategy DeployStrategy,
+	deployedById uint,
+) error {
+	applyK8s := s.applyK8s
+	if strategy == DeployStrategyBlueGreen {
+		applyK8s = s.applyK8sBlueGreen
+	}
+
+	if err := s.checkNoSuspendedVapis(ctx, instance); err != nil {
+		return err
+	}
+
+	tx := helpers.GetTx(ctx)
+	ctx, fDone := functx.WithFuncTx(ctx)
+	defer fDone(ctx, true)
+
 	if instance.State == domain.InstanceStateNone {
 		if err := tx.Transaction(func(tx *gorm.DB) error {
 			if err := instance.TransitionToInitialize(tx); err != nil {
@@ -50,20 +94,29 @@ func (s *service) DeployStack(
 		}
 	}
 
-	if err := tx.Transaction(func(tx *gorm.DB) (rErr error) {
-		if err := s.applyK8s(ctx, instance, timeout); err != nil {
-			return err
+	applied := *instance
+	functx.AddRollback(ctx, func(ctx context.Context) {
+		instance.AppliedK8sYaml, instance.PreviousK8sYaml = applied.AppliedK8sYaml, applied.PreviousK8sYaml
+		instance.Color, instance.PreviousColor = applied.Color, applied.PreviousColor
+		if err := instance.Save(helpers.GetTx(ctx)); err != nil {
+			logger.Warn("failed to restore instance", "err", err)
 		}
+	})
+	if err := applyK8s(ctx, instance, timeout); err != nil {
+		return err
+	}
 
-		if err := s.migrationDatabase(ctx, instance); err != nil {
-			return err
-		}
+	reportPhase(ctx, domain.DeploymentPhaseMigrate)
+	if err := s.migrationDatabase(ctx, instance); err != nil {
+		return err
+	}
 
-		if err := instance.TransitionToRunning(tx); err != nil {
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := s.recordRevision(helpers.WithTx(ctx, tx), instance, nil, deployedById); err != nil {
 			return err
 		}
 
-		return nil
+		return instance.TransitionToRunning(tx)
 	}); err != nil {
 		return err
 	}
@@ -72,6 +125,28 @@ func (s *service) DeployStack(
 	return nil
 }
 
+// checkNoSuspendedVapis blocks deploying the stack while any of its locked vapi releases is suspended.
+func (s *service) checkNoSuspendedVapis(ctx context.Context, instance *domain.Instance) error {
+	vapiReleases, err := domain.FindLockedVapiReleasesByStackID(helpers.GetTx(ctx), instance.Stack.ID)
+	if err != nil {
+		return err
+	}
+
+	for _, rel := range vapiReleases {
+		if rel.Suspended {
+			return errors.Wrapf(
+				tclerrors.ErrPreconditionFailed,
+				"vapi release %s@%s is suspended: %s",
+				rel.Package.Name,
+				rel.Version,
+				rel.SuspensionReason,
+			)
+		}
+	}
+
+	return nil
+}
+
 func (s *service) migrationDatabase(
 	ctx context.Context,
 	instance *domain.Instance,
@@ -79,9 +154,14 @@ func (s *service) migrationDatabase(
 	tx := helpers.GetTx(ctx)
 	dbData := instance.Stack.DB.Data()
 
-	if instance.Stack.Vapis == nil {
-		if err := tx.Model(&instance.Stack).Preload("Vapi").Association("Vapis").Find(&instance.Stack.Vapis); err != nil {
-			return errors.Wrapf(err, "failed to find stack vapis")
+	vapiReleases, err := domain.FindLockedVapiReleasesByStackID(helpers.GetTx(ctx), instance.Stack.ID)
+	if err != nil {
+		return err
+	}
+
+	if instance.Stack.CustomVapis == nil {
+		if err := tx.Model(&instance.Stack).Association("CustomVapis").Find(&instance.Stack.CustomVapis); err != nil {
+			return errors.Wrapf(err, "failed to find stack custom vapis")
 		}
 	}
 
@@ -92,12 +172,24 @@ func (s *service) migrationDatabase(
 	}
 	defer conn.Close(ctx)
 
+	// the queries applied are applied to the replicas of a multi-region stack as well
+	var queries []string
 	if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
-		for _, stackVapi := range instance.Stack.Vapis {
-			vapiRelease := stackVapi.Vapi
-			if err := s.migrateVapiDatabase(ctx, tx, &vapiRelease); err != nil {
+		// locked releases are ordered so that dependencies are migrated before their dependents
+		for _, vapiRelease := range vapiReleases {
+			applied, err := s.migrateVapiDatabase(ctx, tx, vapiRelease)
+			if err != nil {
 				return err
 			}
+			queries = append(queries, applied...)
+		}
+
+		for _, customVapi := range instance.Stack.CustomVapis {
+			applied, err := s.migrateCustomVapiDatabase(ctx, tx, customVapi)
+			if err != nil {
+				return err
+			}
+			queries = append(queries, applied...)
 		}
 
 		return nil
@@ -112,85 +204,177 @@ func (s *service) migrationDatabase(
 		}
 	}
 
+	// the replicas of a multi-region stack copy only the rows, so the migrated schema is synchronized to them
+	if instance.Stack.MultiRegion {
+		if err := s.stacks.SyncReplicas(ctx, instance.Stack.ID, queries); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// migrateVapiDatabase applies the migrations of the vapi release which are not applied yet, and returns their queries.
 func (s *service) migrateVapiDatabase(
 	ctx context.Context,
 	conn pgx.Tx,
-	vapiRelease *domain.VapiRelease,
-) error {
-	tx := helpers.GetTx(ctx)
+	v domain.VapiRelease,
+) ([]string, error) {
+	migrations, err := s.vapis.GetDBMigrations(ctx, v)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := vapiRelease.DFS(tx, func(v domain.VapiRelease, _ *domain.VapiRelease) error {
-		migrations, err := s.vapis.GetDBMigrations(ctx, v)
+	slices.SortStableFunc(migrations, func(lhs, rhs vapi.Migration) int {
+		return lhs.Version.Compare(rhs.Version)
+	})
+
+	var queries []string
+	if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		rows, err := tx.Query(
+			ctx,
+			`SELECT version FROM stack.vapi_schema_migrations WHERE vapi_package_id = $1 ORDER BY version FOR UPDATE;`,
+			v.PackageID,
+		)
 		if err != nil {
-			return err
+			return errors.Wrapf(err, "failed to select migrations")
 		}
 
-		slices.SortStableFunc(migrations, func(lhs, rhs vapi.Migration) int {
-			return lhs.Version.Compare(rhs.Version)
+		versions, err := pgx.AppendRows([]time.Time{}, rows, func(row pgx.CollectableRow) (time.Time, error) {
+			var version time.Time
+			if err := row.Scan(&version); err != nil {
+				return time.Time{}, err
+			}
+			return version, nil
 		})
+		if err != nil {
+			return err
+		}
 
-		return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
-			rows, err := tx.Query(
-				ctx,
-				`SELECT version FROM stack.vapi_schema_migrations WHERE vapi_package_id = $1 ORDER BY version FOR UPDATE;`,
-				v.PackageID,
-			)
-			if err != nil {
-				return errors.Wrapf(err, "failed to select migrations")
+		for _, migration := range migrations {
+			if slices.ContainsFunc(versions, func(version time.Time) bool {
+				return version.Equal(migration.Version)
+			}) {
+				logger.Debug("migration already exists", "version", migration.Version)
+				continue
 			}
+			logger.Debug("migrating", "version", migration.Version)
 
-			versions, err := pgx.AppendRows([]time.Time{}, rows, func(row pgx.CollectableRow) (time.Time, error) {
-				var version time.Time
-				if err := row.Scan(&version); err != nil {
-					return time.Time{}, err
+			if err := pgx.BeginFunc(ctx, tx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Query); err != nil {
+					return errors.Wrapf(err, "failed to execute migration. query=%s", migration.Query)
 				}
-				return version, nil
-			})
-			if err != nil {
+
+				if _, err := tx.Exec(ctx,
+					`INSERT INTO stack.vapi_schema_migrations (version, vapi_package_id) VALUES ($1, $2)`,
+					migration.Version,
+					v.PackageID,
+				); err != nil {
+					var pgErr *pgconn.PgError
+					if errors.As(err, &pgErr) && pgErr.Code == "23505" { // checking duplicated key violates primary key
+						logger.Info("migration already exists", "version", migration.Version)
+						return nil
+					}
+					return errors.Wrapf(err, "failed to insert migration. version=%v", migration.Version)
+				}
+
+				return nil
+			}); err != nil {
 				return err
 			}
+			queries = append(queries, migration.Query)
+		}
 
-			for _, migration := range migrations {
-				if slices.ContainsFunc(versions, func(version time.Time) bool {
-					return version.Equal(migration.Version)
-				}) {
-					logger.Debug("migration already exists", "version", migration.Version)
-					continue
-				}
-				logger.Debug("migrating", "version", migration.Version)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
 
-				if err := pgx.BeginFunc(ctx, tx, func(tx pgx.Tx) error {
-					if _, err := tx.Exec(ctx, migration.Query); err != nil {
-						return errors.Wrapf(err, "failed to execute migration. query=%s", migration.Query)
-					}
+	return queries, nil
+}
 
-					if _, err := tx.Exec(ctx,
-						`INSERT INTO stack.vapi_schema_migrations (version, vapi_package_id) VALUES ($1, $2)`,
-						migration.Version,
-						v.PackageID,
-					); err != nil {
-						var pgErr *pgconn.PgError
-						if errors.As(err, &pgErr) && pgErr.Code == "23505" { // checking duplicated key violates primary key
-							logger.Info("migration already exists", "version", migration.Version)
-							return nil
-						}
-						return errors.Wrapf(err, "failed to insert migration. version=%v", migration.Version)
-					}
+// migrateCustomVapiDatabase applies the migrations of the custom vapi which are not applied yet, and returns their
+// queries.
+func (s *service) migrateCustomVapiDatabase(
+	ctx context.Context,
+	conn pgx.Tx,
+	customVapi domain.CustomVapi,
+) ([]string, error) {
+	migrations, err := s.vapis.GetCustomVapiDBMigrations(ctx, customVapi)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(migrations, func(lhs, rhs vapi.Migration) int {
+		return lhs.Version.Compare(rhs.Version)
+	})
+
+	// stacks created before custom vapis were deployable don't have this table yet
+	queries := []string{`CREATE TABLE IF NOT EXISTS stack.custom_vapi_schema_migrations
+(
+    version        TIMESTAMP NOT NULL,
+    custom_vapi_id BIGINT    NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (version, custom_vapi_id)
+);`}
+	if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, queries[0]); err != nil {
+			return errors.Wrapf(err, "failed to create custom vapi migrations table")
+		}
+
+		rows, err := tx.Query(
+			ctx,
+			`SELECT version FROM stack.custom_vapi_schema_migrations WHERE custom_vapi_id = $1 ORDER BY version FOR UPDATE;`,
+			customVapi.ID,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "failed to select custom vapi migrations")
+		}
+
+		versions, err := pgx.AppendRows([]time.Time{}, rows, func(row pgx.CollectableRow) (time.Time, error) {
+			var version time.Time
+			if err := row.Scan(&version); err != nil {
+				return time.Time{}, err
+			}
+			return version, nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if slices.ContainsFunc(versions, func(version time.Time) bool {
+				return version.Equal(migration.Version)
+			}) {
+				logger.Debug("migration already exists", "customVapi", customVapi.Name, "version", migration.Version)
+				continue
+			}
+			logger.Debug("migrating", "customVapi", customVapi.Name, "version", migration.Version)
+
+			if err := pgx.BeginFunc(ctx, tx, func(tx pgx.Tx) error {
+				if _, err := tx.Exec(ctx, migration.Query); err != nil {
+					return errors.Wrapf(err, "failed to execute migration. query=%s", migration.Query)
+				}
 
-					return nil
-				}); err != nil {
-					return err
+				if _, err := tx.Exec(ctx,
+					`INSERT INTO stack.custom_vapi_schema_migrations (version, custom_vapi_id) VALUES ($1, $2)`,
+					migration.Version,
+					customVapi.ID,
+				); err != nil {
+					return errors.Wrapf(err, "failed to insert custom vapi migration. version=%v", migration.Version)
 				}
+
+				return nil
+			}); err != nil {
+				return err
 			}
+			queries = append(queries, migration.Query)
+		}
 
-			return nil
-		})
+		return nil
 	}); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return queries, nil
 }
@@ -213,7 +213,7 @@ func (s *InstanceServiceTestSuite) TestShapleServicePostgrest() {
 
 	{
 		s.T().Log("-- migrate database")
-		s.Require().NoError(s.stacks.MigrateDatabase(ctx, st.ID, stack.MigrateDatabaseInput{
+		_, err := s.stacks.MigrateDatabase(ctx, st.ID, stack.MigrateDatabaseInput{
 			Migrations: []stack.Migration{
 				{
 					Version: time.Now(),
@@ -226,7 +226,8 @@ CREATE POLICY test_table_policy ON api.test_table FOR ALL TO authenticated USING
 `,
 				},
 			},
-		}))
+		})
+		s.Require().NoError(err)
 	}
 
 	{
@@ -443,9 +444,9 @@ func (s *InstanceServiceTestSuite) TestInstallVapi() {
 	s.stack = s.installVapis(ctx, s.stack.ID)
 	defer s.uninstallVapis(ctx, s.stack.ID)
 
-	s.Require().NoError(s.stacks.SetVapiEnv(ctx, s.stack.ID, map[string]string{
-		"helloworld.HELLO": "world",
-		"sns.THIS_ENV":     "test",
+	s.Require().NoError(s.stacks.SetVapiEnv(ctx, s.stack.ID, []domain.StackVapiEnvVar{
+		{Name: "helloworld.HELLO", Value: "world"},
+		{Name: "sns.THIS_ENV", Value: "test", Secret: true},
 	}))
 	defer s.stacks.UnsetVapiEnv(ctx, s.stack.ID, []string{"helloworld.HELLO", "sns.THIS_ENV"})
 
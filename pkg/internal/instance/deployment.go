@@ -0,0 +1,365 @@
+package instance
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/util/functx/v2"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"sync"
+	"time"
+)
+
+type contextKeyPhaseReporter struct{}
+
+const (
+	deploymentPollInterval      = 2 * time.Second
+	deploymentWatchInterval     = 500 * time.Millisecond
+	deploymentHeartbeatInterval = 5 * time.Second
+	deploymentStaleTimeout      = 1 * time.Minute
+)
+
+var errDeploymentCanceled = errors.New("deployment has been canceled")
+
+func withPhaseReporter(ctx context.Context, report func(phase domain.DeploymentPhase)) context.Context {
+	return context.WithValue(ctx, contextKeyPhaseReporter{}, report)
+}
+
+// reportPhase tells the deployment running in ctx, if any, that it has entered the phase.
+func reportPhase(ctx context.Context, phase domain.DeploymentPhase) {
+	if report, ok := ctx.Value(contextKeyPhaseReporter{}).(func(phase domain.DeploymentPhase)); ok {
+		report(phase)
+	}
+}
+
+// EnqueueDeployment queues a deployment of the stack of the instance for the deployment workers.
+// Only one deployment of an instance can be queued or running at a time.
+func (s *service) EnqueueDeployment(
+	ctx context.Context,
+	instanceId uint,
+	input DeployStackInput,
+) (*domain.Deployment, error) {
+	timeout, strategy, err := parseDeployStackInput(input)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
+	if err != nil {
+		return nil, err
+	}
+
+	return enqueueDeployment(helpers.GetTx(ctx), instance, user.ID, timeout, strategy)
+}
+
+// EnqueueAutoDeployments queues a rolling deployment of every instance of the stack subscribing to auto deployments, on
+// behalf of the owner of the project. Instances not deployed yet or having a queued or running deployment are skipped.
+// The permission of the current user is not checked.
+func (s *service) EnqueueAutoDeployments(
+	ctx context.Context,
+	stackId uint,
+) ([]domain.Deployment, error) {
+	timeout, strategy, err := parseDeployStackInput(DeployStackInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	stack, err := domain.FindStackByID(tx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []domain.Deployment
+	for _, instance := range stack.Instances {
+		if !instance.AutoDeploy || instance.AppliedK8sYaml == "" {
+			continue
+		}
+
+		deployment, err := enqueueDeployment(tx, &instance, stack.Project.OwnerID, timeout, strategy)
+		if errors.Is(err, tclerrors.ErrPreconditionFailed) {
+			logger.Info("skip auto deployment", "instanceId", instance.ID, "reason", err)
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		deployments = append(deployments, *deployment)
+	}
+
+	return deployments, nil
+}
+
+func enqueueDeployment(
+	tx *gorm.DB,
+	instance *domain.Instance,
+	requestedById uint,
+	timeout time.Duration,
+	strategy DeployStrategy,
+) (*domain.Deployment, error) {
+	var deployment domain.Deployment
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		// serializes the deployment requests of the instance
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			First(&domain.Instance{}, instance.ID).Error; err != nil {
+			return errors.Wrapf(err, "failed to lock instance")
+		}
+
+		if active, err := domain.FindActiveDeploymentByInstanceId(tx, instance.ID); err == nil {
+			return errors.Wrapf(tclerrors.ErrPreconditionFailed, "deployment %d of the instance is %s", active.ID, active.Status)
+		} else if !errors.Is(err, tclerrors.ErrNotFound) {
+			return err
+		}
+
+		deployment = domain.Deployment{
+			InstanceID:    instance.ID,
+			RequestedByID: requestedById,
+			Strategy:      string(strategy),
+			Timeout:       timeout.String(),
+		}
+		return deployment.Enqueue(tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &deployment, nil
+}
+
+func (s *service) GetDeployment(
+	ctx context.Context,
+	deploymentId uint,
+) (*domain.Deployment, error) {
+	return s.getDeployment(ctx, deploymentId, domain.OrganizationRoleViewer)
+}
+
+func (s *service) getDeployment(
+	ctx context.Context,
+	deploymentId uint,
+	role domain.OrganizationRole,
+	options ...domain.FindOptions,
+) (*domain.Deployment, error) {
+	deployment, err := domain.FindDeploymentById(helpers.GetTx(ctx), deploymentId, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getInstance(ctx, deployment.InstanceID, role); err != nil {
+		return nil, err
+	}
+
+	return deployment, nil
+}
+
+// CancelDeployment cancels a queued deployment right away. A running one is stopped by its worker,
+// which rolls back the kubernetes objects it has applied.
+func (s *service) CancelDeployment(
+	ctx context.Context,
+	deploymentId uint,
+) error {
+	tx := helpers.GetTx(ctx)
+	deployment, err := s.getDeployment(
+		ctx,
+		deploymentId,
+		domain.OrganizationRoleDeveloper,
+		domain.Locking(clause.Locking{Strength: "UPDATE"}),
+	)
+	if err != nil {
+		return err
+	}
+
+	switch deployment.Status {
+	case domain.DeploymentStatusQueued:
+		return deployment.Finish(tx, domain.DeploymentStatusCanceled, nil)
+	case domain.DeploymentStatusRunning:
+		return deployment.RequestCancel(tx)
+	default:
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "deployment has already %s", deployment.Status)
+	}
+}
+
+// WatchDeployment sends every event of the deployment, starting from the first one, until it finishes.
+func (s *service) WatchDeployment(
+	ctx context.Context,
+	deploymentId uint,
+	send func(event *domain.DeploymentEvent) error,
+) error {
+	tx := helpers.GetTx(ctx)
+	if _, err := s.getDeployment(ctx, deploymentId, domain.OrganizationRoleViewer); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(deploymentWatchInterval)
+	defer ticker.Stop()
+
+	var lastEventId uint
+	for {
+		// the status is read before the events, since the final event is saved together with the final status
+		deployment, err := domain.FindDeploymentById(tx, deploymentId)
+		if err != nil {
+			return err
+		}
+
+		events, err := domain.FindDeploymentEvents(tx, deploymentId, lastEventId)
+		if err != nil {
+			return err
+		}
+
+		for i := range events {
+			if err := send(&events[i]); err != nil {
+				return err
+			}
+			lastEventId = events[i].ID
+		}
+
+		if deployment.IsFinished() {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			// the watcher has gone away
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDeploymentWorkers runs the queued deployments with the given number of workers until ctx is done.
+func (s *service) RunDeploymentWorkers(
+	ctx context.Context,
+	concurrency int,
+) error {
+	if concurrency <= 0 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "concurrency must be positive")
+	}
+
+	logger.Info("start deployment workers", "concurrency", concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.runDeploymentWorker(ctx)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (s *service) runDeploymentWorker(ctx context.Context) {
+	for {
+		db := s.db.WithContext(ctx)
+		if err := domain.FailStaleDeployments(db, time.Now().Add(-deploymentStaleTimeout)); err != nil {
+			logger.Warn("failed to fail stale deployments", "err", err)
+		}
+
+		deployment, err := domain.ClaimQueuedDeployment(db)
+		if err == nil {
+			s.runDeployment(ctx, deployment)
+			continue
+		}
+
+		if !errors.Is(err, tclerrors.ErrNotFound) && ctx.Err() == nil {
+			logger.Warn("failed to claim deployment", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(deploymentPollInterval):
+		}
+	}
+}
+
+// runDeployment deploys the claimed deployment and saves how it has finished.
+// The deployment is canceled if it is requested to or if ctx is done.
+func (s *service) runDeployment(ctx context.Context, deployment *domain.Deployment) {
+	logger.Info("run deployment", "id", deployment.ID, "instance", deployment.InstanceID)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	ctx, fDone := functx.WithFuncTx(ctx)
+	ctx = helpers.WithTx(ctx, s.db.WithContext(ctx))
+
+	// rolling back and saving the result must not be canceled together with the deployment
+	cleanupCtx := context.WithoutCancel(ctx)
+	cleanupDb := s.db.WithContext(cleanupCtx)
+	cleanupCtx = helpers.WithTx(cleanupCtx, cleanupDb)
+
+	ctx = withPhaseReporter(ctx, func(phase domain.DeploymentPhase) {
+		deployment.Phase = phase
+		if err := deployment.Emit(cleanupDb, ""); err != nil {
+			logger.Warn("failed to emit deployment phase", "err", err)
+		}
+	})
+
+	heartbeatCtx, stopHeartbeat := context.WithCancel(ctx)
+	go func() {
+		ticker := time.NewTicker(deploymentHeartbeatInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-heartbeatCtx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			cancelRequested, err := deployment.Heartbeat(cleanupDb)
+			if err != nil {
+				logger.Warn("failed to send deployment heartbeat", "err", err)
+			} else if cancelRequested {
+				cancel(errDeploymentCanceled)
+			}
+		}
+	}()
+
+	err := s.runDeploymentJob(ctx, deployment)
+	stopHeartbeat()
+
+	status := domain.DeploymentStatusSucceeded
+	if err != nil {
+		if errors.Is(context.Cause(ctx), errDeploymentCanceled) {
+			status, err = domain.DeploymentStatusCanceled, errDeploymentCanceled
+		} else {
+			status = domain.DeploymentStatusFailed
+		}
+
+		rollbackCtx, cancelRollback := context.WithTimeout(cleanupCtx, rollbackTimeout)
+		fDone(rollbackCtx, true)
+		cancelRollback()
+	} else {
+		fDone(cleanupCtx, false)
+	}
+
+	logger.Info("finish deployment", "id", deployment.ID, "status", status, "err", err)
+	if err := deployment.Finish(cleanupDb, status, err); err != nil {
+		logger.Error("failed to finish deployment", "id", deployment.ID, "err", err)
+	}
+}
+
+func (s *service) runDeploymentJob(ctx context.Context, deployment *domain.Deployment) error {
+	instance, err := findInstance(helpers.GetTx(ctx), deployment.InstanceID)
+	if err != nil {
+		return err
+	}
+
+	timeout, err := time.ParseDuration(deployment.Timeout)
+	if err != nil {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "failed to parse timeout: %v", err)
+	}
+
+	return s.deploy(ctx, instance, timeout, DeployStrategy(deployment.Strategy), deployment.RequestedByID)
+}
@@ -0,0 +1,55 @@
+package instance_test
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/instance"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/mokiat/gog"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+func (s *InstanceServiceTestSuite) TestGivenQueuedDeploymentWhenRunDeploymentWorkersWithoutUserThenShouldDeploy() {
+	// Given
+	inst, err := s.instances.CreateInstance(s, instance.CreateInstanceInput{
+		StackID: s.stack.ID,
+		Zone:    tcltypes.InstanceZoneDefault,
+	})
+	s.Require().NoError(err)
+	defer func() {
+		s.NoError(s.instances.DeleteInstance(s, inst.ID, true))
+	}()
+
+	deployment, err := s.instances.EnqueueDeployment(s, inst.ID, instance.DeployStackInput{
+		Timeout: gog.PtrOf("60s"),
+	})
+	s.Require().NoError(err)
+	s.Require().Equal(domain.DeploymentStatusQueued, deployment.Status)
+
+	// the worker runs without any user and transaction in its context
+	s.users.Calls = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// When
+	done := make(chan error, 1)
+	go func() {
+		done <- s.instances.RunDeploymentWorkers(ctx, 1)
+	}()
+
+	s.Require().Eventually(func() bool {
+		deployment, err = domain.FindDeploymentById(s.db, deployment.ID)
+		return err == nil && deployment.IsFinished()
+	}, 3*time.Minute, time.Second)
+	cancel()
+	s.Require().NoError(<-done)
+
+	// Then
+	s.Require().Equal(domain.DeploymentStatusSucceeded, deployment.Status, deployment.Error)
+	s.users.AssertNotCalled(s.T(), "GetUser", mock.Anything)
+
+	inst, err = domain.FindInstanceById(s.db, inst.ID)
+	s.Require().NoError(err)
+	s.Require().Equal(domain.InstanceStateRunning, inst.State)
+}
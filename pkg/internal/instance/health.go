@@ -0,0 +1,202 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"strings"
+	"time"
+)
+
+const (
+	healthCheckTimeout = 3 * time.Second
+)
+
+type (
+	InstanceStatus struct {
+		InstanceID uint
+		Components []ComponentStatus
+		CheckedAt  time.Time
+	}
+
+	ComponentStatus struct {
+		// Name is auth, storage, postgrest, the package name of a vapi or the name of a custom vapi
+		Name string
+		// Component is the shaple.io/component label of the pods, which is vapi or custom-vapi for the vapis
+		Component string
+		ReadyPods int64
+		TotalPods int64
+		// Restarts is the sum of the container restarts of the pods
+		Restarts int64
+		Healthy  bool
+		// LastError tells why the pods are not ready or the health check failed. Empty if there is nothing wrong.
+		LastError string
+	}
+)
+
+// GetInstanceStatus diagnoses each component of the instance by its pods and its health check.
+func (s *service) GetInstanceStatus(
+	ctx context.Context,
+	instanceId uint,
+) (*InstanceStatus, error) {
+	instance, err := s.GetInstance(ctx, instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.AppliedK8sYaml == "" {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionRequired, "instance has not been deployed yet")
+	}
+
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	stack := &instance.Stack
+	selector := fmt.Sprintf("shaple.io/project.id=%d,shaple.io/stack.id=%d", stack.ProjectID, stack.ID)
+	if instance.Color != domain.InstanceColorNone {
+		selector += fmt.Sprintf(",shaple.io/color=%s", instance.Color)
+	}
+	objects, err := k8sClient.GetResources(ctx, "pod", stack.Namespace(), selector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := make([]corev1.Pod, len(objects))
+	for i, object := range objects {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, &pods[i]); err != nil {
+			return nil, errors.Wrapf(err, "failed to convert pod %s", object.GetName())
+		}
+	}
+
+	checks, err := s.checkHealth(ctx, instance, "", healthCheckTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	status := InstanceStatus{
+		InstanceID: instance.ID,
+		CheckedAt:  time.Now(),
+	}
+	if stack.AuthEnabled {
+		status.Components = append(status.Components, newComponentStatus("auth", "auth", "", pods, checks["auth"]))
+	}
+	if stack.StorageEnabled {
+		status.Components = append(status.Components, newComponentStatus("storage", "storage", "", pods, checks["storage"]))
+	}
+	if stack.PostgrestEnabled {
+		check := checks["postgrest-live"]
+		if check.OK {
+			check = checks["postgrest-ready"]
+		}
+		status.Components = append(status.Components, newComponentStatus("postgrest", "postgrest", "", pods, check))
+	}
+
+	rels, err := s.stacks.GetLockedVapiReleases(ctx, stack.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, rel := range rels {
+		status.Components = append(status.Components, newComponentStatus(
+			rel.Package.Name,
+			"vapi",
+			fmt.Sprintf("%d", rel.ID),
+			pods,
+			checks["vapi/"+rel.Slug()],
+		))
+	}
+	for _, customVapi := range stack.CustomVapis {
+		status.Components = append(status.Components, newComponentStatus(
+			customVapi.Name,
+			"custom-vapi",
+			fmt.Sprintf("%d", customVapi.ID),
+			pods,
+			checks["custom-vapi/"+customVapi.Name],
+		))
+	}
+
+	return &status, nil
+}
+
+// newComponentStatus sums up the pods labeled with the component and the vapi id. vapiId is empty except for the vapis.
+func newComponentStatus(
+	name string,
+	component string,
+	vapiId string,
+	pods []corev1.Pod,
+	check healthCheck,
+) ComponentStatus {
+	status := ComponentStatus{
+		Name:      name,
+		Component: component,
+		Healthy:   check.OK,
+	}
+
+	var podError string
+	for _, pod := range pods {
+		labels := pod.GetLabels()
+		if labels["shaple.io/component"] != component || (vapiId != "" && labels["shaple.io/vapi.id"] != vapiId) {
+			continue
+		}
+
+		status.TotalPods++
+		if isPodReady(&pod) {
+			status.ReadyPods++
+		}
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			status.Restarts += int64(containerStatus.RestartCount)
+		}
+		if podError == "" {
+			podError = getPodError(&pod)
+		}
+	}
+
+	switch {
+	case podError != "":
+		status.LastError = podError
+	case status.TotalPods == 0:
+		status.LastError = "no pods found"
+	default:
+		status.LastError = check.Error
+	}
+
+	return status
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// getPodError returns why a container of the pod is waiting or was terminated last time, or why the pod is not scheduled.
+func getPodError(pod *corev1.Pod) string {
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason != "" && waiting.Reason != "ContainerCreating" {
+			return strings.TrimSpace(fmt.Sprintf("%s: %s %s", pod.Name, waiting.Reason, waiting.Message))
+		}
+	}
+
+	for _, containerStatus := range pod.Status.ContainerStatuses {
+		if terminated := containerStatus.LastTerminationState.Terminated; terminated != nil && !containerStatus.Ready {
+			return strings.TrimSpace(fmt.Sprintf("%s: %s (exit code %d) %s", pod.Name, terminated.Reason, terminated.ExitCode, terminated.Message))
+		}
+	}
+
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled && condition.Status == corev1.ConditionFalse {
+			return strings.TrimSpace(fmt.Sprintf("%s: %s %s", pod.Name, condition.Reason, condition.Message))
+		}
+	}
+
+	return ""
+}
@@ -27,8 +27,9 @@ func (s *service) applyK8s(
 		return err
 	}
 
+	reportPhase(ctx, domain.DeploymentPhaseRender)
 	oldK8sYaml := instance.AppliedK8sYaml
-	newK8sYaml, err := s.renderK8sYamlValues(ctx, &instance.Stack)
+	newK8sYaml, err := s.renderK8sYamlValues(ctx, instance)
 	if err != nil {
 		return err
 	}
@@ -53,49 +54,18 @@ func (s *service) applyK8s(
 		return err
 	}
 
+	reportPhase(ctx, domain.DeploymentPhaseApply)
 	if err := k8sClient.Upgrade(ctx, oldObjects, newObjects, k8s.WithApplyCheckFn(func(ctx context.Context) error {
-		for {
-			if err := k8sClient.Wait(
-				ctx,
-				"pod",
-				instance.Stack.Namespace(),
-				fmt.Sprintf("shaple.io/project.id=%d,shaple.io/stack.id=%d", instance.Stack.Project.ID, instance.Stack.ID),
-				"ready",
-			); err != nil {
-				if !errors.Is(err, tclerrors.ErrNotFound) {
-					return err
-				}
-				continue
-			}
-
-			break
-		}
-
-		if s.stackConfig.SkipHealthCheck {
-			return nil
-		}
-
-		for allOk := false; !allOk; {
-			results, err := s.isAvailable(ctx, instance, 500*time.Millisecond)
-			if err != nil {
-				return err
-			}
-
-			allOk = true
-			for _, ok := range results {
-				if !ok {
-					allOk = false
-					time.Sleep(250 * time.Millisecond)
-					break
-				}
-			}
-		}
-
-		return nil
+		return s.waitUntilAvailable(ctx, k8sClient, instance, instance.Color, "")
 	})); err != nil {
 		return err
 	}
 
+	// a rolling deployment replaces the standby color, so it can't be rolled back to anymore
+	if err := s.discardPreviousObjects(ctx, k8sClient, instance, newObjects); err != nil {
+		return err
+	}
+
 	if err := tx.Transaction(func(tx *gorm.DB) error {
 		instance.AppliedK8sYaml = newK8sYaml
 		return instance.Save(tx)
@@ -107,6 +77,62 @@ func (s *service) applyK8s(
 	return nil
 }
 
+// waitUntilAvailable blocks until the pods of the color are ready and every component passes its health check
+// through the ingress routing pathPrefix.
+func (s *service) waitUntilAvailable(
+	ctx context.Context,
+	k8sClient k8s.Client,
+	instance *domain.Instance,
+	color domain.InstanceColor,
+	pathPrefix string,
+) error {
+	selector := fmt.Sprintf("shaple.io/project.id=%d,shaple.io/stack.id=%d", instance.Stack.Project.ID, instance.Stack.ID)
+	if color != domain.InstanceColorNone {
+		selector += fmt.Sprintf(",shaple.io/color=%s", color)
+	}
+
+	reportPhase(ctx, domain.DeploymentPhasePodsReady)
+	for {
+		if err := k8sClient.Wait(
+			ctx,
+			"pod",
+			instance.Stack.Namespace(),
+			selector,
+			"ready",
+		); err != nil {
+			if !errors.Is(err, tclerrors.ErrNotFound) {
+				return err
+			}
+			continue
+		}
+
+		break
+	}
+
+	if s.stackConfig.SkipHealthCheck {
+		return nil
+	}
+
+	reportPhase(ctx, domain.DeploymentPhaseHealth)
+	for allOk := false; !allOk; {
+		results, err := s.isAvailable(ctx, instance, pathPrefix, 500*time.Millisecond)
+		if err != nil {
+			return err
+		}
+
+		allOk = true
+		for _, ok := range results {
+			if !ok {
+				allOk = false
+				time.Sleep(250 * time.Millisecond)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
 func (s *service) applyNamespace(ctx context.Context, k8sClient k8s.Client, stack *domain.Stack) error {
 	logger.Info("apply", "namespace", stack.Namespace())
 	objects, err := s.k8sYamlService.RenderYaml([]string{
@@ -139,23 +165,62 @@ func (s *service) deleteNamespace(ctx context.Context, k8sClient k8s.Client, sta
 	return nil
 }
 
-func (s *service) renderK8sYamlValues(ctx context.Context, stack *domain.Stack) (string, error) {
-	values := s.k8sYamlService.NewValuesFromStack(stack)
+func (s *service) renderK8sYamlValues(ctx context.Context, instance *domain.Instance) (string, error) {
+	values, k8sYamlFiles, err := s.newK8sYamlValues(ctx, instance)
+	if err != nil {
+		return "", err
+	}
+
+	return s.k8sYamlService.RenderYaml(k8sYamlFiles, values.WithColor(instance.Color))
+}
+
+// newK8sYamlValues returns the values and templates of every object of the instance. the values are not colored.
+func (s *service) newK8sYamlValues(ctx context.Context, instance *domain.Instance) (k8syaml.Values, []string, error) {
+	var vapiReleases []domain.VapiRelease
+	if len(instance.Stack.Vapis) > 0 {
+		var err error
+		if vapiReleases, err = domain.FindLockedVapiReleasesByStackID(helpers.GetTx(ctx), instance.StackID); err != nil {
+			return k8syaml.Values{}, nil, err
+		}
+	}
+
+	return s.newK8sYamlValuesWithVapis(ctx, instance, vapiReleases, instance.Stack.VapiEnvVars)
+}
+
+// newK8sYamlValuesWithVapis is newK8sYamlValues rendering the given vapi releases and env vars instead of the
+// locked ones of the stack.
+func (s *service) newK8sYamlValuesWithVapis(
+	ctx context.Context,
+	instance *domain.Instance,
+	vapiReleases []domain.VapiRelease,
+	vapiEnvVars []domain.StackVapiEnvVar,
+) (k8syaml.Values, []string, error) {
+	stack := &instance.Stack
+	values := s.k8sYamlService.NewValuesFromStack(stack).WithZone(
+		instance.Zone,
+		s.stackConfig.GetRegionalConfig(instance.Zone).Domain,
+		s.dbConfig.GetRegionalConfig(stack.DefaultRegion),
+	)
 	k8sYamlFiles := []string{
 		"common/network-policy.yaml",
 		"common/ingress.yaml",
+		"common/certificate.yaml",
 		"common/configmap.yaml",
 		"database/configmap.yaml",
 		"database/secret.yaml",
 	}
 
+	if s.stackConfig.ApiKeyVerifyURL != "" {
+		values = values.WithApiKeyVerification(s.stackConfig.ApiKeyVerifyURL, s.stackConfig.ApiKeyVerifySecret)
+	}
+
 	if stack.AuthEnabled {
 		values = values.WithAuth(s.smtpConfig)
 		k8sYamlFiles = append(k8sYamlFiles, "auth/configmap.yaml", "auth/secret.yaml", "auth/deployment.yaml", "auth/service.yaml")
 	}
 
 	if stack.StorageEnabled {
-		values = values.WithStorage(s.s3Config, stack.DefaultRegion)
+		values = values.WithStorage(s.s3Config, instance.Zone)
 		k8sYamlFiles = append(k8sYamlFiles, "storage/service.yaml", "storage/secret.yaml", "storage/deployment.yaml", "storage/configmap.yaml")
 	}
 
@@ -164,15 +229,10 @@ func (s *service) renderK8sYamlValues(ctx context.Context, stack *domain.Stack)
 		k8sYamlFiles = append(k8sYamlFiles, "postgrest/deployment.yaml", "postgrest/service.yaml")
 	}
 
-	if len(stack.Vapis) > 0 {
-		vapiReleases, err := s.vapis.GetAllDependenciesOfVapiReleases(ctx, stack.GetVapiReleases())
-		if err != nil {
-			return "", err
-		}
-
-		vapiValues, err := s.k8sYamlService.GetVapiYamlValues(ctx, vapiReleases, stack.VapiEnvVars)
+	if len(vapiReleases) > 0 {
+		vapiValues, err := s.k8sYamlService.GetVapiYamlValues(ctx, vapiReleases, vapiEnvVars, stack.Vapis)
 		if err != nil {
-			return "", err
+			return values, nil, err
 		}
 		values = values.WithVapis(vapiValues)
 		k8sYamlFiles = append(k8sYamlFiles,
@@ -183,10 +243,22 @@ func (s *service) renderK8sYamlValues(ctx context.Context, stack *domain.Stack)
 		)
 	}
 
-	k8sYaml, err := s.k8sYamlService.RenderYaml(k8sYamlFiles, values)
-	if err != nil {
-		return "", err
+	if len(stack.CustomVapis) > 0 {
+		customVapiValues, err := s.k8sYamlService.GetCustomVapiYamlValues(ctx, stack.CustomVapis, vapiEnvVars)
+		if err != nil {
+			return values, nil, err
+		}
+		values = values.WithCustomVapis(customVapiValues)
+		k8sYamlFiles = append(k8sYamlFiles,
+			"custom-vapi/configmap.yaml",
+			"custom-vapi/secret.yaml",
+			"custom-vapi/service.yaml",
+			"custom-vapi/deployment.yaml",
+		)
 	}
 
-	return k8sYaml, nil
+	values = values.WithRateLimits().WithScaling(instance)
+	k8sYamlFiles = append(k8sYamlFiles, "common/hpa.yaml")
+
+	return values, k8sYamlFiles, nil
 }
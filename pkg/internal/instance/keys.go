@@ -0,0 +1,146 @@
+package instance
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const jwtSecretRetirementPollInterval = 1 * time.Minute
+
+type RotateStackKeysInput struct {
+	// GracePeriod is how long the previous jwt secret is accepted. The configured one is used if not given.
+	GracePeriod *time.Duration `json:"grace_period"`
+}
+
+// RotateStackKeys replaces the jwt secret and the api keys of the stack, and re-deploys its running instances
+// to accept both the new and the previous secret. The previous secret is retired by RunJWTSecretRetirement.
+func (s *service) RotateStackKeys(
+	ctx context.Context,
+	stackId uint,
+	input RotateStackKeysInput,
+) (*domain.Stack, []domain.Deployment, error) {
+	gracePeriod := s.stackConfig.KeyRotationGracePeriod
+	if input.GracePeriod != nil {
+		gracePeriod = *input.GracePeriod
+	}
+
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stack, err := s.stacks.RotateJWTSecret(ctx, stackId, gracePeriod)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deployments, err := s.redeployRunningInstances(helpers.GetTx(ctx), stack.ID, user.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stack, deployments, nil
+}
+
+// RunJWTSecretRetirement retires the previous jwt secrets of the stacks whose grace period is over until ctx is done,
+// and re-deploys their running instances to stop accepting them.
+func (s *service) RunJWTSecretRetirement(ctx context.Context) error {
+	ticker := time.NewTicker(jwtSecretRetirementPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.retireExpiredJWTSecrets(ctx); err != nil && ctx.Err() == nil {
+			logger.Warn("failed to retire expired jwt secrets", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *service) retireExpiredJWTSecrets(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+	stackIds, err := domain.FindStackIdsWithExpiredJWTSecret(db, now)
+	if err != nil {
+		return err
+	}
+
+	for _, stackId := range stackIds {
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			// another server may be retiring it, or may have retired it in the meantime
+			var stack domain.Stack
+			if err := tx.
+				Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Preload("Project").
+				Where("previous_jwt_secret_expires_at <= ?", now).
+				Limit(1).
+				Find(&stack, stackId).Error; err != nil {
+				return errors.Wrapf(err, "failed to lock stack")
+			} else if stack.ID == 0 {
+				return nil
+			}
+
+			stack.RetirePreviousJWTSecret()
+			if err := stack.Save(tx.Omit(clause.Associations)); err != nil {
+				return err
+			}
+
+			_, err := s.redeployRunningInstances(tx, stack.ID, stack.Project.OwnerID)
+			return err
+		}); err != nil {
+			// it is retried on the next poll
+			logger.Warn("failed to retire jwt secret", "stackId", stackId, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// redeployRunningInstances queues a rolling deployment of each running instance of the stack, so that the
+// instances pick up the changes of the stack. A queued deployment of an instance will pick them up by itself.
+func (s *service) redeployRunningInstances(
+	tx *gorm.DB,
+	stackId uint,
+	requestedById uint,
+) ([]domain.Deployment, error) {
+	instances, err := domain.FindInstancesByStackId(tx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, strategy, err := parseDeployStackInput(DeployStackInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	var deployments []domain.Deployment
+	for _, instance := range instances {
+		if instance.State != domain.InstanceStateRunning {
+			continue
+		}
+
+		deployment, err := enqueueDeployment(tx, &instance, requestedById, timeout, strategy)
+		if errors.Is(err, tclerrors.ErrPreconditionFailed) {
+			if active, findErr := domain.FindActiveDeploymentByInstanceId(tx, instance.ID); findErr == nil && active.Status == domain.DeploymentStatusQueued {
+				deployments = append(deployments, *active)
+				continue
+			}
+			return nil, errors.Wrapf(err, "failed to redeploy instance %d", instance.ID)
+		} else if err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, *deployment)
+	}
+
+	return deployments, nil
+}
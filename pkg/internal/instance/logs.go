@@ -0,0 +1,101 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"time"
+)
+
+type (
+	StreamInstanceLogsInput struct {
+		// Component is auth, storage, postgrest or the name of a vapi. Every component is streamed if empty.
+		Component string
+		Since     *string
+		Tail      *int64
+		Follow    bool
+	}
+)
+
+// StreamInstanceLogs sends the log lines of the main containers of the instance's pods.
+func (s *service) StreamInstanceLogs(
+	ctx context.Context,
+	instanceId uint,
+	input StreamInstanceLogsInput,
+	send func(line k8s.LogLine) error,
+) error {
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
+	if err != nil {
+		return err
+	}
+
+	if instance.AppliedK8sYaml == "" {
+		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "instance has not been deployed yet")
+	}
+
+	options := k8s.LogOptions{
+		Container: "main",
+		TailLines: input.Tail,
+		Follow:    input.Follow,
+	}
+	if input.Since != nil && *input.Since != "" {
+		since, err := time.ParseDuration(*input.Since)
+		if err != nil {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "failed to parse since: %v", err)
+		}
+		options.SinceSeconds = gog.PtrOf(int64(since.Seconds()))
+	}
+	if input.Tail != nil && *input.Tail < 0 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "tail must not be negative")
+	}
+
+	selector, err := s.getComponentSelector(ctx, instance, input.Component)
+	if err != nil {
+		return err
+	}
+
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return err
+	}
+
+	return k8sClient.StreamLogs(ctx, instance.Stack.Namespace(), selector, options, send)
+}
+
+func (s *service) getComponentSelector(
+	ctx context.Context,
+	instance *domain.Instance,
+	component string,
+) (string, error) {
+	stack := &instance.Stack
+	selector := fmt.Sprintf("shaple.io/project.id=%d,shaple.io/stack.id=%d", stack.ProjectID, stack.ID)
+
+	switch component {
+	case "":
+		return selector, nil
+	case "auth", "storage", "postgrest":
+		return selector + ",shaple.io/component=" + component, nil
+	}
+
+	vapiReleases, err := s.stacks.GetLockedVapiReleases(ctx, stack.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, vapiRelease := range vapiReleases {
+		if vapiRelease.Package.Name == component {
+			return fmt.Sprintf("%s,shaple.io/component=vapi,shaple.io/vapi.id=%d", selector, vapiRelease.ID), nil
+		}
+	}
+
+	for _, customVapi := range stack.CustomVapis {
+		if customVapi.Name == component {
+			return fmt.Sprintf("%s,shaple.io/component=custom-vapi,shaple.io/vapi.id=%d", selector, customVapi.ID), nil
+		}
+	}
+
+	return "", errors.Wrapf(tclerrors.ErrNotFound, "component '%s' not found in the stack", component)
+}
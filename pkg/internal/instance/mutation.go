@@ -3,16 +3,23 @@ package instance
 import (
 	"context"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/pkg/errors"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
+	"slices"
 )
 
 type (
 	EditInstanceInput struct {
-		Name *string `json:"name"`
+		Name           *string                                 `json:"name"`
+		NumReplicas    *uint                                   `json:"num_replicas"`
+		MaxReplicas    *uint                                   `json:"max_replicas"`
+		ScalingTargets map[string]domain.InstanceScalingTarget `json:"scaling_targets"`
+		AutoDeploy     *bool                                   `json:"auto_deploy"`
 	}
 
 	CreateInstanceInput struct {
@@ -38,7 +45,9 @@ func (s *service) CreateInstance(
 	}
 
 	zone := tcltypes.InstanceZoneDefault
-	if input.Zone != "" {
+	if input.Zone == tcltypes.InstanceZoneMulti {
+		return s.createMultiRegionInstances(ctx, stack, input.Name)
+	} else if input.Zone != "" {
 		zone = input.Zone
 	}
 
@@ -112,7 +121,7 @@ func (s *service) EditInstance(
 ) error {
 	tx := helpers.GetTx(ctx)
 
-	instance, err := domain.FindInstanceById(tx, instanceId)
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
 	if err != nil {
 		return err
 	}
@@ -121,7 +130,79 @@ func (s *service) EditInstance(
 		instance.Name = *input.Name
 	}
 
+	if input.NumReplicas != nil {
+		instance.NumReplicas = *input.NumReplicas
+	}
+	if input.MaxReplicas != nil {
+		instance.MaxReplicas = *input.MaxReplicas
+	}
+	if instance.NumReplicas > constants.MaxInstanceReplicas || instance.MaxReplicas > constants.MaxInstanceReplicas {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "replicas must be less than or equal to %d", constants.MaxInstanceReplicas)
+	}
+	if instance.MaxReplicas < instance.NumReplicas {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "max replicas must be greater than or equal to num replicas")
+	}
+
+	if input.ScalingTargets != nil {
+		for component, target := range input.ScalingTargets {
+			if !slices.Contains(domain.InstanceComponents, component) {
+				return errors.Wrapf(tclerrors.ErrBadRequest, "unknown component '%s'", component)
+			}
+			for _, utilization := range []*int32{target.CPUUtilization, target.MemoryUtilization} {
+				if utilization != nil && (*utilization <= 0 || *utilization > 100) {
+					return errors.Wrapf(tclerrors.ErrBadRequest, "utilization of '%s' must be between 1 and 100", component)
+				}
+			}
+		}
+		instance.ScalingTargets = datatypes.NewJSONType(input.ScalingTargets)
+	}
+
+	if input.AutoDeploy != nil {
+		instance.AutoDeploy = *input.AutoDeploy
+	}
+
 	return tx.Transaction(func(tx *gorm.DB) error {
 		return instance.Save(tx)
 	})
 }
+
+// createMultiRegionInstances makes the stack multi-region and creates its instance in every zone not having one yet.
+// It returns the instance in the default region of the stack.
+func (s *service) createMultiRegionInstances(ctx context.Context, stack *domain.Stack, name string) (*domain.Instance, error) {
+	if _, err := s.stacks.EnableMultiRegion(ctx, stack.ID); err != nil {
+		return nil, err
+	}
+
+	var primary *domain.Instance
+	for _, zone := range tcltypes.InstanceZones {
+		i := slices.IndexFunc(stack.Instances, func(instance domain.Instance) bool {
+			return instance.Zone == zone
+		})
+		if i >= 0 {
+			if zone == stack.DefaultRegion {
+				primary = &stack.Instances[i]
+			}
+			continue
+		}
+
+		instanceName := stack.Name + "-" + zone.String()
+		if name != "" {
+			instanceName = name + "-" + zone.String()
+		}
+
+		instance := domain.Instance{
+			StackID: stack.ID,
+			Zone:    zone,
+			Name:    instanceName,
+		}
+		if err := instance.Save(helpers.GetTx(ctx)); err != nil {
+			return nil, err
+		}
+
+		if zone == stack.DefaultRegion {
+			primary = &instance
+		}
+	}
+
+	return primary, nil
+}
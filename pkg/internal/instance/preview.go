@@ -0,0 +1,70 @@
+package instance
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+)
+
+// DeployPreview creates the instance of the preview stack in its default region and queues its first deployment on
+// behalf of the owner of the project. The instance subscribes to auto deployments, so that it is redeployed on pushes
+// to the pull request. The permission of the current user is not checked.
+func (s *service) DeployPreview(
+	ctx context.Context,
+	stackId uint,
+) (*domain.Deployment, error) {
+	timeout, strategy, err := parseDeployStackInput(DeployStackInput{})
+	if err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	stack, err := domain.FindStackByID(tx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if stack.Preview == nil {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "stack %d is not a preview", stack.ID)
+	} else if len(stack.Instances) > 0 {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "preview stack %d is deployed already", stack.ID)
+	}
+
+	instance := domain.Instance{
+		StackID:    stack.ID,
+		Zone:       stack.DefaultRegion,
+		Name:       stack.Name + "-" + stack.DefaultRegion.String(),
+		AutoDeploy: true,
+	}
+	if err := instance.Save(tx); err != nil {
+		return nil, err
+	}
+
+	return enqueueDeployment(tx, &instance, stack.Project.OwnerID, timeout, strategy)
+}
+
+// DeletePreview deletes the instances of the preview stack forcibly and tears down the stack.
+// The permission of the current user is not checked.
+func (s *service) DeletePreview(
+	ctx context.Context,
+	stackId uint,
+) error {
+	stack, err := domain.FindStackByID(helpers.GetTx(ctx), stackId)
+	if err != nil {
+		return err
+	}
+
+	if stack.Preview == nil {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "stack %d is not a preview", stack.ID)
+	}
+
+	for _, instance := range stack.Instances {
+		if err := s.DeleteInstance(ctx, instance.ID, true); err != nil {
+			return err
+		}
+	}
+
+	return s.stacks.DeletePreviewStack(ctx, stack.ID)
+}
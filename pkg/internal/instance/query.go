@@ -2,15 +2,22 @@ package instance
 
 import (
 	"context"
-	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
-	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func (s *service) GetInstance(
 	ctx context.Context,
 	instanceId uint,
+) (*domain.Instance, error) {
+	return s.getInstance(ctx, instanceId, domain.OrganizationRoleViewer)
+}
+
+func (s *service) getInstance(
+	ctx context.Context,
+	instanceId uint,
+	role domain.OrganizationRole,
 ) (*domain.Instance, error) {
 	tx := helpers.GetTx(ctx)
 
@@ -19,25 +26,32 @@ func (s *service) GetInstance(
 		return nil, err
 	}
 
-	instance, err := domain.FindInstanceById(
-		tx.Preload("Stack").
-			Preload("Stack.Project").
-			Preload("Stack.Vapis").
-			Preload("Stack.Vapis.Vapi").
-			Preload("Stack.Vapis.Vapi.Package"),
-		instanceId,
-	)
+	instance, err := findInstance(tx, instanceId)
 	if err != nil {
 		return nil, err
 	}
 
-	if instance.Stack.Project.OwnerID != user.ID {
-		return nil, errors.Wrapf(tclerrors.ErrForbidden, "user is not owner of the project")
+	if err := instance.Stack.Project.CheckPermission(tx, user, role); err != nil {
+		return nil, err
 	}
 
 	return instance, nil
 }
 
+// findInstance loads the instance with everything needed to deploy it, without checking permissions.
+func findInstance(tx *gorm.DB, instanceId uint) (*domain.Instance, error) {
+	return domain.FindInstanceById(
+		tx.Preload("Stack").
+			Preload("Stack.Project").
+			Preload("Stack.Vapis").
+			Preload("Stack.Vapis.Vapi").
+			Preload("Stack.Vapis.Vapi.Package").
+			Preload("Stack.CustomVapis").
+			Preload("Stack.CustomDomains"),
+		instanceId,
+	)
+}
+
 func (s *service) GetInstancesInStack(
 	ctx context.Context,
 	stackId uint,
@@ -0,0 +1,75 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+type (
+	DeploymentReplicas struct {
+		Name      string
+		Component string
+		// Current is the number of ready replicas
+		Current int64
+		// Desired is the number of replicas requested by the deployment, which the autoscaler adjusts
+		Desired int64
+	}
+)
+
+func (s *service) GetInstanceReplicas(
+	ctx context.Context,
+	instanceId uint,
+) ([]DeploymentReplicas, error) {
+	instance, err := s.GetInstance(ctx, instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	if instance.AppliedK8sYaml == "" {
+		return nil, nil
+	}
+
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return nil, err
+	}
+
+	return getDeploymentReplicas(ctx, k8sClient, &instance.Stack)
+}
+
+func getDeploymentReplicas(ctx context.Context, k8sClient k8s.Client, stack *domain.Stack) ([]DeploymentReplicas, error) {
+	deployments, err := k8sClient.GetResources(
+		ctx,
+		"deployment",
+		stack.Namespace(),
+		fmt.Sprintf("shaple.io/project.id=%d,shaple.io/stack.id=%d", stack.ProjectID, stack.ID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]DeploymentReplicas, 0, len(deployments))
+	for _, deployment := range deployments {
+		desired, _, err := unstructured.NestedInt64(deployment.Object, "spec", "replicas")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read replicas of %s", deployment.GetName())
+		}
+		current, _, err := unstructured.NestedInt64(deployment.Object, "status", "readyReplicas")
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read ready replicas of %s", deployment.GetName())
+		}
+
+		result = append(result, DeploymentReplicas{
+			Name:      deployment.GetName(),
+			Component: deployment.GetLabels()["shaple.io/component"],
+			Current:   current,
+			Desired:   desired,
+		})
+	}
+
+	return result, nil
+}
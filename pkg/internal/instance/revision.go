@@ -0,0 +1,360 @@
+package instance
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
+	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
+	"github.com/habiliai/apidepot/pkg/internal/util/functx/v2"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	ObjectChange string
+
+	ObjectDiff struct {
+		Kind    string
+		Name    string
+		Change  ObjectChange
+		OldYaml string
+		NewYaml string
+	}
+)
+
+const (
+	ObjectChangeAdded   ObjectChange = "added"
+	ObjectChangeDeleted ObjectChange = "deleted"
+	ObjectChangeChanged ObjectChange = "changed"
+
+	rollbackTimeout = 1 * time.Minute
+	redactedValue   = "<redacted>"
+)
+
+// recordRevision snapshots what has just been applied to the instance. If source is given, the revision
+// is a rollback to it and inherits its vapi releases and env vars. Nothing is recorded if the instance is unchanged.
+func (s *service) recordRevision(
+	ctx context.Context,
+	instance *domain.Instance,
+	source *domain.InstanceRevision,
+	deployedById uint,
+) error {
+	tx := helpers.GetTx(ctx)
+
+	if latest, err := domain.FindLatestInstanceRevision(tx, instance.ID); err == nil {
+		if latest.K8sYaml == instance.AppliedK8sYaml && latest.Color == instance.Color {
+			return nil
+		}
+	} else if !errors.Is(err, tclerrors.ErrNotFound) {
+		return err
+	}
+
+	revision := domain.InstanceRevision{
+		InstanceID:   instance.ID,
+		K8sYaml:      instance.AppliedK8sYaml,
+		Color:        instance.Color,
+		DeployedByID: deployedById,
+	}
+
+	if source != nil {
+		revision.VapiReleaseIDs = source.VapiReleaseIDs
+		revision.VapiEnvVars = source.VapiEnvVars
+		revision.RollbackOf = gog.PtrOf(source.Revision)
+	} else {
+		vapiReleases, err := domain.FindLockedVapiReleasesByStackID(tx, instance.StackID)
+		if err != nil {
+			return err
+		}
+
+		revision.VapiReleaseIDs = gog.Map(vapiReleases, func(rel domain.VapiRelease) uint {
+			return rel.ID
+		})
+		revision.VapiEnvVars = instance.Stack.VapiEnvVars
+	}
+
+	return revision.Create(tx)
+}
+
+func (s *service) ListInstanceRevisions(
+	ctx context.Context,
+	instanceId uint,
+) ([]domain.InstanceRevision, error) {
+	if _, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
+	}
+
+	revisions, err := domain.FindInstanceRevisions(helpers.GetTx(ctx), instanceId)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		if revisions[i].K8sYaml, err = redactK8sYaml(revisions[i].K8sYaml); err != nil {
+			return nil, err
+		}
+	}
+
+	return revisions, nil
+}
+
+func (s *service) DiffInstanceRevisions(
+	ctx context.Context,
+	instanceId uint,
+	fromRevision uint,
+	toRevision uint,
+) ([]ObjectDiff, error) {
+	if _, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	from, err := domain.FindInstanceRevision(tx, instanceId, fromRevision)
+	if err != nil {
+		return nil, err
+	}
+	to, err := domain.FindInstanceRevision(tx, instanceId, toRevision)
+	if err != nil {
+		return nil, err
+	}
+
+	oldObjects, err := k8syaml.ParseK8sYaml(from.K8sYaml)
+	if err != nil {
+		return nil, err
+	}
+	newObjects, err := k8syaml.ParseK8sYaml(to.K8sYaml)
+	if err != nil {
+		return nil, err
+	}
+
+	oldObjectMap, newObjectMap, markings, err := k8syaml.DiffK8sObjects(oldObjects, newObjects)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := make([]ObjectDiff, 0, len(markings))
+	for key, marking := range markings {
+		diff := ObjectDiff{
+			Kind: key.GVK.Kind,
+			Name: key.Name,
+		}
+
+		oldObj, hasOld := oldObjectMap[key]
+		newObj, hasNew := newObjectMap[key]
+		switch marking {
+		case k8syaml.K8sObjectDiffAdded:
+			diff.Change = ObjectChangeAdded
+		case k8syaml.K8sObjectDiffDeleted:
+			diff.Change = ObjectChangeDeleted
+		case k8syaml.K8sObjectDiffChanged:
+			// every object in both revisions is marked as changed, so skip the identical ones
+			if reflect.DeepEqual(oldObj.Object, newObj.Object) {
+				continue
+			}
+			diff.Change = ObjectChangeChanged
+		}
+
+		if hasOld {
+			if diff.OldYaml, err = marshalObject(&oldObj); err != nil {
+				return nil, err
+			}
+		}
+		if hasNew {
+			if diff.NewYaml, err = marshalObject(&newObj); err != nil {
+				return nil, err
+			}
+		}
+
+		diffs = append(diffs, diff)
+	}
+
+	slices.SortFunc(diffs, func(lhs, rhs ObjectDiff) int {
+		if c := strings.Compare(lhs.Kind, rhs.Kind); c != 0 {
+			return c
+		}
+		return strings.Compare(lhs.Name, rhs.Name)
+	})
+
+	return diffs, nil
+}
+
+// redactK8sYaml marshals the objects of the yaml again with the values of their secrets redacted.
+func redactK8sYaml(k8sYaml string) (string, error) {
+	objects, err := k8syaml.ParseK8sYaml(k8sYaml)
+	if err != nil {
+		return "", err
+	}
+
+	contents := make([]string, 0, len(objects))
+	for i := range objects {
+		content, err := marshalObject(&objects[i])
+		if err != nil {
+			return "", err
+		}
+		contents = append(contents, content)
+	}
+
+	return strings.Join(contents, "---\n"), nil
+}
+
+// marshalObject marshals the object into yaml. The values of secrets are redacted, since viewers may diff revisions.
+func marshalObject(obj *unstructured.Unstructured) (string, error) {
+	if obj.GetKind() == "Secret" {
+		obj = obj.DeepCopy()
+		for _, field := range []string{"data", "stringData"} {
+			values, ok := obj.Object[field].(map[string]any)
+			if !ok {
+				continue
+			}
+			for key := range values {
+				values[key] = redactedValue
+			}
+		}
+	}
+
+	contents, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to marshal %s/%s", obj.GetKind(), obj.GetName())
+	}
+
+	return string(contents), nil
+}
+
+// RollbackInstance switches the instance back to the previous blue/green color if revision is nil,
+// otherwise applies the vapis of the given revision again in place.
+// Database migrations are not rolled back.
+func (s *service) RollbackInstance(
+	ctx context.Context,
+	instanceId uint,
+	revision *uint,
+) error {
+	if revision == nil {
+		return s.switchToPreviousColor(ctx, instanceId)
+	}
+
+	return s.rollbackToRevision(ctx, instanceId, *revision)
+}
+
+func (s *service) rollbackToRevision(
+	ctx context.Context,
+	instanceId uint,
+	revisionNumber uint,
+) error {
+	tx := helpers.GetTx(ctx)
+	ctx, fDone := functx.WithFuncTx(ctx)
+	defer fDone(ctx, true)
+
+	user, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	instance, err := s.getInstance(ctx, instanceId, domain.OrganizationRoleDeveloper)
+	if err != nil {
+		return err
+	}
+
+	if instance.State != domain.InstanceStateRunning {
+		return errors.Wrapf(tclerrors.ErrForbidden, "instance is not running")
+	}
+
+	revision, err := domain.FindInstanceRevision(tx, instanceId, revisionNumber)
+	if err != nil {
+		return err
+	}
+
+	// the revision is rendered again rather than re-applied as it is, so that rotated secrets aren't brought back
+	newK8sYaml, err := s.renderRevisionK8sYaml(ctx, instance, revision)
+	if err != nil {
+		return err
+	}
+
+	if newK8sYaml == instance.AppliedK8sYaml {
+		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "revision %d is already applied", revisionNumber)
+	}
+
+	k8sClient, err := s.k8sClientPool.GetClient(instance.Zone)
+	if err != nil {
+		return err
+	}
+
+	oldObjects, err := k8syaml.ParseK8sYaml(instance.AppliedK8sYaml)
+	if err != nil {
+		return err
+	}
+	newObjects, err := k8syaml.ParseK8sYaml(newK8sYaml)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeoutCause(ctx, rollbackTimeout, tclerrors.ErrTimeout)
+	defer cancel()
+
+	logger.Info("rollback", "revision", revision.Revision, "namespace", instance.Stack.Namespace())
+	if err := k8sClient.Upgrade(ctx, oldObjects, newObjects, k8s.WithApplyCheckFn(func(ctx context.Context) error {
+		return s.waitUntilAvailable(ctx, k8sClient, instance, revision.Color, "")
+	})); err != nil {
+		return err
+	}
+
+	if err := s.discardPreviousObjects(ctx, k8sClient, instance, newObjects); err != nil {
+		return err
+	}
+
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		instance.AppliedK8sYaml = newK8sYaml
+		instance.Color = revision.Color
+		if err := instance.Save(tx); err != nil {
+			return err
+		}
+
+		return s.recordRevision(helpers.WithTx(ctx, tx), instance, revision, user.ID)
+	}); err != nil {
+		return err
+	}
+
+	fDone(ctx, false)
+	return nil
+}
+
+// renderRevisionK8sYaml renders the vapi releases and env vars of the revision with the current settings and secrets
+// of the stack. Secret env vars which are still set on the stack take their current value.
+func (s *service) renderRevisionK8sYaml(
+	ctx context.Context,
+	instance *domain.Instance,
+	revision *domain.InstanceRevision,
+) (string, error) {
+	vapiReleases, err := domain.FindVapiReleasesByIDs(helpers.GetTx(ctx), revision.VapiReleaseIDs)
+	if err != nil {
+		return "", err
+	}
+
+	vapiEnvVars := slices.Clone(revision.VapiEnvVars)
+	for i, envVar := range vapiEnvVars {
+		if !envVar.Secret {
+			continue
+		}
+
+		j := slices.IndexFunc(instance.Stack.VapiEnvVars, func(v domain.StackVapiEnvVar) bool {
+			return v.Secret && v.Name == envVar.Name
+		})
+		if j >= 0 {
+			vapiEnvVars[i] = instance.Stack.VapiEnvVars[j]
+		}
+	}
+
+	values, k8sYamlFiles, err := s.newK8sYamlValuesWithVapis(ctx, instance, vapiReleases, vapiEnvVars)
+	if err != nil {
+		return "", err
+	}
+
+	return s.k8sYamlService.RenderYaml(k8sYamlFiles, values.WithColor(revision.Color))
+}
@@ -8,10 +8,12 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/k8s"
 	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
 	tclog "github.com/habiliai/apidepot/pkg/internal/log"
+	"github.com/habiliai/apidepot/pkg/internal/services"
 	"github.com/habiliai/apidepot/pkg/internal/stack"
 	"github.com/habiliai/apidepot/pkg/internal/user"
 	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 var (
@@ -33,6 +35,46 @@ type (
 			instanceId uint,
 			input DeployStackInput,
 		) error
+		EnqueueDeployment(
+			ctx context.Context,
+			instanceId uint,
+			input DeployStackInput,
+		) (*domain.Deployment, error)
+		EnqueueAutoDeployments(
+			ctx context.Context,
+			stackId uint,
+		) ([]domain.Deployment, error)
+		DeployPreview(
+			ctx context.Context,
+			stackId uint,
+		) (*domain.Deployment, error)
+		DeletePreview(
+			ctx context.Context,
+			stackId uint,
+		) error
+		GetDeployment(
+			ctx context.Context,
+			deploymentId uint,
+		) (*domain.Deployment, error)
+		CancelDeployment(
+			ctx context.Context,
+			deploymentId uint,
+		) error
+		WatchDeployment(
+			ctx context.Context,
+			deploymentId uint,
+			send func(event *domain.DeploymentEvent) error,
+		) error
+		RunDeploymentWorkers(
+			ctx context.Context,
+			concurrency int,
+		) error
+		RotateStackKeys(
+			ctx context.Context,
+			stackId uint,
+			input RotateStackKeysInput,
+		) (*domain.Stack, []domain.Deployment, error)
+		RunJWTSecretRetirement(ctx context.Context) error
 		LaunchInstance(
 			ctx context.Context,
 			instanceId uint,
@@ -63,9 +105,39 @@ type (
 			ctx context.Context,
 			stackId uint,
 		) ([]domain.Instance, error)
+		GetInstanceReplicas(
+			ctx context.Context,
+			instanceId uint,
+		) ([]DeploymentReplicas, error)
+		GetInstanceStatus(
+			ctx context.Context,
+			instanceId uint,
+		) (*InstanceStatus, error)
+		StreamInstanceLogs(
+			ctx context.Context,
+			instanceId uint,
+			input StreamInstanceLogsInput,
+			send func(line k8s.LogLine) error,
+		) error
+		RollbackInstance(
+			ctx context.Context,
+			instanceId uint,
+			revision *uint,
+		) error
+		ListInstanceRevisions(
+			ctx context.Context,
+			instanceId uint,
+		) ([]domain.InstanceRevision, error)
+		DiffInstanceRevisions(
+			ctx context.Context,
+			instanceId uint,
+			fromRevision uint,
+			toRevision uint,
+		) ([]ObjectDiff, error)
 	}
 
 	service struct {
+		db             *gorm.DB
 		k8sClientPool  *k8s.ClientPool
 		k8sYamlService *k8syaml.Service
 		smtpConfig     config.SMTPConfig
@@ -79,6 +151,7 @@ type (
 )
 
 func NewService(
+	db *gorm.DB,
 	k8sClientPool *k8s.ClientPool,
 	k8sYamlService *k8syaml.Service,
 	smtpConfig config.SMTPConfig,
@@ -90,6 +163,7 @@ func NewService(
 	stackService stack.Service,
 ) Service {
 	return &service{
+		db:             db,
 		k8sClientPool:  k8sClientPool,
 		k8sYamlService: k8sYamlService,
 		smtpConfig:     smtpConfig,
@@ -104,6 +178,11 @@ func NewService(
 
 func init() {
 	digo.ProvideService(ServiceKey, func(ctx *digo.Container) (any, error) {
+		db, err := digo.Get[*gorm.DB](ctx, services.ServiceKeyDB)
+		if err != nil {
+			return nil, err
+		}
+
 		k8sClientPool, err := digo.Get[*k8s.ClientPool](ctx, k8s.ServiceKeyK8sClientPool)
 		if err != nil {
 			return nil, err
@@ -143,6 +222,7 @@ func init() {
 				Port:     6543,
 			}
 			return NewService(
+				db,
 				k8sClientPool,
 				k8sYamlService,
 				config.SMTPConfig{
@@ -182,6 +262,7 @@ func init() {
 			), nil
 		case digo.EnvProd:
 			return NewService(
+				db,
 				k8sClientPool,
 				k8sYamlService,
 				ctx.Config.SMTP,
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/pkg/errors"
 	"golang.org/x/sync/errgroup"
 	"net/http"
@@ -13,17 +14,49 @@ import (
 	"time"
 )
 
+type (
+	// healthCheck is the result of requesting the health path of a component. Error tells why it is not ok.
+	healthCheck struct {
+		OK    bool
+		Error string
+	}
+)
+
 func (s *service) isAvailable(
 	ctx context.Context,
 	instance *domain.Instance,
+	pathPrefix string,
 	readTimeout time.Duration,
 ) (map[string]bool, error) {
+	checks, err := s.checkHealth(ctx, instance, pathPrefix, readTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[string]bool, len(checks))
+	for name, check := range checks {
+		results[name] = check.OK
+	}
+
+	return results, nil
+}
+
+// checkHealth requests the health paths of the components of the instance. The results are keyed by auth, storage,
+// postgrest-live, postgrest-ready, custom-vapi/<name> and vapi/<slug>, so that two major versions of a package locked
+// together don't overwrite each other.
+func (s *service) checkHealth(
+	ctx context.Context,
+	instance *domain.Instance,
+	pathPrefix string,
+	readTimeout time.Duration,
+) (map[string]healthCheck, error) {
 	type Result struct {
-		name string
-		ok   bool
+		name  string
+		check healthCheck
 	}
 
-	endpoint := instance.Stack.Endpoint()
+	// each instance is checked directly, not through the global endpoint of a multi-region stack
+	endpoint := instance.Stack.ZoneEndpoint(instance.Zone, s.stackConfig.GetRegionalConfig(instance.Zone).Domain) + pathPrefix
 
 	var checkRequests []map[string]string
 	if instance.Stack.AuthEnabled {
@@ -51,20 +84,33 @@ func (s *service) isAvailable(
 		})
 	}
 
-	rels, err := s.vapis.GetAllDependenciesOfVapiReleases(ctx, instance.Stack.GetVapiReleases())
+	for _, customVapi := range instance.Stack.CustomVapis {
+		checkRequests = append(checkRequests, map[string]string{
+			"name": "custom-vapi/" + customVapi.Name,
+			"path": endpoint + constants.PathCustomVapis + "/" + customVapi.Name + constants.VapiHealthPath,
+		})
+	}
+
+	rels, err := domain.FindLockedVapiReleasesByStackID(helpers.GetTx(ctx), instance.Stack.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, rel := range rels {
+		checkRequests = append(checkRequests, map[string]string{
+			"name": "vapi/" + rel.Slug(),
+			"path": endpoint + constants.PathVapis + "/" + rel.Slug() + constants.VapiHealthPath,
+		})
+	}
+
 	logger.Debug("print", "len(checkRequests)", len(checkRequests), "len(rels)", len(rels))
 
-	checksCh := make(chan Result, len(checkRequests)+len(rels))
+	checksCh := make(chan Result, len(checkRequests))
 	var eg errgroup.Group
 	for _, checkReq := range checkRequests {
 		eg.Go(func() error {
 			result := Result{
 				name: checkReq["name"],
-				ok:   false,
 			}
 			if err := func() error {
 				ctx, cancel := context.WithTimeout(ctx, readTimeout)
@@ -76,74 +122,34 @@ func (s *service) isAvailable(
 					checkReq["path"],
 					nil,
 				)
-				req.Header.Add("Authorization", "Bearer "+instance.Stack.AnonApiKey)
 				if err != nil {
 					return errors.Wrapf(err, "failed to create request")
 				}
+				req.Header.Add("Authorization", "Bearer "+instance.Stack.AnonApiKey)
 
 				resp, err := http.DefaultClient.Do(req)
 				if err != nil {
 					if errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "i/o timeout") {
+						result.check.Error = fmt.Sprintf("no response in %s", readTimeout)
 						return nil
 					}
 
 					if e := (*tls.CertificateVerificationError)(nil); errors.As(err, &e) {
 						logger.Warn(fmt.Sprintf("'%s' is unavailable. because of %v", result.name, e.Unwrap()))
+						result.check.Error = e.Unwrap().Error()
 						return nil
 					}
-					return errors.Wrapf(err, "failed to do request")
-				}
-
-				result.ok = resp.StatusCode == http.StatusOK
-				return nil
-			}(); err != nil {
-				return err
-			}
-
-			select {
-			case <-ctx.Done():
-				return errors.WithStack(ctx.Err())
-			case checksCh <- result:
-				return nil
-			}
-		})
-	}
 
-	for _, rel := range rels {
-		eg.Go(func() error {
-			result := Result{
-				name: rel.Package.Name,
-				ok:   false,
-			}
-			if err := func() error {
-				ctx, cancel := context.WithTimeout(ctx, readTimeout)
-				defer cancel()
-
-				req, err := http.NewRequestWithContext(
-					ctx,
-					http.MethodGet,
-					endpoint+constants.PathVapis+"/"+rel.Slug()+constants.VapiHealthPath,
-					nil,
-				)
-				req.Header.Add("Authorization", "Bearer "+instance.Stack.AnonApiKey)
-				if err != nil {
-					return errors.Wrapf(err, "failed to create request")
+					// the component is unreachable, e.g. the connection is refused or the host is not resolved yet
+					result.check.Error = err.Error()
+					return nil
 				}
+				defer resp.Body.Close()
 
-				resp, err := http.DefaultClient.Do(req)
-				if err != nil {
-					if errors.Is(err, context.DeadlineExceeded) {
-						return nil
-					}
-
-					if e := (*tls.CertificateVerificationError)(nil); errors.As(err, &e) {
-						logger.Warn(fmt.Sprintf("'%s' is unavailable. because of %v", rel.Package.Name, e.Unwrap()))
-						return nil
-					}
-					return errors.Wrapf(err, "failed to do request")
+				result.check.OK = resp.StatusCode == http.StatusOK
+				if !result.check.OK {
+					result.check.Error = "health check responded " + resp.Status
 				}
-
-				result.ok = resp.StatusCode == http.StatusOK
 				return nil
 			}(); err != nil {
 				return err
@@ -163,7 +169,7 @@ func (s *service) isAvailable(
 		close(checksCh)
 	}()
 
-	results := map[string]bool{}
+	results := map[string]healthCheck{}
 	for interrupt := false; !interrupt; {
 		select {
 		case <-ctx.Done():
@@ -173,10 +179,10 @@ func (s *service) isAvailable(
 				interrupt = true
 				break
 			}
-			if !result.ok {
-				logger.Warn("unavailable", "name", result.name)
+			if !result.check.OK {
+				logger.Warn("unavailable", "name", result.name, "error", result.check.Error)
 			}
-			results[result.name] = result.ok
+			results[result.name] = result.check
 		}
 	}
 
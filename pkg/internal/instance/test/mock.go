@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/instance"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -11,6 +12,16 @@ type ServiceMock struct {
 	mock.Mock
 }
 
+func (s *ServiceMock) GetInstanceReplicas(ctx context.Context, instanceId uint) ([]instance.DeploymentReplicas, error) {
+	args := s.Called(ctx, instanceId)
+	return args.Get(0).([]instance.DeploymentReplicas), args.Error(1)
+}
+
+func (s *ServiceMock) GetInstanceStatus(ctx context.Context, instanceId uint) (*instance.InstanceStatus, error) {
+	args := s.Called(ctx, instanceId)
+	return args.Get(0).(*instance.InstanceStatus), args.Error(1)
+}
+
 func (s *ServiceMock) CreateInstance(ctx context.Context, input instance.CreateInstanceInput) (*domain.Instance, error) {
 	args := s.Called(ctx, input)
 	return args.Get(0).(*domain.Instance), args.Error(1)
@@ -21,6 +32,56 @@ func (s *ServiceMock) DeployStack(ctx context.Context, instanceId uint, input in
 	return args.Error(0)
 }
 
+func (s *ServiceMock) EnqueueDeployment(ctx context.Context, instanceId uint, input instance.DeployStackInput) (*domain.Deployment, error) {
+	args := s.Called(ctx, instanceId, input)
+	return args.Get(0).(*domain.Deployment), args.Error(1)
+}
+
+func (s *ServiceMock) EnqueueAutoDeployments(ctx context.Context, stackId uint) ([]domain.Deployment, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.Deployment), args.Error(1)
+}
+
+func (s *ServiceMock) DeployPreview(ctx context.Context, stackId uint) (*domain.Deployment, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).(*domain.Deployment), args.Error(1)
+}
+
+func (s *ServiceMock) DeletePreview(ctx context.Context, stackId uint) error {
+	args := s.Called(ctx, stackId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) GetDeployment(ctx context.Context, deploymentId uint) (*domain.Deployment, error) {
+	args := s.Called(ctx, deploymentId)
+	return args.Get(0).(*domain.Deployment), args.Error(1)
+}
+
+func (s *ServiceMock) CancelDeployment(ctx context.Context, deploymentId uint) error {
+	args := s.Called(ctx, deploymentId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) WatchDeployment(ctx context.Context, deploymentId uint, send func(event *domain.DeploymentEvent) error) error {
+	args := s.Called(ctx, deploymentId, send)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) RunDeploymentWorkers(ctx context.Context, concurrency int) error {
+	args := s.Called(ctx, concurrency)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) RotateStackKeys(ctx context.Context, stackId uint, input instance.RotateStackKeysInput) (*domain.Stack, []domain.Deployment, error) {
+	args := s.Called(ctx, stackId, input)
+	return args.Get(0).(*domain.Stack), args.Get(1).([]domain.Deployment), args.Error(2)
+}
+
+func (s *ServiceMock) RunJWTSecretRetirement(ctx context.Context) error {
+	args := s.Called(ctx)
+	return args.Error(0)
+}
+
 func (s *ServiceMock) LaunchInstance(ctx context.Context, instanceId uint) error {
 	args := s.Called(ctx, instanceId)
 	return args.Error(0)
@@ -59,3 +120,23 @@ func (s *ServiceMock) GetInstancesInStack(ctx context.Context, stackId uint) ([]
 var (
 	_ instance.Service = (*ServiceMock)(nil)
 )
+
+func (s *ServiceMock) StreamInstanceLogs(ctx context.Context, instanceId uint, input instance.StreamInstanceLogsInput, send func(line k8s.LogLine) error) error {
+	args := s.Called(ctx, instanceId, input, send)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) RollbackInstance(ctx context.Context, instanceId uint, revision *uint) error {
+	args := s.Called(ctx, instanceId, revision)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) ListInstanceRevisions(ctx context.Context, instanceId uint) ([]domain.InstanceRevision, error) {
+	args := s.Called(ctx, instanceId)
+	return args.Get(0).([]domain.InstanceRevision), args.Error(1)
+}
+
+func (s *ServiceMock) DiffInstanceRevisions(ctx context.Context, instanceId uint, fromRevision uint, toRevision uint) ([]instance.ObjectDiff, error) {
+	args := s.Called(ctx, instanceId, fromRevision, toRevision)
+	return args.Get(0).([]instance.ObjectDiff), args.Error(1)
+}
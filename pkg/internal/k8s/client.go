@@ -26,11 +26,18 @@ type (
 		Apply(ctx context.Context, objects []unstructured.Unstructured) error
 		Delete(ctx context.Context, objects []unstructured.Unstructured, wait bool, options ...OptionsFunc) error
 		GetResource(ctx context.Context, kind, name, namespace string) (*unstructured.Unstructured, error)
+		GetResources(ctx context.Context, kind, namespace, selector string) ([]unstructured.Unstructured, error)
 		Wait(ctx context.Context, kind, namespace string, selector string, forCondition string) error
 		GetLogs(
 			ctx context.Context,
 			selector, namespace string,
 		) (map[string]string, error)
+		StreamLogs(
+			ctx context.Context,
+			namespace, selector string,
+			options LogOptions,
+			send func(line LogLine) error,
+		) error
 		Upgrade(
 			ctx context.Context,
 			oldObjects []unstructured.Unstructured,
@@ -71,6 +71,8 @@ func (k *client) getResourceInterface(kind, namespace string) (dynamic.ResourceI
 		gvk = schema.FromAPIVersionAndKind("v1", "Pod")
 	case "job":
 		gvk = schema.FromAPIVersionAndKind("batch/v1", "Job")
+	case "horizontalpodautoscaler":
+		gvk = schema.FromAPIVersionAndKind("autoscaling/v2", "HorizontalPodAutoscaler")
 	default:
 		return nil, errors.Wrapf(tclerrors.ErrRuntime, "i don't know group kind")
 	}
@@ -102,3 +104,19 @@ func (k *client) GetResource(ctx context.Context, kind, name, namespace string)
 
 	return object, nil
 }
+
+func (k *client) GetResources(ctx context.Context, kind, namespace, selector string) ([]unstructured.Unstructured, error) {
+	dri, err := k.getResourceInterface(kind, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := dri.List(ctx, v1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to list. kind=%s, namespace=%s, selector=%s", kind, namespace, selector)
+	}
+
+	return list.Items, nil
+}
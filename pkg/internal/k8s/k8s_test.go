@@ -52,6 +52,38 @@ func (s *K8sClientTestSuite) TestWait() {
 	))
 }
 
+func (s *K8sClientTestSuite) TestGivenBusyDeployment_WhenStreamLogs_ShouldSendLines() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	contents, err := os.ReadFile("testdata/busy_deployment.yaml")
+	s.Require().NoError(err)
+
+	s.NoError(s.k.ApplyYamlFile(ctx, string(contents)))
+	defer s.k.DeleteYamlFile(ctx, string(contents), true, k8s.WithForce(true))
+	time.Sleep(1 * time.Second)
+
+	s.Require().NoError(s.k.Wait(ctx, "pod", "default", "app=busy", "ready"))
+
+	var lines []k8s.LogLine
+	s.Require().NoError(s.k.StreamLogs(ctx, "default", "app=busy", k8s.LogOptions{
+		Container: "main",
+	}, func(line k8s.LogLine) error {
+		lines = append(lines, line)
+		return nil
+	}))
+
+	s.Require().Len(lines, 1)
+	s.Equal("Hello, world!", lines[0].Line)
+	s.Contains(lines[0].Pod, "busy-")
+	s.False(lines[0].Time.IsZero())
+
+	err = s.k.StreamLogs(ctx, "default", "app=nothing", k8s.LogOptions{}, func(k8s.LogLine) error {
+		return nil
+	})
+	s.ErrorIs(err, tclerrors.ErrNotFound)
+}
+
 func (s *K8sClientTestSuite) TestGivenNoSetBurstAndQPS_WhenManyCallK8sIn15Seconds_ShouldBeError() {
 	if os.Getenv("CI") != "" {
 		s.T().Skipf("This test is skipped because it affects the local k8s cluster which has limited resources when running whole of tests.")
@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"bufio"
+	"context"
+	"github.com/habiliai/apidepot/pkg/errors"
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+	v2 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxLogLineSize = 1024 * 1024
+
+type (
+	LogOptions struct {
+		Container    string
+		SinceSeconds *int64
+		TailLines    *int64
+		Follow       bool
+	}
+
+	LogLine struct {
+		Pod  string
+		Time time.Time
+		Line string
+	}
+)
+
+// StreamLogs sends the log lines of every pod matching the selector as they are read.
+// The pods are listed once, so pods started while following are not streamed.
+func (k *client) StreamLogs(
+	ctx context.Context,
+	namespace, selector string,
+	options LogOptions,
+	send func(line LogLine) error,
+) error {
+	pods, err := k.client.CoreV1().Pods(namespace).List(ctx, v1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "failed to list pods")
+	}
+
+	if len(pods.Items) == 0 {
+		return errors.Wrapf(tclerrors.ErrNotFound, "no pods found. selector=%s", selector)
+	}
+
+	var lck sync.Mutex
+	eg, ctx := errgroup.WithContext(ctx)
+	for _, pod := range pods.Items {
+		podName := pod.GetName()
+		eg.Go(func() error {
+			podLogs, err := k.client.CoreV1().Pods(namespace).GetLogs(podName, &v2.PodLogOptions{
+				Container:    options.Container,
+				Follow:       options.Follow,
+				SinceSeconds: options.SinceSeconds,
+				TailLines:    options.TailLines,
+				Timestamps:   true,
+			}).Stream(ctx)
+			if err != nil {
+				return errors.Wrapf(err, "failed to stream logs of %s", podName)
+			}
+			defer podLogs.Close()
+
+			scanner := bufio.NewScanner(podLogs)
+			scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+			for scanner.Scan() {
+				line := parseLogLine(podName, scanner.Text())
+
+				lck.Lock()
+				err := send(line)
+				lck.Unlock()
+				if err != nil {
+					return err
+				}
+			}
+
+			if err := scanner.Err(); err != nil && ctx.Err() == nil {
+				return errors.Wrapf(err, "failed to read logs of %s", podName)
+			}
+
+			return nil
+		})
+	}
+
+	return eg.Wait()
+}
+
+// parseLogLine splits the timestamp kubernetes prefixes to each line off.
+func parseLogLine(pod, text string) LogLine {
+	line := LogLine{
+		Pod:  pod,
+		Line: text,
+	}
+
+	timestamp, rest, ok := strings.Cut(text, " ")
+	if !ok {
+		return line
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, timestamp)
+	if err != nil {
+		return line
+	}
+
+	line.Time = t
+	line.Line = rest
+	return line
+}
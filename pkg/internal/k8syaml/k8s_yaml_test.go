@@ -3,13 +3,19 @@ package k8syaml_test
 import (
 	"context"
 	"github.com/goccy/go-yaml"
+	pkgconfig "github.com/habiliai/apidepot/pkg/config"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/k8s"
 	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/mokiat/gog"
 	"github.com/stretchr/testify/suite"
+	"gorm.io/datatypes"
 	"strings"
 	"testing"
+	"time"
 )
 
 type K8sYamlServiceTestSuite struct {
@@ -60,3 +66,296 @@ func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderYaml() {
 
 	s.Equal(stack.Namespace(), name)
 }
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderIngressWithCustomVapis() {
+	stack := domain.Stack{
+		Hash:   "iktjke1233",
+		Name:   "dev",
+		Domain: "iktjke1233.shaple.io",
+		Scheme: "https",
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).WithCustomVapis([]k8syaml.CustomVapiYamlValues{
+		{
+			CustomVapi: &domain.CustomVapi{
+				Model: domain.Model{ID: 1},
+				Name:  "my-custom-vapi",
+			},
+		},
+	})
+
+	object, err := s.k8sYamlService.RenderYaml([]string{"common/ingress.yaml"}, values)
+	s.Require().NoError(err)
+
+	s.Contains(object, `- "/custom-vapis/v1/my-custom-vapi"`)
+	s.Contains(object, `path: "/custom-vapis/v1/my-custom-vapi"`)
+	s.Contains(object, "name: custom-vapi-1")
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderAutoscaling() {
+	stack := domain.Stack{
+		Hash:        "iktjke1233",
+		Name:        "dev",
+		AuthEnabled: true,
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+	memoryUtilization := int32(70)
+	instance := domain.Instance{
+		NumReplicas: 2,
+		MaxReplicas: 5,
+		ScalingTargets: datatypes.NewJSONType(map[string]domain.InstanceScalingTarget{
+			domain.InstanceComponentAuth: {MemoryUtilization: &memoryUtilization},
+		}),
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack)
+	values.Auth = &k8syaml.AuthYamlValues{}
+	values = values.WithScaling(&instance)
+
+	object, err := s.k8sYamlService.RenderYaml([]string{"auth/deployment.yaml", "common/hpa.yaml"}, values)
+	s.Require().NoError(err)
+
+	// the replicas are left to the autoscaler
+	s.NotContains(object, "\n  replicas:")
+	s.Contains(object, "kind: HorizontalPodAutoscaler")
+	s.Contains(object, "minReplicas: 2")
+	s.Contains(object, "maxReplicas: 5")
+	s.Contains(object, "name: memory")
+	s.NotContains(object, "name: cpu\n")
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderWithoutAutoscaling() {
+	stack := domain.Stack{
+		Hash: "iktjke1233",
+		Name: "dev",
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).WithPostgrest().WithScaling(&domain.Instance{MaxReplicas: 1})
+
+	object, err := s.k8sYamlService.RenderYaml([]string{"postgrest/deployment.yaml", "common/hpa.yaml"}, values)
+	s.Require().NoError(err)
+
+	s.Contains(object, "\n  replicas: 1")
+	s.NotContains(object, "HorizontalPodAutoscaler")
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderColoredWithPreviewIngress() {
+	stack := domain.Stack{
+		Hash:             "iktjke1233",
+		Name:             "dev",
+		PostgrestEnabled: true,
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).
+		WithPostgrest().
+		WithScaling(&domain.Instance{}).
+		WithColor(domain.InstanceColorGreen)
+
+	object, err := s.k8sYamlService.RenderYaml([]string{
+		"database/configmap.yaml",
+		"database/secret.yaml",
+		"postgrest/deployment.yaml",
+		"postgrest/service.yaml",
+		"common/ingress.yaml",
+	}, values.WithPreviewIngress())
+	s.Require().NoError(err)
+
+	objects, err := k8syaml.ParseK8sYaml(object)
+	s.Require().NoError(err)
+
+	names := map[string]string{}
+	for _, obj := range objects {
+		names[obj.GetKind()+"/"+obj.GetName()] = obj.GetLabels()["shaple.io/color"]
+	}
+	s.Contains(names, "Deployment/postgrest-green")
+	s.Contains(names, "Service/postgrest-green")
+	s.Contains(names, "Ingress/ingress-preview")
+	s.Contains(names, "Middleware/stripprefix-preview")
+	s.Equal("green", names["Deployment/postgrest-green"])
+	s.Contains(object, "path: \"/_preview/postgrest/v1\"")
+
+	// the live color keeps its own config until the traffic is switched
+	s.Contains(names, "ConfigMap/database-green")
+	s.Contains(names, "Secret/database-green")
+	s.NotContains(object, "name: database\n")
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderIngressWithApiKeyVerification() {
+	stack := domain.Stack{
+		Model:  domain.Model{ID: 3},
+		Hash:   "iktjke1233",
+		Name:   "dev",
+		Domain: "iktjke1233.shaple.io",
+		Scheme: "https",
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).
+		WithApiKeyVerification("http://apidepot.apidepot.svc:8081/_internal/api-keys/verify", "verify-secret")
+
+	object, err := s.k8sYamlService.RenderYaml([]string{"common/ingress.yaml"}, values.WithPreviewIngress())
+	s.Require().NoError(err)
+
+	s.Contains(object, "name: api-keys-preview")
+	s.Contains(object, `address: "http://apidepot.apidepot.svc:8081/_internal/api-keys/verify?stackId=3&signature=`+util.SignHMAC("verify-secret", "3")+`"`)
+	s.Contains(object, "router.middlewares: ns-iktjke1233-api-keys-preview@kubernetescrd,ns-iktjke1233-cors-headers-preview@kubernetescrd")
+
+	object, err = s.k8sYamlService.RenderYaml([]string{"common/ingress.yaml"}, s.k8sYamlService.NewValuesFromStack(&stack))
+	s.Require().NoError(err)
+
+	s.NotContains(object, "forwardAuth")
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderIngressWithRateLimits() {
+	stack := domain.Stack{
+		Hash:   "iktjke1233",
+		Name:   "dev",
+		Domain: "iktjke1233.shaple.io",
+		Scheme: "https",
+		Project: domain.Project{
+			Name: "test123",
+		},
+		RateLimits: datatypes.NewJSONType(domain.StackRateLimits{
+			Global: &domain.RateLimit{RequestsPerSecond: 100, Burst: 50},
+			Vapis: map[string]domain.RateLimit{
+				"my-custom-vapi": {RequestsPerSecond: 5, Burst: 10, MaxInFlight: 3},
+			},
+		}),
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).WithCustomVapis([]k8syaml.CustomVapiYamlValues{
+		{
+			CustomVapi: &domain.CustomVapi{
+				Model: domain.Model{ID: 1},
+				Name:  "my-custom-vapi",
+			},
+		},
+		{
+			CustomVapi: &domain.CustomVapi{
+				Model: domain.Model{ID: 2},
+				Name:  "other-custom-vapi",
+			},
+		},
+	}).WithRateLimits()
+
+	object, err := s.k8sYamlService.RenderYaml([]string{"common/ingress.yaml"}, values)
+	s.Require().NoError(err)
+
+	objects, err := k8syaml.ParseK8sYaml(object)
+	s.Require().NoError(err)
+
+	names := map[string]bool{}
+	for _, obj := range objects {
+		names[obj.GetKind()+"/"+obj.GetName()] = true
+	}
+	s.True(names["Middleware/ratelimit"])
+	s.True(names["Middleware/ratelimit-custom-vapi-1"])
+	s.True(names["Middleware/inflightreq-custom-vapi-1"])
+	s.False(names["Middleware/inflightreq"])
+	s.True(names["Ingress/ingress"])
+	s.True(names["Ingress/ingress-custom-vapi-1"])
+	s.Contains(object, "router.middlewares: ns-iktjke1233-cors-headers@kubernetescrd,ns-iktjke1233-stripprefix@kubernetescrd,ns-iktjke1233-ratelimit@kubernetescrd\n")
+	s.Contains(object, "router.middlewares: ns-iktjke1233-cors-headers@kubernetescrd,ns-iktjke1233-stripprefix@kubernetescrd,ns-iktjke1233-ratelimit@kubernetescrd,ns-iktjke1233-ratelimit-custom-vapi-1@kubernetescrd,ns-iktjke1233-inflightreq-custom-vapi-1@kubernetescrd\n")
+	s.Equal(1, strings.Count(object, `path: "/custom-vapis/v1/my-custom-vapi"`))
+	s.Contains(object, `path: "/custom-vapis/v1/other-custom-vapi"`)
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderIngressWithCustomDomains() {
+	stack := domain.Stack{
+		Hash:          "iktjke1233",
+		Name:          "dev",
+		Domain:        "iktjke1233.shaple.io",
+		Scheme:        "https",
+		PrimaryDomain: "api.example.com",
+		Project: domain.Project{
+			Name: "test123",
+		},
+		CustomDomains: []domain.StackCustomDomain{
+			{Hostname: "api.example.com", VerifiedAt: gog.PtrOf(time.Now())},
+			{Hostname: "unverified.example.com"},
+		},
+	}
+
+	object, err := s.k8sYamlService.RenderYaml(
+		[]string{"common/ingress.yaml", "common/certificate.yaml"},
+		s.k8sYamlService.NewValuesFromStack(&stack),
+	)
+	s.Require().NoError(err)
+
+	objects, err := k8syaml.ParseK8sYaml(object)
+	s.Require().NoError(err)
+
+	names := map[string]bool{}
+	for _, obj := range objects {
+		names[obj.GetKind()+"/"+obj.GetName()] = true
+	}
+	s.True(names["Ingress/ingress"])
+	s.True(names["Certificate/iktjke1233.shaple.io-tls"])
+	s.True(names["Certificate/api.example.com-tls"])
+	s.False(names["Certificate/unverified.example.com-tls"])
+	// the certificates are owned by the Certificate objects only, not by the ingress shim of cert-manager
+	s.NotContains(object, "cert-manager.io/cluster-issuer")
+	s.Contains(object, `host: "iktjke1233.shaple.io"`)
+	s.Contains(object, `host: "api.example.com"`)
+	s.Contains(object, "secretName: api.example.com-tls")
+	s.NotContains(object, "unverified.example.com")
+	s.Equal("https://api.example.com", stack.Endpoint())
+}
+
+func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderMultiRegionInstanceOutOfDefaultRegion() {
+	stack := domain.Stack{
+		Hash:           "iktjke1233",
+		Name:           "dev",
+		Domain:         "iktjke1233.seoul.shaple.io",
+		Scheme:         "https",
+		DefaultRegion:  tcltypes.InstanceZoneOciApSeoul,
+		MultiRegion:    true,
+		GlobalDomain:   "iktjke1233.global.shaple.io",
+		StorageEnabled: true,
+		Storage: datatypes.NewJSONType(domain.Storage{
+			S3Bucket: "iktjke1233.seoul.shaple.io",
+		}),
+		Project: domain.Project{
+			Name: "test123",
+		},
+	}
+
+	values := s.k8sYamlService.NewValuesFromStack(&stack).
+		WithZone(tcltypes.InstanceZoneOciSingapore, "singapore.shaple.io", pkgconfig.RegionalDBConfig{
+			Host: "db.seoul.shaple.io",
+			Port: 5432,
+		}).
+		WithStorage(pkgconfig.S3Config{
+			Singapore: pkgconfig.RegionalS3Config{Endpoint: "https://s3.singapore.shaple.io"},
+		}, tcltypes.InstanceZoneOciSingapore)
+
+	object, err := s.k8sYamlService.RenderYaml([]string{
+		"common/ingress.yaml",
+		"database/configmap.yaml",
+		"storage/configmap.yaml",
+	}, values)
+	s.Require().NoError(err)
+
+	s.Contains(object, `host: "iktjke1233.singapore.shaple.io"`)
+	s.Contains(object, `host: "iktjke1233.global.shaple.io"`)
+	s.NotContains(object, `host: "iktjke1233.seoul.shaple.io"`)
+	s.Contains(object, `db_host: "db.seoul.shaple.io"`)
+	s.Contains(object, `STORAGE_S3_ENDPOINT: "https://s3.singapore.shaple.io"`)
+	s.Contains(object, `STORAGE_S3_REGION: "ap-singapore-1"`)
+	s.Equal("https://iktjke1233.global.shaple.io", stack.Endpoint())
+	s.Equal("https://iktjke1233.singapore.shaple.io", values.Zone.Endpoint)
+	s.Contains(values.DBReadURL(), "@postgres.default.svc.cluster.local:5432/")
+}
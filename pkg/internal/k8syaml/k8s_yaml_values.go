@@ -1,13 +1,24 @@
 package k8syaml
 
 import (
+	"encoding/base64"
+	"fmt"
 	pkgconfig "github.com/habiliai/apidepot/pkg/config"
 	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const (
+	// localDBHost is the postgres in the kubernetes cluster of each zone
+	localDBHost = "postgres.default.svc.cluster.local"
+	localDBPort = 5432
+)
+
 type (
 	AuthYamlValues struct {
 		RateLimitEmailSent    float64
@@ -19,7 +30,9 @@ type (
 		SiteURL string
 		JWT     struct {
 			Secret string
-			Exp    time.Duration
+			// PreviousSecret is still accepted during the grace period after the secret is rotated
+			PreviousSecret string
+			Exp            time.Duration
 		}
 		SMTP struct {
 			Host       string
@@ -118,6 +131,7 @@ type (
 			AccessKey string
 			SecretKey string
 			Endpoint  string
+			Region    string
 		}
 		TenantID string
 	}
@@ -126,6 +140,55 @@ type (
 		Schemas []string
 	}
 
+	ScaledDeploymentYamlValues struct {
+		Name      string
+		Component string
+		Target    domain.InstanceScalingTarget
+	}
+
+	ScalingYamlValues struct {
+		Replicas    uint
+		MaxReplicas uint
+		Deployments []ScaledDeploymentYamlValues
+	}
+
+	IngressYamlValues struct {
+		// Suffix distinguishes the names of the ingress and its middlewares, e.g. "-preview"
+		Suffix     string
+		PathPrefix string
+		// ApiKeyVerifyURL is the address the ingress forwards the requests to for verifying the stack api keys and
+		// counting the requests against the monthly quota. empty means neither is enforced.
+		ApiKeyVerifyURL string
+		// RateLimit applies to the paths of the ingress. nil means unlimited.
+		RateLimit *domain.RateLimit
+		// RateLimitedVapis are routed by their own ingresses to apply their own rate limits.
+		RateLimitedVapis []RateLimitedVapiYamlValues
+	}
+
+	ZoneYamlValues struct {
+		Name tcltypes.InstanceZone
+		// Domain is the generated domain of the stack in the zone
+		Domain string
+		// Host is the host name the services of the stack in the zone call each other at, e.g. in SHAPLE_URL
+		Host     string
+		Endpoint string
+		Database struct {
+			// Host is the primary database of the stack the services write to
+			Host string
+			Port int
+			// ReadHost is the database in the zone, which is a replica of the primary for a multi-region stack
+			ReadHost string
+			ReadPort int
+		}
+	}
+
+	RateLimitedVapiYamlValues struct {
+		// Name is the name of the service of the vapi, e.g. "vapi-1-v2"
+		Name      string
+		Path      string
+		RateLimit domain.RateLimit
+	}
+
 	Values struct {
 		ShapleEnv string
 
@@ -146,9 +209,28 @@ type (
 		Postgrest   *PostgrestYamlValues
 		Vapis       []VapiYamlValues
 		CustomVapis []CustomVapiYamlValues
+		Scaling     ScalingYamlValues
+		Ingress     IngressYamlValues
+		Zone        ZoneYamlValues
+
+		// Color is the blue/green color of the deployments and services. empty means they are not colored.
+		Color domain.InstanceColor
 	}
 )
 
+func (s ScalingYamlValues) Autoscaling() bool {
+	return s.MaxReplicas > s.Replicas
+}
+
+// Colored returns the name of a colored deployment or service.
+func (v Values) Colored(name string) string {
+	if v.Color == domain.InstanceColorNone {
+		return name
+	}
+
+	return name + "-" + string(v.Color)
+}
+
 func (s *Service) NewValuesFromStack(stack *domain.Stack) Values {
 	values := Values{
 		ShapleEnv: string(s.shapleEnv),
@@ -162,10 +244,143 @@ func (s *Service) NewValuesFromStack(stack *domain.Stack) Values {
 	values.Paths.PostgrestLive = constants.PathPostgrestLive
 	values.Paths.PostgrestReady = constants.PathPostgrestReady
 	values.Paths.Vapi = constants.PathVapis
+	values.Paths.CustomVapi = constants.PathCustomVapis
+	values.Scaling.Replicas = 1
+	values.Scaling.MaxReplicas = 1
+	values.Zone.Name = stack.DefaultRegion
+	values.Zone.Domain = stack.Domain
+	values.Zone.Host = stack.Host()
+	values.Zone.Endpoint = stack.Endpoint()
+	values.Zone.Database.Host = localDBHost
+	values.Zone.Database.Port = localDBPort
+	values.Zone.Database.ReadHost = localDBHost
+	values.Zone.Database.ReadPort = localDBPort
 
 	return values
 }
 
+// JWTKeys returns the jwt secrets accepted by the stack as JSON web keys, the current one first.
+func (v AuthYamlValues) JWTKeys() []map[string]any {
+	keys := []map[string]any{newJWK(v.JWT.Secret, "sign", "verify")}
+	if v.JWT.PreviousSecret != "" {
+		keys = append(keys, newJWK(v.JWT.PreviousSecret, "verify"))
+	}
+
+	return keys
+}
+
+func newJWK(secret string, keyOps ...string) map[string]any {
+	return map[string]any{
+		"kty":     "oct",
+		"alg":     "HS256",
+		"k":       base64.RawURLEncoding.EncodeToString([]byte(secret)),
+		"key_ops": keyOps,
+	}
+}
+
+func (v Values) WithColor(color domain.InstanceColor) Values {
+	v.Color = color
+
+	return v
+}
+
+// WithPreviewIngress routes the paths under constants.PathPreview to the deployments, so that
+// a new color can be health-checked before the main ingress is switched to it.
+func (v Values) WithPreviewIngress() Values {
+	v.Ingress.Suffix = "-preview"
+	v.Ingress.PathPrefix = constants.PathPreview
+
+	return v
+}
+
+// WithApiKeyVerification rejects the requests with revoked, expired or out-of-scope stack api keys, and the requests
+// over the monthly quota at the ingress, by asking verifyURL of the api depot server. The stack id is signed with
+// secret, so that only the ingress of the stack can ask for it.
+func (v Values) WithApiKeyVerification(verifyURL string, secret string) Values {
+	stackId := strconv.FormatUint(uint64(v.Stack.ID), 10)
+	v.Ingress.ApiKeyVerifyURL = fmt.Sprintf("%s?stackId=%s&signature=%s", verifyURL, stackId, util.SignHMAC(secret, stackId))
+
+	return v
+}
+
+// WithRateLimits applies the rate limits of the stack at the ingress.
+// It must be called after the vapis and the custom vapis have been added to the values.
+func (v Values) WithRateLimits() Values {
+	limits := v.Stack.RateLimits.Data()
+	v.Ingress.RateLimit = limits.Global
+	v.Ingress.RateLimitedVapis = nil
+
+	for _, vapi := range v.Vapis {
+		if limit, ok := limits.Vapis[vapi.Slug()]; ok {
+			v.Ingress.RateLimitedVapis = append(v.Ingress.RateLimitedVapis, RateLimitedVapiYamlValues{
+				Name:      fmt.Sprintf("vapi-%d-%s", vapi.PackageID, vapi.MajorVersion()),
+				Path:      v.Paths.Vapi + "/" + vapi.Slug(),
+				RateLimit: limit,
+			})
+		}
+	}
+	for _, customVapi := range v.CustomVapis {
+		if limit, ok := limits.Vapis[customVapi.Name]; ok {
+			v.Ingress.RateLimitedVapis = append(v.Ingress.RateLimitedVapis, RateLimitedVapiYamlValues{
+				Name:      fmt.Sprintf("custom-vapi-%d", customVapi.ID),
+				Path:      v.Paths.CustomVapi + "/" + customVapi.Name,
+				RateLimit: limit,
+			})
+		}
+	}
+
+	return v
+}
+
+// HasOwnRateLimit returns whether the path is routed by the ingress of a rate limited vapi.
+func (v IngressYamlValues) HasOwnRateLimit(path string) bool {
+	for _, vapi := range v.RateLimitedVapis {
+		if vapi.Path == path {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IngressMiddlewares returns the traefik middlewares of an ingress. vapiName is the name of the rate limited vapi the
+// ingress routes to, or empty for the main ingress. The global rate limit is chained before the own one of the vapi,
+// so that the requests to the vapi count against both.
+func (v Values) IngressMiddlewares(vapiName string) string {
+	var names []string
+	if v.Ingress.ApiKeyVerifyURL != "" {
+		names = append(names, "api-keys")
+	}
+	names = append(names, "cors-headers", "stripprefix")
+	names = append(names, rateLimitMiddlewares("", v.Ingress.RateLimit)...)
+	for _, vapi := range v.Ingress.RateLimitedVapis {
+		if vapiName != "" && vapi.Name == vapiName {
+			names = append(names, rateLimitMiddlewares("-"+vapi.Name, &vapi.RateLimit)...)
+		}
+	}
+
+	middlewares := make([]string, 0, len(names))
+	for _, name := range names {
+		middlewares = append(middlewares, fmt.Sprintf("%s-%s%s@kubernetescrd", v.Stack.Namespace(), name, v.Ingress.Suffix))
+	}
+
+	return strings.Join(middlewares, ",")
+}
+
+// rateLimitMiddlewares returns the names of the middlewares applying rateLimit, which is nil if not rate limited.
+func rateLimitMiddlewares(suffix string, rateLimit *domain.RateLimit) []string {
+	if rateLimit == nil {
+		return nil
+	}
+
+	names := []string{"ratelimit" + suffix}
+	if rateLimit.MaxInFlight > 0 {
+		names = append(names, "inflightreq"+suffix)
+	}
+
+	return names
+}
+
 func (v Values) WithPostgrest() Values {
 	postgrest := v.Stack.Postgrest.Data()
 	var values PostgrestYamlValues
@@ -182,6 +397,7 @@ func (v Values) WithAuth(smtpConfig pkgconfig.SMTPConfig) Values {
 	var values AuthYamlValues
 	values.SiteURL = stack.SiteURL
 	values.JWT.Secret = auth.JWTSecret
+	values.JWT.PreviousSecret = auth.PreviousJWTSecret
 	values.JWT.Exp = auth.JWTExp
 	values.SMTP.Host = smtpConfig.Host
 	values.SMTP.Port = smtpConfig.Port
@@ -265,6 +481,7 @@ func (v Values) WithStorage(s3Config pkgconfig.S3Config, region tcltypes.Instanc
 	regionalConfig := s3Config.GetRegionalConfig(region)
 
 	var values StorageYamlValues
+	values.S3.Region = region.ToS3Region()
 	values.S3.Bucket = storage.S3Bucket
 	values.S3.AccessKey = s3Config.AccessKey
 	values.S3.SecretKey = s3Config.SecretKey
@@ -275,6 +492,44 @@ func (v Values) WithStorage(s3Config pkgconfig.S3Config, region tcltypes.Instanc
 	return v
 }
 
+// WithZone sets the values for the instance of the stack in the zone. The instances of a multi-region stack or out of
+// its default region are served from their regional domains, and the ones out of its default region write to its
+// primary database in the default region.
+func (v Values) WithZone(zone tcltypes.InstanceZone, regionalDomain string, primaryDB pkgconfig.RegionalDBConfig) Values {
+	stack := v.Stack
+	v.Zone.Name = zone
+	v.Zone.Domain = stack.RegionalDomain(zone, regionalDomain)
+	v.Zone.Host = stack.ZoneHost(zone, regionalDomain)
+	v.Zone.Endpoint = stack.ZoneEndpoint(zone, regionalDomain)
+	if zone != stack.DefaultRegion {
+		v.Zone.Database.Host = primaryDB.Host
+		v.Zone.Database.Port = primaryDB.Port
+	}
+
+	return v
+}
+
+// IngressHosts returns the host names the ingresses of the zone serve: the generated domain of the zone, the global
+// domain and the verified custom domains of the stack.
+func (v Values) IngressHosts() []string {
+	hosts := v.Stack.Hosts()
+	hosts[0] = v.Zone.Domain
+
+	return hosts
+}
+
+// DBReadURL is the url of the database in the zone, which the vapis of a multi-region stack read from.
+func (v Values) DBReadURL() string {
+	return fmt.Sprintf(
+		"postgres://%s:%s@%s:%d/%s?sslmode=disable",
+		v.DB.Username,
+		v.DB.Password,
+		v.Zone.Database.ReadHost,
+		v.Zone.Database.ReadPort,
+		v.DB.Name,
+	)
+}
+
 func (v Values) WithVapis(values []VapiYamlValues) Values {
 	v.Vapis = values
 
@@ -286,3 +541,37 @@ func (v Values) WithCustomVapis(values []CustomVapiYamlValues) Values {
 
 	return v
 }
+
+// WithScaling configures the replicas and autoscalers of the deployments.
+// It must be called after every component has been added to the values.
+func (v Values) WithScaling(instance *domain.Instance) Values {
+	v.Scaling.Replicas = instance.DesiredReplicas()
+	v.Scaling.MaxReplicas = instance.MaxDesiredReplicas()
+	v.Scaling.Deployments = nil
+
+	addDeployment := func(name, component string) {
+		v.Scaling.Deployments = append(v.Scaling.Deployments, ScaledDeploymentYamlValues{
+			Name:      name,
+			Component: component,
+			Target:    instance.ScalingTarget(component),
+		})
+	}
+
+	if v.Auth != nil {
+		addDeployment("auth", domain.InstanceComponentAuth)
+	}
+	if v.Storage != nil {
+		addDeployment("storage", domain.InstanceComponentStorage)
+	}
+	if v.Postgrest != nil {
+		addDeployment("postgrest", domain.InstanceComponentPostgrest)
+	}
+	for _, vapi := range v.Vapis {
+		addDeployment(fmt.Sprintf("vapi-%d-%s", vapi.PackageID, vapi.MajorVersion()), domain.InstanceComponentVapi)
+	}
+	for _, customVapi := range v.CustomVapis {
+		addDeployment(fmt.Sprintf("custom-vapi-%d", customVapi.ID), domain.InstanceComponentCustomVapi)
+	}
+
+	return v
+}
@@ -18,23 +18,42 @@ type (
 	VapiYamlValues struct {
 		*domain.VapiRelease
 		TarFileUrl string
-		EnvVars    map[string]string
+		// EnvVars are rendered into the configmap, and SecretEnvVars into the secret of the vapi
+		EnvVars       map[string]string
+		SecretEnvVars map[string]string
+		// Overrides holds the resources overridden by the stack
+		Overrides domain.VapiResources
 	}
 
 	CustomVapiYamlValues struct {
 		*domain.CustomVapi
-		TarFileUrl string
-		EnvVars    map[string]string
+		TarFileUrl    string
+		EnvVars       map[string]string
+		SecretEnvVars map[string]string
 	}
 )
 
+// ContainerResources returns the resources of the vapi container: defaults, then apidepot.yml, then the stack overrides.
+func (v VapiYamlValues) ContainerResources() domain.VapiResources {
+	return domain.DefaultVapiResources().
+		Override(v.VapiRelease.Resources.Data()).
+		Override(v.Overrides)
+}
+
 func (s *Service) GetVapiYamlValues(
 	ctx context.Context,
 	vapiReleases []domain.VapiRelease,
 	vapiEnvVars []domain.StackVapiEnvVar,
+	stackVapis []domain.StackVapi,
 ) ([]VapiYamlValues, error) {
 	vapis := make([]VapiYamlValues, 0, len(vapiReleases))
 
+	// locked releases may differ from the installed ones, so overrides are matched by package
+	overrides := make(map[uint]domain.VapiResources, len(stackVapis))
+	for _, stackVapi := range stackVapis {
+		overrides[stackVapi.Vapi.PackageID] = stackVapi.Resources.Data()
+	}
+
 	envVars := map[string][]domain.StackVapiEnvVar{}
 	for _, vapiEnvVar := range vapiEnvVars {
 		vapiName, rest := util.SplitStringToPair(vapiEnvVar.Name, ".")
@@ -55,12 +74,13 @@ func (s *Service) GetVapiYamlValues(
 			envVars[envVar.Name] = envVar.Default
 		}
 
+		secretEnvVars := map[string]string{}
 		for _, envVar := range vapiEnvVars {
 			vapiName, name := util.SplitStringToPair(envVar.Name, ".")
 			if vapiName != vapiRelease.Package.Name {
 				continue
 			}
-			envVars[name] = envVar.Value
+			setEnvVar(envVars, secretEnvVars, name, envVar)
 		}
 
 		tarFileUrl, err := s.getPackageTarFileUrl(ctx, constants.VapiBucketId, vapiRelease.Published, vapiRelease.TarFilePath)
@@ -69,9 +89,11 @@ func (s *Service) GetVapiYamlValues(
 		}
 
 		vapis = append(vapis, VapiYamlValues{
-			VapiRelease: &vapiRelease,
-			TarFileUrl:  tarFileUrl,
-			EnvVars:     envVars,
+			VapiRelease:   &vapiRelease,
+			TarFileUrl:    tarFileUrl,
+			EnvVars:       envVars,
+			SecretEnvVars: secretEnvVars,
+			Overrides:     overrides[vapiRelease.PackageID],
 		})
 	}
 
@@ -101,12 +123,13 @@ func (s *Service) GetCustomVapiYamlValues(
 		}
 
 		envVars := make(map[string]string, len(vapiEnvVars))
+		secretEnvVars := map[string]string{}
 		for _, envVar := range vapiEnvVars {
 			vapiName, name := util.SplitStringToPair(envVar.Name, ".")
 			if vapiName != customVapi.Name {
 				continue
 			}
-			envVars[name] = envVar.Value
+			setEnvVar(envVars, secretEnvVars, name, envVar)
 		}
 
 		tarFileUrl, err := s.getPackageTarFileUrl(ctx, constants.CustomVapiBucketId, false, customVapi.TarFilePath)
@@ -115,15 +138,26 @@ func (s *Service) GetCustomVapiYamlValues(
 		}
 
 		result = append(result, CustomVapiYamlValues{
-			CustomVapi: &customVapi,
-			TarFileUrl: tarFileUrl,
-			EnvVars:    envVars,
+			CustomVapi:    &customVapi,
+			TarFileUrl:    tarFileUrl,
+			EnvVars:       envVars,
+			SecretEnvVars: secretEnvVars,
 		})
 	}
 
 	return result, nil
 }
 
+// setEnvVar puts the env var into either map by whether it is secret. A secret env var hides the default value of the same name.
+func setEnvVar(envVars map[string]string, secretEnvVars map[string]string, name string, envVar domain.StackVapiEnvVar) {
+	if envVar.Secret {
+		delete(envVars, name)
+		secretEnvVars[name] = envVar.Value
+	} else {
+		envVars[name] = envVar.Value
+	}
+}
+
 func (s *Service) getPackageTarFileUrl(
 	ctx context.Context,
 	bucketId string,
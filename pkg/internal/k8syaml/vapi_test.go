@@ -3,6 +3,7 @@ package k8syaml_test
 import (
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
+	"gorm.io/datatypes"
 )
 
 func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderYamlWithVapi() {
@@ -58,6 +59,9 @@ func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderYamlWithVapi() {
 					"TEST_T":   "test",
 					"TEST_T_T": "test",
 				},
+				SecretEnvVars: map[string]string{
+					"TEST_SECRET": "secret-value",
+				},
 			},
 			{
 				VapiRelease: &stack.Vapis[0].Vapi,
@@ -71,6 +75,8 @@ func (s *K8sYamlServiceTestSuite) TestK8sYamlService_RenderYamlWithVapi() {
 		}),
 	)
 	s.Require().NoError(err)
+	s.NotContains(yamlFile, "secret-value")
+	s.Contains(yamlFile, "TEST_SECRET")
 
 	s.T().Logf("yamlFile: %s", yamlFile)
 }
@@ -95,7 +101,7 @@ func (s *K8sYamlServiceTestSuite) TestGetVapiYamlValues() {
 		}
 
 		// when
-		vapiYamlValues, err := s.k8sYamlService.GetVapiYamlValues(s, vapiReleases, vapiEnvVars)
+		vapiYamlValues, err := s.k8sYamlService.GetVapiYamlValues(s, vapiReleases, vapiEnvVars, nil)
 
 		// then
 		s.Require().NoError(err)
@@ -103,6 +109,38 @@ func (s *K8sYamlServiceTestSuite) TestGetVapiYamlValues() {
 		s.Require().Equal("test_value", vapiYamlValues[0].EnvVars["TEST_KEY"])
 	})
 
+	s.Run("given secret env var, when GetVapiYamlValues is called, should be separated from the others", func() {
+		// given
+		vapiReleases := []domain.VapiRelease{
+			{
+				Model: domain.Model{ID: 1},
+				Package: domain.VapiPackage{
+					Model: domain.Model{ID: 1},
+					Name:  "test",
+				},
+				Version:     "1.0.0",
+				Published:   true,
+				TarFilePath: "prj/test/v1.0.0.tar",
+				EnvVars: datatypes.NewJSONSlice([]domain.VapiEnvVar{
+					{Name: "API_KEY", Default: "default_key"},
+				}),
+			},
+		}
+		vapiEnvVars := []domain.StackVapiEnvVar{
+			{Name: "test.TEST_KEY", Value: "test_value"},
+			{Name: "test.API_KEY", Value: "secret_key", Secret: true},
+		}
+
+		// when
+		vapiYamlValues, err := s.k8sYamlService.GetVapiYamlValues(s, vapiReleases, vapiEnvVars, nil)
+
+		// then
+		s.Require().NoError(err)
+		s.Require().Len(vapiYamlValues, 1)
+		s.Equal(map[string]string{"TEST_KEY": "test_value"}, vapiYamlValues[0].EnvVars)
+		s.Equal(map[string]string{"API_KEY": "secret_key"}, vapiYamlValues[0].SecretEnvVars)
+	})
+
 	s.Run("given invalid key name in env var, when GetVapiYamlValues is called, should return error", func() {
 		// given
 		vapiReleases := []domain.VapiRelease{
@@ -122,7 +160,7 @@ func (s *K8sYamlServiceTestSuite) TestGetVapiYamlValues() {
 		}
 
 		// when
-		_, err := s.k8sYamlService.GetVapiYamlValues(s, vapiReleases, vapiEnvVars)
+		_, err := s.k8sYamlService.GetVapiYamlValues(s, vapiReleases, vapiEnvVars, nil)
 
 		// then
 		s.Require().Error(err)
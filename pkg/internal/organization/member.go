@@ -0,0 +1,182 @@
+package organization
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// checkMemberManagement verifies that the current user can grant or revoke the given role in the organization.
+// Admins manage every role below owner, and only owners can manage other owners.
+func (s *service) checkMemberManagement(
+	ctx context.Context,
+	tx *gorm.DB,
+	orgId uint,
+	role domain.OrganizationRole,
+) error {
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	required := domain.OrganizationRoleAdmin
+	if role == domain.OrganizationRoleOwner {
+		required = domain.OrganizationRoleOwner
+	}
+
+	return domain.CheckOrganizationRole(tx, orgId, me, required)
+}
+
+func (s *service) checkLastOwner(tx *gorm.DB, member *domain.OrganizationMember) error {
+	if member.Role != domain.OrganizationRoleOwner {
+		return nil
+	}
+
+	var numOwners int64
+	if err := tx.Model(&domain.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", member.OrganizationID, domain.OrganizationRoleOwner).
+		Count(&numOwners).Error; err != nil {
+		return errors.Wrapf(err, "failed to count owners")
+	}
+
+	if numOwners <= 1 {
+		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "organization must have at least one owner")
+	}
+
+	return nil
+}
+
+func (s *service) AddOrganizationMember(
+	ctx context.Context,
+	orgId uint,
+	memberOwnerId string,
+	role domain.OrganizationRole,
+) (*domain.OrganizationMember, error) {
+	if !role.IsValid() {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "invalid role '%s'", role)
+	}
+
+	u, err := s.users.GetUserByAuthUserId(ctx, memberOwnerId)
+	if err != nil {
+		return nil, err
+	}
+
+	member := domain.OrganizationMember{
+		OrganizationID: orgId,
+		UserID:         u.ID,
+		User:           *u,
+		Role:           role,
+	}
+	if err := helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkMemberManagement(ctx, tx, orgId, role); err != nil {
+			return err
+		}
+
+		if _, err := domain.FindOrganizationMember(tx, orgId, u.ID); err == nil {
+			return errors.Wrapf(tclerrors.ErrPreconditionRequired, "user is already a member of the organization")
+		} else if !errors.Is(err, tclerrors.ErrNotFound) {
+			return err
+		}
+
+		return member.Save(tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	return &member, nil
+}
+
+func (s *service) UpdateOrganizationMemberRole(
+	ctx context.Context,
+	orgId uint,
+	memberOwnerId string,
+	role domain.OrganizationRole,
+) error {
+	if !role.IsValid() {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "invalid role '%s'", role)
+	}
+
+	u, err := s.users.GetUserByAuthUserId(ctx, memberOwnerId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		member, err := domain.FindOrganizationMember(tx, orgId, u.ID)
+		if err != nil {
+			return err
+		}
+
+		if err := s.checkMemberManagement(ctx, tx, orgId, member.Role); err != nil {
+			return err
+		}
+		if err := s.checkMemberManagement(ctx, tx, orgId, role); err != nil {
+			return err
+		}
+
+		if role != domain.OrganizationRoleOwner {
+			if err := s.checkLastOwner(tx, member); err != nil {
+				return err
+			}
+		}
+
+		member.Role = role
+		return member.Save(tx)
+	})
+}
+
+func (s *service) RemoveOrganizationMember(
+	ctx context.Context,
+	orgId uint,
+	memberOwnerId string,
+) error {
+	u, err := s.users.GetUserByAuthUserId(ctx, memberOwnerId)
+	if err != nil {
+		return err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		member, err := domain.FindOrganizationMember(tx, orgId, u.ID)
+		if err != nil {
+			return err
+		}
+
+		// members are always able to leave the organization by themselves
+		if me.ID != u.ID {
+			if err := s.checkMemberManagement(ctx, tx, orgId, member.Role); err != nil {
+				return err
+			}
+		}
+
+		if err := s.checkLastOwner(tx, member); err != nil {
+			return err
+		}
+
+		return member.Delete(tx)
+	})
+}
+
+func (s *service) GetOrganizationMembers(
+	ctx context.Context,
+	orgId uint,
+) ([]domain.OrganizationMember, error) {
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	if err := domain.CheckOrganizationRole(tx, orgId, me, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return domain.FindOrganizationMembers(tx, orgId)
+}
@@ -0,0 +1,81 @@
+package organization_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/organization"
+	"github.com/mokiat/gog"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *OrganizationTestSuite) createOrganization(name string) uint {
+	orgId, err := s.orgService.UpdateOrganization(s.context, organization.CreateOrUpdateOrganizationInput{
+		Name: gog.PtrOf(name),
+	})
+	s.Require().NoError(err)
+
+	return orgId
+}
+
+func (s *OrganizationTestSuite) TestGivenNewOrganizationWhenGetMembersThenCreatorShouldBeOwner() {
+	// Given
+	orgId := s.createOrganization("test")
+
+	// When
+	members, err := s.orgService.GetOrganizationMembers(s.context, orgId)
+
+	// Then
+	s.Require().NoError(err)
+	s.Require().Len(members, 1)
+	s.Equal(s.user.ID, members[0].UserID)
+	s.Equal(domain.OrganizationRoleOwner, members[0].Role)
+}
+
+func (s *OrganizationTestSuite) TestGivenDeveloperWhenAddOrganizationMemberThenShouldBeForbidden() {
+	// Given
+	orgId := s.createOrganization("test")
+
+	developer := s.saveUser("developer")
+	s.userServiceMock.On("GetUserByAuthUserId", mock.Anything, developer.AuthUserId).Return(&developer, nil)
+	_, err := s.orgService.AddOrganizationMember(s.context, orgId, developer.AuthUserId, domain.OrganizationRoleDeveloper)
+	s.Require().NoError(err)
+
+	viewer := s.saveUser("viewer")
+	s.userServiceMock.On("GetUserByAuthUserId", mock.Anything, viewer.AuthUserId).Return(&viewer, nil)
+
+	// When
+	s.user = developer
+	_, err = s.orgService.AddOrganizationMember(s.context, orgId, viewer.AuthUserId, domain.OrganizationRoleViewer)
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrForbidden)
+}
+
+func (s *OrganizationTestSuite) TestGivenSingleOwnerWhenRemoveOwnerThenShouldFail() {
+	// Given
+	orgId := s.createOrganization("test")
+	s.userServiceMock.On("GetUserByAuthUserId", mock.Anything, s.user.AuthUserId).Return(&s.user, nil)
+
+	// When
+	err := s.orgService.RemoveOrganizationMember(s.context, orgId, s.user.AuthUserId)
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrPreconditionRequired)
+}
+
+func (s *OrganizationTestSuite) TestGivenAdminWhenPromoteToOwnerThenShouldBeForbidden() {
+	// Given
+	orgId := s.createOrganization("test")
+
+	admin := s.saveUser("admin")
+	s.userServiceMock.On("GetUserByAuthUserId", mock.Anything, admin.AuthUserId).Return(&admin, nil)
+	_, err := s.orgService.AddOrganizationMember(s.context, orgId, admin.AuthUserId, domain.OrganizationRoleAdmin)
+	s.Require().NoError(err)
+
+	// When
+	s.user = admin
+	err = s.orgService.UpdateOrganizationMemberRole(s.context, orgId, admin.AuthUserId, domain.OrganizationRoleOwner)
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrForbidden)
+}
@@ -10,6 +10,11 @@ import (
 )
 
 func (s *service) UpdateOrganization(ctx context.Context, input CreateOrUpdateOrganizationInput) (uint, error) {
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	var org domain.Organization
 	if err := helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
 		if input.Id != nil {
@@ -22,11 +27,32 @@ func (s *service) UpdateOrganization(ctx context.Context, input CreateOrUpdateOr
 
 		logger.Debug("organization found")
 
+		if org.ID != 0 {
+			if err := domain.CheckOrganizationRole(tx, org.ID, me, domain.OrganizationRoleAdmin); err != nil {
+				return err
+			}
+		}
+
 		if input.Name != nil {
 			org.Name = *input.Name
 		}
 
-		return errors.Wrapf(tx.Save(&org).Error, "failed to save organization")
+		isNew := org.ID == 0
+		if err := tx.Save(&org).Error; err != nil {
+			return errors.Wrapf(err, "failed to save organization")
+		}
+
+		if !isNew {
+			return nil
+		}
+
+		// the creator owns the organization
+		member := domain.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         me.ID,
+			Role:           domain.OrganizationRoleOwner,
+		}
+		return member.Save(tx)
 	}); err != nil {
 		return 0, err
 	}
@@ -35,13 +61,26 @@ func (s *service) UpdateOrganization(ctx context.Context, input CreateOrUpdateOr
 }
 
 func (s *service) DeleteOrganization(ctx context.Context, id uint) error {
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
 	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := domain.CheckOrganizationRole(tx, id, me, domain.OrganizationRoleOwner); err != nil {
+			return err
+		}
+
 		if r := tx.Delete(&domain.Organization{}, "id = ?", id); r.Error != nil {
 			return errors.Wrapf(r.Error, "failed to delete organization")
 		} else if r.RowsAffected == 0 {
 			return errors.Wrapf(tclerrors.ErrNotFound, "organization not found")
 		}
 
+		if err := tx.Delete(&domain.OrganizationMember{}, "organization_id = ?", id).Error; err != nil {
+			return errors.Wrapf(err, "failed to delete organization members")
+		}
+
 		return nil
 	})
 }
@@ -5,16 +5,52 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func (s *service) GetOrganizationById(ctx context.Context, id uint) (org domain.Organization, err error) {
-	err = errors.Wrapf(helpers.GetTx(ctx).Where("id = ?", id).First(&org).Error, "failed to find organization by id")
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return
+	}
+
+	tx := helpers.GetTx(ctx)
+	if err = domain.CheckOrganizationRole(tx, id, me, domain.OrganizationRoleViewer); err != nil {
+		return
+	}
+
+	err = errors.Wrapf(
+		tx.
+			Preload("Members", func(db *gorm.DB) *gorm.DB { return db.Order("id ASC") }).
+			Preload("Members.User").
+			Where("id = ?", id).
+			First(&org).
+			Error,
+		"failed to find organization by id",
+	)
 	return
 }
 
+// GetOrganizations returns the organizations the current user is a member of, narrowed down to the ones of the given
+// member if memberOwnerId is not nil. Superusers see every organization.
 func (s *service) GetOrganizations(ctx context.Context, memberOwnerId *string) (orgs []domain.Organization, err error) {
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return
+	}
+
 	tx := helpers.GetTx(ctx)
 
+	if !me.IsSuperuser() {
+		tx = tx.Where(
+			"organizations.id IN (?)",
+			tx.Session(&gorm.Session{NewDB: true}).
+				Model(&domain.OrganizationMember{}).
+				Select("organization_id").
+				Where("user_id = ?", me.ID),
+		)
+	}
+
 	if memberOwnerId != nil {
 		tx = tx.Joins("JOIN organization_members ON organizations.id = organization_members.organization_id").
 			Joins("JOIN users ON organization_members.user_id = users.id").
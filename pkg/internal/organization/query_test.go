@@ -1,6 +1,7 @@
 package organization_test
 
 import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/organization"
 	"github.com/mokiat/gog"
 )
@@ -32,3 +33,31 @@ func (s *OrganizationTestSuite) TestGetAllOrganizationsGivenTwoCreatedOrganizati
 	s.Require().Equal("test", orgs[0].Name)
 	s.Require().Equal("test1", orgs[1].Name)
 }
+
+func (s *OrganizationTestSuite) TestGivenOtherUsersOrganizationWhenGetOrganizationThenShouldBeForbidden() {
+	// Given
+	orgId := s.createOrganization("test")
+
+	// When
+	s.user = s.saveUser("stranger")
+	_, err := s.orgService.GetOrganizationById(s.context, orgId)
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrForbidden)
+}
+
+func (s *OrganizationTestSuite) TestGivenOtherUsersOrganizationWhenGetOrganizationsThenShouldBeExcluded() {
+	// Given
+	s.createOrganization("test")
+	stranger := s.saveUser("stranger")
+	s.user = stranger
+	strangerOrgId := s.createOrganization("stranger's")
+
+	// When
+	orgs, err := s.orgService.GetOrganizations(s.context, nil)
+
+	// Then
+	s.Require().NoError(err)
+	s.Require().Len(orgs, 1)
+	s.Equal(strangerOrgId, orgs[0].ID)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	tclog "github.com/habiliai/apidepot/pkg/internal/log"
+	"github.com/habiliai/apidepot/pkg/internal/user"
 )
 
 type (
@@ -13,8 +14,14 @@ type (
 		GetOrganizationById(ctx context.Context, id uint) (domain.Organization, error)
 		DeleteOrganization(ctx context.Context, id uint) error
 		GetOrganizations(ctx context.Context, memberOwnerId *string) ([]domain.Organization, error)
+
+		AddOrganizationMember(ctx context.Context, orgId uint, memberOwnerId string, role domain.OrganizationRole) (*domain.OrganizationMember, error)
+		UpdateOrganizationMemberRole(ctx context.Context, orgId uint, memberOwnerId string, role domain.OrganizationRole) error
+		RemoveOrganizationMember(ctx context.Context, orgId uint, memberOwnerId string) error
+		GetOrganizationMembers(ctx context.Context, orgId uint) ([]domain.OrganizationMember, error)
 	}
 	service struct {
+		users user.Service
 	}
 )
 
@@ -29,6 +36,13 @@ var (
 
 func init() {
 	digo.ProvideService(ServiceKey, func(serviceContainer *digo.Container) (interface{}, error) {
-		return &service{}, nil
+		users, err := digo.Get[user.Service](serviceContainer, user.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return &service{
+			users: users,
+		}, nil
 	})
 }
@@ -2,13 +2,16 @@ package organization_test
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	tclog "github.com/habiliai/apidepot/pkg/internal/log"
 	"github.com/habiliai/apidepot/pkg/internal/organization"
 	"github.com/habiliai/apidepot/pkg/internal/services"
 	"github.com/habiliai/apidepot/pkg/internal/user"
 	usertest "github.com/habiliai/apidepot/pkg/internal/user/test"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/suite"
 	"gorm.io/gorm"
 	"testing"
@@ -20,8 +23,10 @@ type OrganizationTestSuite struct {
 	context context.Context
 	cancel  context.CancelFunc
 
+	db              *gorm.DB
 	orgService      organization.Service
 	userServiceMock *usertest.Service
+	user            domain.User
 }
 
 func TestOrganization(t *testing.T) {
@@ -40,15 +45,29 @@ func (s *OrganizationTestSuite) SetupTest() {
 
 	db, err := digo.Get[*gorm.DB](container, services.ServiceKeyDB)
 	s.Require().NoError(err)
+	s.db = db
 	s.context = helpers.WithTx(s.context, db)
 
+	s.user = s.saveUser("owner")
+
 	s.userServiceMock = usertest.NewService()
+	s.userServiceMock.On("GetUser", mock.Anything).Return(&s.user, nil).Maybe()
 	digo.Set(container, user.ServiceKey, s.userServiceMock)
 
 	s.orgService, err = digo.Get[organization.Service](container, organization.ServiceKey)
 	s.Require().NoError(err)
 }
 
+func (s *OrganizationTestSuite) saveUser(name string) domain.User {
+	u := domain.User{
+		Name:       name,
+		AuthUserId: uuid.NewString(),
+	}
+	s.Require().NoError(u.Save(s.db))
+
+	return u
+}
+
 func (s *OrganizationTestSuite) TearDownTest() {
 	defer s.cancel()
 }
@@ -14,7 +14,7 @@ import (
 
 type Service interface {
 	GetProjects(ctx context.Context, input GetProjectsInput) ([]domain.Project, error)
-	CreateProject(ctx context.Context, name string, description string) (*domain.Project, error)
+	CreateProject(ctx context.Context, name string, description string, organizationId *uint) (*domain.Project, error)
 	GetProject(ctx context.Context, id uint) (*domain.Project, error)
 	DeleteProject(ctx context.Context, id uint) error
 }
@@ -57,7 +57,12 @@ func (s *service) GetProjects(
 	}
 
 	var projects []domain.Project
-	if err := stmt.Find(&projects, "owner_id = ?", user.ID).Error; err != nil {
+	if err := stmt.Find(
+		&projects,
+		"(owner_id = ? OR organization_id IN (?))",
+		user.ID,
+		tx.Model(&domain.OrganizationMember{}).Select("organization_id").Where("user_id = ?", user.ID),
+	).Error; err != nil {
 		return nil, err
 	}
 
@@ -66,7 +71,7 @@ func (s *service) GetProjects(
 	return projects, nil
 }
 
-func (s *service) CreateProject(ctx context.Context, name string, description string) (*domain.Project, error) {
+func (s *service) CreateProject(ctx context.Context, name string, description string, organizationId *uint) (*domain.Project, error) {
 	tx := helpers.GetTx(ctx)
 
 	owner, err := s.users.GetUser(ctx)
@@ -80,10 +85,17 @@ func (s *service) CreateProject(ctx context.Context, name string, description st
 		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "name is too long, max length is 50 characters")
 	}
 
+	if organizationId != nil {
+		if err := domain.CheckOrganizationRole(tx, *organizationId, owner, domain.OrganizationRoleAdmin); err != nil {
+			return nil, err
+		}
+	}
+
 	project := domain.Project{
-		Name:        name,
-		Description: description,
-		OwnerID:     owner.ID,
+		Name:           name,
+		Description:    description,
+		OwnerID:        owner.ID,
+		OrganizationID: organizationId,
 	}
 
 	if err := tx.Transaction(func(tx *gorm.DB) error {
@@ -100,6 +112,10 @@ func (s *service) CreateProject(ctx context.Context, name string, description st
 }
 
 func (s *service) GetProject(ctx context.Context, id uint) (*domain.Project, error) {
+	return s.getProject(ctx, id, domain.OrganizationRoleViewer)
+}
+
+func (s *service) getProject(ctx context.Context, id uint, role domain.OrganizationRole) (*domain.Project, error) {
 	user, err := s.users.GetUser(ctx)
 	if err != nil {
 		return nil, err
@@ -111,15 +127,15 @@ func (s *service) GetProject(ctx context.Context, id uint) (*domain.Project, err
 		return nil, err
 	}
 
-	if project.OwnerID != user.ID && !user.IsSuperuser() {
-		return nil, errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this project")
+	if err := project.CheckPermission(tx, user, role); err != nil {
+		return nil, err
 	}
 
 	return &project, nil
 }
 
 func (s *service) DeleteProject(ctx context.Context, id uint) error {
-	if _, err := s.GetProject(ctx, id); err != nil {
+	if _, err := s.getProject(ctx, id, domain.OrganizationRoleAdmin); err != nil {
 		return err
 	}
 
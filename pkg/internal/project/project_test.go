@@ -87,6 +87,7 @@ func (s *ProjectServiceTestSuite) TestCreateProject() {
 		project, err := s.projects.CreateProject(s,
 			"test",
 			"",
+			nil,
 		)
 		s.Require().NoError(err)
 
@@ -97,6 +98,7 @@ func (s *ProjectServiceTestSuite) TestCreateProject() {
 		project, err := s.projects.CreateProject(s,
 			"",
 			"",
+			nil,
 		)
 		s.Require().Error(err)
 		s.Require().Nil(project)
@@ -106,6 +108,7 @@ func (s *ProjectServiceTestSuite) TestCreateProject() {
 		project, err := s.projects.CreateProject(s,
 			"abcdefghijklmnopqrstuvwxyz1234567890abcdefghijklmnopqrstuvwxyz1234567890",
 			"",
+			nil,
 		)
 		s.Require().Error(err)
 		s.Require().ErrorAs(err, &tclerrors.ErrBadRequest)
@@ -116,6 +119,7 @@ func (s *ProjectServiceTestSuite) TestCreateProject() {
 		project, err := s.projects.CreateProject(s,
 			"한글 이름 입니다.",
 			"",
+			nil,
 		)
 		s.Require().NoError(err)
 		s.Require().NotNil(project)
@@ -11,8 +11,8 @@ type ServiceMock struct {
 	mock.Mock
 }
 
-func (m *ServiceMock) CreateProject(ctx context.Context, name string, description string) (*domain.Project, error) {
-	args := m.Called(ctx, name, description)
+func (m *ServiceMock) CreateProject(ctx context.Context, name string, description string, organizationId *uint) (*domain.Project, error) {
+	args := m.Called(ctx, name, description, organizationId)
 	return args.Get(0).(*domain.Project), args.Error(1)
 }
 
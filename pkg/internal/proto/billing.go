@@ -0,0 +1,30 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func (s *apiDepotServer) GetInvoices(ctx context.Context, _ *emptypb.Empty) (*GetInvoicesResponse, error) {
+	invoices, err := s.billingService.GetInvoices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetInvoicesResponse{
+		Invoices: gog.Map(invoices, func(invoice domain.Invoice) *Invoice {
+			return newInvoicePbFromDb(&invoice)
+		}),
+	}, nil
+}
+
+func (s *apiDepotServer) GetCurrentUsage(ctx context.Context, _ *emptypb.Empty) (*Invoice, error) {
+	invoice, err := s.billingService.GetCurrentUsage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return newInvoicePbFromDb(invoice), nil
+}
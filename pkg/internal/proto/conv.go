@@ -3,6 +3,7 @@ package proto
 import (
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"github.com/mokiat/gog"
 	tspb "google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -17,6 +18,7 @@ func newProjectPbFromDb(project *domain.Project) *Project {
 		Stacks: gog.Map(project.Stacks, func(s domain.Stack) *Stack {
 			return newStackPbFromDb(s)
 		}),
+		OrganizationId: newOrganizationIdPbFromDb(project.OrganizationID),
 	}
 }
 
@@ -57,6 +59,22 @@ func newStackPbFromDb(stack domain.Stack) *Stack {
 		LogoImageUrl:  stack.LogoImageUrl,
 		CustomVapis:   gog.Map(stack.CustomVapis, newCustomVapiPbFromDb),
 		DefaultRegion: getInstanceZonePbFromDb(stack.DefaultRegion),
+		EnvVars:       gog.Map(stack.VapiEnvVars, newStackEnvVarPbFromDb),
+		RateLimits:    newStackRateLimitsPbFromDb(stack.RateLimits.Data()),
+		PrimaryDomain: stack.PrimaryDomain,
+		CustomDomains: gog.Map(stack.CustomDomains, func(customDomain domain.StackCustomDomain) *StackCustomDomain {
+			return newStackCustomDomainPbFromDb(&customDomain)
+		}),
+		Endpoint:        stack.Endpoint(),
+		MultiRegion:     stack.MultiRegion,
+		GlobalDomain:    stack.GlobalDomain,
+		PreviewsEnabled: stack.PreviewsEnabled,
+	}
+
+	if stack.Preview != nil {
+		preview := *stack.Preview
+		preview.Stack = stack
+		result.Preview = newStackPreviewPbFromDb(preview)
 	}
 
 	if stack.TelegramMiniappPromotion != nil {
@@ -70,6 +88,24 @@ func newStackPbFromDb(stack domain.Stack) *Stack {
 	return result
 }
 
+// newStackEnvVarPbFromDb masks the value of a secret env var, which is never returned.
+func newStackEnvVarPbFromDb(v domain.StackVapiEnvVar) *StackEnvVar {
+	result := &StackEnvVar{
+		Name:   v.Name,
+		Secret: v.Secret,
+	}
+	if v.Secret {
+		result.MaskedValue = v.MaskedValue()
+	} else {
+		result.Value = v.Value
+	}
+	if !v.UpdatedAt.IsZero() {
+		result.UpdatedAt = tspb.New(v.UpdatedAt)
+	}
+
+	return result
+}
+
 func newCustomVapiPbFromDb(v domain.CustomVapi) *CustomVapi {
 	return &CustomVapi{
 		StackId:     int32(v.StackID),
@@ -143,9 +179,30 @@ func newStackAuthPbFromDb(auth domain.Auth) *StackAuth {
 
 func newStackVapiPbFromDb(vapi *domain.StackVapi) *StackVapi {
 	return &StackVapi{
-		StackId: int32(vapi.StackID),
-		VapiId:  int32(vapi.VapiID),
-		Vapi:    newVapiReleasePbFromDb(&vapi.Vapi),
+		StackId:   int32(vapi.StackID),
+		VapiId:    int32(vapi.VapiID),
+		Vapi:      newVapiReleasePbFromDb(&vapi.Vapi),
+		Resources: newVapiResourcesPbFromDb(vapi.Resources.Data()),
+	}
+}
+
+func newVapiResourcesPbFromDb(r domain.VapiResources) *VapiResources {
+	return &VapiResources{
+		CpuRequest:         r.CPURequest,
+		CpuLimit:           r.CPULimit,
+		MemoryRequest:      r.MemoryRequest,
+		MemoryLimit:        r.MemoryLimit,
+		EdgeRuntimeVersion: r.EdgeRuntimeVersion,
+	}
+}
+
+func newVapiResourcesFromPb(r *VapiResources) domain.VapiResources {
+	return domain.VapiResources{
+		CPURequest:         r.GetCpuRequest(),
+		CPULimit:           r.GetCpuLimit(),
+		MemoryRequest:      r.GetMemoryRequest(),
+		MemoryLimit:        r.GetMemoryLimit(),
+		EdgeRuntimeVersion: r.GetEdgeRuntimeVersion(),
 	}
 }
 
@@ -161,22 +218,59 @@ func newVapiReleasePbFromDb(vapi *domain.VapiRelease) *VapiRelease {
 		TarFilePath: vapi.TarFilePath,
 		GitHash:     vapi.GitHash,
 		PackageId:   int32(vapi.PackageID),
+		Resources:   newVapiResourcesPbFromDb(vapi.Resources.Data()),
+		Access:      newVapiPackageAccessPbFromDb(vapi.Package.Access),
+
+		Yanked:             vapi.Yanked,
+		DeprecationMessage: vapi.DeprecationMessage,
+		SuspensionReason:   vapi.SuspensionReason,
+		YankReason:         vapi.YankReason,
+	}
+}
+
+func newStackDependencyTreeNodePbFromVapi(node *vapi.DependencyTreeNode) *StackDependencyTreeNode {
+	return &StackDependencyTreeNode{
+		PackageName:  node.Release.Package.Name,
+		Vapi:         newVapiReleasePbFromDb(&node.Release),
+		Constraint:   node.Constraint,
+		Dependencies: gog.Map(node.Dependencies, newStackDependencyTreeNodePbFromVapi),
 	}
 }
 
 func newVapiPackagePbFromDb(v *domain.VapiPackage) *VapiPackage {
 	return &VapiPackage{
-		Id:          int32(v.ID),
-		Name:        v.Name,
-		CreatedAt:   tspb.New(v.CreatedAt),
-		UpdatedAt:   tspb.New(v.UpdatedAt),
-		OwnerId:     int32(v.OwnerId),
-		GitRepo:     v.GitRepo,
-		GitBranch:   v.GitBranch,
-		Releases:    nil,
-		Description: v.Description,
-		Domains:     v.Domains,
+		Id:             int32(v.ID),
+		Name:           v.Name,
+		CreatedAt:      tspb.New(v.CreatedAt),
+		UpdatedAt:      tspb.New(v.UpdatedAt),
+		OwnerId:        int32(v.OwnerId),
+		GitRepo:        v.GitRepo,
+		GitBranch:      v.GitBranch,
+		Releases:       nil,
+		Description:    v.Description,
+		Domains:        v.Domains,
+		OrganizationId: newOrganizationIdPbFromDb(v.OrganizationID),
+		Access:         newVapiPackageAccessPbFromDb(v.Access),
+	}
+}
+
+func newVapiPackageAccessPbFromDb(access domain.VapiPackageAccess) VapiPackageAccess {
+	switch access {
+	case domain.VapiPackageAccessPrivate:
+		return VapiPackageAccess_VapiPackageAccessPrivate
+	case domain.VapiPackageAccessUnlisted:
+		return VapiPackageAccess_VapiPackageAccessUnlisted
+	default:
+		return VapiPackageAccess_VapiPackageAccessPublic
+	}
+}
+
+func newOrganizationIdPbFromDb(id *uint) *int32 {
+	if id == nil {
+		return nil
 	}
+
+	return gog.PtrOf(int32(*id))
 }
 
 func newOrganizationPbFromDb(org domain.Organization) *Organization {
@@ -185,6 +279,41 @@ func newOrganizationPbFromDb(org domain.Organization) *Organization {
 		Name:      org.Name,
 		CreatedAt: tspb.New(org.CreatedAt),
 		UpdatedAt: tspb.New(org.UpdatedAt),
+		Members:   gog.Map(org.Members, newOrganizationMemberPbFromDb),
+	}
+}
+
+func newOrganizationMemberPbFromDb(member domain.OrganizationMember) *OrganizationMember {
+	return &OrganizationMember{
+		User:      newUserPbFromDb(member.User),
+		Role:      newOrganizationRolePbFromDb(member.Role),
+		CreatedAt: tspb.New(member.CreatedAt),
+	}
+}
+
+func newOrganizationRolePbFromDb(role domain.OrganizationRole) OrganizationRole {
+	switch role {
+	case domain.OrganizationRoleOwner:
+		return OrganizationRole_OrganizationRoleOwner
+	case domain.OrganizationRoleAdmin:
+		return OrganizationRole_OrganizationRoleAdmin
+	case domain.OrganizationRoleDeveloper:
+		return OrganizationRole_OrganizationRoleDeveloper
+	default:
+		return OrganizationRole_OrganizationRoleViewer
+	}
+}
+
+func newOrganizationRoleFromPb(role OrganizationRole) domain.OrganizationRole {
+	switch role {
+	case OrganizationRole_OrganizationRoleOwner:
+		return domain.OrganizationRoleOwner
+	case OrganizationRole_OrganizationRoleAdmin:
+		return domain.OrganizationRoleAdmin
+	case OrganizationRole_OrganizationRoleDeveloper:
+		return domain.OrganizationRoleDeveloper
+	default:
+		return domain.OrganizationRoleViewer
 	}
 }
 
@@ -206,16 +335,26 @@ func newUserPbFromDb(user domain.User) *User {
 }
 
 func newInstancePbFromDb(instance *domain.Instance) *Instance {
+	scalingTargets := map[string]*InstanceScalingTarget{}
+	for component, target := range instance.ScalingTargets.Data() {
+		scalingTargets[component] = &InstanceScalingTarget{
+			CpuUtilization:    target.CPUUtilization,
+			MemoryUtilization: target.MemoryUtilization,
+		}
+	}
+
 	return &Instance{
-		Id:          int32(instance.ID),
-		Name:        instance.Name,
-		StackId:     int32(instance.StackID),
-		NumReplicas: int32(instance.NumReplicas),
-		MaxReplicas: int32(instance.MaxReplicas),
-		State:       getInstanceStatePbFromDb(instance.State),
-		CreatedAt:   tspb.New(instance.CreatedAt),
-		UpdatedAt:   tspb.New(instance.UpdatedAt),
-		Zone:        getInstanceZonePbFromDb(instance.Zone),
+		Id:             int32(instance.ID),
+		Name:           instance.Name,
+		StackId:        int32(instance.StackID),
+		NumReplicas:    int32(instance.NumReplicas),
+		MaxReplicas:    int32(instance.MaxReplicas),
+		State:          getInstanceStatePbFromDb(instance.State),
+		CreatedAt:      tspb.New(instance.CreatedAt),
+		UpdatedAt:      tspb.New(instance.UpdatedAt),
+		Zone:           getInstanceZonePbFromDb(instance.Zone),
+		ScalingTargets: scalingTargets,
+		AutoDeploy:     instance.AutoDeploy,
 	}
 }
 
@@ -280,3 +419,62 @@ func newTelegramMiniappPromotionPbFromDb(promo domain.TelegramMiniappPromotion,
 
 	return result
 }
+
+func newInvoicePbFromDb(invoice *domain.Invoice) *Invoice {
+	result := &Invoice{
+		Id:     int32(invoice.ID),
+		Period: tspb.New(invoice.Period),
+	}
+	if !invoice.CreatedAt.IsZero() {
+		result.CreatedAt = tspb.New(invoice.CreatedAt)
+	}
+
+	for _, item := range invoice.Items {
+		result.TotalRunningSeconds += item.RunningSeconds
+		result.TotalStorageByteDays += item.StorageByteDays
+		result.Items = append(result.Items, newInvoiceItemPbFromDb(item))
+	}
+
+	return result
+}
+
+func newInvoiceItemPbFromDb(item domain.InvoiceItem) *InvoiceItem {
+	result := &InvoiceItem{
+		StackId:         int32(item.StackID),
+		StackName:       item.Stack.Name,
+		Kind:            InvoiceItemKind_InvoiceItemKindInstance,
+		Zone:            getInstanceZonePbFromDb(item.Zone),
+		RunningSeconds:  item.RunningSeconds,
+		StorageByteDays: item.StorageByteDays,
+	}
+	if item.Kind == domain.InvoiceItemKindStorage {
+		result.Kind = InvoiceItemKind_InvoiceItemKindStorage
+	}
+	if item.InstanceID != nil {
+		result.InstanceId = gog.PtrOf(int32(*item.InstanceID))
+	}
+
+	return result
+}
+
+func newInstanceRevisionPbFromDb(r *domain.InstanceRevision) *InstanceRevision {
+	var rollbackOf *int32
+	if r.RollbackOf != nil {
+		rollbackOf = gog.PtrOf(int32(*r.RollbackOf))
+	}
+
+	return &InstanceRevision{
+		Revision:   int32(r.Revision),
+		InstanceId: int32(r.InstanceID),
+		K8SYaml:    r.K8sYaml,
+		Color:      string(r.Color),
+		VapiReleaseIds: gog.Map(r.VapiReleaseIDs, func(id uint) int32 {
+			return int32(id)
+		}),
+		EnvVars:        gog.Map(r.VapiEnvVars, newStackEnvVarPbFromDb),
+		DeployedById:   int32(r.DeployedByID),
+		DeployedByName: r.DeployedBy.Name,
+		CreatedAt:      tspb.New(r.CreatedAt),
+		RollbackOf:     rollbackOf,
+	}
+}
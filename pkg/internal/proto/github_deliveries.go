@@ -0,0 +1,64 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *apiDepotServer) GetStackGithubDeliveries(ctx context.Context, id *StackId) (*GetGithubDeliveriesResponse, error) {
+	deliveries, err := s.githookService.GetStackDeliveries(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetGithubDeliveriesResponse{
+		Deliveries: gog.Map(deliveries, newGithubDeliveryPbFromDb),
+	}, nil
+}
+
+func (s *apiDepotServer) GetVapiPackageGithubDeliveries(ctx context.Context, id *VapiPackageId) (*GetGithubDeliveriesResponse, error) {
+	deliveries, err := s.githookService.GetVapiPackageDeliveries(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetGithubDeliveriesResponse{
+		Deliveries: gog.Map(deliveries, newGithubDeliveryPbFromDb),
+	}, nil
+}
+
+func newGithubDeliveryPbFromDb(d domain.GithubDelivery) *GithubDelivery {
+	return &GithubDelivery{
+		Id:         int32(d.ID),
+		Guid:       d.GUID,
+		Event:      d.Event,
+		Repo:       d.Repo,
+		Ref:        d.Ref,
+		CommitHash: d.CommitHash,
+		Status:     string(d.Status),
+		Error:      d.Error,
+		Actions:    gog.Map(d.Actions, newGithubDeliveryActionPbFromDb),
+		CreatedAt:  tspb.New(d.CreatedAt),
+	}
+}
+
+func newGithubDeliveryActionPbFromDb(a domain.GithubDeliveryAction) *GithubDeliveryAction {
+	pb := &GithubDeliveryAction{
+		Kind:   string(a.Kind),
+		Result: a.Result,
+		Error:  a.Error,
+	}
+	if a.VapiPackageID != nil {
+		pb.VapiPackageId = gog.PtrOf(int32(*a.VapiPackageID))
+	}
+	if a.StackID != nil {
+		pb.StackId = gog.PtrOf(int32(*a.StackID))
+	}
+	if a.InstanceID != nil {
+		pb.InstanceId = gog.PtrOf(int32(*a.InstanceID))
+	}
+
+	return pb
+}
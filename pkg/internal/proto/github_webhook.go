@@ -0,0 +1,155 @@
+package proto
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/go-github/v60/github"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"gorm.io/gorm"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// maxGithubWebhookPayload is the size github caps the payloads of the webhooks at
+	maxGithubWebhookPayload = 25 << 20
+	githubWebhookTimeout    = 10 * time.Minute
+)
+
+// GithubWebhookHandler is the handler of the github webhook. Push and pull request events are accepted right away and
+// handled in the background, since github waits 10 seconds at most. Other events like ping are acknowledged and
+// ignored.
+type GithubWebhookHandler struct {
+	ctx            context.Context
+	githookService githook.Service
+	db             *gorm.DB
+	secret         string
+
+	wg sync.WaitGroup
+}
+
+var _ http.Handler = (*GithubWebhookHandler)(nil)
+
+// NewGithubWebhookHandler returns the handler of the github webhook, whose deliveries are signed with secret. The
+// deliveries are handled in the background until ctx is done.
+func NewGithubWebhookHandler(ctx context.Context, githookService githook.Service, db *gorm.DB, secret string) *GithubWebhookHandler {
+	return &GithubWebhookHandler{
+		ctx:            ctx,
+		githookService: githookService,
+		db:             db,
+		secret:         secret,
+	}
+}
+
+// Wait waits for the deliveries being handled in the background. A delivery interrupted by the shutdown is left
+// pending, and is handled again when github redelivers it.
+func (h *GithubWebhookHandler) Wait() {
+	h.wg.Wait()
+}
+
+func (h *GithubWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.secret == "" {
+		http.Error(w, "github webhook is disabled", http.StatusNotFound)
+		return
+	}
+	if h.ctx.Err() != nil {
+		// github redelivers it after the restart
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxGithubWebhookPayload)
+	payload, err := github.ValidatePayload(r, []byte(h.secret))
+	if err != nil {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	guid := github.DeliveryID(r)
+	if guid == "" {
+		http.Error(w, "delivery id is required", http.StatusBadRequest)
+		return
+	}
+
+	eventType := github.WebHookType(r)
+	if eventType != "push" && eventType != "pull_request" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid payload: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	var handle func(ctx context.Context) error
+	switch event := event.(type) {
+	case *github.PushEvent:
+		input := newPushInput(guid, event)
+		handle = func(ctx context.Context) error {
+			_, err := h.githookService.HandlePush(ctx, input)
+			return err
+		}
+	case *github.PullRequestEvent:
+		input := newPullRequestInput(guid, event)
+		handle = func(ctx context.Context) error {
+			_, err := h.githookService.HandlePullRequest(ctx, input)
+			return err
+		}
+	}
+
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+
+		ctx, cancel := context.WithTimeout(h.ctx, githubWebhookTimeout)
+		defer cancel()
+
+		ctx = helpers.WithTx(ctx, h.db.WithContext(ctx))
+		if err := handle(ctx); err != nil {
+			logger.Error(fmt.Sprintf("failed to handle github %s: %+v", eventType, err), "guid", guid)
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func newPushInput(guid string, event *github.PushEvent) githook.PushInput {
+	input := githook.PushInput{
+		GUID:           guid,
+		Repo:           event.GetRepo().GetFullName(),
+		Ref:            event.GetRef(),
+		CommitHash:     event.GetAfter(),
+		InstallationID: event.GetInstallation().GetID(),
+		Created:        event.GetCreated(),
+		Deleted:        event.GetDeleted(),
+		Forced:         event.GetForced(),
+	}
+	for _, commit := range event.Commits {
+		var paths []string
+		paths = append(paths, commit.Added...)
+		paths = append(paths, commit.Modified...)
+		paths = append(paths, commit.Removed...)
+		input.Commits = append(input.Commits, paths)
+	}
+
+	return input
+}
+
+func newPullRequestInput(guid string, event *github.PullRequestEvent) githook.PullRequestInput {
+	pr := event.GetPullRequest()
+	return githook.PullRequestInput{
+		GUID:           guid,
+		Action:         event.GetAction(),
+		Repo:           event.GetRepo().GetFullName(),
+		Number:         event.GetNumber(),
+		BaseBranch:     pr.GetBase().GetRef(),
+		HeadRepo:       pr.GetHead().GetRepo().GetFullName(),
+		HeadBranch:     pr.GetHead().GetRef(),
+		HeadCommitHash: pr.GetHead().GetSHA(),
+		InstallationID: event.GetInstallation().GetID(),
+	}
+}
@@ -0,0 +1,99 @@
+package proto_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
+	githooktest "github.com/habiliai/apidepot/pkg/internal/githook/test"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"time"
+)
+
+const testGithubWebhookSecret = "webhook-secret"
+
+func newGithubWebhookRequest(event string, payload []byte, secret string) *http.Request {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/_webhooks/github", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-GitHub-Delivery", "delivery-1")
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	return req
+}
+
+func (s *ProtoTestSuite) TestGivenInvalidSignatureWhenGithubWebhookThenUnauthorized() {
+	// Given
+	githooks := &githooktest.ServiceMock{}
+	defer githooks.AssertExpectations(s.T())
+	handler := proto.NewGithubWebhookHandler(s.Context(), githooks, s.db, testGithubWebhookSecret)
+
+	// When
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newGithubWebhookRequest("push", []byte(`{}`), "wrong-secret"))
+
+	// Then
+	s.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func (s *ProtoTestSuite) TestGivenPushWhenGithubWebhookThenHandlePush() {
+	// Given
+	githooks := &githooktest.ServiceMock{}
+	defer githooks.AssertExpectations(s.T())
+	handler := proto.NewGithubWebhookHandler(s.Context(), githooks, s.db, testGithubWebhookSecret)
+
+	handled := make(chan githook.PushInput, 1)
+	githooks.On("HandlePush", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			handled <- args.Get(1).(githook.PushInput)
+		}).
+		Return(&domain.GithubDelivery{}, nil).Once()
+
+	payload := []byte(`{
+		"ref": "refs/heads/main",
+		"after": "abcdef",
+		"repository": {"full_name": "habiliai/vapi-githook"},
+		"installation": {"id": 1},
+		"commits": [{"added": ["vapis/hello/index.ts"], "modified": ["README.md"]}]
+	}`)
+
+	// When
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newGithubWebhookRequest("push", payload, testGithubWebhookSecret))
+
+	// Then
+	s.Require().Equal(http.StatusAccepted, w.Code)
+	select {
+	case input := <-handled:
+		s.Equal("delivery-1", input.GUID)
+		s.Equal("habiliai/vapi-githook", input.Repo)
+		s.Equal("refs/heads/main", input.Ref)
+		s.Equal("abcdef", input.CommitHash)
+		s.Equal(int64(1), input.InstallationID)
+		s.Equal([][]string{{"vapis/hello/index.ts", "README.md"}}, input.Commits)
+	case <-time.After(5 * time.Second):
+		s.Fail("push is not handled")
+	}
+}
+
+func (s *ProtoTestSuite) TestGivenPingWhenGithubWebhookThenNoContent() {
+	// Given
+	githooks := &githooktest.ServiceMock{}
+	defer githooks.AssertExpectations(s.T())
+	handler := proto.NewGithubWebhookHandler(s.Context(), githooks, s.db, testGithubWebhookSecret)
+
+	// When
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, newGithubWebhookRequest("ping", []byte(`{"zen": "Keep it logically awesome."}`), testGithubWebhookSecret))
+
+	// Then
+	s.Equal(http.StatusNoContent, w.Code)
+}
@@ -42,11 +42,54 @@ func handleErrorToGrpcStatus(err error) error {
 		return status.Error(codes.FailedPrecondition, err.Error())
 	} else if errors.Is(err, tclerrors.ErrPreconditionRequired) {
 		return status.Error(codes.FailedPrecondition, err.Error())
+	} else if errors.Is(err, tclerrors.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
 	} else {
 		return status.Error(codes.Internal, err.Error())
 	}
 }
 
+// withMetadata puts the device id, the auth token and the github token of the request into ctx.
+func withMetadata(ctx context.Context) (context.Context, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		md = metadata.New(nil)
+	}
+
+	if values, ok := md["x-device-id"]; ok && len(values) > 0 {
+		deviceId := strings.TrimSpace(values[0])
+		ctx = helpers.WithDeviceId(ctx, deviceId)
+		logger.Debug("metadata", "x-device-id", deviceId)
+	}
+
+	if values, ok := md["authorization"]; ok && len(values) > 0 {
+		token, ok := strings.CutPrefix(values[0], "Bearer")
+		if !ok {
+			return nil, errors.Wrapf(tclerrors.ErrUnauthorized, "Invalid authorization header")
+		}
+		token = strings.TrimSpace(token)
+		ctx = helpers.WithAuthToken(ctx, token)
+		logger.Debug("metadata", "token", token)
+	}
+
+	if values, ok := md["x-github-token"]; ok && len(values) > 0 {
+		token := strings.TrimSpace(values[0])
+		ctx = helpers.WithGithubToken(ctx, token)
+		logger.Debug("metadata", "x-github-token", token)
+	}
+
+	return ctx, nil
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
 func createGrpcServer(
 	db *gorm.DB,
 	server ApiDepotServer,
@@ -61,31 +104,9 @@ func createGrpcServer(
 			ctx, cancel := context.WithCancel(ctx)
 			defer cancel()
 
-			md, ok := metadata.FromIncomingContext(ctx)
-			if !ok {
-				md = metadata.New(nil)
-			}
-
-			if values, ok := md["x-device-id"]; ok && len(values) > 0 {
-				deviceId := strings.TrimSpace(values[0])
-				ctx = helpers.WithDeviceId(ctx, deviceId)
-				logger.Debug("metadata", "x-device-id", deviceId)
-			}
-
-			if values, ok := md["authorization"]; ok && len(values) > 0 {
-				token, ok := strings.CutPrefix(values[0], "Bearer")
-				if !ok {
-					return nil, errors.Wrapf(tclerrors.ErrUnauthorized, "Invalid authorization header")
-				}
-				token = strings.TrimSpace(token)
-				ctx = helpers.WithAuthToken(ctx, token)
-				logger.Debug("metadata", "token", token)
-			}
-
-			if values, ok := md["x-github-token"]; ok && len(values) > 0 {
-				token := strings.TrimSpace(values[0])
-				ctx = helpers.WithGithubToken(ctx, token)
-				logger.Debug("metadata", "x-github-token", token)
+			ctx, err := withMetadata(ctx)
+			if err != nil {
+				return nil, err
 			}
 
 			rErr = db.WithContext(ctx).Transaction(func(tx *gorm.DB) (err error) {
@@ -100,6 +121,26 @@ func createGrpcServer(
 
 			return
 		}),
+		grpc.StreamInterceptor(func(
+			srv interface{},
+			ss grpc.ServerStream,
+			info *grpc.StreamServerInfo,
+			handler grpc.StreamHandler,
+		) error {
+			ctx, cancel := context.WithCancel(ss.Context())
+			defer cancel()
+
+			ctx, err := withMetadata(ctx)
+			if err != nil {
+				return err
+			}
+
+			// streams can be open for a long time, so they don't run in a single transaction
+			ctx = helpers.WithTx(ctx, db.WithContext(ctx))
+
+			logger.Info("stream", "method", info.FullMethod)
+			return handleErrorToGrpcStatus(handler(srv, &serverStream{ServerStream: ss, ctx: ctx}))
+		}),
 	)
 	RegisterApiDepotServer(grpcServer, server)
 	healthServer := health.NewServer()
@@ -3,10 +3,14 @@ package proto
 import (
 	"context"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/instance"
+	"github.com/habiliai/apidepot/pkg/internal/k8s"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/mokiat/gog"
 	"github.com/pkg/errors"
 	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (s *apiDepotServer) CreateInstance(ctx context.Context, req *CreateInstanceRequest) (*Instance, error) {
@@ -14,6 +18,8 @@ func (s *apiDepotServer) CreateInstance(ctx context.Context, req *CreateInstance
 	switch req.Zone {
 	case Instance_InstanceZoneNone:
 		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "zone is required")
+	case Instance_InstanceZoneMulti:
+		zone = tcltypes.InstanceZoneMulti
 	case Instance_InstanceZoneDefault:
 		zone = tcltypes.InstanceZoneDefault
 	case Instance_InstanceZoneOciApSeoul:
@@ -42,25 +48,171 @@ func (s *apiDepotServer) GetInstanceById(ctx context.Context, id *InstanceId) (*
 		return nil, err
 	}
 
-	return newInstancePbFromDb(inst), nil
+	result := newInstancePbFromDb(inst)
+	if inst.AppliedK8sYaml != "" {
+		// the instance is still returned without its replicas when the cluster is unreachable
+		replicas, err := s.instanceService.GetInstanceReplicas(ctx, inst.ID)
+		if err != nil {
+			logger.Warn("failed to get instance replicas", "instanceId", inst.ID, "err", err)
+			replicas = nil
+		}
+
+		result.Replicas = gog.Map(replicas, func(r instance.DeploymentReplicas) *DeploymentReplicas {
+			return &DeploymentReplicas{
+				Name:      r.Name,
+				Component: r.Component,
+				Current:   r.Current,
+				Desired:   r.Desired,
+			}
+		})
+	}
+
+	return result, nil
 }
 
 func (s *apiDepotServer) EditInstance(ctx context.Context, req *EditInstanceRequest) (*emptypb.Empty, error) {
-	return &emptypb.Empty{}, s.instanceService.EditInstance(ctx, uint(req.GetId()), instance.EditInstanceInput{
-		Name: req.Name,
-	})
+	input := instance.EditInstanceInput{
+		Name:       req.Name,
+		AutoDeploy: req.AutoDeploy,
+	}
+	if req.NumReplicas != nil {
+		input.NumReplicas = gog.PtrOf(uint(*req.NumReplicas))
+	}
+	if req.MaxReplicas != nil {
+		input.MaxReplicas = gog.PtrOf(uint(*req.MaxReplicas))
+	}
+	if len(req.ScalingTargets) > 0 {
+		input.ScalingTargets = make(map[string]domain.InstanceScalingTarget, len(req.ScalingTargets))
+		for component, target := range req.ScalingTargets {
+			input.ScalingTargets[component] = domain.InstanceScalingTarget{
+				CPUUtilization:    target.CpuUtilization,
+				MemoryUtilization: target.MemoryUtilization,
+			}
+		}
+	}
+
+	return &emptypb.Empty{}, s.instanceService.EditInstance(ctx, uint(req.GetId()), input)
 }
 
 func (s *apiDepotServer) DeleteInstance(ctx context.Context, id *InstanceId) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, s.instanceService.DeleteInstance(ctx, uint(id.Id), false)
 }
 
-func (s *apiDepotServer) DeployStack(ctx context.Context, req *DeployStackRequest) (*emptypb.Empty, error) {
-	return &emptypb.Empty{}, s.instanceService.DeployStack(ctx, uint(req.Id), instance.DeployStackInput{
-		Timeout: req.Timeout,
+func (s *apiDepotServer) DeployStack(ctx context.Context, req *DeployStackRequest) (*Deployment, error) {
+	strategy := instance.DeployStrategyRolling
+	if req.Strategy == DeployStrategy_DeployStrategyBlueGreen {
+		strategy = instance.DeployStrategyBlueGreen
+	}
+
+	deployment, err := s.instanceService.EnqueueDeployment(ctx, uint(req.Id), instance.DeployStackInput{
+		Timeout:  req.Timeout,
+		Strategy: strategy,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return newDeploymentPbFromDb(deployment), nil
+}
+
+func (s *apiDepotServer) GetDeployment(ctx context.Context, id *DeploymentId) (*Deployment, error) {
+	deployment, err := s.instanceService.GetDeployment(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newDeploymentPbFromDb(deployment), nil
+}
+
+func (s *apiDepotServer) WatchDeployment(id *DeploymentId, stream ApiDepot_WatchDeploymentServer) error {
+	return s.instanceService.WatchDeployment(stream.Context(), uint(id.Id), func(event *domain.DeploymentEvent) error {
+		return errors.WithStack(stream.Send(newDeploymentEventPbFromDb(event)))
+	})
+}
+
+func (s *apiDepotServer) CancelDeployment(ctx context.Context, id *DeploymentId) (*emptypb.Empty, error) {
+	return &emptypb.Empty{}, s.instanceService.CancelDeployment(ctx, uint(id.Id))
+}
+
+func (s *apiDepotServer) RollbackInstance(ctx context.Context, req *RollbackInstanceRequest) (*emptypb.Empty, error) {
+	var revision *uint
+	if req.Revision != nil {
+		revision = gog.PtrOf(uint(*req.Revision))
+	}
+
+	return &emptypb.Empty{}, s.instanceService.RollbackInstance(ctx, uint(req.InstanceId), revision)
+}
+
+func (s *apiDepotServer) ListInstanceRevisions(ctx context.Context, id *InstanceId) (*ListInstanceRevisionsResponse, error) {
+	revisions, err := s.instanceService.ListInstanceRevisions(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListInstanceRevisionsResponse{
+		Revisions: gog.Map(revisions, func(r domain.InstanceRevision) *InstanceRevision {
+			return newInstanceRevisionPbFromDb(&r)
+		}),
+	}, nil
+}
+
+func (s *apiDepotServer) DiffInstanceRevisions(
+	ctx context.Context,
+	req *DiffInstanceRevisionsRequest,
+) (*DiffInstanceRevisionsResponse, error) {
+	diffs, err := s.instanceService.DiffInstanceRevisions(
+		ctx,
+		uint(req.InstanceId),
+		uint(req.FromRevision),
+		uint(req.ToRevision),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DiffInstanceRevisionsResponse{
+		Diffs: gog.Map(diffs, newK8sObjectDiffPb),
+	}, nil
+}
+
+func (s *apiDepotServer) StreamInstanceLogs(req *StreamInstanceLogsRequest, stream ApiDepot_StreamInstanceLogsServer) error {
+	return s.instanceService.StreamInstanceLogs(stream.Context(), uint(req.InstanceId), instance.StreamInstanceLogsInput{
+		Component: req.GetComponent(),
+		Since:     req.Since,
+		Tail:      req.Tail,
+		Follow:    req.Follow,
+	}, func(line k8s.LogLine) error {
+		return errors.WithStack(stream.Send(&InstanceLogLine{
+			Pod:  line.Pod,
+			Time: tspb.New(line.Time),
+			Line: line.Line,
+		}))
 	})
 }
 
+func (s *apiDepotServer) GetInstanceStatus(ctx context.Context, id *InstanceId) (*InstanceStatus, error) {
+	status, err := s.instanceService.GetInstanceStatus(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstanceStatus{
+		InstanceId: int32(status.InstanceID),
+		Components: gog.Map(status.Components, func(c instance.ComponentStatus) *InstanceComponentStatus {
+			return &InstanceComponentStatus{
+				Name:      c.Name,
+				Component: c.Component,
+				ReadyPods: c.ReadyPods,
+				TotalPods: c.TotalPods,
+				Restarts:  c.Restarts,
+				Healthy:   c.Healthy,
+				LastError: c.LastError,
+			}
+		}),
+		CheckedAt: tspb.New(status.CheckedAt),
+	}, nil
+}
+
 func (s *apiDepotServer) LaunchInstance(ctx context.Context, id *InstanceId) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, s.instanceService.LaunchInstance(ctx, uint(id.Id))
 }
@@ -68,3 +220,80 @@ func (s *apiDepotServer) LaunchInstance(ctx context.Context, id *InstanceId) (*e
 func (s *apiDepotServer) StopInstance(ctx context.Context, id *InstanceId) (*emptypb.Empty, error) {
 	return &emptypb.Empty{}, s.instanceService.StopInstance(ctx, uint(id.Id))
 }
+
+func newK8sObjectDiffPb(diff instance.ObjectDiff) *K8SObjectDiff {
+	var change K8SObjectDiff_Change
+	switch diff.Change {
+	case instance.ObjectChangeAdded:
+		change = K8SObjectDiff_ChangeAdded
+	case instance.ObjectChangeDeleted:
+		change = K8SObjectDiff_ChangeDeleted
+	case instance.ObjectChangeChanged:
+		change = K8SObjectDiff_ChangeChanged
+	}
+
+	return &K8SObjectDiff{
+		Kind:    diff.Kind,
+		Name:    diff.Name,
+		Change:  change,
+		OldYaml: diff.OldYaml,
+		NewYaml: diff.NewYaml,
+	}
+}
+
+var (
+	deploymentStatusPbs = map[domain.DeploymentStatus]Deployment_DeploymentStatus{
+		domain.DeploymentStatusQueued:    Deployment_DeploymentStatusQueued,
+		domain.DeploymentStatusRunning:   Deployment_DeploymentStatusRunning,
+		domain.DeploymentStatusSucceeded: Deployment_DeploymentStatusSucceeded,
+		domain.DeploymentStatusFailed:    Deployment_DeploymentStatusFailed,
+		domain.DeploymentStatusCanceled:  Deployment_DeploymentStatusCanceled,
+	}
+	deploymentPhasePbs = map[domain.DeploymentPhase]Deployment_DeploymentPhase{
+		domain.DeploymentPhaseNone:      Deployment_DeploymentPhaseNone,
+		domain.DeploymentPhaseRender:    Deployment_DeploymentPhaseRender,
+		domain.DeploymentPhaseApply:     Deployment_DeploymentPhaseApply,
+		domain.DeploymentPhasePodsReady: Deployment_DeploymentPhasePodsReady,
+		domain.DeploymentPhaseHealth:    Deployment_DeploymentPhaseHealth,
+		domain.DeploymentPhaseMigrate:   Deployment_DeploymentPhaseMigrate,
+	}
+)
+
+func newDeploymentPbFromDb(deployment *domain.Deployment) *Deployment {
+	strategy := DeployStrategy_DeployStrategyRolling
+	if instance.DeployStrategy(deployment.Strategy) == instance.DeployStrategyBlueGreen {
+		strategy = DeployStrategy_DeployStrategyBlueGreen
+	}
+
+	pb := &Deployment{
+		Id:              int32(deployment.ID),
+		InstanceId:      int32(deployment.InstanceID),
+		RequestedById:   int32(deployment.RequestedByID),
+		Strategy:        strategy,
+		Timeout:         deployment.Timeout,
+		Status:          deploymentStatusPbs[deployment.Status],
+		Phase:           deploymentPhasePbs[deployment.Phase],
+		Error:           deployment.Error,
+		CancelRequested: deployment.CancelRequested,
+		CreatedAt:       tspb.New(deployment.CreatedAt),
+	}
+	if deployment.StartedAt != nil {
+		pb.StartedAt = tspb.New(*deployment.StartedAt)
+	}
+	if deployment.FinishedAt != nil {
+		pb.FinishedAt = tspb.New(*deployment.FinishedAt)
+	}
+
+	return pb
+}
+
+func newDeploymentEventPbFromDb(event *domain.DeploymentEvent) *DeploymentEvent {
+	return &DeploymentEvent{
+		Id:           int32(event.ID),
+		DeploymentId: int32(event.DeploymentID),
+		Status:       deploymentStatusPbs[event.Status],
+		Phase:        deploymentPhasePbs[event.Phase],
+		Message:      event.Message,
+		CreatedAt:    tspb.New(event.CreatedAt),
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/instance"
 	"github.com/habiliai/apidepot/pkg/internal/proto"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/mock"
 )
 
@@ -45,3 +46,56 @@ func (s *ProtoTestSuite) TestCreateInstance() {
 	s.Equal(proto.Instance_InstanceZoneOciApSeoul, resp.Zone)
 	s.Equal(proto.Instance_InstanceStateReady, resp.State)
 }
+
+func (s *ProtoTestSuite) TestCreateMultiRegionInstance() {
+	s.instances.On("CreateInstance", mock.Anything, instance.CreateInstanceInput{
+		Name:    "test",
+		StackID: 1,
+		Zone:    tcltypes.InstanceZoneMulti,
+	}).Return(&domain.Instance{
+		Model: domain.Model{
+			ID: 1,
+		},
+		Zone:    tcltypes.InstanceZoneOciApSeoul,
+		Name:    "test-oci-ap-seoul-1",
+		StackID: 1,
+	}, nil).Once()
+	defer s.instances.AssertExpectations(s.T())
+
+	client, dispose := s.newClient()
+	defer dispose()
+
+	resp, err := client.CreateInstance(s.Context(), &proto.CreateInstanceRequest{
+		Name:    "test",
+		StackId: 1,
+		Zone:    proto.Instance_InstanceZoneMulti,
+	})
+	s.Require().NoError(err)
+
+	s.Equal(proto.Instance_InstanceZoneOciApSeoul, resp.Zone)
+}
+
+func (s *ProtoTestSuite) TestGivenUnreachableClusterWhenGetInstanceByIdThenShouldReturnWithoutReplicas() {
+	s.instances.On("GetInstance", mock.Anything, uint(1)).Return(&domain.Instance{
+		Model: domain.Model{
+			ID: 1,
+		},
+		Zone:           tcltypes.InstanceZoneOciApSeoul,
+		State:          domain.InstanceStateRunning,
+		Name:           "test",
+		AppliedK8sYaml: "kind: Deployment",
+		StackID:        1,
+	}, nil).Once()
+	s.instances.On("GetInstanceReplicas", mock.Anything, uint(1)).
+		Return([]instance.DeploymentReplicas(nil), errors.New("connection refused")).Once()
+	defer s.instances.AssertExpectations(s.T())
+
+	client, dispose := s.newClient()
+	defer dispose()
+
+	resp, err := client.GetInstanceById(s.Context(), &proto.InstanceId{Id: 1})
+	s.Require().NoError(err)
+
+	s.Equal("test", resp.Name)
+	s.Empty(resp.Replicas)
+}
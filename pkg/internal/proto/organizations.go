@@ -14,8 +14,9 @@ func (s *apiDepotServer) UpsertOrganization(ctx context.Context, request *Upsert
 	}
 
 	orgId, err := s.orgService.UpdateOrganization(ctx, organization.CreateOrUpdateOrganizationInput{
-		Id:   id,
-		Name: request.Name,
+		Id:       id,
+		Name:     request.Name,
+		NoCreate: request.NoCreate,
 	})
 	if err != nil {
 		return nil, err
@@ -57,3 +58,39 @@ func (s *apiDepotServer) GetAllOrganizations(ctx context.Context, req *GetAllOrg
 		Organizations: organizations,
 	}, nil
 }
+
+func (s *apiDepotServer) AddOrganizationMember(ctx context.Context, req *AddOrganizationMemberRequest) (*OrganizationMember, error) {
+	member, err := s.orgService.AddOrganizationMember(ctx, uint(req.OrganizationId), req.MemberAuthId, newOrganizationRoleFromPb(req.Role))
+	if err != nil {
+		return nil, err
+	}
+
+	return newOrganizationMemberPbFromDb(*member), nil
+}
+
+func (s *apiDepotServer) UpdateOrganizationMemberRole(ctx context.Context, req *UpdateOrganizationMemberRoleRequest) (*emptypb.Empty, error) {
+	if err := s.orgService.UpdateOrganizationMemberRole(ctx, uint(req.OrganizationId), req.MemberAuthId, newOrganizationRoleFromPb(req.Role)); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *apiDepotServer) RemoveOrganizationMember(ctx context.Context, req *RemoveOrganizationMemberRequest) (*emptypb.Empty, error) {
+	if err := s.orgService.RemoveOrganizationMember(ctx, uint(req.OrganizationId), req.MemberAuthId); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *apiDepotServer) GetOrganizationMembers(ctx context.Context, id *OrganizationId) (*GetOrganizationMembersResponse, error) {
+	members, err := s.orgService.GetOrganizationMembers(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetOrganizationMembersResponse{
+		Members: gog.Map(members, newOrganizationMemberPbFromDb),
+	}, nil
+}
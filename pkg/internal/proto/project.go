@@ -10,7 +10,12 @@ import (
 
 // CreateProject 함수
 func (s *apiDepotServer) CreateProject(ctx context.Context, req *CreateProjectRequest) (*Project, error) {
-	project, err := s.projectService.CreateProject(ctx, req.Name, req.Description)
+	var organizationId *uint
+	if req.OrganizationId != nil {
+		organizationId = gog.PtrOf(uint(*req.OrganizationId))
+	}
+
+	project, err := s.projectService.CreateProject(ctx, req.Name, req.Description, organizationId)
 	if err != nil {
 		return nil, err
 	}
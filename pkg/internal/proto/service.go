@@ -3,9 +3,11 @@ package proto
 import (
 	"context"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/billing"
 	"github.com/habiliai/apidepot/pkg/internal/cliapp"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/githook"
 	"github.com/habiliai/apidepot/pkg/internal/instance"
 	"github.com/habiliai/apidepot/pkg/internal/organization"
 	"github.com/habiliai/apidepot/pkg/internal/project"
@@ -37,6 +39,8 @@ type (
 		svctplService   svctpl.Service
 		gitService      services.GitService
 		storageClient   *storage.Client
+		billingService  billing.Service
+		githookService  githook.Service
 	}
 )
 
@@ -125,6 +129,16 @@ func init() {
 			return nil, err
 		}
 
+		billingService, err := digo.Get[billing.Service](ctx, billing.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
+		githookService, err := digo.Get[githook.Service](ctx, githook.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+
 		switch ctx.Env {
 		case digo.EnvProd:
 			return &apiDepotServer{
@@ -141,6 +155,8 @@ func init() {
 				svctplService:   svctplService,
 				gitService:      gitService,
 				storageClient:   storageClient,
+				billingService:  billingService,
+				githookService:  githookService,
 			}, nil
 		case digo.EnvTest:
 			return &apiDepotServer{
@@ -157,6 +173,8 @@ func init() {
 				svctplService:   svctplService,
 				gitService:      gitService,
 				storageClient:   storageClient,
+				billingService:  billingService,
+				githookService:  githookService,
 			}, nil
 		default:
 			return nil, errors.Errorf("unknown env: %s", ctx.Env)
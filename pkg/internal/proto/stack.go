@@ -6,14 +6,16 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/stack"
 	"github.com/mokiat/gog"
 	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
 )
 
 func (s *apiDepotServer) UpdateStack(ctx context.Context, req *UpdateStackRequest) (*emptypb.Empty, error) {
 	if err := s.stackService.PatchStack(ctx, uint(req.StackId), stack.PatchStackInput{
-		SiteURL:      req.SiteUrl,
-		Name:         req.Name,
-		Description:  req.Description,
-		LogoImageUrl: req.LogoImageUrl,
+		SiteURL:         req.SiteUrl,
+		Name:            req.Name,
+		Description:     req.Description,
+		LogoImageUrl:    req.LogoImageUrl,
+		PreviewsEnabled: req.PreviewsEnabled,
 	}); err != nil {
 		return nil, err
 	}
@@ -189,19 +191,63 @@ func (s *apiDepotServer) InstallStorage(ctx context.Context, request *InstallSto
 	return &emptypb.Empty{}, nil
 }
 
-func (s *apiDepotServer) MigrateDatabase(ctx context.Context, request *MigrateDatabaseRequest) (*emptypb.Empty, error) {
-	if err := s.stackService.MigrateDatabase(ctx, uint(request.StackId), stack.MigrateDatabaseInput{
-		Migrations: gog.Map(request.Migrations, func(s *Migration) stack.Migration {
-			return stack.Migration{
-				Query:   s.Query,
-				Version: s.Version.AsTime(),
+func (s *apiDepotServer) MigrateDatabase(ctx context.Context, request *MigrateDatabaseRequest) (*MigrateDatabaseResponse, error) {
+	output, err := s.stackService.MigrateDatabase(ctx, uint(request.StackId), stack.MigrateDatabaseInput{
+		Migrations: gog.Map(request.Migrations, newMigrationFromPb),
+		DryRun:     request.DryRun,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &MigrateDatabaseResponse{
+		AppliedVersions: gog.Map(output.AppliedVersions, tspb.New),
+	}, nil
+}
+
+func (s *apiDepotServer) GetMigrationStatus(ctx context.Context, request *GetMigrationStatusRequest) (*GetMigrationStatusResponse, error) {
+	statuses, err := s.stackService.GetMigrationStatus(ctx, uint(request.StackId), gog.Map(request.Migrations, newMigrationFromPb))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetMigrationStatusResponse{
+		Migrations: gog.Map(statuses, func(status stack.MigrationStatus) *MigrationStatus {
+			pb := &MigrationStatus{
+				Source:     status.Source,
+				Version:    tspb.New(status.Version),
+				Applied:    status.Applied,
+				Reversible: status.Reversible,
 			}
+			if status.AppliedAt != nil {
+				pb.AppliedAt = tspb.New(*status.AppliedAt)
+			}
+			return pb
 		}),
-	}); err != nil {
+	}, nil
+}
+
+func (s *apiDepotServer) RollbackMigrations(ctx context.Context, request *RollbackMigrationsRequest) (*RollbackMigrationsResponse, error) {
+	output, err := s.stackService.RollbackMigrations(ctx, uint(request.StackId), stack.RollbackMigrationsInput{
+		Source: request.GetSource(),
+		Steps:  int(request.Steps),
+		DryRun: request.DryRun,
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return &emptypb.Empty{}, nil
+	return &RollbackMigrationsResponse{
+		RolledBackVersions: gog.Map(output.RolledBackVersions, tspb.New),
+	}, nil
+}
+
+func newMigrationFromPb(m *Migration) stack.Migration {
+	return stack.Migration{
+		Query:     m.Query,
+		Version:   m.Version.AsTime(),
+		DownQuery: m.GetDownQuery(),
+	}
 }
 
 func (s *apiDepotServer) GetStackInstances(ctx context.Context, id *StackId) (*GetStackInstancesResponse, error) {
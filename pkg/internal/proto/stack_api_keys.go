@@ -0,0 +1,141 @@
+package proto
+
+import (
+	"context"
+	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+func (s *apiDepotServer) CreateStackApiKey(ctx context.Context, req *CreateStackApiKeyRequest) (*CreateStackApiKeyResponse, error) {
+	input := stack.CreateApiKeyInput{
+		Name:         req.Name,
+		Role:         req.Role,
+		AllowedVapis: req.AllowedVapis,
+	}
+	if req.ExpiresAt != nil {
+		input.ExpiresAt = gog.PtrOf(req.ExpiresAt.AsTime())
+	}
+
+	apiKey, token, err := s.stackService.CreateApiKey(ctx, uint(req.StackId), input)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreateStackApiKeyResponse{
+		ApiKey: newStackApiKeyPbFromDb(apiKey),
+		Token:  token,
+	}, nil
+}
+
+func (s *apiDepotServer) ListStackApiKeys(ctx context.Context, id *StackId) (*ListStackApiKeysResponse, error) {
+	apiKeys, err := s.stackService.ListApiKeys(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListStackApiKeysResponse{
+		ApiKeys: gog.Map(apiKeys, func(apiKey domain.StackApiKey) *StackApiKey {
+			return newStackApiKeyPbFromDb(&apiKey)
+		}),
+	}, nil
+}
+
+func (s *apiDepotServer) RevokeStackApiKey(ctx context.Context, req *RevokeStackApiKeyRequest) (*emptypb.Empty, error) {
+	if err := s.stackService.RevokeApiKey(ctx, uint(req.StackId), uint(req.ApiKeyId)); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func newStackApiKeyPbFromDb(apiKey *domain.StackApiKey) *StackApiKey {
+	pb := &StackApiKey{
+		Id:           int32(apiKey.ID),
+		StackId:      int32(apiKey.StackID),
+		KeyId:        apiKey.KeyID,
+		Name:         apiKey.Name,
+		Role:         apiKey.Role,
+		AllowedVapis: apiKey.AllowedVapis,
+		Revoked:      apiKey.Revoked,
+		CreatedAt:    tspb.New(apiKey.CreatedAt),
+	}
+	if apiKey.ExpiresAt != nil {
+		pb.ExpiresAt = tspb.New(*apiKey.ExpiresAt)
+	}
+	if apiKey.RevokedAt != nil {
+		pb.RevokedAt = tspb.New(*apiKey.RevokedAt)
+	}
+
+	return pb
+}
+
+// NewApiKeyVerifyHandler returns the forward auth handler of the ingresses of the stacks, which verifies the stack api keys
+// of the requests and counts them against the monthly request quota of the owner of the stack. The stack is given by
+// the stackId query signed with secret by the signature query, and the original uri by the X-Forwarded-Uri header.
+func NewApiKeyVerifyHandler(stackService stack.Service, db *gorm.DB, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if secret == "" {
+			http.Error(w, "api key verification is disabled", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		stackId, err := strconv.ParseUint(query.Get("stackId"), 10, 64)
+		if err != nil {
+			http.Error(w, "invalid stackId", http.StatusBadRequest)
+			return
+		}
+
+		if !util.VerifyHMAC(secret, query.Get("stackId"), query.Get("signature")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var tokens []string
+		if apiKey := r.Header.Get("apikey"); apiKey != "" {
+			tokens = append(tokens, apiKey)
+		}
+		if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer"); ok {
+			tokens = append(tokens, strings.TrimSpace(token))
+		}
+
+		ctx := helpers.WithTx(r.Context(), db.WithContext(r.Context()))
+		uri := r.Header.Get("X-Forwarded-Uri")
+		for _, token := range tokens {
+			if err := stackService.VerifyApiKey(ctx, uint(stackId), token, uri); errors.Is(err, tclerrors.ErrUnauthorized) {
+				http.Error(w, err.Error(), http.StatusUnauthorized)
+				return
+			} else if errors.Is(err, tclerrors.ErrForbidden) {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			} else if err != nil {
+				logger.Error(fmt.Sprintf("failed to verify api key: %+v", err))
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := stackService.CountRequest(uint(stackId)); errors.Is(err, tclerrors.ErrQuotaExceeded) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		} else if err != nil {
+			logger.Error(fmt.Sprintf("failed to count request: %+v", err))
+			http.Error(w, "internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
@@ -0,0 +1,49 @@
+package proto_test
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	stacktest "github.com/habiliai/apidepot/pkg/internal/stack/test"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"net/http"
+	"net/http/httptest"
+)
+
+const testApiKeyVerifySecret = "verify-secret"
+
+func (s *ProtoTestSuite) TestGivenInvalidSignatureWhenVerifyApiKeyThenUnauthorized() {
+	// Given
+	stacks := &stacktest.ServiceMock{}
+	defer stacks.AssertExpectations(s.T())
+	handler := proto.NewApiKeyVerifyHandler(stacks, s.db, testApiKeyVerifySecret)
+
+	// When
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet,
+		"/_internal/api-keys/verify?stackId=1&signature="+util.SignHMAC(testApiKeyVerifySecret, "2"),
+		nil,
+	))
+
+	// Then
+	s.Equal(http.StatusUnauthorized, w.Code)
+}
+
+func (s *ProtoTestSuite) TestGivenSignedStackIdWhenVerifyApiKeyThenCountRequest() {
+	// Given
+	stacks := &stacktest.ServiceMock{}
+	defer stacks.AssertExpectations(s.T())
+	handler := proto.NewApiKeyVerifyHandler(stacks, s.db, testApiKeyVerifySecret)
+
+	stacks.On("CountRequest", uint(1)).Return(nil).Once()
+
+	// When
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(
+		http.MethodGet,
+		"/_internal/api-keys/verify?stackId=1&signature="+util.SignHMAC(testApiKeyVerifySecret, "1"),
+		nil,
+	))
+
+	// Then
+	s.Equal(http.StatusOK, w.Code)
+}
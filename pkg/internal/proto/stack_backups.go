@@ -0,0 +1,70 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	stackBackupKindPbs = map[domain.StackBackupKind]StackBackup_StackBackupKind{
+		domain.StackBackupKindScheduled:  StackBackup_StackBackupKindScheduled,
+		domain.StackBackupKindManual:     StackBackup_StackBackupKindManual,
+		domain.StackBackupKindFinal:      StackBackup_StackBackupKindFinal,
+		domain.StackBackupKindPreRestore: StackBackup_StackBackupKindPreRestore,
+	}
+	stackBackupStatusPbs = map[domain.StackBackupStatus]StackBackup_StackBackupStatus{
+		domain.StackBackupStatusRunning:   StackBackup_StackBackupStatusRunning,
+		domain.StackBackupStatusSucceeded: StackBackup_StackBackupStatusSucceeded,
+		domain.StackBackupStatusFailed:    StackBackup_StackBackupStatusFailed,
+	}
+)
+
+func (s *apiDepotServer) CreateBackup(ctx context.Context, id *StackId) (*StackBackup, error) {
+	backup, err := s.stackService.CreateBackup(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackBackupPbFromDb(backup), nil
+}
+
+func (s *apiDepotServer) ListBackups(ctx context.Context, id *StackId) (*ListBackupsResponse, error) {
+	backups, err := s.stackService.ListBackups(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ListBackupsResponse{
+		Backups: gog.Map(backups, func(backup domain.StackBackup) *StackBackup {
+			return newStackBackupPbFromDb(&backup)
+		}),
+	}, nil
+}
+
+func (s *apiDepotServer) RestoreBackup(ctx context.Context, req *RestoreBackupRequest) (*emptypb.Empty, error) {
+	if err := s.stackService.RestoreBackup(ctx, uint(req.StackId), uint(req.BackupId)); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func newStackBackupPbFromDb(backup *domain.StackBackup) *StackBackup {
+	pb := &StackBackup{
+		Id:        int32(backup.ID),
+		StackId:   int32(backup.StackID),
+		Kind:      stackBackupKindPbs[backup.Kind],
+		Status:    stackBackupStatusPbs[backup.Status],
+		Size:      backup.Size,
+		Error:     backup.Error,
+		CreatedAt: tspb.New(backup.CreatedAt),
+	}
+	if backup.FinishedAt != nil {
+		pb.FinishedAt = tspb.New(*backup.FinishedAt)
+	}
+
+	return pb
+}
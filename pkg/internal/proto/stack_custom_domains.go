@@ -0,0 +1,60 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *apiDepotServer) AddStackCustomDomain(ctx context.Context, req *AddStackCustomDomainRequest) (*StackCustomDomain, error) {
+	customDomain, err := s.stackService.AddCustomDomain(ctx, uint(req.StackId), req.Hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackCustomDomainPbFromDb(customDomain), nil
+}
+
+func (s *apiDepotServer) VerifyStackCustomDomain(ctx context.Context, id *StackCustomDomainId) (*StackCustomDomain, error) {
+	customDomain, err := s.stackService.VerifyCustomDomain(ctx, uint(id.StackId), uint(id.CustomDomainId))
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackCustomDomainPbFromDb(customDomain), nil
+}
+
+func (s *apiDepotServer) RemoveStackCustomDomain(ctx context.Context, id *StackCustomDomainId) (*emptypb.Empty, error) {
+	if err := s.stackService.RemoveCustomDomain(ctx, uint(id.StackId), uint(id.CustomDomainId)); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *apiDepotServer) SetStackPrimaryDomain(ctx context.Context, req *SetStackPrimaryDomainRequest) (*Stack, error) {
+	stack, err := s.stackService.SetPrimaryDomain(ctx, uint(req.StackId), req.Hostname)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackPbFromDb(*stack), nil
+}
+
+func newStackCustomDomainPbFromDb(customDomain *domain.StackCustomDomain) *StackCustomDomain {
+	pb := &StackCustomDomain{
+		Id:                      int32(customDomain.ID),
+		StackId:                 int32(customDomain.StackID),
+		Hostname:                customDomain.Hostname,
+		Verified:                customDomain.Verified(),
+		VerificationRecordName:  customDomain.VerificationRecordName(),
+		VerificationRecordValue: customDomain.VerificationRecordValue(),
+		CreatedAt:               tspb.New(customDomain.CreatedAt),
+	}
+	if customDomain.VerifiedAt != nil {
+		pb.VerifiedAt = tspb.New(*customDomain.VerifiedAt)
+	}
+
+	return pb
+}
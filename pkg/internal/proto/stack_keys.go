@@ -0,0 +1,40 @@
+package proto
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/instance"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+func (s *apiDepotServer) RotateStackKeys(ctx context.Context, req *RotateStackKeysRequest) (*RotateStackKeysResponse, error) {
+	var input instance.RotateStackKeysInput
+	if req.GracePeriod != nil {
+		gracePeriod, err := time.ParseDuration(*req.GracePeriod)
+		if err != nil {
+			return nil, errors.Wrapf(tclerrors.ErrBadRequest, "failed to parse grace period: %v", err)
+		}
+		input.GracePeriod = &gracePeriod
+	}
+
+	stack, deployments, err := s.instanceService.RotateStackKeys(ctx, uint(req.StackId), input)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &RotateStackKeysResponse{
+		Stack: newStackPbFromDb(*stack),
+		Deployments: gog.Map(deployments, func(deployment domain.Deployment) *Deployment {
+			return newDeploymentPbFromDb(&deployment)
+		}),
+	}
+	if stack.PreviousJWTSecretExpiresAt != nil {
+		resp.PreviousJwtSecretExpiresAt = tspb.New(*stack.PreviousJWTSecretExpiresAt)
+	}
+
+	return resp, nil
+}
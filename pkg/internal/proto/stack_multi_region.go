@@ -0,0 +1,16 @@
+package proto
+
+import (
+	"context"
+)
+
+// DisableStackMultiRegion drops the replicas of a multi-region stack. A stack becomes multi-region by creating its
+// instance with the zone InstanceZoneMulti.
+func (s *apiDepotServer) DisableStackMultiRegion(ctx context.Context, id *StackId) (*Stack, error) {
+	stack, err := s.stackService.DisableMultiRegion(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackPbFromDb(*stack), nil
+}
@@ -0,0 +1,32 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *apiDepotServer) GetStackPreviews(ctx context.Context, id *StackId) (*GetStackPreviewsResponse, error) {
+	previews, err := s.stackService.GetStackPreviews(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetStackPreviewsResponse{
+		Previews: gog.Map(previews, newStackPreviewPbFromDb),
+	}, nil
+}
+
+func newStackPreviewPbFromDb(preview domain.StackPreview) *StackPreview {
+	return &StackPreview{
+		Id:                int32(preview.ID),
+		StackId:           int32(preview.StackID),
+		ParentStackId:     int32(preview.ParentStackID),
+		GitRepo:           preview.GitRepo,
+		PullRequestNumber: int32(preview.PullRequestNumber),
+		StackName:         preview.Stack.Name,
+		Endpoint:          preview.Stack.Endpoint(),
+		CreatedAt:         tspb.New(preview.CreatedAt),
+	}
+}
@@ -0,0 +1,38 @@
+package proto_test
+
+import (
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/proto"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *ProtoTestSuite) TestGetStackPreviews() {
+	const stackId uint = 1
+
+	s.stacks.On("GetStackPreviews", mock.Anything, stackId).Return([]domain.StackPreview{
+		{
+			Model:             domain.Model{ID: 2},
+			StackID:           3,
+			ParentStackID:     stackId,
+			GitRepo:           "habiliai/test-stack",
+			PullRequestNumber: 7,
+			Stack: domain.Stack{
+				Name:   "test-stack-pr-7",
+				Scheme: "https",
+				Domain: "test-stack-pr-7.apidepot.local",
+			},
+		},
+	}, nil).Once()
+	defer s.stacks.AssertExpectations(s.T())
+
+	client, dispose := s.newClient()
+	defer dispose()
+
+	resp, err := client.GetStackPreviews(s.Context(), &proto.StackId{Id: int32(stackId)})
+	s.Require().NoError(err)
+	s.Require().Len(resp.Previews, 1)
+	s.Equal(int32(3), resp.Previews[0].StackId)
+	s.Equal(int32(7), resp.Previews[0].PullRequestNumber)
+	s.Equal("test-stack-pr-7", resp.Previews[0].StackName)
+	s.Equal("https://test-stack-pr-7.apidepot.local", resp.Previews[0].Endpoint)
+}
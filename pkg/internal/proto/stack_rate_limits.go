@@ -0,0 +1,79 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+)
+
+func (s *apiDepotServer) SetStackRateLimits(ctx context.Context, req *SetStackRateLimitsRequest) (*Stack, error) {
+	stack, err := s.stackService.SetRateLimits(ctx, uint(req.StackId), newStackRateLimitsDbFromPb(req.RateLimits))
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackPbFromDb(*stack), nil
+}
+
+func (s *apiDepotServer) GetStackRequestUsage(ctx context.Context, id *StackId) (*StackRequestUsage, error) {
+	usage, err := s.stackService.GetRequestUsage(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &StackRequestUsage{
+		Month:         usage.Month,
+		StackRequests: usage.StackRequests,
+		OwnerRequests: usage.OwnerRequests,
+		Quota:         usage.Quota,
+	}, nil
+}
+
+func newStackRateLimitPbFromDb(limit domain.RateLimit) *StackRateLimit {
+	return &StackRateLimit{
+		RequestsPerSecond: limit.RequestsPerSecond,
+		Burst:             limit.Burst,
+		MaxInFlight:       limit.MaxInFlight,
+	}
+}
+
+func newStackRateLimitsPbFromDb(limits domain.StackRateLimits) *StackRateLimits {
+	result := &StackRateLimits{
+		Vapis: map[string]*StackRateLimit{},
+	}
+	if limits.Global != nil {
+		result.Global = newStackRateLimitPbFromDb(*limits.Global)
+	}
+	for slug, limit := range limits.Vapis {
+		result.Vapis[slug] = newStackRateLimitPbFromDb(limit)
+	}
+
+	return result
+}
+
+func newStackRateLimitDbFromPb(limit *StackRateLimit) domain.RateLimit {
+	return domain.RateLimit{
+		RequestsPerSecond: limit.RequestsPerSecond,
+		Burst:             limit.Burst,
+		MaxInFlight:       limit.MaxInFlight,
+	}
+}
+
+func newStackRateLimitsDbFromPb(limits *StackRateLimits) domain.StackRateLimits {
+	var result domain.StackRateLimits
+	if limits == nil {
+		return result
+	}
+
+	if limits.Global != nil {
+		global := newStackRateLimitDbFromPb(limits.Global)
+		result.Global = &global
+	}
+	if len(limits.Vapis) > 0 {
+		result.Vapis = map[string]domain.RateLimit{}
+		for slug, limit := range limits.Vapis {
+			result.Vapis[slug] = newStackRateLimitDbFromPb(limit)
+		}
+	}
+
+	return result
+}
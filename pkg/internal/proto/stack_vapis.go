@@ -2,7 +2,9 @@ package proto
 
 import (
 	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/mokiat/gog"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -40,14 +42,34 @@ func (s *apiDepotServer) UpdateVapi(ctx context.Context, request *UpdateVapiRequ
 	return newStackVapiPbFromDb(vapi), nil
 }
 
+func (s *apiDepotServer) SetStackVapiResources(
+	ctx context.Context,
+	req *SetStackVapiResourcesRequest,
+) (*StackVapi, error) {
+	vapi, err := s.stackService.SetVapiResources(
+		ctx,
+		uint(req.StackId),
+		uint(req.VapiId),
+		newVapiResourcesFromPb(req.Resources),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return newStackVapiPbFromDb(vapi), nil
+}
+
 func (s *apiDepotServer) SetStackEnv(
 	ctx context.Context,
 	req *SetStackEnvRequest,
 ) (*emptypb.Empty, error) {
-	envVars := make(map[string]string, len(req.EnvVars))
-	for _, v := range req.EnvVars {
-		envVars[v.Name] = v.Value
-	}
+	envVars := gog.Map(req.EnvVars, func(v *SetStackEnvRequest_EnvVar) domain.StackVapiEnvVar {
+		return domain.StackVapiEnvVar{
+			Name:   v.Name,
+			Value:  v.Value,
+			Secret: v.Secret,
+		}
+	})
 
 	if err := s.stackService.SetVapiEnv(ctx, uint(req.StackId), envVars); err != nil {
 		return nil, err
@@ -66,3 +88,25 @@ func (s *apiDepotServer) UnsetStackEnv(
 
 	return &emptypb.Empty{}, nil
 }
+
+func (s *apiDepotServer) GetStackDependencyTree(
+	ctx context.Context,
+	req *StackId,
+) (*GetStackDependencyTreeResponse, error) {
+	tree, err := s.stackService.GetStackDependencyTree(ctx, uint(req.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	locked, err := s.stackService.GetLockedVapiReleases(ctx, uint(req.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetStackDependencyTreeResponse{
+		Roots: gog.Map(tree.Roots, newStackDependencyTreeNodePbFromVapi),
+		LockedVapis: gog.Map(locked, func(rel domain.VapiRelease) *VapiRelease {
+			return newVapiReleasePbFromDb(&rel)
+		}),
+	}, nil
+}
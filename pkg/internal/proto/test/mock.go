@@ -132,9 +132,19 @@ func (c *ApiDepotServerMock) DeleteInstance(ctx context.Context, id *proto.Insta
 	return args.Get(0).(*emptypb.Empty), args.Error(1)
 }
 
-func (c *ApiDepotServerMock) DeployStack(ctx context.Context, request *proto.DeployStackRequest) (*emptypb.Empty, error) {
+func (c *ApiDepotServerMock) DeployStack(ctx context.Context, request *proto.DeployStackRequest) (*proto.Deployment, error) {
 	args := c.Called(ctx, request)
-	return args.Get(0).(*emptypb.Empty), args.Error(1)
+	return args.Get(0).(*proto.Deployment), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) StreamInstanceLogs(req *proto.StreamInstanceLogsRequest, stream proto.ApiDepot_StreamInstanceLogsServer) error {
+	args := c.Called(req, stream)
+	return args.Error(0)
+}
+
+func (c *ApiDepotServerMock) WatchDeployment(id *proto.DeploymentId, stream proto.ApiDepot_WatchDeploymentServer) error {
+	args := c.Called(id, stream)
+	return args.Error(0)
 }
 
 func (c *ApiDepotServerMock) LaunchInstance(ctx context.Context, id *proto.InstanceId) (*emptypb.Empty, error) {
@@ -212,9 +222,14 @@ func (c *ApiDepotServerMock) UpdateVapi(ctx context.Context, request *proto.Upda
 	return args.Get(0).(*proto.StackVapi), args.Error(1)
 }
 
-func (c *ApiDepotServerMock) MigrateDatabase(ctx context.Context, request *proto.MigrateDatabaseRequest) (*emptypb.Empty, error) {
+func (c *ApiDepotServerMock) GetStackDependencyTree(ctx context.Context, id *proto.StackId) (*proto.GetStackDependencyTreeResponse, error) {
+	args := c.Called(ctx, id)
+	return args.Get(0).(*proto.GetStackDependencyTreeResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) MigrateDatabase(ctx context.Context, request *proto.MigrateDatabaseRequest) (*proto.MigrateDatabaseResponse, error) {
 	args := c.Called(ctx, request)
-	return args.Get(0).(*emptypb.Empty), args.Error(1)
+	return args.Get(0).(*proto.MigrateDatabaseResponse), args.Error(1)
 }
 
 func (c *ApiDepotServerMock) GetStackInstances(ctx context.Context, id *proto.StackId) (*proto.GetStackInstancesResponse, error) {
@@ -357,6 +372,246 @@ func (c *ApiDepotServerMock) DeleteVapiPackage(ctx context.Context, id *proto.Va
 	return args.Get(0).(*emptypb.Empty), args.Error(1)
 }
 
+func (c *ApiDepotServerMock) TransferVapiPackage(ctx context.Context, req *proto.TransferVapiPackageRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) UpsertOrganization(ctx context.Context, req *proto.UpsertOrganizationRequest) (*proto.OrganizationId, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.OrganizationId), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetOrganization(ctx context.Context, req *proto.OrganizationId) (*proto.Organization, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Organization), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetAllOrganizations(ctx context.Context, req *proto.GetAllOrganizationsRequest) (*proto.GetAllOrganizationsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetAllOrganizationsResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) DeleteOrganization(ctx context.Context, req *proto.OrganizationId) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) AddOrganizationMember(ctx context.Context, req *proto.AddOrganizationMemberRequest) (*proto.OrganizationMember, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.OrganizationMember), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) UpdateOrganizationMemberRole(ctx context.Context, req *proto.UpdateOrganizationMemberRoleRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RemoveOrganizationMember(ctx context.Context, req *proto.RemoveOrganizationMemberRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetOrganizationMembers(ctx context.Context, req *proto.OrganizationId) (*proto.GetOrganizationMembersResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetOrganizationMembersResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetInvoices(ctx context.Context, req *emptypb.Empty) (*proto.GetInvoicesResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetInvoicesResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetCurrentUsage(ctx context.Context, req *emptypb.Empty) (*proto.Invoice, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Invoice), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) SetStackVapiResources(ctx context.Context, req *proto.SetStackVapiResourcesRequest) (*proto.StackVapi, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.StackVapi), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RollbackInstance(ctx context.Context, req *proto.RollbackInstanceRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) ListInstanceRevisions(ctx context.Context, req *proto.InstanceId) (*proto.ListInstanceRevisionsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.ListInstanceRevisionsResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) DiffInstanceRevisions(ctx context.Context, req *proto.DiffInstanceRevisionsRequest) (*proto.DiffInstanceRevisionsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.DiffInstanceRevisionsResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetDeployment(ctx context.Context, req *proto.DeploymentId) (*proto.Deployment, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Deployment), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) CancelDeployment(ctx context.Context, req *proto.DeploymentId) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetMigrationStatus(ctx context.Context, req *proto.GetMigrationStatusRequest) (*proto.GetMigrationStatusResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetMigrationStatusResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RollbackMigrations(ctx context.Context, req *proto.RollbackMigrationsRequest) (*proto.RollbackMigrationsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.RollbackMigrationsResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) CreateBackup(ctx context.Context, req *proto.StackId) (*proto.StackBackup, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.StackBackup), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) ListBackups(ctx context.Context, req *proto.StackId) (*proto.ListBackupsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.ListBackupsResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RestoreBackup(ctx context.Context, req *proto.RestoreBackupRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RotateStackKeys(ctx context.Context, req *proto.RotateStackKeysRequest) (*proto.RotateStackKeysResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.RotateStackKeysResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) CreateStackApiKey(ctx context.Context, req *proto.CreateStackApiKeyRequest) (*proto.CreateStackApiKeyResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.CreateStackApiKeyResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) ListStackApiKeys(ctx context.Context, req *proto.StackId) (*proto.ListStackApiKeysResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.ListStackApiKeysResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RevokeStackApiKey(ctx context.Context, req *proto.RevokeStackApiKeyRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) SetStackRateLimits(ctx context.Context, req *proto.SetStackRateLimitsRequest) (*proto.Stack, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Stack), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetStackRequestUsage(ctx context.Context, req *proto.StackId) (*proto.StackRequestUsage, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.StackRequestUsage), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) AddStackCustomDomain(ctx context.Context, req *proto.AddStackCustomDomainRequest) (*proto.StackCustomDomain, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.StackCustomDomain), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) VerifyStackCustomDomain(ctx context.Context, req *proto.StackCustomDomainId) (*proto.StackCustomDomain, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.StackCustomDomain), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RemoveStackCustomDomain(ctx context.Context, req *proto.StackCustomDomainId) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) SetStackPrimaryDomain(ctx context.Context, req *proto.SetStackPrimaryDomainRequest) (*proto.Stack, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Stack), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) DisableStackMultiRegion(ctx context.Context, req *proto.StackId) (*proto.Stack, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.Stack), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) SetVapiPackageAccess(ctx context.Context, req *proto.SetVapiPackageAccessRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GrantVapiPackage(ctx context.Context, req *proto.GrantVapiPackageRequest) (*proto.VapiPackageBorrower, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiPackageBorrower), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RevokeVapiPackageGrant(ctx context.Context, req *proto.RevokeVapiPackageGrantRequest) (*emptypb.Empty, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*emptypb.Empty), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetVapiPackageBorrowers(ctx context.Context, req *proto.VapiPackageId) (*proto.GetVapiPackageBorrowersResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetVapiPackageBorrowersResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) DeprecateVapiRelease(ctx context.Context, req *proto.DeprecateVapiReleaseRequest) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) SuspendVapiRelease(ctx context.Context, req *proto.SuspendVapiReleaseRequest) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) YankVapiRelease(ctx context.Context, req *proto.YankVapiReleaseRequest) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) UnpublishVapiRelease(ctx context.Context, req *proto.VapiReleaseId) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) PublishVapiRelease(ctx context.Context, req *proto.VapiReleaseId) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) RestoreVapiRelease(ctx context.Context, req *proto.VapiReleaseId) (*proto.VapiRelease, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.VapiRelease), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetStackVapiNotices(ctx context.Context, req *proto.StackId) (*proto.GetStackVapiNoticesResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetStackVapiNoticesResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetInstanceStatus(ctx context.Context, req *proto.InstanceId) (*proto.InstanceStatus, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.InstanceStatus), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetVapiPackageGithubDeliveries(ctx context.Context, req *proto.VapiPackageId) (*proto.GetGithubDeliveriesResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetGithubDeliveriesResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetStackGithubDeliveries(ctx context.Context, req *proto.StackId) (*proto.GetGithubDeliveriesResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetGithubDeliveriesResponse), args.Error(1)
+}
+
+func (c *ApiDepotServerMock) GetStackPreviews(ctx context.Context, req *proto.StackId) (*proto.GetStackPreviewsResponse, error) {
+	args := c.Called(ctx, req)
+	return args.Get(0).(*proto.GetStackPreviewsResponse), args.Error(1)
+}
+
 var (
 	_ proto.ApiDepotServer = (*ApiDepotServerMock)(nil)
 )
@@ -0,0 +1,95 @@
+package proto
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/emptypb"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *apiDepotServer) SetVapiPackageAccess(ctx context.Context, req *SetVapiPackageAccessRequest) (*emptypb.Empty, error) {
+	access, err := req.Access.ToDomain()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.vapiService.SetPackageAccess(ctx, uint(req.PackageId), access); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *apiDepotServer) GrantVapiPackage(ctx context.Context, req *GrantVapiPackageRequest) (*VapiPackageBorrower, error) {
+	input := vapi.GrantPackageInput{
+		UserAuthID: req.UserAuthId,
+	}
+	if req.StackId != nil {
+		input.StackID = gog.PtrOf(uint(*req.StackId))
+	}
+	if req.OrganizationId != nil {
+		input.OrganizationID = gog.PtrOf(uint(*req.OrganizationId))
+	}
+
+	borrower, err := s.vapiService.GrantPackage(ctx, uint(req.PackageId), input)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiPackageBorrowerPbFromDb(borrower), nil
+}
+
+func (s *apiDepotServer) RevokeVapiPackageGrant(ctx context.Context, req *RevokeVapiPackageGrantRequest) (*emptypb.Empty, error) {
+	if err := s.vapiService.RevokePackageGrant(ctx, uint(req.PackageId), uint(req.BorrowerId)); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (s *apiDepotServer) GetVapiPackageBorrowers(ctx context.Context, id *VapiPackageId) (*GetVapiPackageBorrowersResponse, error) {
+	borrowers, err := s.vapiService.GetPackageBorrowers(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetVapiPackageBorrowersResponse{
+		Borrowers: gog.Map(borrowers, func(borrower domain.VapiPackageBorrower) *VapiPackageBorrower {
+			return newVapiPackageBorrowerPbFromDb(&borrower)
+		}),
+	}, nil
+}
+
+func (a VapiPackageAccess) ToDomain() (domain.VapiPackageAccess, error) {
+	switch a {
+	case VapiPackageAccess_VapiPackageAccessPublic:
+		return domain.VapiPackageAccessPublic, nil
+	case VapiPackageAccess_VapiPackageAccessPrivate:
+		return domain.VapiPackageAccessPrivate, nil
+	case VapiPackageAccess_VapiPackageAccessUnlisted:
+		return domain.VapiPackageAccessUnlisted, nil
+	default:
+		return "", errors.Wrapf(tclerrors.ErrBadRequest, "invalid access %d", a)
+	}
+}
+
+func newVapiPackageBorrowerPbFromDb(borrower *domain.VapiPackageBorrower) *VapiPackageBorrower {
+	pb := &VapiPackageBorrower{
+		Id:             int32(borrower.ID),
+		PackageId:      int32(borrower.VapiPackageID),
+		OrganizationId: newOrganizationIdPbFromDb(borrower.OrganizationID),
+		CreatedAt:      tspb.New(borrower.CreatedAt),
+	}
+	if borrower.StackID != nil {
+		pb.StackId = gog.PtrOf(int32(*borrower.StackID))
+	}
+	if borrower.User != nil {
+		pb.UserAuthId = gog.PtrOf(borrower.User.AuthUserId)
+	}
+
+	return pb
+}
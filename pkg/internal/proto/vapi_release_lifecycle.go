@@ -0,0 +1,83 @@
+package proto
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	tspb "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *apiDepotServer) DeprecateVapiRelease(ctx context.Context, req *DeprecateVapiReleaseRequest) (*VapiRelease, error) {
+	rel, err := s.vapiService.DeprecateRelease(ctx, uint(req.ReleaseId), req.Message)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) SuspendVapiRelease(ctx context.Context, req *SuspendVapiReleaseRequest) (*VapiRelease, error) {
+	rel, err := s.vapiService.SuspendRelease(ctx, uint(req.ReleaseId), req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) YankVapiRelease(ctx context.Context, req *YankVapiReleaseRequest) (*VapiRelease, error) {
+	rel, err := s.vapiService.YankRelease(ctx, uint(req.ReleaseId), req.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) UnpublishVapiRelease(ctx context.Context, id *VapiReleaseId) (*VapiRelease, error) {
+	rel, err := s.vapiService.UnpublishRelease(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) PublishVapiRelease(ctx context.Context, id *VapiReleaseId) (*VapiRelease, error) {
+	rel, err := s.vapiService.PublishRelease(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) RestoreVapiRelease(ctx context.Context, id *VapiReleaseId) (*VapiRelease, error) {
+	rel, err := s.vapiService.RestoreRelease(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return newVapiReleasePbFromDb(rel), nil
+}
+
+func (s *apiDepotServer) GetStackVapiNotices(ctx context.Context, id *StackId) (*GetStackVapiNoticesResponse, error) {
+	notices, err := s.stackService.GetVapiNotices(ctx, uint(id.Id))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GetStackVapiNoticesResponse{
+		Notices: gog.Map(notices, func(notice domain.StackVapiNotice) *StackVapiNotice {
+			return &StackVapiNotice{
+				Id:          int32(notice.ID),
+				StackId:     int32(notice.StackID),
+				PackageName: notice.VapiRelease.Package.Name,
+				Vapi:        newVapiReleasePbFromDb(&notice.VapiRelease),
+				Event:       string(notice.Event),
+				Message:     notice.Message,
+				CreatedAt:   tspb.New(notice.CreatedAt),
+			}
+		}),
+	}, nil
+}
@@ -19,6 +19,19 @@ func (s *apiDepotServer) DeleteVapiPackage(ctx context.Context, id *VapiPackageI
 	return &emptypb.Empty{}, nil
 }
 
+func (s *apiDepotServer) TransferVapiPackage(ctx context.Context, req *TransferVapiPackageRequest) (*emptypb.Empty, error) {
+	var organizationId *uint
+	if req.OrganizationId != nil {
+		organizationId = gog.PtrOf(uint(*req.OrganizationId))
+	}
+
+	if err := s.vapiService.TransferPackage(ctx, uint(req.PackageId), organizationId); err != nil {
+		return nil, err
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
 func (s *apiDepotServer) DeleteAllVapiReleasesInPackage(ctx context.Context, id *VapiPackageId) (*emptypb.Empty, error) {
 	if err := s.vapiService.DeleteReleasesByPackageId(ctx, uint(id.GetId())); err != nil {
 		return nil, err
@@ -0,0 +1,79 @@
+package secrets
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+const (
+	envelopeSeparator = ":"
+	// envelopePrefix starts every encrypted secret: enc:v1:<key id>:<wrapped data key>:<ciphertext>
+	envelopePrefix = "enc" + envelopeSeparator + "v1" + envelopeSeparator
+	dataKeySize    = 32
+)
+
+// Encrypt encrypts the plaintext with a new data key wrapped by the primary key of the provider.
+func Encrypt(ctx context.Context, provider KeyProvider, plaintext []byte) (string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", errors.Wrapf(err, "failed to generate data key")
+	}
+
+	keyId, wrapped, err := provider.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	ciphertext, err := util.EncryptAES(dataKey, plaintext)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to encrypt")
+	}
+
+	return envelopePrefix + strings.Join([]string{
+		keyId,
+		base64.StdEncoding.EncodeToString(wrapped),
+		base64.StdEncoding.EncodeToString(ciphertext),
+	}, envelopeSeparator), nil
+}
+
+func Decrypt(ctx context.Context, provider KeyProvider, envelope string) ([]byte, error) {
+	parts := strings.Split(strings.TrimPrefix(envelope, envelopePrefix), envelopeSeparator)
+	if !IsEncrypted(envelope) || len(parts) != 3 {
+		return nil, errors.New("invalid encrypted secret")
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode data key")
+	}
+
+	ciphertext, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decode ciphertext")
+	}
+
+	dataKey, err := provider.UnwrapKey(ctx, parts[0], wrapped)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := util.DecryptAES(dataKey, ciphertext)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to decrypt")
+	}
+
+	return plaintext, nil
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, envelopePrefix)
+}
+
+// KeyIdPrefix is the prefix of the secrets encrypted with the given key, to find the ones to re-encrypt.
+func KeyIdPrefix(keyId string) string {
+	return envelopePrefix + keyId + envelopeSeparator
+}
@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/habiliai/apidepot/pkg/internal/util"
+	"github.com/pkg/errors"
+	"os"
+	"strings"
+)
+
+// KeyProvider wraps the data keys encrypting each secret with its master keys.
+// It is implemented by a local key file for now, and can be backed by a KMS later.
+type KeyProvider interface {
+	// PrimaryKeyId returns the id of the master key wrapping new data keys.
+	PrimaryKeyId() string
+	WrapKey(ctx context.Context, dataKey []byte) (keyId string, wrapped []byte, err error)
+	UnwrapKey(ctx context.Context, keyId string, wrapped []byte) ([]byte, error)
+}
+
+type (
+	// LocalKeyFile is the json file of the master keys.
+	// Old keys are kept in it after rotation, so that secrets which are not re-encrypted yet can be read.
+	LocalKeyFile struct {
+		PrimaryKeyId string            `json:"primary_key_id"`
+		Keys         map[string]string `json:"keys"` // key id -> base64 encoded key
+	}
+
+	localKeyProvider struct {
+		primaryKeyId string
+		keys         map[string][]byte
+	}
+)
+
+func NewLocalKeyProvider(path string) (KeyProvider, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key file")
+	}
+
+	var keyFile LocalKeyFile
+	if err := json.Unmarshal(contents, &keyFile); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse key file")
+	}
+
+	p := &localKeyProvider{
+		primaryKeyId: keyFile.PrimaryKeyId,
+		keys:         make(map[string][]byte, len(keyFile.Keys)),
+	}
+	for keyId, encoded := range keyFile.Keys {
+		if keyId == "" || strings.Contains(keyId, envelopeSeparator) {
+			return nil, errors.Errorf("invalid key id '%s'", keyId)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode key '%s'", keyId)
+		} else if len(key) < 32 {
+			return nil, errors.Errorf("key '%s' must be at least 32 bytes", keyId)
+		}
+		p.keys[keyId] = key
+	}
+
+	if _, ok := p.keys[p.primaryKeyId]; !ok {
+		return nil, errors.Errorf("primary key '%s' not found in key file", p.primaryKeyId)
+	}
+
+	return p, nil
+}
+
+func (p *localKeyProvider) PrimaryKeyId() string {
+	return p.primaryKeyId
+}
+
+func (p *localKeyProvider) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := util.EncryptAES(p.keys[p.primaryKeyId], dataKey)
+	if err != nil {
+		return "", nil, errors.Wrapf(err, "failed to wrap data key")
+	}
+
+	return p.primaryKeyId, wrapped, nil
+}
+
+func (p *localKeyProvider) UnwrapKey(_ context.Context, keyId string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyId]
+	if !ok {
+		return nil, errors.Errorf("key '%s' not found", keyId)
+	}
+
+	dataKey, err := util.DecryptAES(key, wrapped)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to unwrap data key")
+	}
+
+	return dataKey, nil
+}
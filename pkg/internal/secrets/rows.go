@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"fmt"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"slices"
+	"strings"
+)
+
+const batchSize = 100
+
+// secretTable lists the columns of a table which are encrypted by the serializer.
+type secretTable struct {
+	name        string
+	textColumns []string
+	jsonColumns []string
+	save        func(db *gorm.DB, name string, cond any, columns []string) (int, error)
+}
+
+var secretTables = []secretTable{
+	{
+		name:        "stack",
+		textColumns: []string{"admin_api_key"},
+		jsonColumns: []string{"db", "auth", "vapi_env_vars"},
+		save:        saveRowsWhere[domain.Stack],
+	},
+	{
+		name:        "user",
+		textColumns: []string{"github_access_token"},
+		save:        saveRowsWhere[domain.User],
+	},
+	{
+		name:        "instance",
+		textColumns: []string{"applied_k8s_yaml", "previous_k8s_yaml"},
+		save:        saveRowsWhere[domain.Instance],
+	},
+	{
+		name:        "instance revision",
+		textColumns: []string{"k8s_yaml"},
+		jsonColumns: []string{"vapi_env_vars"},
+		save:        saveRowsWhere[domain.InstanceRevision],
+	},
+}
+
+// EncryptPlaintextSecrets encrypts the secrets which were stored in plaintext before the encryption was enabled.
+func EncryptPlaintextSecrets(db *gorm.DB) (int, error) {
+	return saveSecretsWithoutPrefix(db, envelopePrefix)
+}
+
+// ReencryptSecrets encrypts again the secrets which are not encrypted with the primary key.
+// It is run after the primary key is rotated, so that the old key can be removed from the key file.
+func ReencryptSecrets(db *gorm.DB) (int, error) {
+	provider := GetKeyProvider()
+	if provider == nil {
+		return 0, errors.New("no key provider is configured")
+	}
+
+	return saveSecretsWithoutPrefix(db, KeyIdPrefix(provider.PrimaryKeyId()))
+}
+
+// saveSecretsWithoutPrefix saves again the secret columns of the rows having any secret which doesn't start with the
+// prefix, so that the serializer encrypts them with the primary key.
+func saveSecretsWithoutPrefix(db *gorm.DB, prefix string) (int, error) {
+	if GetKeyProvider() == nil {
+		return 0, errors.New("no key provider is configured")
+	}
+
+	prefix = escapeLike(prefix) + "%"
+
+	count := 0
+	for _, table := range secretTables {
+		conds := make([]string, 0, len(table.textColumns)+len(table.jsonColumns))
+		for _, column := range table.textColumns {
+			conds = append(conds, fmt.Sprintf("(%s <> '' AND %s NOT LIKE @prefix)", column, column))
+		}
+		// json columns keep the encrypted secret as a json string
+		for _, column := range table.jsonColumns {
+			conds = append(conds, fmt.Sprintf("(%s IS NOT NULL AND %s::text NOT LIKE @jsonPrefix)", column, column))
+		}
+
+		n, err := table.save(
+			db,
+			table.name,
+			gorm.Expr(strings.Join(conds, " OR "), map[string]any{"prefix": prefix, "jsonPrefix": `"` + prefix}),
+			slices.Concat(table.textColumns, table.jsonColumns),
+		)
+		count += n
+		if err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// saveRowsWhere saves again the columns of the matching rows.
+// Deleted rows are included, since their secrets are kept as well.
+func saveRowsWhere[T any](db *gorm.DB, name string, cond any, columns []string) (int, error) {
+	count := 0
+
+	var rows []T
+	if err := db.Unscoped().
+		Where(cond).
+		FindInBatches(&rows, batchSize, func(tx *gorm.DB, _ int) error {
+			for i := range rows {
+				if err := tx.Unscoped().
+					Model(&rows[i]).
+					Select(columns).
+					UpdateColumns(&rows[i]).Error; err != nil {
+					return errors.Wrapf(err, "failed to encrypt secrets of %s", name)
+				}
+				count++
+			}
+			return nil
+		}).Error; err != nil {
+		return count, err
+	}
+
+	return count, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,134 @@
+package secrets_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/habiliai/apidepot/pkg/internal/secrets"
+	"github.com/stretchr/testify/suite"
+	"gorm.io/datatypes"
+	"gorm.io/gorm/schema"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type SecretsTestSuite struct {
+	suite.Suite
+	context.Context
+
+	keyFilePath string
+}
+
+func TestSecrets(t *testing.T) {
+	suite.Run(t, new(SecretsTestSuite))
+}
+
+func (s *SecretsTestSuite) SetupTest() {
+	s.Context = context.TODO()
+	s.keyFilePath = filepath.Join(s.T().TempDir(), "keys.json")
+	s.writeKeyFile("key-1", "key-1")
+}
+
+func (s *SecretsTestSuite) TearDownTest() {
+	secrets.SetKeyProvider(nil)
+}
+
+func (s *SecretsTestSuite) writeKeyFile(primaryKeyId string, keyIds ...string) {
+	keyFile := secrets.LocalKeyFile{
+		PrimaryKeyId: primaryKeyId,
+		Keys:         map[string]string{},
+	}
+	for _, keyId := range keyIds {
+		keyFile.Keys[keyId] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(keyId, 8)))
+	}
+
+	contents, err := json.Marshal(keyFile)
+	s.Require().NoError(err)
+	s.Require().NoError(os.WriteFile(s.keyFilePath, contents, 0600))
+}
+
+func (s *SecretsTestSuite) TestGivenRotatedKeyFileWhenDecryptThenShouldReadSecretsOfTheOldKey() {
+	// given
+	provider, err := secrets.NewLocalKeyProvider(s.keyFilePath)
+	s.Require().NoError(err)
+	envelope, err := secrets.Encrypt(s, provider, []byte("postgres-password"))
+	s.Require().NoError(err)
+	s.True(secrets.IsEncrypted(envelope))
+	s.True(strings.HasPrefix(envelope, secrets.KeyIdPrefix("key-1")))
+
+	s.writeKeyFile("key-2", "key-1", "key-2")
+	rotated, err := secrets.NewLocalKeyProvider(s.keyFilePath)
+	s.Require().NoError(err)
+
+	// when
+	plaintext, err := secrets.Decrypt(s, rotated, envelope)
+	s.Require().NoError(err)
+	reencrypted, err := secrets.Encrypt(s, rotated, plaintext)
+	s.Require().NoError(err)
+
+	// then
+	s.Equal("postgres-password", string(plaintext))
+	s.True(strings.HasPrefix(reencrypted, secrets.KeyIdPrefix("key-2")))
+
+	s.writeKeyFile("key-3", "key-3")
+	other, err := secrets.NewLocalKeyProvider(s.keyFilePath)
+	s.Require().NoError(err)
+	_, err = secrets.Decrypt(s, other, envelope)
+	s.Error(err)
+}
+
+type secretModel struct {
+	Token  string                          `gorm:"serializer:encrypted"`
+	Values datatypes.JSONType[secretValue] `gorm:"serializer:encrypted"`
+}
+
+type secretValue struct {
+	Password string `json:"password"`
+}
+
+func (s *SecretsTestSuite) TestGivenKeyProviderWhenSerializeThenShouldStoreEncryptedAndReadPlaintext() {
+	// given
+	provider, err := secrets.NewLocalKeyProvider(s.keyFilePath)
+	s.Require().NoError(err)
+
+	sch, err := schema.Parse(&secretModel{}, &sync.Map{}, schema.NamingStrategy{})
+	s.Require().NoError(err)
+	tokenField := sch.LookUpField("Token")
+	valuesField := sch.LookUpField("Values")
+
+	model := secretModel{
+		Token:  "github-token",
+		Values: datatypes.NewJSONType(secretValue{Password: "secret"}),
+	}
+
+	// when
+	plainToken, err := secrets.Serializer{}.Value(s, tokenField, reflect.Value{}, model.Token)
+	s.Require().NoError(err)
+
+	secrets.SetKeyProvider(provider)
+	token, err := secrets.Serializer{}.Value(s, tokenField, reflect.Value{}, model.Token)
+	s.Require().NoError(err)
+	values, err := secrets.Serializer{}.Value(s, valuesField, reflect.Value{}, model.Values)
+	s.Require().NoError(err)
+
+	// then
+	s.Equal("github-token", plainToken)
+	s.True(secrets.IsEncrypted(token.(string)))
+	s.NotContains(values.(string), "secret")
+	s.True(json.Valid([]byte(values.(string))))
+
+	var read secretModel
+	dst := reflect.ValueOf(&read)
+	s.Require().NoError(secrets.Serializer{}.Scan(s, tokenField, dst, token))
+	s.Require().NoError(secrets.Serializer{}.Scan(s, valuesField, dst, []byte(values.(string))))
+	s.Equal(model.Token, read.Token)
+	s.Equal("secret", read.Values.Data().Password)
+
+	var legacy secretModel
+	s.Require().NoError(secrets.Serializer{}.Scan(s, valuesField, reflect.ValueOf(&legacy), []byte(`{"password":"plain"}`)))
+	s.Equal("plain", legacy.Values.Data().Password)
+}
@@ -0,0 +1,127 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/schema"
+	"reflect"
+	"sync"
+)
+
+// SerializerName is the gorm serializer encrypting a field, like `gorm:"serializer:encrypted"`.
+const SerializerName = "encrypted"
+
+var (
+	keyProviderLck sync.RWMutex
+	keyProvider    KeyProvider
+)
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// SetKeyProvider enables the encryption of the secrets with the provider. Secrets are stored in plaintext until it is set.
+func SetKeyProvider(provider KeyProvider) {
+	keyProviderLck.Lock()
+	defer keyProviderLck.Unlock()
+
+	keyProvider = provider
+}
+
+func GetKeyProvider() KeyProvider {
+	keyProviderLck.RLock()
+	defer keyProviderLck.RUnlock()
+
+	return keyProvider
+}
+
+// Serializer encrypts the whole value of the field. Strings are encrypted as they are, and the other types are
+// marshaled into json before. Json columns keep the encrypted secret as a json string.
+// Plaintext values stored before the encryption was enabled are read as they are.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	if dbValue != nil {
+		var raw []byte
+		switch v := dbValue.(type) {
+		case []byte:
+			raw = v
+		case string:
+			raw = []byte(v)
+		default:
+			return errors.Errorf("failed to scan secret of type %T", dbValue)
+		}
+
+		envelope := string(raw)
+		if isJsonField(field) {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				envelope = s
+			}
+		}
+
+		plaintext := raw
+		if IsEncrypted(envelope) {
+			provider := GetKeyProvider()
+			if provider == nil {
+				return errors.Errorf("failed to decrypt %s. no key provider is configured", field.Name)
+			}
+
+			var err error
+			if plaintext, err = Decrypt(ctx, provider, envelope); err != nil {
+				return errors.Wrapf(err, "failed to decrypt %s", field.Name)
+			}
+		}
+
+		if field.FieldType.Kind() == reflect.String {
+			fieldValue.Elem().SetString(string(plaintext))
+		} else if len(plaintext) > 0 {
+			if err := json.Unmarshal(plaintext, fieldValue.Interface()); err != nil {
+				return errors.Wrapf(err, "failed to unmarshal %s", field.Name)
+			}
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, _ reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	if field.FieldType.Kind() == reflect.String {
+		s := reflect.ValueOf(fieldValue).String()
+		if s == "" {
+			return "", nil
+		}
+		plaintext = []byte(s)
+	} else {
+		var err error
+		if plaintext, err = json.Marshal(fieldValue); err != nil {
+			return nil, errors.Wrapf(err, "failed to marshal %s", field.Name)
+		}
+	}
+
+	provider := GetKeyProvider()
+	if provider == nil {
+		return string(plaintext), nil
+	}
+
+	envelope, err := Encrypt(ctx, provider, plaintext)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to encrypt %s", field.Name)
+	}
+
+	if isJsonField(field) {
+		value, err := json.Marshal(envelope)
+		return string(value), errors.WithStack(err)
+	}
+
+	return envelope, nil
+}
+
+func isJsonField(field *schema.Field) bool {
+	return field.DataType == "json"
+}
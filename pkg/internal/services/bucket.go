@@ -8,6 +8,7 @@ import (
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/pkg/errors"
+	"io"
 	"strings"
 )
 
@@ -19,7 +20,10 @@ type BucketService interface {
 		ctx context.Context,
 		zone tcltypes.InstanceZone, name string,
 	) (totalSize int64, err error)
-	PutObject(ctx context.Context, zone tcltypes.InstanceZone, bucket, path string, contents []byte) error
+	// PutObject uploads the contents until EOF and returns the size of the object.
+	PutObject(ctx context.Context, zone tcltypes.InstanceZone, bucket, path string, contents io.Reader) (int64, error)
+	GetObject(ctx context.Context, zone tcltypes.InstanceZone, bucket, path string) (io.ReadCloser, error)
+	RemoveObject(ctx context.Context, zone tcltypes.InstanceZone, bucket, path string) error
 }
 
 type bucketService struct {
@@ -78,15 +82,52 @@ func (bs *bucketService) PutObject(
 	zone tcltypes.InstanceZone,
 	bucket string,
 	path string,
-	contents []byte,
+	contents io.Reader,
+) (int64, error) {
+	client, err := bs.getClient(zone)
+	if err != nil {
+		return 0, err
+	}
+
+	info, err := client.PutObject(ctx, bucket, path, contents, -1, minio.PutObjectOptions{})
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	return info.Size, nil
+}
+
+func (bs *bucketService) GetObject(
+	ctx context.Context,
+	zone tcltypes.InstanceZone,
+	bucket string,
+	path string,
+) (io.ReadCloser, error) {
+	client, err := bs.getClient(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.GetObject(ctx, bucket, path, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return object, nil
+}
+
+func (bs *bucketService) RemoveObject(
+	ctx context.Context,
+	zone tcltypes.InstanceZone,
+	bucket string,
+	path string,
 ) error {
 	client, err := bs.getClient(zone)
 	if err != nil {
 		return err
 	}
 
-	_, err = client.PutObject(ctx, bucket, path, strings.NewReader(string(contents)), int64(len(contents)), minio.PutObjectOptions{})
-	return errors.WithStack(err)
+	return errors.WithStack(client.RemoveObject(ctx, bucket, path, minio.RemoveObjectOptions{}))
 }
 
 func (bs *bucketService) CreateBucket(
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"net"
+)
+
+// DNSResolver looks up the DNS records, e.g. to verify the ownership of the custom domains of the stacks.
+type DNSResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+const (
+	ServiceKeyDNSResolver = "dnsResolver"
+)
+
+func NewDNSResolver() DNSResolver {
+	return net.DefaultResolver
+}
+
+func init() {
+	digo.ProvideService(ServiceKeyDNSResolver, func(ctx *digo.Container) (interface{}, error) {
+		return NewDNSResolver(), nil
+	})
+}
@@ -101,7 +101,7 @@ func (s *gitService) Clone(
 
 	cloneOptions := git.CloneOptions{
 		URL:           gitUrl,
-		ReferenceName: plumbing.NewBranchReferenceName(gitBranch),
+		ReferenceName: referenceNameOf(gitBranch),
 		SingleBranch:  true,
 		Auth:          auth,
 	}
@@ -257,6 +257,15 @@ func (s *gitService) CopyRepo(
 	return nil
 }
 
+// referenceNameOf takes a full reference like refs/tags/v1.0.0 as it is, and a name without refs/ as a branch.
+func referenceNameOf(ref string) plumbing.ReferenceName {
+	if strings.HasPrefix(ref, "refs/") {
+		return plumbing.ReferenceName(ref)
+	}
+
+	return plumbing.NewBranchReferenceName(ref)
+}
+
 func GitCloneOptionsAllowCommit() func(*CloneOptions) {
 	return func(o *CloneOptions) {
 		o.allowCommit = true
@@ -49,6 +49,20 @@ type GithubClient interface {
 		repoName string,
 		description string,
 	) error
+	CreateIssueComment(
+		ctx context.Context,
+		accessToken string,
+		gitRepo string,
+		number int,
+		body string,
+	) (int64, error)
+	EditIssueComment(
+		ctx context.Context,
+		accessToken string,
+		gitRepo string,
+		commentId int64,
+		body string,
+	) error
 }
 
 type githubClient struct {
@@ -291,6 +305,52 @@ func (g *githubClient) CreateRepository(
 	return nil
 }
 
+// CreateIssueComment comments on the issue or the pull request of gitRepo like owner/name. It returns the id of the comment.
+func (g *githubClient) CreateIssueComment(
+	ctx context.Context,
+	accessToken string,
+	gitRepo string,
+	number int,
+	body string,
+) (int64, error) {
+	owner, repoName, ok := strings.Cut(gitRepo, "/")
+	if !ok {
+		return 0, errors.Errorf("invalid git repo %s", gitRepo)
+	}
+
+	githubClient := github.NewClient(nil).WithAuthToken(accessToken)
+	comment, _, err := githubClient.Issues.CreateComment(ctx, owner, repoName, number, &github.IssueComment{
+		Body: github.String(body),
+	})
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to create issue comment")
+	}
+
+	return comment.GetID(), nil
+}
+
+func (g *githubClient) EditIssueComment(
+	ctx context.Context,
+	accessToken string,
+	gitRepo string,
+	commentId int64,
+	body string,
+) error {
+	owner, repoName, ok := strings.Cut(gitRepo, "/")
+	if !ok {
+		return errors.Errorf("invalid git repo %s", gitRepo)
+	}
+
+	githubClient := github.NewClient(nil).WithAuthToken(accessToken)
+	if _, _, err := githubClient.Issues.EditComment(ctx, owner, repoName, commentId, &github.IssueComment{
+		Body: github.String(body),
+	}); err != nil {
+		return errors.Wrapf(err, "failed to edit issue comment")
+	}
+
+	return nil
+}
+
 func init() {
 	digo.ProvideService(ServiceKeyGithubClient, func(ctx *digo.Container) (any, error) {
 		switch ctx.Env {
@@ -4,6 +4,7 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	tclog "github.com/habiliai/apidepot/pkg/internal/log"
+	"github.com/habiliai/apidepot/pkg/internal/secrets"
 	"github.com/habiliai/apidepot/pkg/internal/util"
 	"github.com/pkg/errors"
 	"gorm.io/driver/postgres"
@@ -17,11 +18,29 @@ func init() {
 	digo.ProvideService(ServiceKeyDB, func(ctx *digo.Container) (any, error) {
 		switch ctx.Env {
 		case digo.EnvProd:
+			if ctx.Config.Secrets.KeyFile != "" {
+				keyProvider, err := secrets.NewLocalKeyProvider(ctx.Config.Secrets.KeyFile)
+				if err != nil {
+					return nil, err
+				}
+				secrets.SetKeyProvider(keyProvider)
+			} else {
+				logger.Warn("secrets are stored in plaintext since no key file is given")
+			}
+
 			db, err := util.NewDBFromConfig(ctx, ctx.Config.DB)
 			logger.Debug("new", "db", db)
 			if err != nil {
 				return nil, err
 			}
+
+			if ctx.Config.DB.AutoMigration && secrets.GetKeyProvider() != nil {
+				if count, err := secrets.EncryptPlaintextSecrets(db); err != nil {
+					return nil, err
+				} else if count > 0 {
+					logger.Info("encrypted plaintext secrets", "count", count)
+				}
+			}
 			go func() {
 				<-ctx.Done()
 				logger.Info("closing database")
@@ -2,10 +2,12 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"github.com/habiliai/apidepot/pkg/config"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/pkg/errors"
 	"strings"
 	"text/template"
@@ -17,14 +19,17 @@ type RuntimeSchema struct {
 	stackSQLTemplates    map[string][]*template.Template
 	installRoleTemplates []*template.Template
 	afterCreateUserTmpl  *template.Template
+	// replicationTemplates set up the logical replication of the databases of the multi-region stacks
+	replicationTemplates map[string]*template.Template
 }
 
 func NewRuntimeSchema(
 	dbConfig config.DBConfig,
 ) (*RuntimeSchema, error) {
 	rs := &RuntimeSchema{
-		dbConfig:          dbConfig,
-		stackSQLTemplates: map[string][]*template.Template{},
+		dbConfig:             dbConfig,
+		stackSQLTemplates:    map[string][]*template.Template{},
+		replicationTemplates: map[string]*template.Template{},
 	}
 
 	{
@@ -81,6 +86,48 @@ ALTER SCHEMA public OWNER TO {{ .Username }};
 
 		rs.afterCreateUserTmpl = tmpl
 	}
+	{
+		replicationSqls := map[string]string{
+			"createPublication":  "CREATE PUBLICATION {{ .Name }} FOR ALL TABLES",
+			"dropPublication":    "DROP PUBLICATION IF EXISTS {{ .Name }}",
+			"createSubscription": "CREATE SUBSCRIPTION {{ .Name }} CONNECTION '{{ .ConnInfo }}' PUBLICATION {{ .Publication }}",
+			"dropSubscription":   "DROP SUBSCRIPTION IF EXISTS {{ .Name }}",
+			// the tables of the database not subscribed yet, except the unlogged ones and the ones of the extensions
+			// which are not replicated, are emptied before subscribing to them
+			"truncateUnsubscribedTables": `DO $$
+DECLARE
+    tables TEXT;
+BEGIN
+    SELECT string_agg(c.oid::regclass::text, ', ')
+    INTO tables
+    FROM pg_class c
+             JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE c.relkind = 'r'
+      AND c.relpersistence = 'p'
+      AND n.nspname <> 'information_schema'
+      AND n.nspname NOT LIKE 'pg\_%'
+      AND NOT EXISTS (SELECT 1 FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+      AND NOT EXISTS (SELECT 1
+                      FROM pg_subscription_rel sr
+                               JOIN pg_subscription s ON s.oid = sr.srsubid
+                      WHERE s.subname = '{{ .Name }}'
+                        AND sr.srrelid = c.oid);
+    IF tables IS NOT NULL THEN
+        EXECUTE 'TRUNCATE ' || tables;
+    END IF;
+END
+$$`,
+			"refreshSubscription": "ALTER SUBSCRIPTION {{ .Name }} REFRESH PUBLICATION WITH (copy_data = true)",
+		}
+
+		for key, sql := range replicationSqls {
+			tmpl, err := template.New("").Parse(sql)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to parse sql template. key: %s", key)
+			}
+			rs.replicationTemplates[key] = tmpl
+		}
+	}
 
 	return rs, nil
 }
@@ -198,3 +245,82 @@ func (rs *RuntimeSchema) DropUserAndDB(ctx context.Context, region tcltypes.Inst
 		DBName   string
 	}{username, dbname})
 }
+
+// execInDB executes the replication sql in the database of the zone as the admin.
+func (rs *RuntimeSchema) execInDB(ctx context.Context, zone tcltypes.InstanceZone, dbname string, key string, values any) error {
+	conn, err := pgx.Connect(ctx, rs.dbConfig.GetRegionalConfig(zone).WithDBName(dbname).GetURI())
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to db")
+	}
+	defer conn.Close(ctx)
+
+	return exec(ctx, conn, rs.replicationTemplates[key], values)
+}
+
+// subscriptionName is unique in each zone, since it names the replication slot in the primary zone as well.
+func subscriptionName(zone tcltypes.InstanceZone, dbname string) string {
+	return dbname + "_" + strings.NewReplacer("-", "_").Replace(zone.String())
+}
+
+// CreatePublication publishes every table of the database in the zone to its replicas.
+func (rs *RuntimeSchema) CreatePublication(ctx context.Context, zone tcltypes.InstanceZone, dbname string) error {
+	return rs.execInDB(ctx, zone, dbname, "createPublication", struct{ Name string }{dbname})
+}
+
+func (rs *RuntimeSchema) DropPublication(ctx context.Context, zone tcltypes.InstanceZone, dbname string) error {
+	return rs.execInDB(ctx, zone, dbname, "dropPublication", struct{ Name string }{dbname})
+}
+
+// CreateSubscription subscribes the database in the replica zone to the publication of the database in the primary
+// zone. The tables must exist in the replica, and their rows are copied from the primary at first.
+func (rs *RuntimeSchema) CreateSubscription(
+	ctx context.Context,
+	primaryZone tcltypes.InstanceZone,
+	replicaZone tcltypes.InstanceZone,
+	dbname string,
+) error {
+	primary := rs.dbConfig.GetRegionalConfig(primaryZone)
+	return rs.execInDB(ctx, replicaZone, dbname, "createSubscription", struct {
+		Name        string
+		ConnInfo    string
+		Publication string
+	}{
+		Name: subscriptionName(replicaZone, dbname),
+		ConnInfo: fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=disable",
+			primary.Host, primary.Port, primary.User, primary.Password, dbname,
+		),
+		Publication: dbname,
+	})
+}
+
+// RefreshSubscription subscribes the database in the replica zone to the tables created in the primary zone since it
+// was subscribed, copying their rows from the primary. The tables must have been created in the replica by the same
+// migrations, and they are emptied first, since the rows the migrations have inserted are copied from the primary.
+func (rs *RuntimeSchema) RefreshSubscription(ctx context.Context, replicaZone tcltypes.InstanceZone, dbname string) error {
+	name := subscriptionName(replicaZone, dbname)
+	if err := rs.execInDB(ctx, replicaZone, dbname, "truncateUnsubscribedTables", struct{ Name string }{name}); err != nil {
+		return err
+	}
+
+	// it can't run in the same transaction with the truncation
+	return rs.execInDB(ctx, replicaZone, dbname, "refreshSubscription", struct{ Name string }{name})
+}
+
+// DropSubscription drops the subscription of the database in the replica zone with its replication slot in the primary zone.
+func (rs *RuntimeSchema) DropSubscription(ctx context.Context, replicaZone tcltypes.InstanceZone, dbname string) error {
+	return rs.execInDB(ctx, replicaZone, dbname, "dropSubscription", struct{ Name string }{subscriptionName(replicaZone, dbname)})
+}
+
+// DropReplica drops the replica of the database in the zone with its owner, if any.
+func (rs *RuntimeSchema) DropReplica(ctx context.Context, replicaZone tcltypes.InstanceZone, username, dbname string) error {
+	if err := rs.DropSubscription(ctx, replicaZone, dbname); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "3D000" { // checking the database does not exist
+			return nil
+		}
+		return err
+	}
+
+	return rs.DropUserAndDB(ctx, replicaZone, username, dbname)
+}
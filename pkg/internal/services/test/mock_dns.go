@@ -0,0 +1,24 @@
+package servicestest
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockDNSResolver struct {
+	mock.Mock
+}
+
+func (m *MockDNSResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	args := m.Called(ctx, name)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+var (
+	_ services.DNSResolver = (*MockDNSResolver)(nil)
+)
+
+func NewTestDNSResolver() *MockDNSResolver {
+	return new(MockDNSResolver)
+}
@@ -63,6 +63,16 @@ func (m *MockGithubClient) GetUser(ctx context.Context, token string) (*github.U
 	return args.Get(0).(*github.User), args.Error(1)
 }
 
+func (m *MockGithubClient) CreateIssueComment(ctx context.Context, accessToken string, gitRepo string, number int, body string) (int64, error) {
+	args := m.Called(ctx, accessToken, gitRepo, number, body)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockGithubClient) EditIssueComment(ctx context.Context, accessToken string, gitRepo string, commentId int64, body string) error {
+	args := m.Called(ctx, accessToken, gitRepo, commentId, body)
+	return args.Error(0)
+}
+
 func NewTestGithubClient() *MockGithubClient {
 	return &MockGithubClient{}
 }
@@ -0,0 +1,219 @@
+package stack
+
+import (
+	"context"
+	"github.com/Masterminds/goutils"
+	"github.com/golang-jwt/jwt/v5"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiKeyIdPrefix tells the stack api keys from the other jwts by their jti claim
+const apiKeyIdPrefix = "sak_"
+
+var (
+	apiKeyRoleRegexp      = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+	vapiMajorVersionRegex = regexp.MustCompile(`^v[0-9]+$`)
+
+	// reservedApiKeyRoles are the roles of the fixed api keys and the services of the stack
+	reservedApiKeyRoles = []string{
+		"service_role",
+		"authenticator",
+		"postgres",
+		"supabase_admin",
+		"supabase_auth_admin",
+		"supabase_storage_admin",
+	}
+)
+
+type CreateApiKeyInput struct {
+	Name string
+	// Role is the role claim of the key, which postgrest switches to
+	Role string
+	// AllowedVapis are the slugs of the vapis the key can call, e.g. "sns/v2". Every vapi is allowed if empty.
+	AllowedVapis []string
+	// ExpiresAt is when the key expires. The key never expires if nil.
+	ExpiresAt *time.Time
+}
+
+// CreateApiKey signs a new api key of the stack with its jwt secret. The key is returned only once, since it is not stored.
+// Keys are signed with the current jwt secret, so they are invalidated once the secret is rotated and the previous one is retired.
+func (ss *service) CreateApiKey(
+	ctx context.Context,
+	stackId uint,
+	input CreateApiKeyInput,
+) (*domain.StackApiKey, string, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, "", err
+	}
+
+	if !stack.AuthEnabled {
+		return nil, "", errors.Wrapf(tclerrors.ErrPreconditionRequired, "auth is not created")
+	}
+
+	if input.Name == "" {
+		return nil, "", errors.Wrapf(tclerrors.ErrBadRequest, "name is required")
+	} else if !apiKeyRoleRegexp.MatchString(input.Role) {
+		return nil, "", errors.Wrapf(tclerrors.ErrBadRequest, "invalid role '%s'", input.Role)
+	} else if slices.Contains(reservedApiKeyRoles, input.Role) {
+		return nil, "", errors.Wrapf(tclerrors.ErrBadRequest, "role '%s' is reserved", input.Role)
+	} else if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", errors.Wrapf(tclerrors.ErrBadRequest, "expiry must be in the future")
+	}
+
+	user, err := ss.users.GetUser(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyId, err := goutils.CryptoRandomAlphaNumeric(24)
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to generate random hash")
+	}
+
+	key := domain.StackApiKey{
+		StackID:      stack.ID,
+		KeyID:        apiKeyIdPrefix + keyId,
+		Name:         input.Name,
+		Role:         input.Role,
+		AllowedVapis: datatypes.NewJSONSlice(input.AllowedVapis),
+		ExpiresAt:    input.ExpiresAt,
+		CreatedByID:  user.ID,
+	}
+
+	claims := jwt.MapClaims{
+		"role": key.Role,
+		"jti":  key.KeyID,
+		"iat":  time.Now().Unix(),
+	}
+	if len(input.AllowedVapis) > 0 {
+		claims["vapis"] = input.AllowedVapis
+	}
+	if key.ExpiresAt != nil {
+		claims["exp"] = key.ExpiresAt.Unix()
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(stack.Auth.Data().JWTSecret))
+	if err != nil {
+		return nil, "", errors.Wrapf(err, "failed to sign jwt")
+	}
+
+	if err := key.Save(helpers.GetTx(ctx)); err != nil {
+		return nil, "", err
+	}
+
+	return &key, token, nil
+}
+
+func (ss *service) ListApiKeys(ctx context.Context, stackId uint) ([]domain.StackApiKey, error) {
+	if _, err := ss.GetStack(ctx, stackId); err != nil {
+		return nil, err
+	}
+
+	return domain.FindStackApiKeysByStackId(helpers.GetTx(ctx), stackId)
+}
+
+// RevokeApiKey revokes the api key, which is rejected at the ingress of the stack from then on. It fails unless the
+// ingresses verify the api keys, i.e. stack.apiKeyVerifyUrl is set, since the key would keep working otherwise.
+// The keys of such a stack are invalidated only by rotating its jwt secret.
+func (ss *service) RevokeApiKey(ctx context.Context, stackId uint, apiKeyId uint) error {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return err
+	}
+
+	if ss.stackConfig.ApiKeyVerifyURL == "" {
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "api keys are not verified at the ingresses, so revoked keys would keep working. rotate the stack keys instead")
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return err
+	}
+
+	tx := helpers.GetTx(ctx)
+	key, err := domain.FindStackApiKeyById(tx, apiKeyId)
+	if err != nil {
+		return err
+	} else if key.StackID != stack.ID {
+		return errors.Wrapf(tclerrors.ErrNotFound, "api key not found in the stack. apiKeyId=%d", apiKeyId)
+	} else if key.Revoked {
+		return nil
+	}
+
+	return key.Revoke(tx)
+}
+
+// VerifyApiKey checks the token of a request to the uri of the stack, on behalf of the ingress of the stack.
+// Tokens other than the stack api keys are let through, since they are verified by the services of the stack.
+func (ss *service) VerifyApiKey(ctx context.Context, stackId uint, token string, uri string) error {
+	var claims jwt.MapClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return nil
+	}
+
+	keyId, _ := claims["jti"].(string)
+	if !strings.HasPrefix(keyId, apiKeyIdPrefix) {
+		return nil
+	}
+
+	key, err := domain.FindStackApiKeyByKeyId(helpers.GetTx(ctx), keyId)
+	if errors.Is(err, tclerrors.ErrNotFound) {
+		return errors.Wrapf(tclerrors.ErrUnauthorized, "unknown api key")
+	} else if err != nil {
+		return err
+	}
+
+	if key.StackID != stackId {
+		return errors.Wrapf(tclerrors.ErrUnauthorized, "api key is not of the stack")
+	} else if !key.IsValid(time.Now()) {
+		return errors.Wrapf(tclerrors.ErrUnauthorized, "api key is revoked or expired")
+	}
+
+	if slug, ok := getVapiSlugFromUri(uri); ok && !key.AllowsVapi(slug) {
+		return errors.Wrapf(tclerrors.ErrForbidden, "api key is not allowed to call vapi '%s'", slug)
+	}
+
+	return nil
+}
+
+// getVapiSlugFromUri returns the slug of the vapi or the name of the custom vapi the uri is routed to, if any.
+func getVapiSlugFromUri(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", false
+	}
+	path := strings.TrimPrefix(u.Path, constants.PathPreview)
+
+	if rest, ok := strings.CutPrefix(path, constants.PathCustomVapis+"/"); ok {
+		name, _, _ := strings.Cut(rest, "/")
+		return name, name != ""
+	}
+
+	rest, ok := strings.CutPrefix(path, constants.PathVapis+"/")
+	if !ok {
+		return "", false
+	}
+
+	segments := strings.SplitN(rest, "/", 3)
+	if segments[0] == "" {
+		return "", false
+	} else if len(segments) > 1 && vapiMajorVersionRegex.MatchString(segments[1]) {
+		return segments[0] + "/" + segments[1], true
+	}
+
+	return segments[0], true
+}
@@ -0,0 +1,50 @@
+package stack_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+)
+
+func (s *StackServiceTestSuite) TestGivenAuthEnabledStackWhenCreateApiKeyThenShouldBeVerifiedUntilRevoked() {
+	// given
+	s.installAuth(s.Context, s.stack)
+	defer s.uninstallAuth(s.Context, s.stack)
+
+	// when
+	apiKey, token, err := s.stackService.CreateApiKey(s, s.stack.ID, stack.CreateApiKeyInput{
+		Name:         "partner",
+		Role:         "partner",
+		AllowedVapis: []string{"sns/v2"},
+	})
+
+	// then
+	s.Require().NoError(err)
+	s.NotEmpty(token)
+	s.NoError(s.stackService.VerifyApiKey(s, s.stack.ID, token, "/vapis/v1/sns/v2/posts"))
+	s.ErrorIs(s.stackService.VerifyApiKey(s, s.stack.ID, token, "/vapis/v1/storage/files"), tclerrors.ErrForbidden)
+	s.ErrorIs(s.stackService.VerifyApiKey(s, s.stack.ID+1, token, "/vapis/v1/sns/v2/posts"), tclerrors.ErrUnauthorized)
+	s.NoError(s.stackService.VerifyApiKey(s, s.stack.ID, s.stack.AnonApiKey, "/vapis/v1/storage/files"))
+
+	apiKeys, err := s.stackService.ListApiKeys(s, s.stack.ID)
+	s.Require().NoError(err)
+	s.Require().Len(apiKeys, 1)
+	s.Equal(apiKey.KeyID, apiKeys[0].KeyID)
+
+	s.Require().NoError(s.stackService.RevokeApiKey(s, s.stack.ID, apiKey.ID))
+	s.ErrorIs(s.stackService.VerifyApiKey(s, s.stack.ID, token, "/vapis/v1/sns/v2/posts"), tclerrors.ErrUnauthorized)
+}
+
+func (s *StackServiceTestSuite) TestGivenAuthEnabledStackWhenCreateApiKeyWithReservedRoleShouldBeError() {
+	// given
+	s.installAuth(s.Context, s.stack)
+	defer s.uninstallAuth(s.Context, s.stack)
+
+	// when
+	_, _, err := s.stackService.CreateApiKey(s, s.stack.ID, stack.CreateApiKeyInput{
+		Name: "admin",
+		Role: "service_role",
+	})
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrBadRequest)
+}
@@ -0,0 +1,348 @@
+package stack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+const (
+	backupSchedulerPollInterval = 10 * time.Minute
+	// backupLockKey namespaces the advisory locks taken while backing up a stack, so that only one server does it
+	backupLockKey = 0x6261636b
+)
+
+// CreateBackup dumps the database of the stack into the backup bucket of its region.
+// A failed backup is returned with its error instead of failing the call, so that it is recorded.
+func (ss *service) CreateBackup(ctx context.Context, stackId uint) (*domain.StackBackup, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	backup, err := ss.backupDatabase(ctx, stack, domain.StackBackupKindManual)
+	if backup != nil && err != nil {
+		logger.Warn("failed to back up stack", "stackId", stackId, "err", err)
+		return backup, nil
+	}
+
+	return backup, err
+}
+
+// ListBackups returns the backups of the stack. The backups of a deleted stack are listed as well, so that its final
+// snapshot can be found.
+func (ss *service) ListBackups(ctx context.Context, stackId uint) ([]domain.StackBackup, error) {
+	tx := helpers.GetTx(ctx)
+	stack, err := domain.FindStackByID(tx.Unscoped(), stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return domain.FindStackBackupsByStackId(tx, stackId)
+}
+
+// RestoreBackup replaces the database of the stack with the backup.
+// The backup may be one of a deleted stack of the same project, to recover its final snapshot.
+// The current database is backed up before, so that the restore can be undone.
+func (ss *service) RestoreBackup(ctx context.Context, stackId uint, backupId uint) error {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	tx := helpers.GetTx(ctx)
+	backup, err := domain.FindStackBackupById(tx, backupId)
+	if err != nil {
+		return err
+	} else if backup.Status != domain.StackBackupStatusSucceeded {
+		return errors.Wrapf(tclerrors.ErrPreconditionFailed, "backup is %s", backup.Status)
+	}
+
+	if backup.StackID != stack.ID {
+		source, err := domain.FindStackByID(tx.Unscoped(), backup.StackID)
+		if err != nil {
+			return err
+		} else if source.DeletedAt == 0 || source.ProjectID != stack.ProjectID {
+			return errors.Wrapf(tclerrors.ErrNotFound, "backup not found in the stack. backupId=%d", backupId)
+		}
+	}
+
+	if _, err := ss.backupDatabase(ctx, stack, domain.StackBackupKindPreRestore); err != nil {
+		return errors.Wrapf(err, "failed to back up before restoring")
+	}
+
+	dump, err := ss.bucketService.GetObject(ctx, backup.Region, ss.stackConfig.Backup.Bucket, backup.ObjectPath)
+	if err != nil {
+		return errors.Wrapf(err, "failed to download backup")
+	}
+	defer dump.Close()
+
+	if err := ss.runPgTool(ctx, stack, stack.DefaultRegion, dump, nil, "pg_restore", "--clean", "--if-exists", "--no-owner", "--single-transaction"); err != nil {
+		return errors.Wrapf(err, "failed to restore backup")
+	}
+
+	conn, err := ss.connectDatabase(ctx, stack)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	if err := ss.reloadPostgrestSchema(ctx, conn, stack); err != nil {
+		return err
+	}
+
+	// the restore drops and creates the tables again, which logical replication doesn't follow, so the replicas are recreated
+	stack.ReplicaSchemaHash = ""
+	return ss.syncReplicas(ctx, stack, nil)
+}
+
+// RunBackupScheduler backs up the databases of the stacks every configured interval until ctx is done.
+// The transaction in ctx is used as the database connection.
+func (ss *service) RunBackupScheduler(ctx context.Context) error {
+	if ss.stackConfig.Backup.Bucket == "" {
+		logger.Info("stack backups are disabled")
+		return nil
+	}
+
+	ticker := time.NewTicker(backupSchedulerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ss.runScheduledBackups(ctx); err != nil {
+			logger.Warn("failed to run scheduled backups", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (ss *service) runScheduledBackups(ctx context.Context) error {
+	db := helpers.GetTx(ctx)
+	stackIds, err := domain.FindStackIdsDueForBackup(db, time.Now().Add(-ss.stackConfig.Backup.Interval))
+	if err != nil {
+		return err
+	}
+
+	for _, stackId := range stackIds {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		var (
+			stack  *domain.Stack
+			backup *domain.StackBackup
+		)
+		// the backup is claimed in a short transaction. its running record keeps the other servers from backing up
+		// the stack, so that neither the transaction nor the lock is held while dumping.
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			var locked bool
+			if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?, ?)", backupLockKey, stackId).Scan(&locked).Error; err != nil {
+				return errors.Wrapf(err, "failed to lock stack for backup")
+			} else if !locked {
+				return nil
+			}
+
+			// another server may have backed it up in the meantime
+			if ids, err := domain.FindStackIdsDueForBackup(tx.Where("stacks.id = ?", stackId), time.Now().Add(-ss.stackConfig.Backup.Interval)); err != nil {
+				return err
+			} else if len(ids) == 0 {
+				return nil
+			}
+
+			var err error
+			if stack, err = domain.FindStackByID(tx, stackId); err != nil {
+				return err
+			}
+
+			backup, err = ss.startBackup(tx, stack, domain.StackBackupKindScheduled)
+			return err
+		}); err != nil {
+			logger.Warn("failed to run scheduled backup", "stackId", stackId, "err", err)
+			continue
+		} else if backup == nil {
+			continue
+		}
+
+		if err := ss.finishBackup(ctx, stack, backup); err != nil {
+			// the failed backup is kept, and retried on the next poll
+			logger.Warn("failed to back up stack", "stackId", stackId, "err", err)
+			continue
+		}
+
+		if err := db.Transaction(func(tx *gorm.DB) error {
+			return ss.pruneBackups(helpers.WithTx(ctx, tx), stack)
+		}); err != nil {
+			logger.Warn("failed to prune backups", "stackId", stackId, "err", err)
+		}
+	}
+
+	return nil
+}
+
+// pruneBackups removes the scheduled backups of the stack beyond the configured retention.
+func (ss *service) pruneBackups(ctx context.Context, stack *domain.Stack) error {
+	tx := helpers.GetTx(ctx)
+	backups, err := domain.FindStackBackupsByStackId(tx, stack.ID)
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, backup := range backups {
+		if backup.Kind != domain.StackBackupKindScheduled || backup.Status != domain.StackBackupStatusSucceeded {
+			continue
+		}
+
+		kept++
+		if kept <= ss.stackConfig.Backup.Retention {
+			continue
+		}
+
+		if err := ss.bucketService.RemoveObject(ctx, backup.Region, ss.stackConfig.Backup.Bucket, backup.ObjectPath); err != nil {
+			return errors.Wrapf(err, "failed to remove backup object. id=%d", backup.ID)
+		}
+
+		if err := backup.Delete(tx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// backupDatabase dumps the database of the stack and records it as a backup of the given kind.
+// A failed backup is recorded and returned with the error.
+func (ss *service) backupDatabase(
+	ctx context.Context,
+	stack *domain.Stack,
+	kind domain.StackBackupKind,
+) (*domain.StackBackup, error) {
+	backup, err := ss.startBackup(helpers.GetTx(ctx), stack, kind)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.finishBackup(ctx, stack, backup); err != nil {
+		return backup, err
+	}
+
+	return backup, nil
+}
+
+// startBackup records a running backup of the stack.
+func (ss *service) startBackup(tx *gorm.DB, stack *domain.Stack, kind domain.StackBackupKind) (*domain.StackBackup, error) {
+	if ss.stackConfig.Backup.Bucket == "" {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionRequired, "stack backups are not configured")
+	}
+
+	backup := domain.StackBackup{
+		StackID: stack.ID,
+		Kind:    kind,
+		Status:  domain.StackBackupStatusRunning,
+		Region:  stack.DefaultRegion,
+		ObjectPath: fmt.Sprintf(
+			"%s/%s-%s.dump",
+			stack.Hash,
+			time.Now().UTC().Format("20060102150405"),
+			kind,
+		),
+	}
+	if err := backup.Save(tx); err != nil {
+		return nil, err
+	}
+
+	return &backup, nil
+}
+
+// finishBackup dumps the database of the stack into the object of the backup, and records the outcome.
+func (ss *service) finishBackup(ctx context.Context, stack *domain.Stack, backup *domain.StackBackup) error {
+	size, err := ss.uploadBackup(ctx, stack, backup)
+	if err := backup.Finish(helpers.GetTx(ctx), size, err); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(err, "failed to back up database of stack. stackId=%d", stack.ID)
+}
+
+// uploadBackup streams the dump of the database into the bucket, so that it is never held in memory.
+func (ss *service) uploadBackup(ctx context.Context, stack *domain.Stack, backup *domain.StackBackup) (int64, error) {
+	bucket := ss.stackConfig.Backup.Bucket
+	if err := ss.createBucketIfNotExists(ctx, backup.Region, bucket); err != nil {
+		return 0, errors.Wrapf(err, "failed to create backup bucket")
+	}
+
+	// pg_dump is stopped if the upload fails before reading the whole dump
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dump, w := io.Pipe()
+	defer dump.Close()
+	go func() {
+		w.CloseWithError(ss.runPgTool(ctx, stack, stack.DefaultRegion, nil, w, "pg_dump", "--format=custom", "--no-owner"))
+	}()
+
+	size, err := ss.bucketService.PutObject(ctx, backup.Region, bucket, backup.ObjectPath, dump)
+	return size, errors.Wrapf(err, "failed to upload backup")
+}
+
+// runPgTool runs a postgres client tool like pg_dump against the database of the stack in the zone as its owner.
+func (ss *service) runPgTool(
+	ctx context.Context,
+	stack *domain.Stack,
+	zone tcltypes.InstanceZone,
+	stdin io.Reader,
+	stdout io.Writer,
+	name string,
+	args ...string,
+) error {
+	regionalDbConfig := ss.dbConfig.GetRegionalConfig(zone)
+	db := stack.DB.Data()
+	args = append(
+		args,
+		"--host", regionalDbConfig.Host,
+		"--port", strconv.Itoa(regionalDbConfig.Port),
+		"--username", db.Username,
+		"--dbname", db.Name,
+	)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	// the password is passed through the environment not to be exposed in the process list
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+db.Password)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return errors.Wrapf(err, "failed to run %s: %s", name, stderr.String())
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package stack_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+)
+
+func (s *StackServiceTestSuite) TestGivenBackupsNotConfiguredWhenCreateBackupThenShouldReturnError() {
+	// when
+	_, err := s.stackService.CreateBackup(s, s.stack.ID)
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrPreconditionRequired)
+
+	backups, err := s.stackService.ListBackups(s, s.stack.ID)
+	s.Require().NoError(err)
+	s.Empty(backups)
+}
+
+func (s *StackServiceTestSuite) TestGivenDeletedStackWhenListBackupsThenShouldReturnItsFinalSnapshot() {
+	// given
+	finalSnapshot := domain.StackBackup{
+		StackID: s.stack.ID,
+		Kind:    domain.StackBackupKindFinal,
+		Status:  domain.StackBackupStatusRunning,
+	}
+	s.Require().NoError(finalSnapshot.Save(s.db))
+	s.Require().NoError(finalSnapshot.Finish(s.db, 1024, nil))
+	s.Require().NoError(s.stack.Delete(s.db))
+
+	// when
+	backups, err := s.stackService.ListBackups(s, s.stack.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.Require().Len(backups, 1)
+	s.Equal(finalSnapshot.ID, backups[0].ID)
+	s.Equal(domain.StackBackupKindFinal, backups[0].Kind)
+}
@@ -0,0 +1,184 @@
+package stack
+
+import (
+	"context"
+	"github.com/Masterminds/goutils"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/mokiat/gog"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+var hostnameRegexp = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+
+// AddCustomDomain adds the hostname to the stack unverified. The stack is served from it once its ownership is
+// verified by the TXT record of VerificationRecordName with the value of VerificationRecordValue.
+func (ss *service) AddCustomDomain(ctx context.Context, stackId uint, hostname string) (*domain.StackCustomDomain, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	hostname = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(hostname)), ".")
+	if !hostnameRegexp.MatchString(hostname) || len(hostname) > 253 {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "invalid hostname '%s'", hostname)
+	}
+	for _, regionalConfig := range []string{ss.stackConfig.Seoul.Domain, ss.stackConfig.Singapore.Domain} {
+		if regionalConfig != "" && (hostname == regionalConfig || strings.HasSuffix(hostname, "."+regionalConfig)) {
+			return nil, errors.Wrapf(tclerrors.ErrBadRequest, "hostname '%s' is reserved", hostname)
+		}
+	}
+
+	if slices.ContainsFunc(stack.CustomDomains, func(d domain.StackCustomDomain) bool {
+		return d.Hostname == hostname
+	}) {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "hostname '%s' is already added", hostname)
+	}
+
+	// other stacks may have claimed the hostname as well, and the first one to verify it takes it
+	tx := helpers.GetTx(ctx)
+	if exists, err := domain.ExistsVerifiedStackCustomDomainByHostname(tx, hostname); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "hostname '%s' is already used by another stack", hostname)
+	}
+
+	token, err := goutils.CryptoRandomAlphaNumeric(32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate random hash")
+	}
+
+	customDomain := domain.StackCustomDomain{
+		StackID:           stack.ID,
+		Hostname:          hostname,
+		VerificationToken: token,
+	}
+	if err := customDomain.Save(tx); err != nil {
+		return nil, err
+	}
+
+	return &customDomain, nil
+}
+
+// VerifyCustomDomain looks up the TXT record of the custom domain and marks it verified if the record has the
+// verification value, removing the claims of the other stacks on the hostname. Verified domains are served from on the
+// next deployment.
+func (ss *service) VerifyCustomDomain(ctx context.Context, stackId uint, customDomainId uint) (*domain.StackCustomDomain, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	customDomain, err := domain.FindStackCustomDomainById(tx, customDomainId)
+	if err != nil {
+		return nil, err
+	} else if customDomain.StackID != stack.ID {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "custom domain not found in the stack. customDomainId=%d", customDomainId)
+	} else if customDomain.Verified() {
+		return customDomain, nil
+	}
+
+	records, err := ss.dnsResolver.LookupTXT(ctx, customDomain.VerificationRecordName())
+	if err != nil {
+		logger.Warn("failed to lookup txt records", "name", customDomain.VerificationRecordName(), "err", err)
+	}
+	if !slices.Contains(records, customDomain.VerificationRecordValue()) {
+		return nil, errors.Wrapf(
+			tclerrors.ErrPreconditionFailed,
+			"TXT record '%s' with the value '%s' is not found",
+			customDomain.VerificationRecordName(),
+			customDomain.VerificationRecordValue(),
+		)
+	}
+
+	if exists, err := domain.ExistsVerifiedStackCustomDomainByHostname(tx, customDomain.Hostname); err != nil {
+		return nil, err
+	} else if exists {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "hostname '%s' is already used by another stack", customDomain.Hostname)
+	}
+
+	customDomain.VerifiedAt = gog.PtrOf(time.Now())
+	if err := customDomain.Save(tx); err != nil {
+		return nil, err
+	}
+
+	if err := domain.DeleteUnverifiedStackCustomDomainsByHostname(tx, customDomain.Hostname, customDomain.ID); err != nil {
+		return nil, err
+	}
+
+	return customDomain, nil
+}
+
+// RemoveCustomDomain removes the custom domain from the stack. The generated domain becomes primary again if the
+// custom domain was.
+func (ss *service) RemoveCustomDomain(ctx context.Context, stackId uint, customDomainId uint) error {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return err
+	}
+
+	tx := helpers.GetTx(ctx)
+	customDomain, err := domain.FindStackCustomDomainById(tx, customDomainId)
+	if err != nil {
+		return err
+	} else if customDomain.StackID != stack.ID {
+		return errors.Wrapf(tclerrors.ErrNotFound, "custom domain not found in the stack. customDomainId=%d", customDomainId)
+	}
+
+	if stack.PrimaryDomain == customDomain.Hostname {
+		stack.PrimaryDomain = ""
+		if err := stack.Save(tx.Omit(clause.Associations)); err != nil {
+			return err
+		}
+	}
+
+	return customDomain.Delete(tx)
+}
+
+// SetPrimaryDomain sets the host the endpoint of the stack is on, which is the generated domain or one of the
+// verified custom domains.
+func (ss *service) SetPrimaryDomain(ctx context.Context, stackId uint, hostname string) (*domain.Stack, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	if hostname == "" || hostname == stack.Domain {
+		stack.PrimaryDomain = ""
+	} else if slices.ContainsFunc(stack.VerifiedCustomDomains(), func(d domain.StackCustomDomain) bool {
+		return d.Hostname == hostname
+	}) {
+		stack.PrimaryDomain = hostname
+	} else {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "hostname '%s' is not a verified custom domain of the stack", hostname)
+	}
+
+	if err := stack.Save(helpers.GetTx(ctx).Omit(clause.Associations)); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
@@ -0,0 +1,113 @@
+package stack_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/mokiat/gog"
+	"github.com/stretchr/testify/mock"
+	"time"
+)
+
+func (s *StackServiceTestSuite) TestGivenStackWhenAddCustomDomainThenShouldBeUnverified() {
+	// when
+	customDomain, err := s.stackService.AddCustomDomain(s, s.stack.ID, "API.Example.com")
+
+	// then
+	s.Require().NoError(err)
+	s.Equal("api.example.com", customDomain.Hostname)
+	s.False(customDomain.Verified())
+	s.Equal("_apidepot-challenge.api.example.com", customDomain.VerificationRecordName())
+
+	_, err = s.stackService.AddCustomDomain(s, s.stack.ID, "api.example.com")
+	s.ErrorIs(err, tclerrors.ErrBadRequest)
+}
+
+func (s *StackServiceTestSuite) TestGivenStackWhenAddInvalidCustomDomainShouldBeError() {
+	for _, hostname := range []string{"", "localhost", "not a domain", "my.local.shaple.io"} {
+		_, err := s.stackService.AddCustomDomain(s, s.stack.ID, hostname)
+		s.ErrorIs(err, tclerrors.ErrBadRequest, hostname)
+	}
+}
+
+func (s *StackServiceTestSuite) TestGivenCustomDomainWhenVerifyWithTxtRecordThenShouldBePrimaryDomainCandidate() {
+	// given
+	customDomain, err := s.stackService.AddCustomDomain(s, s.stack.ID, "api.example.com")
+	s.Require().NoError(err)
+
+	_, err = s.stackService.SetPrimaryDomain(s, s.stack.ID, "api.example.com")
+	s.ErrorIs(err, tclerrors.ErrBadRequest)
+
+	s.dnsResolver.On("LookupTXT", mock.Anything, "_apidepot-challenge.api.example.com").
+		Return([]string{"other", customDomain.VerificationRecordValue()}, nil).
+		Once()
+
+	// when
+	customDomain, err = s.stackService.VerifyCustomDomain(s, s.stack.ID, customDomain.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.True(customDomain.Verified())
+
+	stack, err := s.stackService.SetPrimaryDomain(s, s.stack.ID, "api.example.com")
+	s.Require().NoError(err)
+	s.Equal("http://api.example.com", stack.Endpoint())
+	s.Equal([]string{stack.Domain, "api.example.com"}, stack.Hosts())
+
+	s.Require().NoError(s.stackService.RemoveCustomDomain(s, s.stack.ID, customDomain.ID))
+	stack, err = s.stackService.GetStack(s, s.stack.ID)
+	s.Require().NoError(err)
+	s.Empty(stack.PrimaryDomain)
+	s.Empty(stack.CustomDomains)
+}
+
+func (s *StackServiceTestSuite) TestGivenCustomDomainWhenVerifyWithoutTxtRecordShouldBeError() {
+	// given
+	customDomain, err := s.stackService.AddCustomDomain(s, s.stack.ID, "api.example.com")
+	s.Require().NoError(err)
+
+	s.dnsResolver.On("LookupTXT", mock.Anything, customDomain.VerificationRecordName()).
+		Return([]string{}, nil).
+		Once()
+
+	// when
+	_, err = s.stackService.VerifyCustomDomain(s, s.stack.ID, customDomain.ID)
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrPreconditionFailed)
+}
+
+func (s *StackServiceTestSuite) TestGivenHostnameClaimedByOtherStackWhenVerifyThenShouldTakeItOver() {
+	// given
+	other := domain.Stack{ProjectID: s.project.ID, Name: "other", Hash: "other-hash"}
+	s.Require().NoError(other.Save(s.db))
+	defer func() {
+		s.Require().NoError(domain.DeleteStackCustomDomainsByStackId(s.db, other.ID))
+		s.Require().NoError(s.db.Unscoped().Delete(&other).Error)
+	}()
+
+	claim := domain.StackCustomDomain{StackID: other.ID, Hostname: "api.example.com", VerificationToken: "other-token"}
+	s.Require().NoError(claim.Save(s.db))
+
+	customDomain, err := s.stackService.AddCustomDomain(s, s.stack.ID, "api.example.com")
+	s.Require().NoError(err)
+
+	s.dnsResolver.On("LookupTXT", mock.Anything, customDomain.VerificationRecordName()).
+		Return([]string{customDomain.VerificationRecordValue()}, nil).
+		Once()
+
+	// when
+	customDomain, err = s.stackService.VerifyCustomDomain(s, s.stack.ID, customDomain.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.True(customDomain.Verified())
+
+	_, err = domain.FindStackCustomDomainById(s.db, claim.ID)
+	s.ErrorIs(err, tclerrors.ErrNotFound)
+
+	// only one stack can have the hostname verified
+	claim = domain.StackCustomDomain{StackID: other.ID, Hostname: "api.example.com", VerificationToken: "other-token"}
+	s.Require().NoError(claim.Save(s.db))
+	claim.VerifiedAt = gog.PtrOf(time.Now())
+	s.Error(claim.Save(s.db))
+}
@@ -11,11 +11,19 @@ CREATE TABLE IF NOT EXISTS stack.vapi_schema_migrations
     created_at      TIMESTAMP NOT NULL DEFAULT now(),
     PRIMARY KEY (version, vapi_package_id)
 );
+CREATE TABLE IF NOT EXISTS stack.custom_vapi_schema_migrations
+(
+    version        TIMESTAMP NOT NULL,
+    custom_vapi_id BIGINT    NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT now(),
+    PRIMARY KEY (version, custom_vapi_id)
+);
 -- name 'migrations' is duplicated 'storage.migrations' for supabase/storage-api
 CREATE TABLE IF NOT EXISTS stack.schema_migrations
 (
     version    TIMESTAMP NOT NULL PRIMARY KEY,
-    created_at TIMESTAMP NOT NULL DEFAULT now()
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    down_query TEXT
 );
 
 CREATE SCHEMA IF NOT EXISTS api;
@@ -3,94 +3,508 @@ package stack
 import (
 	"context"
 	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/mokiat/gog"
 	"github.com/pkg/errors"
 	"slices"
 	"time"
 )
 
+const MigrationSourceStack = "stack"
+
+type appliedMigration struct {
+	Version   time.Time
+	AppliedAt time.Time
+	DownQuery *string
+}
+
+// MigrateDatabase applies the migrations which are not applied yet in a single transaction,
+// so a failing migration leaves the database untouched. Nothing is committed on dry run.
 func (ss *service) MigrateDatabase(
 	ctx context.Context,
 	stackId uint,
 	input MigrateDatabaseInput,
-) error {
+) (*MigrateDatabaseOutput, error) {
 	stack, err := ss.GetStack(ctx, stackId)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	regionalDbConfig := ss.dbConfig.GetRegionalConfig(stack.DefaultRegion)
-	conn, err := pgx.Connect(
-		ctx,
-		stack.DB.Data().PostgresURI(regionalDbConfig.Host, regionalDbConfig.Port),
-	)
+	conn, err := ss.connectDatabase(ctx, stack)
 	if err != nil {
-		return errors.Wrapf(err, "failed to connect to database")
+		return nil, err
 	}
 	defer conn.Close(ctx)
 
-	if err := conn.Ping(ctx); err != nil {
-		return errors.Wrapf(err, "failed to ping database")
-	}
-
 	slices.SortStableFunc(input.Migrations, func(i, j Migration) int {
-		return int(i.Version.Sub(j.Version))
+		return i.Version.Compare(j.Version)
 	})
 
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	tableName, err := getMigrationTableName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// it also locks the table until the end of the transaction, so concurrent migrations are serialized
+	if _, err := tx.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS down_query TEXT", tableName)); err != nil {
+		return nil, errors.Wrapf(err, "failed to add down_query column")
+	}
+
+	applied, err := selectAppliedMigrations(ctx, tx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	output := MigrateDatabaseOutput{
+		AppliedVersions: []time.Time{},
+	}
+	var queries []string
 	for _, migration := range input.Migrations {
-		skip := false
-		if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
-			migrationTableNameCandidates := []string{
-				"stack.schema_migrations",
-				"stack.migrations", // for backward compatibility
-			}
+		if slices.ContainsFunc(applied, func(m appliedMigration) bool {
+			return m.Version.Equal(migration.Version)
+		}) {
+			logger.Info("migration already exists", "version", migration.Version)
+			continue
+		}
 
-			for i, tableName := range migrationTableNameCandidates {
-				isLast := len(migrationTableNameCandidates) == i+1
-
-				if _, err := tx.Exec(
-					ctx,
-					fmt.Sprintf(
-						"INSERT INTO %s (version) VALUES ($1)",
-						tableName,
-					),
-					migration.Version,
-				); err != nil {
-					var pgErr *pgconn.PgError
-					if errors.As(err, &pgErr) {
-						if pgErr.Code == "23505" { // checking duplicated key violates primary key
-							logger.Info("migration already exists", "version", migration.Version)
-							skip = true
-						} else if pgErr.Code == "42P01" && !isLast {
-							continue
-						}
-					}
-
-					return errors.Wrapf(err, "failed to insert migration. version=%v", migration.Version)
+		if _, err := tx.Exec(ctx, migration.Query); err != nil {
+			return nil, errors.Wrapf(err, "failed to execute migration. version=%v", migration.Version)
+		}
+
+		var downQuery *string
+		if migration.DownQuery != "" {
+			downQuery = &migration.DownQuery
+		}
+		if _, err := tx.Exec(
+			ctx,
+			fmt.Sprintf("INSERT INTO %s (version, down_query) VALUES ($1, $2)", tableName),
+			migration.Version,
+			downQuery,
+		); err != nil {
+			return nil, errors.Wrapf(err, "failed to insert migration. version=%v", migration.Version)
+		}
+
+		output.AppliedVersions = append(output.AppliedVersions, migration.Version)
+		queries = append(queries, migration.Query)
+	}
+
+	if input.DryRun {
+		return &output, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit migrations")
+	}
+
+	if err := ss.reloadPostgrestSchema(ctx, conn, stack); err != nil {
+		return nil, err
+	}
+
+	if err := ss.syncReplicas(ctx, stack, queries); err != nil {
+		return nil, err
+	}
+
+	return &output, nil
+}
+
+// GetMigrationStatus lists the applied and pending migrations of the stack and of its locked vapis.
+// The pending stack migrations are the given ones which are not applied yet.
+func (ss *service) GetMigrationStatus(
+	ctx context.Context,
+	stackId uint,
+	migrations []Migration,
+) ([]MigrationStatus, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	vapiReleases, err := ss.GetLockedVapiReleases(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := ss.connectDatabase(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	var statuses []MigrationStatus
+	if err := pgx.BeginTxFunc(ctx, conn, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+		tableName, err := getMigrationTableName(ctx, tx)
+		if err != nil {
+			return err
+		}
+
+		applied, err := selectAppliedMigrations(ctx, tx, tableName)
+		if err != nil {
+			return err
+		}
+
+		statuses = append(statuses, mergeMigrationStatuses(
+			MigrationSourceStack,
+			applied,
+			gog.Map(migrations, func(m Migration) vapi.Migration {
+				return vapi.Migration{
+					Version:   m.Version,
+					Query:     m.Query,
+					DownQuery: m.DownQuery,
 				}
+			}),
+			true,
+		)...)
 
-				break
+		for _, vapiRelease := range vapiReleases {
+			migrations, err := ss.vapis.GetDBMigrations(ctx, vapiRelease)
+			if err != nil {
+				return err
 			}
 
-			if _, err := tx.Exec(ctx, migration.Query); err != nil {
-				return errors.Wrapf(err, "failed to execute migration")
+			applied, err := selectAppliedVapiMigrations(ctx, tx, vapiRelease.PackageID, false)
+			if err != nil {
+				return err
 			}
-			return nil
-		}); err != nil && !skip {
-			return err
+
+			statuses = append(statuses, mergeMigrationStatuses(vapiRelease.Package.Name, applied, migrations, false)...)
 		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// RollbackMigrations reverts the last applied migrations of the stack or of one of its vapis
+// with their down migrations. Nothing is committed on dry run.
+func (ss *service) RollbackMigrations(
+	ctx context.Context,
+	stackId uint,
+	input RollbackMigrationsInput,
+) (*RollbackMigrationsOutput, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
 	}
 
-	if stack.PostgrestEnabled {
-		if _, err := conn.Exec(ctx, "NOTIFY pgrst, 'reload schema';"); err != nil {
-			return errors.Wrapf(err, "failed to notify pgrst")
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	if input.Steps <= 0 {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "steps must be positive")
+	}
+
+	var vapiRelease *domain.VapiRelease
+	if input.Source != "" && input.Source != MigrationSourceStack {
+		vapiReleases, err := ss.GetLockedVapiReleases(ctx, stackId)
+		if err != nil {
+			return nil, err
 		}
 
-		if err := ss.waitPostgrestForReady(ctx, stack, 5*time.Second); err != nil {
-			return errors.Wrapf(err, "failed to wait postgrest for ready")
+		i := slices.IndexFunc(vapiReleases, func(v domain.VapiRelease) bool {
+			return v.Package.Name == input.Source
+		})
+		if i < 0 {
+			return nil, errors.Wrapf(tclerrors.ErrNotFound, "vapi '%s' not found in the stack", input.Source)
 		}
+		vapiRelease = &vapiReleases[i]
+	}
+
+	conn, err := ss.connectDatabase(ctx, stack)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to begin transaction")
+	}
+	defer tx.Rollback(ctx)
+
+	var rolledBack []appliedMigration
+	if vapiRelease == nil {
+		rolledBack, err = rollbackStackMigrations(ctx, tx, input.Steps)
+	} else {
+		rolledBack, err = ss.rollbackVapiMigrations(ctx, tx, *vapiRelease, input.Steps)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	output := RollbackMigrationsOutput{
+		RolledBackVersions: gog.Map(rolledBack, func(m appliedMigration) time.Time {
+			return m.Version
+		}),
+	}
+
+	if input.DryRun {
+		return &output, nil
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, errors.Wrapf(err, "failed to commit rollback")
+	}
+
+	if err := ss.reloadPostgrestSchema(ctx, conn, stack); err != nil {
+		return nil, err
+	}
+
+	queries := gog.Map(rolledBack, func(m appliedMigration) string {
+		return *m.DownQuery
+	})
+	if err := ss.syncReplicas(ctx, stack, queries); err != nil {
+		return nil, err
+	}
+
+	return &output, nil
+}
+
+func rollbackStackMigrations(
+	ctx context.Context,
+	tx pgx.Tx,
+	steps int,
+) ([]appliedMigration, error) {
+	tableName, err := getMigrationTableName(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec(ctx, fmt.Sprintf("LOCK TABLE %s IN EXCLUSIVE MODE", tableName)); err != nil {
+		return nil, errors.Wrapf(err, "failed to lock migrations")
+	}
+
+	applied, err := selectAppliedMigrations(ctx, tx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := takeLastMigrations(applied, steps)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, migration := range targets {
+		if migration.DownQuery == nil {
+			return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "migration has no down migration. version=%v", migration.Version)
+		}
+
+		if _, err := tx.Exec(ctx, *migration.DownQuery); err != nil {
+			return nil, errors.Wrapf(err, "failed to execute down migration. version=%v", migration.Version)
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			fmt.Sprintf("DELETE FROM %s WHERE version = $1", tableName),
+			migration.Version,
+		); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete migration. version=%v", migration.Version)
+		}
+	}
+
+	return targets, nil
+}
+
+// rollbackVapiMigrations reverts with the down migrations of the locked release,
+// so the reverted migrations are applied again on the next deployment.
+func (ss *service) rollbackVapiMigrations(
+	ctx context.Context,
+	tx pgx.Tx,
+	vapiRelease domain.VapiRelease,
+	steps int,
+) ([]appliedMigration, error) {
+	migrations, err := ss.vapis.GetDBMigrations(ctx, vapiRelease)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := selectAppliedVapiMigrations(ctx, tx, vapiRelease.PackageID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	targets, err := takeLastMigrations(applied, steps)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, target := range targets {
+		i := slices.IndexFunc(migrations, func(m vapi.Migration) bool {
+			return m.Version.Equal(target.Version)
+		})
+		if i < 0 || migrations[i].DownQuery == "" {
+			return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "migration has no down migration. vapi=%s, version=%v", vapiRelease.Package.Name, target.Version)
+		}
+		targets[j].DownQuery = &migrations[i].DownQuery
+
+		if _, err := tx.Exec(ctx, migrations[i].DownQuery); err != nil {
+			return nil, errors.Wrapf(err, "failed to execute down migration. version=%v", target.Version)
+		}
+
+		if _, err := tx.Exec(
+			ctx,
+			`DELETE FROM stack.vapi_schema_migrations WHERE version = $1 AND vapi_package_id = $2`,
+			target.Version,
+			vapiRelease.PackageID,
+		); err != nil {
+			return nil, errors.Wrapf(err, "failed to delete migration. version=%v", target.Version)
+		}
+	}
+
+	return targets, nil
+}
+
+func (ss *service) connectDatabase(ctx context.Context, stack *domain.Stack) (*pgx.Conn, error) {
+	regionalDbConfig := ss.dbConfig.GetRegionalConfig(stack.DefaultRegion)
+	conn, err := pgx.Connect(
+		ctx,
+		stack.DB.Data().PostgresURI(regionalDbConfig.Host, regionalDbConfig.Port),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to database")
+	}
+
+	if err := conn.Ping(ctx); err != nil {
+		conn.Close(ctx)
+		return nil, errors.Wrapf(err, "failed to ping database")
+	}
+
+	return conn, nil
+}
+
+func (ss *service) reloadPostgrestSchema(ctx context.Context, conn *pgx.Conn, stack *domain.Stack) error {
+	if !stack.PostgrestEnabled {
+		return nil
+	}
+
+	if _, err := conn.Exec(ctx, "NOTIFY pgrst, 'reload schema';"); err != nil {
+		return errors.Wrapf(err, "failed to notify pgrst")
+	}
+
+	if err := ss.waitPostgrestForReady(ctx, stack, 5*time.Second); err != nil {
+		return errors.Wrapf(err, "failed to wait postgrest for ready")
 	}
 
 	return nil
 }
+
+// getMigrationTableName returns the table recording the stack migrations.
+// Stacks created before it was renamed record them in stack.migrations.
+func getMigrationTableName(ctx context.Context, tx pgx.Tx) (string, error) {
+	var exists bool
+	if err := tx.QueryRow(ctx, `SELECT to_regclass('stack.schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return "", errors.Wrapf(err, "failed to find migrations table")
+	}
+
+	if exists {
+		return "stack.schema_migrations", nil
+	}
+	return "stack.migrations", nil
+}
+
+func selectAppliedMigrations(ctx context.Context, tx pgx.Tx, tableName string) ([]appliedMigration, error) {
+	// down_query is read through jsonb because tables which never had a down migration don't have the column
+	rows, err := tx.Query(
+		ctx,
+		fmt.Sprintf("SELECT version, created_at, to_jsonb(m)->>'down_query' FROM %s m ORDER BY version", tableName),
+	)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select migrations")
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (appliedMigration, error) {
+		var m appliedMigration
+		err := row.Scan(&m.Version, &m.AppliedAt, &m.DownQuery)
+		return m, err
+	})
+}
+
+func selectAppliedVapiMigrations(ctx context.Context, tx pgx.Tx, packageId uint, forUpdate bool) ([]appliedMigration, error) {
+	query := `SELECT version, created_at FROM stack.vapi_schema_migrations WHERE vapi_package_id = $1 ORDER BY version`
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+
+	rows, err := tx.Query(ctx, query, packageId)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to select vapi migrations")
+	}
+
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (appliedMigration, error) {
+		var m appliedMigration
+		err := row.Scan(&m.Version, &m.AppliedAt)
+		return m, err
+	})
+}
+
+// takeLastMigrations returns the last applied migrations from the newest one.
+func takeLastMigrations(applied []appliedMigration, steps int) ([]appliedMigration, error) {
+	if steps > len(applied) {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "only %d migrations are applied", len(applied))
+	}
+
+	targets := slices.Clone(applied[len(applied)-steps:])
+	slices.Reverse(targets)
+	return targets, nil
+}
+
+// mergeMigrationStatuses lists the applied migrations with the known ones which are not applied yet.
+// The down query of a stack migration is recorded when it is applied, while the one of a vapi migration is in its release.
+func mergeMigrationStatuses(
+	source string,
+	applied []appliedMigration,
+	migrations []vapi.Migration,
+	recordsDownQuery bool,
+) []MigrationStatus {
+	statuses := make([]MigrationStatus, 0, len(applied)+len(migrations))
+	for _, m := range applied {
+		i := slices.IndexFunc(migrations, func(migration vapi.Migration) bool {
+			return migration.Version.Equal(m.Version)
+		})
+
+		reversible := m.DownQuery != nil
+		if !recordsDownQuery {
+			reversible = i >= 0 && migrations[i].DownQuery != ""
+		}
+
+		statuses = append(statuses, MigrationStatus{
+			Source:     source,
+			Version:    m.Version,
+			Applied:    true,
+			AppliedAt:  gog.PtrOf(m.AppliedAt),
+			Reversible: reversible,
+		})
+	}
+
+	for _, migration := range migrations {
+		if slices.ContainsFunc(applied, func(m appliedMigration) bool {
+			return m.Version.Equal(migration.Version)
+		}) {
+			continue
+		}
+
+		statuses = append(statuses, MigrationStatus{
+			Source:     source,
+			Version:    migration.Version,
+			Reversible: migration.DownQuery != "",
+		})
+	}
+
+	slices.SortStableFunc(statuses, func(lhs, rhs MigrationStatus) int {
+		return lhs.Version.Compare(rhs.Version)
+	})
+
+	return statuses
+}
@@ -0,0 +1,77 @@
+package stack_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"time"
+)
+
+func (s *StackServiceTestSuite) TestGivenMigrationsWhenDryRunThenShouldNotBeApplied() {
+	// given
+	migrations := []stack.Migration{
+		{
+			Version:   time.Date(2024, 3, 26, 13, 52, 0, 0, time.UTC),
+			Query:     `CREATE TABLE public.dry_run_table (id SERIAL PRIMARY KEY);`,
+			DownQuery: `DROP TABLE public.dry_run_table;`,
+		},
+	}
+
+	// when
+	output, err := s.stackService.MigrateDatabase(s, s.stack.ID, stack.MigrateDatabaseInput{
+		Migrations: migrations,
+		DryRun:     true,
+	})
+
+	// then
+	s.Require().NoError(err)
+	s.Len(output.AppliedVersions, 1)
+
+	statuses, err := s.stackService.GetMigrationStatus(s, s.stack.ID, migrations)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 1)
+	s.Equal(stack.MigrationSourceStack, statuses[0].Source)
+	s.False(statuses[0].Applied)
+	s.True(statuses[0].Reversible)
+}
+
+func (s *StackServiceTestSuite) TestGivenAppliedMigrationsWhenRollbackThenShouldRevertTheLastOnes() {
+	// given
+	migrations := []stack.Migration{
+		{
+			Version: time.Date(2024, 3, 26, 13, 52, 0, 0, time.UTC),
+			Query:   `CREATE TABLE public.rollback_table (id SERIAL PRIMARY KEY);`,
+		},
+		{
+			Version:   time.Date(2024, 3, 27, 13, 52, 0, 0, time.UTC),
+			Query:     `ALTER TABLE public.rollback_table ADD COLUMN name TEXT;`,
+			DownQuery: `ALTER TABLE public.rollback_table DROP COLUMN name;`,
+		},
+	}
+	output, err := s.stackService.MigrateDatabase(s, s.stack.ID, stack.MigrateDatabaseInput{
+		Migrations: migrations,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(output.AppliedVersions, 2)
+
+	// when
+	_, err = s.stackService.RollbackMigrations(s, s.stack.ID, stack.RollbackMigrationsInput{
+		Steps: 2,
+	})
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrPreconditionFailed)
+
+	rolledBack, err := s.stackService.RollbackMigrations(s, s.stack.ID, stack.RollbackMigrationsInput{
+		Steps: 1,
+	})
+	s.Require().NoError(err)
+	s.Require().Len(rolledBack.RolledBackVersions, 1)
+	s.True(migrations[1].Version.Equal(rolledBack.RolledBackVersions[0]))
+
+	statuses, err := s.stackService.GetMigrationStatus(s, s.stack.ID, migrations)
+	s.Require().NoError(err)
+	s.Require().Len(statuses, 2)
+	s.True(statuses[0].Applied)
+	s.False(statuses[0].Reversible)
+	s.False(statuses[1].Applied)
+}
@@ -0,0 +1,265 @@
+package stack
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
+	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
+	"gorm.io/gorm/clause"
+)
+
+// EnableMultiRegion replicates the database of the stack into every zone other than its default region, and serves
+// the stack from its global domain. The instances in the zones are created by the instance service.
+func (ss *service) EnableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	if ss.stackConfig.GlobalDomain == "" {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionRequired, "multi-region stacks are not configured")
+	} else if stack.MultiRegion {
+		return stack, nil
+	}
+
+	stack.MultiRegion = true
+	stack.GlobalDomain = stack.Hash + "." + ss.stackConfig.GlobalDomain
+	stack.ReplicaSchemaHash = ""
+
+	dbName := stack.DB.Data().Name
+	// a publication left by a failed attempt is recreated
+	if err := ss.runtimeSchema.DropPublication(ctx, stack.DefaultRegion, dbName); err != nil {
+		return nil, err
+	}
+	if err := ss.runtimeSchema.CreatePublication(ctx, stack.DefaultRegion, dbName); err != nil {
+		return nil, err
+	}
+
+	if stack.StorageEnabled {
+		for _, zone := range stack.ReplicaZones() {
+			if err := ss.createBucketIfNotExists(ctx, zone, stack.Storage.Data().S3Bucket); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := ss.syncReplicas(ctx, stack, nil); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+// DisableMultiRegion drops the replicas of the database of the stack. The stack must have no instance out of its default region.
+func (ss *service) DisableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	if !stack.MultiRegion {
+		return stack, nil
+	}
+
+	for _, instance := range stack.Instances {
+		if instance.Zone != stack.DefaultRegion {
+			return nil, errors.Wrapf(tclerrors.ErrPreconditionRequired, "stack has an instance in zone %s", instance.Zone)
+		}
+	}
+
+	if err := ss.dropReplicas(ctx, stack); err != nil {
+		return nil, err
+	}
+
+	if stack.StorageEnabled {
+		for _, zone := range stack.ReplicaZones() {
+			if err := ss.bucketService.DeleteBucket(ctx, zone, stack.Storage.Data().S3Bucket); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	stack.MultiRegion = false
+	stack.GlobalDomain = ""
+	stack.ReplicaSchemaHash = ""
+	if err := stack.Save(helpers.GetTx(ctx).Omit(clause.Associations)); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+// SyncReplicas synchronizes the schema of the replicas of the database of the stack with the primary, by applying
+// queries, the migrations just applied to the primary, to them as well. The permission of the current user is not
+// checked, since deployments run without one.
+func (ss *service) SyncReplicas(ctx context.Context, stackId uint, queries []string) error {
+	stack, err := domain.FindStackByID(helpers.GetTx(ctx), stackId)
+	if err != nil {
+		return err
+	}
+
+	return ss.syncReplicas(ctx, stack, queries)
+}
+
+// syncReplicas applies queries, the migrations just applied to the primary, to the replicas whenever the schema of the
+// primary changes, since logical replication copies only the rows and not the schema. The replicas keep serving while
+// they are migrated. They are created from the schema of the primary if they haven't been synchronized yet, and
+// recreated as a last resort if their schema still differs from the primary after being migrated.
+func (ss *service) syncReplicas(ctx context.Context, stack *domain.Stack, queries []string) error {
+	if !stack.MultiRegion {
+		return nil
+	}
+
+	schema, err := ss.dumpSchema(ctx, stack, stack.DefaultRegion)
+	if err != nil {
+		return err
+	}
+
+	schemaHash := hashSchema(schema)
+	if schemaHash == stack.ReplicaSchemaHash {
+		return nil
+	}
+
+	for _, zone := range stack.ReplicaZones() {
+		logger.Info("sync replica", "stackId", stack.ID, "zone", zone)
+		if stack.ReplicaSchemaHash == "" {
+			if err := ss.createReplica(ctx, stack, zone, schema); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := ss.migrateReplica(ctx, stack, zone, queries); err != nil {
+			return err
+		}
+
+		replicaSchema, err := ss.dumpSchema(ctx, stack, zone)
+		if err != nil {
+			return err
+		}
+
+		if hashSchema(replicaSchema) != schemaHash {
+			logger.Warn("recreate replica whose schema differs from the primary", "stackId", stack.ID, "zone", zone)
+			if err := ss.createReplica(ctx, stack, zone, schema); err != nil {
+				return err
+			}
+		}
+	}
+
+	stack.ReplicaSchemaHash = schemaHash
+	return stack.Save(helpers.GetTx(ctx).Omit(clause.Associations))
+}
+
+// createReplica creates the replica in the zone from the schema of the primary, dropping the one left if any, and
+// subscribes it to the primary, which copies the rows.
+func (ss *service) createReplica(ctx context.Context, stack *domain.Stack, zone tcltypes.InstanceZone, schema []byte) error {
+	db := stack.DB.Data()
+	if err := ss.runtimeSchema.DropReplica(ctx, zone, db.Username, db.Name); err != nil {
+		return err
+	}
+
+	if err := ss.runtimeSchema.CreateUserAndDB(ctx, zone, db.Username, db.Password, db.Name); err != nil {
+		return err
+	}
+
+	if err := ss.runPgTool(ctx, stack, zone, bytes.NewReader(schema), nil, "psql", "--quiet", "--set", "ON_ERROR_STOP=1"); err != nil {
+		return errors.Wrapf(err, "failed to restore schema in zone %s", zone)
+	}
+
+	return ss.runtimeSchema.CreateSubscription(ctx, stack.DefaultRegion, zone, db.Name)
+}
+
+// migrateReplica applies the migrations to the replica in the zone in a single transaction, and subscribes it to the
+// tables they have created.
+func (ss *service) migrateReplica(ctx context.Context, stack *domain.Stack, zone tcltypes.InstanceZone, queries []string) error {
+	db := stack.DB.Data()
+	regionalDbConfig := ss.dbConfig.GetRegionalConfig(zone)
+	conn, err := pgx.Connect(ctx, db.PostgresURI(regionalDbConfig.Host, regionalDbConfig.Port))
+	if err != nil {
+		return errors.Wrapf(err, "failed to connect to replica in zone %s", zone)
+	}
+	defer conn.Close(ctx)
+
+	if err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		for _, query := range queries {
+			if _, err := tx.Exec(ctx, query); err != nil {
+				return errors.Wrapf(err, "failed to execute migration in zone %s. query=%s", zone, query)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if err := ss.runtimeSchema.RefreshSubscription(ctx, zone, db.Name); err != nil {
+		return err
+	}
+
+	if stack.PostgrestEnabled {
+		// the postgrest of the instance in the zone reads the replica
+		if _, err := conn.Exec(ctx, "NOTIFY pgrst, 'reload schema';"); err != nil {
+			return errors.Wrapf(err, "failed to notify pgrst in zone %s", zone)
+		}
+	}
+
+	return nil
+}
+
+// dumpSchema dumps the schema of the database of the stack in the zone without what differs between the primary and
+// the replicas.
+func (ss *service) dumpSchema(ctx context.Context, stack *domain.Stack, zone tcltypes.InstanceZone) ([]byte, error) {
+	var schema bytes.Buffer
+	if err := ss.runPgTool(
+		ctx,
+		stack,
+		zone,
+		nil,
+		&schema,
+		"pg_dump", "--schema-only", "--no-owner", "--no-publications", "--no-subscriptions",
+	); err != nil {
+		return nil, errors.Wrapf(err, "failed to dump schema in zone %s", zone)
+	}
+
+	return schema.Bytes(), nil
+}
+
+// hashSchema hashes the schema dumped by pg_dump without its comments and psql meta-commands, which differ between
+// the dumps of the same schema, e.g. by the versions of the servers.
+func hashSchema(schema []byte) string {
+	hash := sha256.New()
+	for _, line := range bytes.Split(schema, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("--")) || bytes.HasPrefix(line, []byte("\\")) {
+			continue
+		}
+		hash.Write(line)
+		hash.Write([]byte("\n"))
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (ss *service) dropReplicas(ctx context.Context, stack *domain.Stack) error {
+	db := stack.DB.Data()
+	for _, zone := range stack.ReplicaZones() {
+		if err := ss.runtimeSchema.DropReplica(ctx, zone, db.Username, db.Name); err != nil {
+			return err
+		}
+	}
+
+	return ss.runtimeSchema.DropPublication(ctx, stack.DefaultRegion, db.Name)
+}
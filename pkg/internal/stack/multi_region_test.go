@@ -0,0 +1,134 @@
+package stack_test
+
+import (
+	"context"
+	pkgconfig "github.com/habiliai/apidepot/pkg/config"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/digo"
+	"github.com/habiliai/apidepot/pkg/internal/services"
+	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/jackc/pgx/v5"
+	"os"
+	"strconv"
+	"time"
+)
+
+func (s *StackServiceTestSuite) TestGivenNoGlobalDomainWhenEnableMultiRegionShouldBeError() {
+	// when
+	_, err := s.stackService.EnableMultiRegion(s, s.stack.ID)
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrPreconditionRequired)
+}
+
+func (s *StackServiceTestSuite) TestGivenSingleRegionStackWhenDisableMultiRegionThenShouldBeNoop() {
+	// when
+	stack, err := s.stackService.DisableMultiRegion(s, s.stack.ID)
+
+	// then
+	s.Require().NoError(err)
+	s.False(stack.MultiRegion)
+	s.Empty(stack.ReplicaZones())
+}
+
+func (s *StackServiceTestSuite) TestGivenMultiRegionStackWhenMigrateDatabaseTwiceThenShouldMigrateReplicaInPlace() {
+	// the replica zone needs another postgres initialized like the test db, which reaches the test db on localhost:6543
+	replicaPort, err := strconv.Atoi(os.Getenv("REPLICA_DB_PORT"))
+	if err != nil {
+		s.T().Skip("replica db port is missing")
+	}
+
+	// Given
+	primaryDbConfig := pkgconfig.RegionalDBConfig{
+		Host:     "localhost",
+		User:     "postgres",
+		Password: "postgres",
+		Name:     "test",
+		Port:     6543,
+	}
+	replicaDbConfig := primaryDbConfig
+	replicaDbConfig.Port = replicaPort
+	dbConfig := pkgconfig.DBConfig{
+		Seoul:     primaryDbConfig,
+		Singapore: replicaDbConfig,
+	}
+	regionalStackConfig := pkgconfig.RegionalStackConfig{
+		Domain: "local.shaple.io",
+		Scheme: "http",
+	}
+
+	container := digo.NewContainer(s, digo.EnvTest, nil)
+	runtimeSchema, err := services.NewRuntimeSchema(dbConfig)
+	s.Require().NoError(err)
+	stacks := stack.NewService(
+		digo.MustGet[services.BucketService](container, services.ServiceKeyBucketService),
+		runtimeSchema,
+		pkgconfig.StackConfig{
+			ForceDelete:  true,
+			GlobalDomain: "global.local.shaple.io",
+			Seoul:        regionalStackConfig,
+			Singapore:    regionalStackConfig,
+		},
+		dbConfig,
+		s.vapis,
+		s.users,
+		s.storageClient,
+		digo.MustGet[services.GitService](container, services.ServiceKeyGitService),
+		s.dnsResolver,
+	)
+
+	st, err := stacks.EnableMultiRegion(s, s.stack.ID)
+	s.Require().NoError(err)
+	s.Require().True(st.MultiRegion)
+	defer func() {
+		_, err := stacks.DisableMultiRegion(s, s.stack.ID)
+		s.NoError(err)
+	}()
+
+	db := st.DB.Data()
+	replica, err := pgx.Connect(s, replicaDbConfig.GetURI())
+	s.Require().NoError(err)
+	defer replica.Close(s)
+
+	var replicaOid uint32
+	s.Require().NoError(replica.QueryRow(s, `SELECT oid FROM pg_database WHERE datname = $1`, db.Name).Scan(&replicaOid))
+
+	// When
+	_, err = stacks.MigrateDatabase(s, s.stack.ID, stack.MigrateDatabaseInput{
+		Migrations: []stack.Migration{
+			{
+				Version: time.Date(2024, 3, 26, 13, 52, 0, 0, time.UTC),
+				Query:   `CREATE TABLE public.replicated_table (id BIGINT PRIMARY KEY); INSERT INTO public.replicated_table VALUES (1);`,
+			},
+		},
+	})
+	s.Require().NoError(err)
+
+	_, err = stacks.MigrateDatabase(s, s.stack.ID, stack.MigrateDatabaseInput{
+		Migrations: []stack.Migration{
+			{
+				Version: time.Date(2024, 3, 27, 13, 52, 0, 0, time.UTC),
+				Query:   `ALTER TABLE public.replicated_table ADD COLUMN name TEXT;`,
+			},
+		},
+	})
+	s.Require().NoError(err)
+
+	// Then
+	var oid uint32
+	s.Require().NoError(replica.QueryRow(s, `SELECT oid FROM pg_database WHERE datname = $1`, db.Name).Scan(&oid))
+	s.Equal(replicaOid, oid, "replica must not be recreated")
+
+	replicaDb, err := pgx.Connect(s, db.PostgresURI(replicaDbConfig.Host, replicaDbConfig.Port))
+	s.Require().NoError(err)
+	defer replicaDb.Close(s)
+
+	s.Require().Eventually(func() bool {
+		ctx, cancel := context.WithTimeout(s, time.Second)
+		defer cancel()
+
+		var count int
+		err := replicaDb.QueryRow(ctx, `SELECT count(*) FROM public.replicated_table WHERE id = 1 AND name IS NULL`).Scan(&count)
+		return err == nil && count == 1
+	}, 30*time.Second, time.Second)
+}
@@ -54,13 +54,22 @@ func (ss *service) CreateStack(
 		}
 	}
 
-	tx := helpers.GetTx(ctx)
-	regionalStackConfig := ss.stackConfig.GetRegionalConfig(input.DefaultRegion)
-
-	if err := ss.hasPermission(ctx, input.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, input.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return nil, err
 	}
 
+	return ss.createStack(ctx, input, ss.applySchema)
+}
+
+// createStack creates the stack with its database, which is initialized by initDB in the same transaction.
+func (ss *service) createStack(
+	ctx context.Context,
+	input CreateStackInput,
+	initDB func(ctx context.Context, stack *domain.Stack) error,
+) (*domain.Stack, error) {
+	tx := helpers.GetTx(ctx)
+	regionalStackConfig := ss.stackConfig.GetRegionalConfig(input.DefaultRegion)
+
 	dbPassword, err := goutils.CryptoRandomAlphaNumeric(32)
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to generate random db password")
@@ -103,12 +112,16 @@ func (ss *service) CreateStack(
 			return err
 		}
 
-		if err = ss.applySchema(ctx, stack); err != nil {
+		if err = initDB(ctx, stack); err != nil {
 			return err
 		}
 
 		return nil
 	}); err != nil {
+		// the database is created outside the transaction, so it is dropped here not to leak it
+		if err := ss.runtimeSchema.DropUserAndDB(ctx, stack.DefaultRegion, stack.DB.Data().Username, stack.DB.Data().Name); err != nil {
+			logger.Warn("failed to drop the database of the uncreated stack", "db", stack.DB.Data().Name, "err", err)
+		}
 		return nil, err
 	}
 
@@ -126,10 +139,8 @@ func (ss *service) DeleteStack(ctx context.Context, id uint) error {
 		return errors.Wrapf(err, "failed to find stack by id")
 	}
 
-	if user, err := ss.users.GetUser(ctx); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleAdmin); err != nil {
 		return err
-	} else if stack.Project.OwnerID != user.ID && !user.IsSuperuser() {
-		return errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this stack")
 	}
 
 	logger.Debug("print", "numInstances", len(stack.Instances))
@@ -137,25 +148,55 @@ func (ss *service) DeleteStack(ctx context.Context, id uint) error {
 		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "stack has instances")
 	}
 
-	if stack.PostgrestEnabled {
-		if err := ss.DisablePostgrest(ctx, stack.ID); err != nil {
-			return err
+	// the database is dropped irreversibly, so it is backed up for the last time
+	if ss.stackConfig.Backup.Bucket != "" && stack.Preview == nil {
+		if _, err := ss.backupDatabase(ctx, stack, domain.StackBackupKindFinal); err != nil {
+			return errors.Wrapf(err, "failed to take final snapshot")
 		}
 	}
 
+	return ss.deleteStack(ctx, stack)
+}
+
+// deleteStack deletes the stack with its buckets and its database. The stack must not have instances.
+func (ss *service) deleteStack(ctx context.Context, stack *domain.Stack) error {
+	tx := helpers.GetTx(ctx)
 	if stack.StorageEnabled {
-		if err := ss.DisableStorage(ctx, stack.ID); err != nil {
-			return err
+		for _, zone := range stack.Zones() {
+			if err := ss.bucketService.DeleteBucket(ctx, zone, stack.Domain); err != nil {
+				return err
+			}
 		}
 	}
 
-	if stack.AuthEnabled {
-		if err := ss.DisableAuth(ctx, stack.ID); err != nil {
+	stack.PostgrestEnabled = false
+	stack.StorageEnabled = false
+	stack.AuthEnabled = false
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := stack.Save(tx.Omit(clause.Associations)); err != nil {
+			return err
+		}
+
+		if err := domain.DeleteStackPreviewsByStackId(tx, stack.ID); err != nil {
+			return err
+		}
+
+		if err := domain.DeleteStackCustomDomainsByStackId(tx, stack.ID); err != nil {
+			return err
+		}
+
+		if err := domain.DeleteVapiPackageBorrowersByStackId(tx, stack.ID); err != nil {
+			return err
+		}
+
+		if err := domain.DeleteStackVapiNoticesByStackId(tx, stack.ID); err != nil {
+			return err
+		}
+
+		if err := domain.DeleteGithubDeliveryActionsByStackId(tx, stack.ID); err != nil {
 			return err
 		}
-	}
 
-	if err := tx.Transaction(func(tx *gorm.DB) error {
 		if err := stack.Delete(tx); err != nil {
 			return errors.Wrapf(err, "failed to delete stack")
 		}
@@ -165,6 +206,11 @@ func (ss *service) DeleteStack(ctx context.Context, id uint) error {
 		return err
 	}
 
+	// the replicas are dropped first, since the replication slots keep the primary from being dropped
+	if err := ss.dropReplicas(ctx, stack); err != nil {
+		return err
+	}
+
 	if err := ss.runtimeSchema.DropUserAndDB(ctx, stack.DefaultRegion, stack.DB.Data().Username, stack.DB.Data().Name); err != nil {
 		return err
 	}
@@ -183,7 +229,7 @@ func (ss *service) PatchStack(
 		return errors.Wrapf(err, "failed to find stack by id")
 	}
 
-	if err := ss.hasPermission(ctx, stack.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
 	}
 
@@ -203,6 +249,15 @@ func (ss *service) PatchStack(
 		stack.LogoImageUrl = *input.LogoImageUrl
 	}
 
+	if input.PreviewsEnabled != nil {
+		if *input.PreviewsEnabled && stack.GitRepo == "" {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "git repo is required to enable previews")
+		} else if *input.PreviewsEnabled && stack.Preview != nil {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "previews can't be enabled on a preview")
+		}
+		stack.PreviewsEnabled = *input.PreviewsEnabled
+	}
+
 	return tx.Transaction(func(tx *gorm.DB) error {
 		if err := stack.Save(tx.Omit(clause.Associations)); err != nil {
 			return errors.Wrapf(err, "failed to save stack")
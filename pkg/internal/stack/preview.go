@@ -0,0 +1,406 @@
+package stack
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/Masterminds/goutils"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/datatypes"
+	"gorm.io/gorm/clause"
+	"os"
+	"slices"
+	"strings"
+)
+
+const (
+	// maxPreviewParentNameLen keeps the name of the preview stacks, suffixed with the number of the pull request,
+	// within the length of the stack names
+	maxPreviewParentNameLen = 40
+)
+
+type CreatePreviewStackInput struct {
+	// GitRepo is the repository the pull request is opened on, like owner/name
+	GitRepo           string
+	PullRequestNumber int
+	// HeadGitRepo and HeadGitBranch are where the custom vapis of the preview are built from. HeadGitRepo differs
+	// from GitRepo for the pull requests from forks.
+	HeadGitRepo   string
+	HeadGitBranch string
+	// AccessToken clones HeadGitRepo, e.g. the token of the github app installation. Public repositories are cloned
+	// without it.
+	AccessToken string
+}
+
+// CreatePreviewStack creates an ephemeral stack previewing the pull request on the git branch of the parent stack.
+// It is cloned from the configuration of the parent: its auth settings, services, vapis and env vars, without the
+// secrets. Its database is seeded with the schema of the parent without the rows, and its custom vapis are built from
+// the head branch of the pull request. The permission of the current user is not checked.
+func (ss *service) CreatePreviewStack(
+	ctx context.Context,
+	parentStackId uint,
+	input CreatePreviewStackInput,
+) (_ *domain.Stack, err error) {
+	if input.GitRepo == "" || input.PullRequestNumber <= 0 {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "pull request is required")
+	} else if input.HeadGitRepo == "" || input.HeadGitBranch == "" {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "head branch of the pull request is required")
+	}
+
+	tx := helpers.GetTx(ctx)
+	parent, err := domain.FindStackByID(tx, parentStackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if parent.Preview != nil {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "stack %d is a preview itself", parent.ID)
+	}
+
+	if _, err := domain.FindStackPreviewByParentStackIdAndPullRequest(tx, parent.ID, input.GitRepo, input.PullRequestNumber); err == nil {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "stack %d already has a preview of pull request #%d", parent.ID, input.PullRequestNumber)
+	} else if !errors.Is(err, tclerrors.ErrNotFound) {
+		return nil, err
+	}
+
+	parentName := parent.Name
+	if len(parentName) > maxPreviewParentNameLen {
+		parentName = parentName[:maxPreviewParentNameLen]
+	}
+	stack, err := ss.createStack(ctx, CreateStackInput{
+		ProjectID:     parent.ProjectID,
+		Name:          fmt.Sprintf("%s-pr-%d", parentName, input.PullRequestNumber),
+		SiteURL:       parent.SiteURL,
+		Description:   fmt.Sprintf("Preview of %s#%d", input.GitRepo, input.PullRequestNumber),
+		LogoImageUrl:  parent.LogoImageUrl,
+		DefaultRegion: parent.DefaultRegion,
+		GitRepo:       input.HeadGitRepo,
+		GitBranch:     input.HeadGitBranch,
+	}, func(ctx context.Context, stack *domain.Stack) error {
+		return ss.seedPreviewDB(ctx, parent, stack)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the half-made preview is torn down not to leave its database and its buckets behind
+	defer func() {
+		if err == nil {
+			return
+		}
+
+		if err := ss.deleteStack(ctx, stack); err != nil {
+			logger.Warn("failed to delete preview stack", "stackId", stack.ID, "err", err)
+		}
+	}()
+
+	if err = ss.clonePreviewConfig(ctx, parent, stack); err != nil {
+		return nil, err
+	}
+
+	if err = ss.buildPreviewCustomVapis(ctx, parent, stack, input.AccessToken); err != nil {
+		return nil, err
+	}
+
+	preview := domain.StackPreview{
+		StackID:           stack.ID,
+		ParentStackID:     parent.ID,
+		GitRepo:           input.GitRepo,
+		PullRequestNumber: input.PullRequestNumber,
+	}
+	if err = preview.Save(tx.Omit(clause.Associations)); err != nil {
+		return nil, err
+	}
+
+	stack.Preview = &preview
+	logger.Info("created preview stack", "stackId", stack.ID, "parentStackId", parent.ID, "repo", input.GitRepo, "pullRequest", input.PullRequestNumber)
+
+	return stack, nil
+}
+
+// DeletePreviewStack tears down the preview stack, whose instances must have been deleted. The database is dropped
+// without the final backup. The permission of the current user is not checked.
+func (ss *service) DeletePreviewStack(ctx context.Context, stackId uint) error {
+	stack, err := domain.FindStackByID(helpers.GetTx(ctx), stackId)
+	if err != nil {
+		return err
+	}
+
+	if stack.Preview == nil {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "stack %d is not a preview", stack.ID)
+	} else if len(stack.Instances) > 0 {
+		return errors.Wrapf(tclerrors.ErrPreconditionRequired, "stack has instances")
+	}
+
+	return ss.deleteStack(ctx, stack)
+}
+
+func (ss *service) GetStackPreviews(ctx context.Context, stackId uint) ([]domain.StackPreview, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	return domain.FindStackPreviewsByParentStackId(helpers.GetTx(ctx), stack.ID)
+}
+
+// seedPreviewDB restores the schema of the database of the parent into the database of the preview. The histories of
+// the migrations are restored as well, so that only the migrations added by the pull request are applied on deploy.
+// The objects are restored as owned by the owner of the preview database.
+func (ss *service) seedPreviewDB(ctx context.Context, parent *domain.Stack, preview *domain.Stack) error {
+	var schema, histories bytes.Buffer
+	if err := ss.runPgTool(
+		ctx,
+		parent,
+		parent.DefaultRegion,
+		nil,
+		&schema,
+		"pg_dump", "--format=custom", "--schema-only", "--no-owner", "--no-publications", "--no-subscriptions",
+	); err != nil {
+		return errors.Wrapf(err, "failed to dump schema")
+	}
+
+	if err := ss.runPgTool(
+		ctx,
+		parent,
+		parent.DefaultRegion,
+		nil,
+		&histories,
+		"pg_dump", "--format=custom", "--data-only",
+		"--table=stack.*schema_migrations",
+		"--table=auth.schema_migrations",
+		"--table=storage.migrations",
+	); err != nil {
+		return errors.Wrapf(err, "failed to dump migration histories")
+	}
+
+	listFile, err := ss.writePreviewRestoreList(ctx, preview, schema.Bytes())
+	if err != nil {
+		return err
+	}
+	defer os.Remove(listFile)
+
+	role := "--role=" + preview.DB.Data().Username
+	if err := ss.runPgTool(
+		ctx,
+		preview,
+		preview.DefaultRegion,
+		bytes.NewReader(schema.Bytes()),
+		nil,
+		"pg_restore", "--no-owner", role, "--single-transaction", "--use-list="+listFile,
+	); err != nil {
+		return errors.Wrapf(err, "failed to restore schema")
+	}
+
+	if err := ss.runPgTool(
+		ctx,
+		preview,
+		preview.DefaultRegion,
+		bytes.NewReader(histories.Bytes()),
+		nil,
+		"pg_restore", "--no-owner", role, "--single-transaction", "--data-only",
+	); err != nil {
+		return errors.Wrapf(err, "failed to restore migration histories")
+	}
+
+	return nil
+}
+
+// writePreviewRestoreList writes the table of contents of the schema dump of the parent to a file to be restored with
+// --use-list. The default privileges are left out, since they are of the objects created by the owner of the parent
+// database, and the owner of the preview database can't alter them.
+func (ss *service) writePreviewRestoreList(ctx context.Context, preview *domain.Stack, schema []byte) (string, error) {
+	var toc bytes.Buffer
+	if err := ss.runPgTool(ctx, preview, preview.DefaultRegion, bytes.NewReader(schema), &toc, "pg_restore", "--list"); err != nil {
+		return "", errors.Wrapf(err, "failed to list schema dump")
+	}
+
+	var list bytes.Buffer
+	for _, line := range strings.SplitAfter(toc.String(), "\n") {
+		if strings.Contains(line, " DEFAULT ACL ") {
+			continue
+		}
+		list.WriteString(line)
+	}
+
+	f, err := os.CreateTemp("", "preview-restore-*.list")
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to create restore list")
+	}
+	defer f.Close()
+
+	if _, err := f.Write(list.Bytes()); err != nil {
+		os.Remove(f.Name())
+		return "", errors.Wrapf(err, "failed to write restore list")
+	}
+
+	return f.Name(), nil
+}
+
+// clonePreviewConfig enables the services of the parent on the preview and installs the vapis of the parent with the
+// same locked releases. The preview signs its api keys with its own jwt secret. The secrets of the parent, i.e. the
+// secret env vars and the credentials of the auth providers, are not cloned, since the preview runs the code of the
+// pull request.
+func (ss *service) clonePreviewConfig(ctx context.Context, parent *domain.Stack, preview *domain.Stack) error {
+	tx := helpers.GetTx(ctx)
+
+	if parent.AuthEnabled {
+		jwtSecret, err := goutils.CryptoRandomAlphaNumeric(32)
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate random hash")
+		}
+
+		auth := previewAuth(parent.Auth.Data())
+		auth.JWTSecret = jwtSecret
+		auth.PreviousJWTSecret = ""
+
+		preview.AuthEnabled = true
+		preview.Auth = datatypes.NewJSONType(auth)
+		if err := signApiKeys(preview); err != nil {
+			return err
+		}
+	}
+
+	if parent.StorageEnabled {
+		storage := domain.Storage{
+			S3Bucket: preview.Domain,
+			TenantID: parent.Storage.Data().TenantID,
+		}
+		if err := ss.bucketService.CreateBucket(ctx, preview.DefaultRegion, storage.S3Bucket); err != nil {
+			return err
+		}
+
+		preview.StorageEnabled = true
+		preview.Storage = datatypes.NewJSONType(storage)
+	}
+
+	preview.PostgrestEnabled = parent.PostgrestEnabled
+	preview.Postgrest = parent.Postgrest
+	preview.VapiEnvVars = slices.DeleteFunc(slices.Clone(parent.VapiEnvVars), func(envVar domain.StackVapiEnvVar) bool {
+		return envVar.Secret
+	})
+	preview.RateLimits = parent.RateLimits
+	if err := preview.Save(tx.Omit(clause.Associations)); err != nil {
+		return err
+	}
+
+	for _, parentVapi := range parent.Vapis {
+		stackVapi := domain.StackVapi{
+			StackID:   preview.ID,
+			VapiID:    parentVapi.VapiID,
+			Resources: parentVapi.Resources,
+		}
+		if err := stackVapi.Create(tx.Omit(clause.Associations)); err != nil {
+			return err
+		}
+	}
+
+	locks, err := domain.FindStackVapiLocksByStackID(tx, parent.ID)
+	if err != nil {
+		return err
+	}
+	for _, parentLock := range locks {
+		lock := domain.StackVapiLock{
+			StackID: preview.ID,
+			VapiID:  parentLock.VapiID,
+			Seq:     parentLock.Seq,
+			Direct:  parentLock.Direct,
+		}
+		if err := lock.Save(tx.Omit(clause.Associations)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// previewAuth returns the auth settings of the parent without the credentials of the sms, oauth and captcha
+// providers. The providers are disabled, since they don't work without the credentials.
+func previewAuth(auth domain.Auth) domain.Auth {
+	auth.SMSProvider = ""
+	auth.SMSTwilioAccountSID = ""
+	auth.SMSTwilioAuthToken = ""
+	auth.SMSTwilioMessageServiceSID = ""
+	auth.SMSTwilioContentSID = ""
+	auth.SMSTwilioVerifyAccountSID = ""
+	auth.SMSTwilioVerifyAuthToken = ""
+	auth.SMSTwilioVerifyMessageServiceSID = ""
+	auth.SMSMessagebirdAccessKey = ""
+	auth.SMSMessagebirdOriginator = ""
+	auth.SMSVonageAPIKey = ""
+	auth.SMSVonageAPISecret = ""
+	auth.SMSVonageFrom = ""
+	auth.ExternalPhoneEnabled = false
+
+	providers := make([]domain.AuthExternalOAuthProvider, 0, len(auth.ExternalOAuthProviders))
+	for _, provider := range auth.ExternalOAuthProviders {
+		provider.Enabled = false
+		provider.Secret = ""
+		providers = append(providers, provider)
+	}
+	auth.ExternalOAuthProviders = providers
+
+	auth.SecurityCaptchaEnabled = false
+	auth.SecurityCaptchaSecret = ""
+
+	return auth
+}
+
+// buildPreviewCustomVapis builds the custom vapis of the parent from the git branch of the preview. The migration
+// histories of the custom vapis seeded from the parent are moved over to the custom vapis of the preview.
+func (ss *service) buildPreviewCustomVapis(
+	ctx context.Context,
+	parent *domain.Stack,
+	preview *domain.Stack,
+	accessToken string,
+) error {
+	if len(parent.CustomVapis) == 0 {
+		return nil
+	}
+
+	repo, err := ss.cloneStackRepo(ctx, preview, accessToken)
+	if err != nil {
+		return err
+	}
+
+	tx := helpers.GetTx(ctx)
+	for _, parentCustomVapi := range parent.CustomVapis {
+		tarFilePath, err := ss.uploadVapiTarFile(ctx, preview, repo, parentCustomVapi.Name)
+		if err != nil {
+			return err
+		}
+
+		customVapi := domain.CustomVapi{
+			StackID:     preview.ID,
+			Name:        parentCustomVapi.Name,
+			TarFilePath: tarFilePath,
+		}
+		if err := customVapi.Save(tx.Omit(clause.Associations)); err != nil {
+			return err
+		}
+
+		preview.CustomVapis = append(preview.CustomVapis, customVapi)
+	}
+
+	conn, err := ss.connectDatabase(ctx, preview)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(ctx)
+
+	for i, customVapi := range preview.CustomVapis {
+		if _, err := conn.Exec(
+			ctx,
+			`UPDATE stack.custom_vapi_schema_migrations SET custom_vapi_id = $1 WHERE custom_vapi_id = $2`,
+			customVapi.ID,
+			parent.CustomVapis[i].ID,
+		); err != nil {
+			return errors.Wrapf(err, "failed to move migration history of custom vapi %s", customVapi.Name)
+		}
+	}
+
+	return nil
+}
@@ -2,7 +2,6 @@ package stack
 
 import (
 	"context"
-	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
@@ -25,8 +24,8 @@ func (ss *service) GetStacks(
 
 	if user, err := ss.users.GetUser(ctx); err != nil {
 		return nil, err
-	} else if project.OwnerID != user.ID && !user.IsSuperuser() {
-		return nil, errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this project")
+	} else if err := project.CheckPermission(tx, user, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
 	}
 
 	tx = tx.Where("project_id = ? and id > ?", project.ID, cursor)
@@ -57,8 +56,8 @@ func (ss *service) GetStack(ctx context.Context, id uint) (*domain.Stack, error)
 
 	if user, err := ss.users.GetUser(ctx); err != nil {
 		return nil, err
-	} else if st.Project.OwnerID != user.ID && !user.IsSuperuser() {
-		return nil, errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this stack")
+	} else if err := st.Project.CheckPermission(tx, user, domain.OrganizationRoleViewer); err != nil {
+		return nil, err
 	}
 
 	return st, nil
@@ -0,0 +1,35 @@
+package stack
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SetRateLimits replaces the rate limits of the stack, which are applied at its ingress on the next deployment.
+func (ss *service) SetRateLimits(ctx context.Context, stackId uint, limits domain.StackRateLimits) (*domain.Stack, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	if err := limits.Validate(stack); err != nil {
+		return nil, err
+	}
+
+	stack.RateLimits = datatypes.NewJSONType(limits)
+	if err := helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
+		return stack.Save(tx.Omit(clause.Associations))
+	}); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
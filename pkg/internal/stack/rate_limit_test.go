@@ -0,0 +1,32 @@
+package stack_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+)
+
+func (s *StackServiceTestSuite) TestGivenStackWhenSetRateLimitsThenShouldBeSaved() {
+	// when
+	stack, err := s.stackService.SetRateLimits(s, s.stack.ID, domain.StackRateLimits{
+		Global: &domain.RateLimit{RequestsPerSecond: 100, Burst: 50},
+	})
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(int64(100), stack.RateLimits.Data().Global.RequestsPerSecond)
+
+	stack, err = s.stackService.GetStack(s, s.stack.ID)
+	s.Require().NoError(err)
+	s.Require().NotNil(stack.RateLimits.Data().Global)
+	s.Equal(int64(50), stack.RateLimits.Data().Global.Burst)
+}
+
+func (s *StackServiceTestSuite) TestGivenStackWhenSetRateLimitsOfNotInstalledVapiShouldBeError() {
+	// when
+	_, err := s.stackService.SetRateLimits(s, s.stack.ID, domain.StackRateLimits{
+		Vapis: map[string]domain.RateLimit{"not-installed": {RequestsPerSecond: 10}},
+	})
+
+	// then
+	s.ErrorIs(err, tclerrors.ErrBadRequest)
+}
@@ -0,0 +1,195 @@
+package stack
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+const requestUsageFlushInterval = 10 * time.Second
+
+type RequestUsage struct {
+	Month string
+	// StackRequests is the number of the requests to the stack in the month
+	StackRequests int64
+	// OwnerRequests is the number of the requests to all the stacks of the owner of the stack in the month
+	OwnerRequests int64
+	// Quota is the monthly request quota of the owner of the stack
+	Quota int64
+}
+
+type requestUsageKey struct {
+	stackId uint
+	month   string
+}
+
+// requestUsageCounter counts the requests to the stacks in memory until they are flushed to the database.
+// The quotas are checked on flush, so the requests can exceed a quota by those of a flush interval, and a raised
+// quota lets the requests through again from the next flush.
+type requestUsageCounter struct {
+	mu      sync.Mutex
+	pending map[requestUsageKey]int64
+	// exceeded are the stacks whose owner has used up the quota of the month
+	exceeded map[requestUsageKey]bool
+}
+
+func newRequestUsageCounter() *requestUsageCounter {
+	return &requestUsageCounter{
+		pending:  map[requestUsageKey]int64{},
+		exceeded: map[requestUsageKey]bool{},
+	}
+}
+
+func (c *requestUsageCounter) add(stackId uint, now time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := requestUsageKey{stackId: stackId, month: domain.RequestUsageMonth(now)}
+	if c.exceeded[key] {
+		return errors.Wrapf(tclerrors.ErrQuotaExceeded, "monthly request quota is exceeded")
+	}
+
+	c.pending[key]++
+	return nil
+}
+
+func (c *requestUsageCounter) takePending() map[requestUsageKey]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := c.pending
+	c.pending = map[requestUsageKey]int64{}
+	return pending
+}
+
+// restore puts back the requests failed to be flushed, to flush them again later.
+func (c *requestUsageCounter) restore(key requestUsageKey, requests int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.pending[key] += requests
+}
+
+// exceededKeys returns the stacks over the quota in the month, whose quotas are checked again on every flush since
+// their rejected requests are not counted. The ones of the past months are forgotten.
+func (c *requestUsageCounter) exceededKeys(month string) []requestUsageKey {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]requestUsageKey, 0, len(c.exceeded))
+	for key := range c.exceeded {
+		if key.month != month {
+			delete(c.exceeded, key)
+			continue
+		}
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+func (c *requestUsageCounter) setExceeded(key requestUsageKey, exceeded bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if exceeded {
+		c.exceeded[key] = true
+	} else {
+		delete(c.exceeded, key)
+	}
+}
+
+// CountRequest counts a request to the stack at its ingress, failing with ErrQuotaExceeded
+// once the owner of the stack has used up the monthly request quota.
+func (ss *service) CountRequest(stackId uint) error {
+	return ss.requestUsages.add(stackId, time.Now())
+}
+
+func (ss *service) GetRequestUsage(ctx context.Context, stackId uint) (*RequestUsage, error) {
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	owner, err := domain.FindStackOwner(tx, stack.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := RequestUsage{
+		Month: domain.RequestUsageMonth(time.Now()),
+		Quota: owner.RequestQuota(),
+	}
+	if usage.StackRequests, err = domain.FindStackRequestUsage(tx, stack.ID, usage.Month); err != nil {
+		return nil, err
+	}
+	if usage.OwnerRequests, err = domain.SumRequestUsagesOfOwner(tx, owner.ID, usage.Month); err != nil {
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// RunRequestUsageFlusher flushes the counted requests to the database and checks the quotas of their stacks
+// every interval until ctx is done. The transaction in ctx is used as the database connection.
+func (ss *service) RunRequestUsageFlusher(ctx context.Context) error {
+	ticker := time.NewTicker(requestUsageFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// the requests counted until the shutdown are not lost
+			ss.flushRequestUsages(context.WithoutCancel(ctx))
+			return nil
+		case <-ticker.C:
+			ss.flushRequestUsages(ctx)
+		}
+	}
+}
+
+func (ss *service) flushRequestUsages(ctx context.Context) {
+	db := helpers.GetTx(ctx).WithContext(ctx)
+
+	pending := ss.requestUsages.takePending()
+	for key, requests := range pending {
+		if err := domain.AddStackRequestUsage(db, key.stackId, key.month, requests); err != nil {
+			logger.Warn("failed to flush request usage", "stackId", key.stackId, "err", err)
+			ss.requestUsages.restore(key, requests)
+		}
+	}
+
+	keys := map[requestUsageKey]struct{}{}
+	for key := range pending {
+		keys[key] = struct{}{}
+	}
+	for _, key := range ss.requestUsages.exceededKeys(domain.RequestUsageMonth(time.Now())) {
+		keys[key] = struct{}{}
+	}
+
+	ownerRequests := map[requestUsageKey]int64{}
+	for key := range keys {
+		owner, err := domain.FindStackOwner(db, key.stackId)
+		if err != nil {
+			logger.Warn("failed to find stack owner", "stackId", key.stackId, "err", err)
+			continue
+		}
+
+		ownerKey := requestUsageKey{stackId: owner.ID, month: key.month}
+		requests, ok := ownerRequests[ownerKey]
+		if !ok {
+			if requests, err = domain.SumRequestUsagesOfOwner(db, owner.ID, key.month); err != nil {
+				logger.Warn("failed to sum request usages", "userId", owner.ID, "err", err)
+				continue
+			}
+			ownerRequests[ownerKey] = requests
+		}
+
+		ss.requestUsages.setExceeded(key, requests >= owner.RequestQuota())
+	}
+}
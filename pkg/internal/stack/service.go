@@ -3,14 +3,18 @@ package stack
 import (
 	"context"
 	pkgconfig "github.com/habiliai/apidepot/pkg/config"
+	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/digo"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	tclog "github.com/habiliai/apidepot/pkg/internal/log"
 	"github.com/habiliai/apidepot/pkg/internal/services"
 	"github.com/habiliai/apidepot/pkg/internal/storage"
 	"github.com/habiliai/apidepot/pkg/internal/user"
 	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"time"
 )
 
 var logger = tclog.GetLogger()
@@ -34,7 +38,9 @@ type Service interface {
 		id uint,
 		input PatchStackInput,
 	) error
-	MigrateDatabase(context.Context, uint, MigrateDatabaseInput) error
+	MigrateDatabase(context.Context, uint, MigrateDatabaseInput) (*MigrateDatabaseOutput, error)
+	GetMigrationStatus(ctx context.Context, stackId uint, migrations []Migration) ([]MigrationStatus, error)
+	RollbackMigrations(ctx context.Context, stackId uint, input RollbackMigrationsInput) (*RollbackMigrationsOutput, error)
 
 	EnableOrUpdateAuth(ctx context.Context, stackId uint, input EnableOrUpdateAuthInput, isCreate bool) error
 	DisableAuth(ctx context.Context, stackId uint) error
@@ -61,14 +67,40 @@ type Service interface {
 		stackId uint,
 		vapiId uint,
 	) error
+	SetVapiResources(
+		ctx context.Context,
+		stackId uint,
+		vapiId uint,
+		resources domain.VapiResources,
+	) (*domain.StackVapi, error)
+	LockVapis(ctx context.Context, stackId uint) ([]domain.StackVapiLock, error)
+	GetLockedVapiReleases(ctx context.Context, stackId uint) ([]domain.VapiRelease, error)
+	GetVapiNotices(ctx context.Context, stackId uint) ([]domain.StackVapiNotice, error)
+	GetStackDependencyTree(ctx context.Context, stackId uint) (*vapi.DependencyResolution, error)
 	GetStorageUsage(ctx context.Context, stackId uint) (int64, error)
 	GetMyTotalStorageUsage(
 		ctx context.Context,
 	) (int64, error)
+	RotateJWTSecret(ctx context.Context, stackId uint, gracePeriod time.Duration) (*domain.Stack, error)
+	CreateApiKey(ctx context.Context, stackId uint, input CreateApiKeyInput) (*domain.StackApiKey, string, error)
+	ListApiKeys(ctx context.Context, stackId uint) ([]domain.StackApiKey, error)
+	RevokeApiKey(ctx context.Context, stackId uint, apiKeyId uint) error
+	VerifyApiKey(ctx context.Context, stackId uint, token string, uri string) error
+	SetRateLimits(ctx context.Context, stackId uint, limits domain.StackRateLimits) (*domain.Stack, error)
+	CountRequest(stackId uint) error
+	GetRequestUsage(ctx context.Context, stackId uint) (*RequestUsage, error)
+	RunRequestUsageFlusher(ctx context.Context) error
+	AddCustomDomain(ctx context.Context, stackId uint, hostname string) (*domain.StackCustomDomain, error)
+	VerifyCustomDomain(ctx context.Context, stackId uint, customDomainId uint) (*domain.StackCustomDomain, error)
+	RemoveCustomDomain(ctx context.Context, stackId uint, customDomainId uint) error
+	SetPrimaryDomain(ctx context.Context, stackId uint, hostname string) (*domain.Stack, error)
+	EnableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error)
+	DisableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error)
+	SyncReplicas(ctx context.Context, stackId uint, queries []string) error
 	SetVapiEnv(
 		ctx context.Context,
 		stackId uint,
-		env map[string]string,
+		envVars []domain.StackVapiEnvVar,
 	) error
 	UnsetVapiEnv(ctx context.Context, stackId uint, names []string) error
 	EnableCustomVapi(
@@ -92,6 +124,24 @@ type Service interface {
 		ctx context.Context,
 		stackId uint,
 	) ([]domain.CustomVapi, error)
+	RebuildCustomVapis(
+		ctx context.Context,
+		stackId uint,
+		names []string,
+		accessToken string,
+	) ([]domain.CustomVapi, error)
+	CreatePreviewStack(
+		ctx context.Context,
+		parentStackId uint,
+		input CreatePreviewStackInput,
+	) (*domain.Stack, error)
+	DeletePreviewStack(ctx context.Context, stackId uint) error
+	GetStackPreviews(ctx context.Context, stackId uint) ([]domain.StackPreview, error)
+
+	CreateBackup(ctx context.Context, stackId uint) (*domain.StackBackup, error)
+	ListBackups(ctx context.Context, stackId uint) ([]domain.StackBackup, error)
+	RestoreBackup(ctx context.Context, stackId uint, backupId uint) error
+	RunBackupScheduler(ctx context.Context) error
 }
 
 type service struct {
@@ -103,6 +153,8 @@ type service struct {
 	users         user.Service
 	storageClient *storage.Client
 	git           services.GitService
+	dnsResolver   services.DNSResolver
+	requestUsages *requestUsageCounter
 }
 
 func NewService(
@@ -114,6 +166,7 @@ func NewService(
 	userService user.Service,
 	storageClient *storage.Client,
 	git services.GitService,
+	dnsResolver services.DNSResolver,
 ) Service {
 	return &service{
 		stackConfig:   stackConfig,
@@ -124,6 +177,8 @@ func NewService(
 		users:         userService,
 		storageClient: storageClient,
 		git:           git,
+		dnsResolver:   dnsResolver,
+		requestUsages: newRequestUsageCounter(),
 	}
 }
 
@@ -161,9 +216,14 @@ func init() {
 			return nil, err
 		}
 
+		dnsResolver, err := digo.Get[services.DNSResolver](ctx, services.ServiceKeyDNSResolver)
+		if err != nil {
+			return nil, err
+		}
+
 		switch ctx.Env {
 		case digo.EnvProd:
-			return NewService(
+			s := NewService(
 				bs,
 				rs,
 				ctx.Config.Stack,
@@ -172,7 +232,23 @@ func init() {
 				users,
 				storageClient,
 				gitClient,
-			), nil
+				dnsResolver,
+			).(*service)
+
+			if ctx.Config.DB.AutoMigration {
+				db, err := digo.Get[*gorm.DB](ctx, services.ServiceKeyDB)
+				if err != nil {
+					return nil, err
+				}
+
+				if count, err := s.lockUnlockedStacks(helpers.WithTx(ctx, db.WithContext(ctx))); err != nil {
+					return nil, err
+				} else if count > 0 {
+					logger.Info("locked vapis of stacks installed before locking", "count", count)
+				}
+			}
+
+			return s, nil
 		case digo.EnvTest:
 			{
 				regionalConf := pkgconfig.RegionalStackConfig{
@@ -190,9 +266,11 @@ func init() {
 					bs,
 					rs,
 					pkgconfig.StackConfig{
-						ForceDelete: true,
-						Seoul:       regionalConf,
-						Singapore:   regionalConf,
+						ForceDelete:        true,
+						ApiKeyVerifyURL:    "http://localhost:8081" + constants.PathApiKeyVerify,
+						ApiKeyVerifySecret: "verify-secret",
+						Seoul:              regionalConf,
+						Singapore:          regionalConf,
 					},
 					pkgconfig.DBConfig{
 						Seoul:           regionalDbConf,
@@ -206,6 +284,7 @@ func init() {
 					users,
 					storageClient,
 					gitClient,
+					dnsResolver,
 				), nil
 			}
 		default:
@@ -9,6 +9,7 @@ import (
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/habiliai/apidepot/pkg/internal/k8syaml"
 	"github.com/habiliai/apidepot/pkg/internal/services"
+	servicestest "github.com/habiliai/apidepot/pkg/internal/services/test"
 	"github.com/habiliai/apidepot/pkg/internal/stack"
 	"github.com/habiliai/apidepot/pkg/internal/storage"
 	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
@@ -37,6 +38,7 @@ type StackServiceTestSuite struct {
 	stackService   stack.Service
 	vapis          vapi.Service
 	users          *usertest.Service
+	dnsResolver    *servicestest.MockDNSResolver
 	user           domain.User
 	storageClient  *storage.Client
 	k8syamlService *k8syaml.Service
@@ -55,6 +57,9 @@ func (s *StackServiceTestSuite) SetupTest() {
 		digo.Set(container, user.ServiceKey, s.users)
 		s.users.On("GetUser", mock.Anything).Return(&s.user, nil).Maybe()
 
+		s.dnsResolver = servicestest.NewTestDNSResolver()
+		digo.Set(container, services.ServiceKeyDNSResolver, s.dnsResolver)
+
 		s.db = digo.MustGet[*gorm.DB](container, services.ServiceKeyDB)
 		ctx = helpers.WithTx(ctx, s.db)
 		s.stackService = digo.MustGet[stack.Service](container, stack.ServiceKey)
@@ -294,7 +294,7 @@ func (ss *service) EnableOrUpdateAuth(ctx context.Context, stackID uint, input E
 		return err
 	}
 
-	if err := ss.hasPermission(ctx, stack.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
 	}
 
@@ -316,17 +316,8 @@ func (ss *service) EnableOrUpdateAuth(ctx context.Context, stackID uint, input E
 	}
 
 	stack.Auth = datatypes.NewJSONType(auth)
-	stack.AdminApiKey, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"role": "service_role",
-	}).SignedString([]byte(stack.Auth.Data().JWTSecret))
-	if err != nil {
-		return errors.Wrapf(err, "failed to sign jwt")
-	}
-	stack.AnonApiKey, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"role": "anon",
-	}).SignedString([]byte(stack.Auth.Data().JWTSecret))
-	if err != nil {
-		return errors.Wrapf(err, "failed to sign jwt")
+	if err := signApiKeys(stack); err != nil {
+		return err
 	}
 
 	if err := tx.Transaction(func(tx *gorm.DB) (err error) {
@@ -351,10 +342,8 @@ func (ss *service) DisableAuth(ctx context.Context, stackID uint) error {
 		return err
 	}
 
-	if user, err := ss.users.GetUser(ctx); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
-	} else if stack.Project.OwnerID != user.ID && !user.IsSuperuser() {
-		return errors.Wrapf(tclerrors.ErrForbidden, "you are not allowed to access this stack")
 	}
 
 	if !stack.AuthEnabled {
@@ -370,3 +359,69 @@ func (ss *service) DisableAuth(ctx context.Context, stackID uint) error {
 		return nil
 	})
 }
+
+// RotateJWTSecret replaces the jwt secret of the stack and re-signs its api keys.
+// The current secret is still accepted for the grace period, so that the old api keys keep working until then.
+func (ss *service) RotateJWTSecret(ctx context.Context, stackId uint, gracePeriod time.Duration) (*domain.Stack, error) {
+	if gracePeriod < 0 {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "grace period must not be negative")
+	}
+
+	tx := helpers.GetTx(ctx)
+
+	// serializes the rotations of the stack
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("id").
+		First(&domain.Stack{}, stackId).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to lock stack")
+	}
+
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	if !stack.AuthEnabled {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionRequired, "auth is not created")
+	} else if stack.PreviousJWTSecretExpiresAt != nil {
+		return nil, errors.Wrapf(tclerrors.ErrPreconditionFailed, "previous jwt secret is accepted until %s", stack.PreviousJWTSecretExpiresAt.Format(time.RFC3339))
+	}
+
+	secret, err := goutils.CryptoRandomAlphaNumeric(32)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to generate random hash")
+	}
+	stack.RotateJWTSecret(secret, gracePeriod)
+	if err := signApiKeys(stack); err != nil {
+		return nil, err
+	}
+
+	if err := stack.Save(tx.Omit(clause.Associations)); err != nil {
+		return nil, err
+	}
+
+	return stack, nil
+}
+
+// signApiKeys signs the admin and anon api keys of the stack with its jwt secret.
+func signApiKeys(stack *domain.Stack) (err error) {
+	secret := []byte(stack.Auth.Data().JWTSecret)
+	stack.AdminApiKey, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"role": "service_role",
+	}).SignedString(secret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign jwt")
+	}
+	stack.AnonApiKey, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"role": "anon",
+	}).SignedString(secret)
+	if err != nil {
+		return errors.Wrapf(err, "failed to sign jwt")
+	}
+
+	return nil
+}
@@ -55,6 +55,30 @@ func (s *StackServiceTestSuite) TestGivenNotEnabledAuthWhenPatchAuthShouldBeErro
 	s.ErrorAs(err, &tclerrors.ErrPreconditionRequired)
 }
 
+func (s *StackServiceTestSuite) TestGivenAuthEnabledStackWhenRotateJWTSecretThenPreviousSecretShouldBeKeptForGracePeriod() {
+	// given
+	s.installAuth(s.Context, s.stack)
+	defer s.uninstallAuth(s.Context, s.stack)
+
+	before, err := s.stackService.GetStack(s, s.stack.ID)
+	s.Require().NoError(err)
+
+	// when
+	rotated, err := s.stackService.RotateJWTSecret(s, s.stack.ID, time.Hour)
+
+	// then
+	s.Require().NoError(err)
+	s.Equal(before.Auth.Data().JWTSecret, rotated.Auth.Data().PreviousJWTSecret)
+	s.NotEqual(before.Auth.Data().JWTSecret, rotated.Auth.Data().JWTSecret)
+	s.NotEqual(before.AdminApiKey, rotated.AdminApiKey)
+	s.NotEqual(before.AnonApiKey, rotated.AnonApiKey)
+	s.Require().NotNil(rotated.PreviousJWTSecretExpiresAt)
+	s.WithinDuration(time.Now().Add(time.Hour), *rotated.PreviousJWTSecretExpiresAt, time.Minute)
+
+	_, err = s.stackService.RotateJWTSecret(s, s.stack.ID, time.Hour)
+	s.ErrorIs(err, tclerrors.ErrPreconditionFailed)
+}
+
 func (s *StackServiceTestSuite) installAuth(ctx context.Context, st *domain.Stack) {
 	require := s.Require()
 
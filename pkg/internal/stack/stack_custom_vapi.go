@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"github.com/go-git/go-git/v5"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/constants"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
@@ -158,6 +159,53 @@ func (s *service) GetCustomVapis(
 	return customVapis, nil
 }
 
+// RebuildCustomVapis rebuilds the tar files of the custom vapis of the stack from its git branch, cloned with
+// accessToken, e.g. of the github app installation. The permission of the current user is not checked.
+// Every custom vapi is rebuilt if names is nil. It returns the rebuilt ones.
+func (s *service) RebuildCustomVapis(
+	ctx context.Context,
+	stackId uint,
+	names []string,
+	accessToken string,
+) ([]domain.CustomVapi, error) {
+	tx := helpers.GetTx(ctx)
+	stack, err := domain.FindStackByID(tx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := tx.Where("stack_id = ?", stack.ID)
+	if names != nil {
+		stmt = stmt.Where("name IN ?", names)
+	}
+	var customVapis []domain.CustomVapi
+	if err := stmt.Order("id ASC").Find(&customVapis).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to get custom vapis")
+	} else if len(customVapis) == 0 {
+		return nil, nil
+	}
+
+	repo, err := s.cloneStackRepo(ctx, stack, accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range customVapis {
+		customVapi := &customVapis[i]
+		tarFilePath, err := s.uploadVapiTarFile(ctx, stack, repo, customVapi.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		customVapi.TarFilePath = tarFilePath
+		if err := customVapi.Save(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return customVapis, nil
+}
+
 func (s *service) createVapiTarFile(ctx context.Context, stack *domain.Stack, name string) (string, error) {
 	user, err := s.users.GetUser(ctx)
 	if err != nil {
@@ -169,13 +217,22 @@ func (s *service) createVapiTarFile(ctx context.Context, stack *domain.Stack, na
 		return "", err
 	}
 
-	gitUrl := fmt.Sprintf("https://%s@github.com/%s", accessToken, stack.GitRepo)
-
-	repo, err := s.git.Clone(ctx, gitUrl, stack.GitBranch)
+	repo, err := s.cloneStackRepo(ctx, stack, accessToken)
 	if err != nil {
 		return "", err
 	}
 
+	return s.uploadVapiTarFile(ctx, stack, repo, name)
+}
+
+func (s *service) cloneStackRepo(ctx context.Context, stack *domain.Stack, accessToken string) (*git.Repository, error) {
+	gitUrl := fmt.Sprintf("https://%s@github.com/%s", accessToken, stack.GitRepo)
+
+	return s.git.Clone(ctx, gitUrl, stack.GitBranch)
+}
+
+// uploadVapiTarFile archives /vapis/<name> of the repo and uploads it as the tar file of the custom vapi.
+func (s *service) uploadVapiTarFile(ctx context.Context, stack *domain.Stack, repo *git.Repository, name string) (string, error) {
 	tree, err := s.git.OpenDir(repo, fmt.Sprintf("/vapis/%s", name))
 	if err != nil {
 		return "", err
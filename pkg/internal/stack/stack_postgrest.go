@@ -34,7 +34,7 @@ func (ss *service) EnableOrUpdatePostgrest(
 		return err
 	}
 
-	if err := ss.hasPermission(ctx, stack.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
 	}
 
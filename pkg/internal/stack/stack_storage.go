@@ -5,6 +5,7 @@ import (
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	tcltypes "github.com/habiliai/apidepot/pkg/internal/types"
 	"github.com/pkg/errors"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -32,7 +33,7 @@ func (ss *service) EnableOrUpdateStorage(
 		return err
 	}
 
-	if err := ss.hasPermission(ctx, stack.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
 	}
 
@@ -81,6 +82,13 @@ func (ss *service) EnableOrUpdateStorage(
 			if err := ss.bucketService.CreateBucket(ctx, stack.DefaultRegion, storage.S3Bucket); err != nil {
 				return err
 			}
+
+			// the storage of multi-region stacks is served from the bucket in each zone
+			for _, zone := range stack.ReplicaZones() {
+				if err := ss.createBucketIfNotExists(ctx, zone, storage.S3Bucket); err != nil {
+					return err
+				}
+			}
 		}
 
 		return nil
@@ -111,8 +119,10 @@ func (ss *service) DisableStorage(ctx context.Context, stackId uint) error {
 			return err
 		}
 
-		if err := ss.bucketService.DeleteBucket(ctx, stack.DefaultRegion, stack.Domain); err != nil {
-			return err
+		for _, zone := range stack.Zones() {
+			if err := ss.bucketService.DeleteBucket(ctx, zone, stack.Domain); err != nil {
+				return err
+			}
 		}
 
 		return nil
@@ -164,3 +174,13 @@ func (s *service) GetMyTotalStorageUsage(
 
 	return totalUsage, nil
 }
+
+func (ss *service) createBucketIfNotExists(ctx context.Context, zone tcltypes.InstanceZone, bucket string) error {
+	if exists, err := ss.bucketService.IsBucketExists(ctx, zone, bucket); err != nil {
+		return err
+	} else if exists {
+		return nil
+	}
+
+	return ss.bucketService.CreateBucket(ctx, zone, bucket)
+}
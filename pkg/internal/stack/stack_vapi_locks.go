@@ -0,0 +1,133 @@
+package stack
+
+import (
+	"context"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func (s *service) LockVapis(
+	ctx context.Context,
+	stackId uint,
+) ([]domain.StackVapiLock, error) {
+	if _, err := s.GetStack(ctx, stackId); err != nil {
+		return nil, err
+	}
+
+	var locks []domain.StackVapiLock
+	if err := helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) (err error) {
+		locks, err = s.lockVapis(helpers.WithTx(ctx, tx), stackId)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return locks, nil
+}
+
+func (s *service) GetLockedVapiReleases(
+	ctx context.Context,
+	stackId uint,
+) ([]domain.VapiRelease, error) {
+	if _, err := s.GetStack(ctx, stackId); err != nil {
+		return nil, err
+	}
+
+	return domain.FindLockedVapiReleasesByStackID(helpers.GetTx(ctx), stackId)
+}
+
+// GetStackDependencyTree returns the dependency tree of the locked vapis of the stack, i.e. of what gets deployed.
+func (s *service) GetStackDependencyTree(
+	ctx context.Context,
+	stackId uint,
+) (*vapi.DependencyResolution, error) {
+	if _, err := s.GetStack(ctx, stackId); err != nil {
+		return nil, err
+	}
+
+	locks, err := domain.FindStackVapiLocksByStackID(helpers.GetTx(ctx), stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.vapis.BuildLockedDependencyTree(ctx, locks)
+}
+
+// lockUnlockedStacks locks the vapis of the stacks whose vapis were installed before locking was introduced. A stack
+// whose vapis can't be resolved is skipped with a warning, and is locked once its vapis are changed.
+func (s *service) lockUnlockedStacks(ctx context.Context) (int, error) {
+	tx := helpers.GetTx(ctx)
+	stackIds, err := domain.FindStackIdsWithoutVapiLocks(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, stackId := range stackIds {
+		if err := tx.Transaction(func(tx *gorm.DB) error {
+			_, err := s.lockVapis(helpers.WithTx(ctx, tx), stackId)
+			return err
+		}); err != nil {
+			logger.Warn("failed to lock vapis of stack", "stackId", stackId, "err", err)
+			continue
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+func (s *service) lockVapis(
+	ctx context.Context,
+	stackId uint,
+) ([]domain.StackVapiLock, error) {
+	tx := helpers.GetTx(ctx)
+
+	var stackVapis []domain.StackVapi
+	if err := tx.
+		Preload("Vapi").
+		Preload("Vapi.Package").
+		Where("stack_id = ?", stackId).
+		Find(&stackVapis).
+		Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find stack vapis")
+	}
+
+	roots := make([]domain.VapiRelease, 0, len(stackVapis))
+	direct := make(map[uint]bool, len(stackVapis))
+	for _, stackVapi := range stackVapis {
+		roots = append(roots, stackVapi.Vapi)
+		direct[stackVapi.VapiID] = true
+	}
+
+	resolution, err := s.vapis.ResolveDependencies(ctx, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := domain.DeleteStackVapiLocksByStackID(tx, stackId); err != nil {
+		return nil, err
+	}
+
+	locks := make([]domain.StackVapiLock, 0, len(resolution.Releases))
+	for i, rel := range resolution.Releases {
+		lock := domain.StackVapiLock{
+			StackID: stackId,
+			VapiID:  rel.ID,
+			Vapi:    rel,
+			Seq:     i,
+			Direct:  direct[rel.ID],
+		}
+		if err := tx.Omit("Vapi", "Stack").Create(&lock).Error; err != nil {
+			return nil, errors.Wrapf(err, "failed to create stack vapi lock")
+		}
+		locks = append(locks, lock)
+	}
+
+	logger.Info("locked vapis", "stackId", stackId, "num", len(locks))
+
+	return locks, nil
+}
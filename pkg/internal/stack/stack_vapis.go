@@ -5,7 +5,9 @@ import (
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/util"
 	"github.com/pkg/errors"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
@@ -35,7 +37,7 @@ func (ss *service) DisableVapi(
 		return err
 	}
 
-	if err := ss.hasPermission(ctx, stack.ProjectID); err != nil {
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
 		return err
 	}
 
@@ -46,7 +48,12 @@ func (ss *service) DisableVapi(
 	}
 
 	return tx.Transaction(func(tx *gorm.DB) error {
-		return errors.Wrapf(stackVapi.Delete(tx), "failed to delete stack vapi")
+		if err := stackVapi.Delete(tx); err != nil {
+			return errors.Wrapf(err, "failed to delete stack vapi")
+		}
+
+		_, err := ss.lockVapis(helpers.WithTx(ctx, tx), stackId)
+		return err
 	})
 }
 
@@ -71,6 +78,26 @@ func (ss *service) EnableVapi(
 		return nil, err
 	}
 
+	if err := vapiRelease.CheckInstallable(); err != nil {
+		return nil, err
+	}
+
+	if err := ss.vapis.IsGrantedVapi(ctx, stackId, vapiRelease); err != nil {
+		return nil, err
+	}
+
+	var owner domain.User
+	if err := tx.First(&owner, stack.Project.OwnerID).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find project owner")
+	}
+
+	// the resources the release asks for must fit in the quota of the owner as the overrides do
+	if err := domain.DefaultVapiResources().
+		Override(vapiRelease.Resources.Data()).
+		CheckQuota(&owner); err != nil {
+		return nil, err
+	}
+
 	if err := stack.ValidateVapiNameUniqueness(tx, vapiRelease.Package.Name); err != nil {
 		return nil, err
 	}
@@ -81,11 +108,19 @@ func (ss *service) EnableVapi(
 	}
 
 	if err := tx.Transaction(func(tx *gorm.DB) error {
-		return errors.Wrapf(stackVapi.Create(tx), "failed to create stack vapi")
+		if err := stackVapi.Create(tx); err != nil {
+			return errors.Wrapf(err, "failed to create stack vapi")
+		}
+
+		_, err := ss.lockVapis(helpers.WithTx(ctx, tx), stackId)
+		return err
 	}); err != nil {
 		return nil, err
 	}
 
+	// the deprecation message of the release is shown to the installer
+	stackVapi.Vapi = *vapiRelease
+
 	return &stackVapi, nil
 }
 
@@ -120,6 +155,14 @@ func (ss *service) UpdateVapi(
 		return nil, err
 	}
 
+	if err := vapiRelease.CheckInstallable(); err != nil {
+		return nil, err
+	}
+
+	if err := ss.vapis.IsGrantedVapi(ctx, stackId, vapiRelease); err != nil {
+		return nil, err
+	}
+
 	if err := st.ValidateVapiNameUniqueness(tx, vapiRelease.Package.Name); err != nil {
 		return nil, err
 	}
@@ -129,7 +172,8 @@ func (ss *service) UpdateVapi(
 			return err
 		}
 
-		return nil
+		_, err := ss.lockVapis(helpers.WithTx(ctx, tx), stackId)
+		return err
 	}); err != nil {
 		return nil, err
 	}
@@ -137,21 +181,85 @@ func (ss *service) UpdateVapi(
 	return nil, nil
 }
 
+// GetVapiNotices returns the state changes of the vapi releases installed on the stack, from the newest one.
+func (ss *service) GetVapiNotices(ctx context.Context, stackId uint) ([]domain.StackVapiNotice, error) {
+	if _, err := ss.GetStack(ctx, stackId); err != nil {
+		return nil, err
+	}
+
+	return domain.FindStackVapiNoticesByStackId(helpers.GetTx(ctx), stackId)
+}
+
+// SetVapiResources overrides the resources of the vapi on the stack. The override is bounded by the quota of the project owner.
+func (ss *service) SetVapiResources(
+	ctx context.Context,
+	stackId uint,
+	vapiId uint,
+	resources domain.VapiResources,
+) (*domain.StackVapi, error) {
+	if err := resources.Validate(); err != nil {
+		return nil, err
+	}
+
+	stack, err := ss.GetStack(ctx, stackId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ss.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	stackVapi, err := domain.GetStackVapiByStackIDAndVapiID(tx, stackId, vapiId)
+	if err != nil {
+		return nil, err
+	}
+
+	var owner domain.User
+	if err := tx.First(&owner, stack.Project.OwnerID).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find project owner")
+	}
+
+	effective := domain.DefaultVapiResources().
+		Override(stackVapi.Vapi.Resources.Data()).
+		Override(resources)
+	if err := effective.Validate(); err != nil {
+		return nil, err
+	}
+	if err := effective.CheckQuota(&owner); err != nil {
+		return nil, err
+	}
+
+	stackVapi.Resources = datatypes.NewJSONType(resources)
+	if err := tx.Model(&stackVapi).Update("resources", stackVapi.Resources).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to update stack vapi resources")
+	}
+
+	return &stackVapi, nil
+}
+
+// SetVapiEnv adds or updates the env vars of the stack's vapis. Each name is prefixed with the vapi name, e.g. "sns.MAX_USERS".
 func (s *service) SetVapiEnv(
 	ctx context.Context,
 	stackId uint,
-	env map[string]string,
+	envVars []domain.StackVapiEnvVar,
 ) error {
 	stack, err := s.GetStack(ctx, stackId)
 	if err != nil {
 		return err
 	}
 
-	envVars := stack.GetVapiEnvVarsMap()
-	for key, value := range env {
-		envVars[key] = value
+	if err := s.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return err
+	}
+
+	for _, envVar := range envVars {
+		if vapiName, name := util.SplitStringToPair(envVar.Name, "."); vapiName == "" || name == "" {
+			return errors.Wrapf(tclerrors.ErrBadRequest, "invalid vapi env var name: %s", envVar.Name)
+		}
+		stack.SetVapiEnvVar(envVar)
 	}
-	stack.SetVapiEnvVarsMap(envVars)
 
 	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
 		return stack.Save(tx)
@@ -164,14 +272,13 @@ func (s *service) UnsetVapiEnv(ctx context.Context, stackId uint, names []string
 		return err
 	}
 
-	envVars := stack.GetVapiEnvVarsMap()
+	if err := s.hasPermission(ctx, stack.ProjectID, domain.OrganizationRoleDeveloper); err != nil {
+		return err
+	}
+
 	for _, name := range names {
-		if _, ok := envVars[name]; !ok {
-			continue
-		}
-		delete(envVars, name)
+		stack.UnsetVapiEnvVar(name)
 	}
-	stack.SetVapiEnvVarsMap(envVars)
 
 	return helpers.GetTx(ctx).Transaction(func(tx *gorm.DB) error {
 		return stack.Save(tx)
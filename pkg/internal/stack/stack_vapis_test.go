@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"github.com/google/uuid"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/stack"
 	"github.com/jackc/pgx/v5"
+	"gorm.io/datatypes"
 )
 
 func (s *StackServiceTestSuite) TestMigrateVapiDatabaseGivenDuplicated() {
@@ -127,3 +129,30 @@ func (s *StackServiceTestSuite) disableVapis(
 		))
 	}
 }
+
+func (s *StackServiceTestSuite) TestGivenReleaseOverQuotaWhenEnableVapiThenShouldBeForbidden() {
+	// Given
+	pkg := domain.VapiPackage{
+		Name:    "vapi-over-quota",
+		OwnerId: s.user.ID,
+		Releases: []domain.VapiRelease{
+			{
+				Version:   "0.1.0",
+				Published: true,
+				Resources: datatypes.NewJSONType(domain.VapiResources{
+					CPURequest: "64",
+					CPULimit:   "64",
+				}),
+			},
+		},
+	}
+	s.Require().NoError(pkg.Save(s.db))
+
+	// When
+	_, err := s.stackService.EnableVapi(s, s.stack.ID, stack.EnableVapiInput{
+		VapiID: pkg.Releases[0].ID,
+	})
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrForbidden)
+}
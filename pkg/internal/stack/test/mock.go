@@ -4,7 +4,9 @@ import (
 	"context"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/stack"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"github.com/stretchr/testify/mock"
+	"time"
 )
 
 type ServiceMock struct {
@@ -36,8 +38,108 @@ func (s *ServiceMock) GetCustomVapis(ctx context.Context, stackId uint) ([]domai
 	return args.Get(0).([]domain.CustomVapi), args.Error(1)
 }
 
-func (s *ServiceMock) SetVapiEnv(ctx context.Context, stackId uint, env map[string]string) error {
-	args := s.Called(ctx, stackId, env)
+func (s *ServiceMock) RebuildCustomVapis(ctx context.Context, stackId uint, names []string, accessToken string) ([]domain.CustomVapi, error) {
+	args := s.Called(ctx, stackId, names, accessToken)
+	return args.Get(0).([]domain.CustomVapi), args.Error(1)
+}
+
+func (s *ServiceMock) CreatePreviewStack(ctx context.Context, parentStackId uint, input stack.CreatePreviewStackInput) (*domain.Stack, error) {
+	args := s.Called(ctx, parentStackId, input)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) DeletePreviewStack(ctx context.Context, stackId uint) error {
+	args := s.Called(ctx, stackId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) GetStackPreviews(ctx context.Context, stackId uint) ([]domain.StackPreview, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.StackPreview), args.Error(1)
+}
+
+func (s *ServiceMock) RotateJWTSecret(ctx context.Context, stackId uint, gracePeriod time.Duration) (*domain.Stack, error) {
+	args := s.Called(ctx, stackId, gracePeriod)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) CreateApiKey(ctx context.Context, stackId uint, input stack.CreateApiKeyInput) (*domain.StackApiKey, string, error) {
+	args := s.Called(ctx, stackId, input)
+	return args.Get(0).(*domain.StackApiKey), args.String(1), args.Error(2)
+}
+
+func (s *ServiceMock) ListApiKeys(ctx context.Context, stackId uint) ([]domain.StackApiKey, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.StackApiKey), args.Error(1)
+}
+
+func (s *ServiceMock) RevokeApiKey(ctx context.Context, stackId uint, apiKeyId uint) error {
+	args := s.Called(ctx, stackId, apiKeyId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) VerifyApiKey(ctx context.Context, stackId uint, token string, uri string) error {
+	args := s.Called(ctx, stackId, token, uri)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) SetRateLimits(ctx context.Context, stackId uint, limits domain.StackRateLimits) (*domain.Stack, error) {
+	args := s.Called(ctx, stackId, limits)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) CountRequest(stackId uint) error {
+	args := s.Called(stackId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) GetRequestUsage(ctx context.Context, stackId uint) (*stack.RequestUsage, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).(*stack.RequestUsage), args.Error(1)
+}
+
+func (s *ServiceMock) RunRequestUsageFlusher(ctx context.Context) error {
+	args := s.Called(ctx)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) AddCustomDomain(ctx context.Context, stackId uint, hostname string) (*domain.StackCustomDomain, error) {
+	args := s.Called(ctx, stackId, hostname)
+	return args.Get(0).(*domain.StackCustomDomain), args.Error(1)
+}
+
+func (s *ServiceMock) VerifyCustomDomain(ctx context.Context, stackId uint, customDomainId uint) (*domain.StackCustomDomain, error) {
+	args := s.Called(ctx, stackId, customDomainId)
+	return args.Get(0).(*domain.StackCustomDomain), args.Error(1)
+}
+
+func (s *ServiceMock) RemoveCustomDomain(ctx context.Context, stackId uint, customDomainId uint) error {
+	args := s.Called(ctx, stackId, customDomainId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) SetPrimaryDomain(ctx context.Context, stackId uint, hostname string) (*domain.Stack, error) {
+	args := s.Called(ctx, stackId, hostname)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) EnableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) DisableMultiRegion(ctx context.Context, stackId uint) (*domain.Stack, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).(*domain.Stack), args.Error(1)
+}
+
+func (s *ServiceMock) SyncReplicas(ctx context.Context, stackId uint, queries []string) error {
+	args := s.Called(ctx, stackId, queries)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) SetVapiEnv(ctx context.Context, stackId uint, envVars []domain.StackVapiEnvVar) error {
+	args := s.Called(ctx, stackId, envVars)
 	return args.Error(0)
 }
 
@@ -46,6 +148,26 @@ func (s *ServiceMock) UnsetVapiEnv(ctx context.Context, stackId uint, names []st
 	return args.Error(0)
 }
 
+func (s *ServiceMock) LockVapis(ctx context.Context, stackId uint) ([]domain.StackVapiLock, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.StackVapiLock), args.Error(1)
+}
+
+func (s *ServiceMock) GetLockedVapiReleases(ctx context.Context, stackId uint) ([]domain.VapiRelease, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) GetVapiNotices(ctx context.Context, stackId uint) ([]domain.StackVapiNotice, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).([]domain.StackVapiNotice), args.Error(1)
+}
+
+func (s *ServiceMock) GetStackDependencyTree(ctx context.Context, stackId uint) (*vapi.DependencyResolution, error) {
+	args := s.Called(ctx, stackId)
+	return args.Get(0).(*vapi.DependencyResolution), args.Error(1)
+}
+
 func (s *ServiceMock) GetStorageUsage(ctx context.Context, stackId uint) (int64, error) {
 	args := s.Called(ctx, stackId)
 	return args.Get(0).(int64), args.Error(1)
@@ -103,10 +225,22 @@ func (s *ServiceMock) GetStack(ctx context.Context, id uint) (*domain.Stack, err
 	return args.Get(0).(*domain.Stack), args.Error(1)
 }
 
-func (s *ServiceMock) MigrateDatabase(ctx context.Context, id uint, input stack.MigrateDatabaseInput) error {
+func (s *ServiceMock) MigrateDatabase(ctx context.Context, id uint, input stack.MigrateDatabaseInput) (*stack.MigrateDatabaseOutput, error) {
 	args := s.Called(ctx, id, input)
 
-	return args.Error(0)
+	return args.Get(0).(*stack.MigrateDatabaseOutput), args.Error(1)
+}
+
+func (s *ServiceMock) GetMigrationStatus(ctx context.Context, id uint, migrations []stack.Migration) ([]stack.MigrationStatus, error) {
+	args := s.Called(ctx, id, migrations)
+
+	return args.Get(0).([]stack.MigrationStatus), args.Error(1)
+}
+
+func (s *ServiceMock) RollbackMigrations(ctx context.Context, id uint, input stack.RollbackMigrationsInput) (*stack.RollbackMigrationsOutput, error) {
+	args := s.Called(ctx, id, input)
+
+	return args.Get(0).(*stack.RollbackMigrationsOutput), args.Error(1)
 }
 
 func (s *ServiceMock) WaitForAvailable(ctx context.Context, id uint, types []stack.ShapleServiceType) error {
@@ -162,3 +296,32 @@ var _ stack.Service = (*ServiceMock)(nil)
 func NewTestService() *ServiceMock {
 	return &ServiceMock{}
 }
+
+func (s *ServiceMock) SetVapiResources(ctx context.Context, stackId uint, vapiId uint, resources domain.VapiResources) (*domain.StackVapi, error) {
+	args := s.Called(ctx, stackId, vapiId, resources)
+	return args.Get(0).(*domain.StackVapi), args.Error(1)
+}
+
+func (s *ServiceMock) CreateBackup(ctx context.Context, stackId uint) (*domain.StackBackup, error) {
+	args := s.Called(ctx, stackId)
+
+	return args.Get(0).(*domain.StackBackup), args.Error(1)
+}
+
+func (s *ServiceMock) ListBackups(ctx context.Context, stackId uint) ([]domain.StackBackup, error) {
+	args := s.Called(ctx, stackId)
+
+	return args.Get(0).([]domain.StackBackup), args.Error(1)
+}
+
+func (s *ServiceMock) RestoreBackup(ctx context.Context, stackId uint, backupId uint) error {
+	args := s.Called(ctx, stackId, backupId)
+
+	return args.Error(0)
+}
+
+func (s *ServiceMock) RunBackupScheduler(ctx context.Context) error {
+	args := s.Called(ctx)
+
+	return args.Error(0)
+}
@@ -30,6 +30,8 @@ type PatchStackInput struct {
 	Name         *string
 	Description  *string
 	LogoImageUrl *string
+	// PreviewsEnabled requires the git repository of the stack
+	PreviewsEnabled *bool
 }
 
 type GetStatusOutput struct {
@@ -40,10 +42,34 @@ type GetStatusOutput struct {
 }
 
 type Migration struct {
-	Version time.Time `json:"version"` // format: yymmddHHMMSS
-	Query   string    `json:"query"`
+	Version   time.Time `json:"version"` // format: yymmddHHMMSS
+	Query     string    `json:"query"`
+	DownQuery string    `json:"down_query,omitempty"` // reverts Query. the migration can't be rolled back if empty
 }
 
 type MigrateDatabaseInput struct {
 	Migrations []Migration `json:"migrations"`
+	DryRun     bool        `json:"dry_run"`
+}
+
+type MigrateDatabaseOutput struct {
+	AppliedVersions []time.Time `json:"applied_versions"`
+}
+
+type MigrationStatus struct {
+	Source     string     `json:"source"` // "stack" or the name of a vapi
+	Version    time.Time  `json:"version"`
+	Applied    bool       `json:"applied"`
+	AppliedAt  *time.Time `json:"applied_at"`
+	Reversible bool       `json:"reversible"`
+}
+
+type RollbackMigrationsInput struct {
+	Source string `json:"source"` // "stack" if empty or the name of a vapi
+	Steps  int    `json:"steps"`
+	DryRun bool   `json:"dry_run"`
+}
+
+type RollbackMigrationsOutput struct {
+	RolledBackVersions []time.Time `json:"rolled_back_versions"`
 }
@@ -46,15 +46,18 @@ func DecryptAES(key []byte, ciphertext []byte) ([]byte, error) {
 
 	gcm, err := cipher.NewGCM(aes)
 	if err != nil {
-		panic(err)
+		return nil, errors.WithStack(err)
 	}
 
 	nonceSize := gcm.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, errors.New("ciphertext is too short")
+	}
 	nonce, ciphertext := ciphertext[:nonceSize], ciphertext[nonceSize:]
 
 	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
 	if err != nil {
-		panic(err)
+		return nil, errors.WithStack(err)
 	}
 
 	return plaintext, nil
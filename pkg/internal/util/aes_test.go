@@ -28,3 +28,11 @@ func (s *AESTestSuite) TestEncryptAndDecrypt() {
 
 	s.Equal(expected, string(plainBytes))
 }
+
+func (s *AESTestSuite) TestDecryptWithWrongKey() {
+	cipherBytes, err := util.EncryptAES([]byte("simple_key"), []byte("simple_t"))
+	s.Require().NoError(err)
+
+	_, err = util.DecryptAES([]byte("wrong_key"), cipherBytes)
+	s.Error(err)
+}
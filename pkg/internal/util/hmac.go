@@ -0,0 +1,19 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignHMAC returns the hex encoded hmac-sha256 of the message with the secret.
+func SignHMAC(secret string, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyHMAC returns whether the signature is the one of the message with the secret, in constant time.
+func VerifyHMAC(secret string, message string, signature string) bool {
+	return hmac.Equal([]byte(SignHMAC(secret, message)), []byte(signature))
+}
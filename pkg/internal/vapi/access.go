@@ -0,0 +1,225 @@
+package vapi
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+)
+
+// GrantPackageInput is the grantee of a private package. Exactly one of them is required.
+type GrantPackageInput struct {
+	StackID        *uint
+	OrganizationID *uint
+	UserAuthID     *string
+}
+
+func (i GrantPackageInput) Validate() error {
+	numGrantees := 0
+	if i.StackID != nil {
+		numGrantees++
+	}
+	if i.OrganizationID != nil {
+		numGrantees++
+	}
+	if i.UserAuthID != nil {
+		numGrantees++
+	}
+
+	if numGrantees != 1 {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "exactly one of stack, organization and user is required")
+	}
+
+	return nil
+}
+
+// SetPackageAccess changes who can find and enable the package. Stacks having already enabled the package keep it.
+func (s *service) SetPackageAccess(
+	ctx context.Context,
+	id uint,
+	access domain.VapiPackageAccess,
+) error {
+	if !access.IsValid() {
+		return errors.Wrapf(tclerrors.ErrBadRequest, "invalid access '%s'", access)
+	}
+
+	tx := helpers.GetTx(ctx)
+	pkg, err := domain.FindVapiPackageByID(tx, id)
+	if err != nil {
+		return err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	return errors.Wrapf(
+		tx.Model(pkg).Update("access", access).Error,
+		"failed to update package access",
+	)
+}
+
+// GrantPackage lets the stack, the stacks of the organization or the user find and enable the private package.
+func (s *service) GrantPackage(
+	ctx context.Context,
+	id uint,
+	input GrantPackageInput,
+) (*domain.VapiPackageBorrower, error) {
+	if err := input.Validate(); err != nil {
+		return nil, err
+	}
+
+	tx := helpers.GetTx(ctx)
+	pkg, err := domain.FindVapiPackageByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	borrower := domain.VapiPackageBorrower{
+		VapiPackageID: pkg.ID,
+		CreatedByID:   me.ID,
+	}
+	var user *domain.User
+	stmt := tx.Preload("User").Where("vapi_package_id = ?", pkg.ID)
+	switch {
+	case input.StackID != nil:
+		if _, err := domain.FindStackByID(tx, *input.StackID); err != nil {
+			return nil, err
+		}
+		borrower.StackID = input.StackID
+		stmt = stmt.Where("stack_id = ?", *input.StackID)
+	case input.OrganizationID != nil:
+		if _, err := domain.GetOrganizationById(tx, *input.OrganizationID); err != nil {
+			return nil, err
+		}
+		borrower.OrganizationID = input.OrganizationID
+		stmt = stmt.Where("organization_id = ?", *input.OrganizationID)
+	default:
+		user, err = s.users.GetUserByAuthUserId(ctx, *input.UserAuthID)
+		if err != nil {
+			return nil, err
+		}
+		borrower.UserID = &user.ID
+		stmt = stmt.Where("user_id = ?", user.ID)
+	}
+
+	var existing domain.VapiPackageBorrower
+	if err := stmt.Limit(1).Find(&existing).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed to find vapi package borrower")
+	} else if existing.ID != 0 {
+		return &existing, nil
+	}
+
+	if err := borrower.Save(tx); err != nil {
+		return nil, err
+	}
+	borrower.User = user
+
+	return &borrower, nil
+}
+
+// RevokePackageGrant takes back the grant. Stacks having already enabled the package keep it.
+func (s *service) RevokePackageGrant(
+	ctx context.Context,
+	id uint,
+	borrowerId uint,
+) error {
+	tx := helpers.GetTx(ctx)
+	pkg, err := domain.FindVapiPackageByID(tx, id)
+	if err != nil {
+		return err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	borrower, err := domain.FindVapiPackageBorrowerById(tx, borrowerId)
+	if err != nil {
+		return err
+	} else if borrower.VapiPackageID != pkg.ID {
+		return errors.Wrapf(tclerrors.ErrNotFound, "grant not found in the package. borrowerId=%d", borrowerId)
+	}
+
+	return borrower.Delete(tx)
+}
+
+func (s *service) GetPackageBorrowers(
+	ctx context.Context,
+	id uint,
+) ([]domain.VapiPackageBorrower, error) {
+	tx := helpers.GetTx(ctx)
+	pkg, err := domain.FindVapiPackageByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleDeveloper); err != nil {
+		return nil, err
+	}
+
+	return domain.FindVapiPackageBorrowersByPackageId(tx, pkg.ID)
+}
+
+// IsGrantedVapi returns ErrForbidden unless the package of the release is granted to the stack or to the current user.
+func (s *service) IsGrantedVapi(
+	ctx context.Context,
+	stackId uint,
+	rel *domain.VapiRelease,
+) error {
+	tx := helpers.GetTx(ctx)
+
+	pkg := &rel.Package
+	if pkg.ID == 0 {
+		var err error
+		if pkg, err = domain.FindVapiPackageByID(tx, rel.PackageID); err != nil {
+			return err
+		}
+	}
+
+	stack, err := domain.FindStackByID(tx, stackId)
+	if err != nil {
+		return err
+	}
+
+	me, err := s.getUserIfAuthorized(ctx)
+	if err != nil {
+		return err
+	}
+
+	return pkg.IsGrantedTo(tx, stack, me)
+}
+
+// getUserIfAuthorized returns nil without an error if the request has no auth token.
+func (s *service) getUserIfAuthorized(ctx context.Context) (*domain.User, error) {
+	if helpers.GetAuthToken(ctx) == "" {
+		return nil, nil
+	}
+
+	return s.users.GetUser(ctx)
+}
@@ -0,0 +1,116 @@
+package vapi_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+	"github.com/mokiat/gog"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *VapiTestSuite) createReleaseOfPackage(name string, access domain.VapiPackageAccess) domain.VapiRelease {
+	rel := domain.VapiRelease{
+		Version:   "1.0.0",
+		Published: true,
+		Package: domain.VapiPackage{
+			Name:    name,
+			OwnerId: s.user.ID,
+			Access:  access,
+		},
+	}
+	s.Require().NoError(rel.Save(s.db))
+
+	return rel
+}
+
+func (s *VapiTestSuite) TestGivenPrivatePackageWhenGrantToUserThenListedToTheUser() {
+	// given
+	s.createReleaseOfPackage("public-vapi", domain.VapiPackageAccessPublic)
+	rel := s.createReleaseOfPackage("private-vapi", domain.VapiPackageAccessPrivate)
+
+	borrower := domain.User{AuthUserId: "borrower-auth-id"}
+	s.Require().NoError(borrower.Save(s.db))
+
+	ctx := helpers.WithAuthToken(s.Context(), "token")
+	s.users.On("GetUser", mock.Anything).Return(&borrower, nil).Once()
+
+	output, err := s.vapiService.SearchVapis(ctx, vapi.SearchVapisInput{})
+	s.Require().NoError(err)
+	s.Require().Len(output.Releases, 1)
+	s.Equal("public-vapi", output.Releases[0].Package.Name)
+
+	// when
+	s.users.On("GetUser", mock.Anything).Return(&s.user, nil).Once()
+	s.users.On("GetUserByAuthUserId", mock.Anything, borrower.AuthUserId).Return(&borrower, nil).Once()
+	grant, err := s.vapiService.GrantPackage(ctx, rel.PackageID, vapi.GrantPackageInput{
+		UserAuthID: gog.PtrOf(borrower.AuthUserId),
+	})
+	s.Require().NoError(err)
+
+	// then
+	s.Require().NotNil(grant.UserID)
+	s.Equal(borrower.ID, *grant.UserID)
+
+	s.users.On("GetUser", mock.Anything).Return(&borrower, nil).Once()
+	output, err = s.vapiService.SearchVapis(ctx, vapi.SearchVapisInput{})
+	s.Require().NoError(err)
+	s.Require().Len(output.Releases, 2)
+
+	s.users.AssertExpectations(s.T())
+}
+
+func (s *VapiTestSuite) TestGivenPrivatePackageWhenGrantToStackThenGrantedUntilRevoked() {
+	// given
+	rel := s.createReleaseOfPackage("private-vapi", domain.VapiPackageAccessPrivate)
+
+	other := domain.User{AuthUserId: "other-auth-id"}
+	s.Require().NoError(other.Save(s.db))
+	stack := domain.Stack{
+		Name:   "borrower",
+		Hash:   "borrower",
+		Domain: "borrower.shaple.io",
+		Project: domain.Project{
+			Name:    "borrower",
+			OwnerID: other.ID,
+		},
+	}
+	s.Require().NoError(stack.Save(s.db))
+
+	err := s.vapiService.IsGrantedVapi(s.Context(), stack.ID, &rel)
+	s.Require().ErrorIs(err, tclerrors.ErrForbidden)
+
+	// when
+	ctx := helpers.WithAuthToken(s.Context(), "token")
+	s.users.On("GetUser", mock.Anything).Return(&s.user, nil).Twice()
+	grant, err := s.vapiService.GrantPackage(ctx, rel.PackageID, vapi.GrantPackageInput{
+		StackID: gog.PtrOf(stack.ID),
+	})
+	s.Require().NoError(err)
+
+	// then
+	s.NoError(s.vapiService.IsGrantedVapi(s.Context(), stack.ID, &rel))
+
+	s.Require().NoError(s.vapiService.RevokePackageGrant(ctx, rel.PackageID, grant.ID))
+	err = s.vapiService.IsGrantedVapi(s.Context(), stack.ID, &rel)
+	s.ErrorIs(err, tclerrors.ErrForbidden)
+
+	s.users.AssertExpectations(s.T())
+}
+
+func (s *VapiTestSuite) TestGivenUnlistedPackageWhenGetPackagesByNameThenFoundButNotListed() {
+	// given
+	s.createReleaseOfPackage("unlisted-vapi", domain.VapiPackageAccessUnlisted)
+	name := "unlisted-vapi"
+
+	// when
+	pkgs, err := s.vapiService.GetPackages(s.Context(), vapi.GetPackagesInput{Name: &name})
+	s.Require().NoError(err)
+	output, err := s.vapiService.SearchVapis(s.Context(), vapi.SearchVapisInput{})
+	s.Require().NoError(err)
+
+	// then
+	s.Require().Len(pkgs, 1)
+	s.Equal(name, pkgs[0].Name)
+	s.Len(output.Releases, 0)
+}
@@ -1,16 +1,22 @@
 package vapi
 
 import (
+	"fmt"
+	"github.com/Masterminds/semver/v3"
 	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/mitchellh/mapstructure"
 	"github.com/modern-go/reflect2"
 	"github.com/pkg/errors"
 	"golang.org/x/exp/maps"
+	"gorm.io/gorm"
 	"reflect"
 	"slices"
 	"strings"
 )
 
+const VersionLatest = "latest"
+
 type DependencyItem struct {
 	Name    string `mapstructure:"-"`
 	Version string `mapstructure:"version"`
@@ -22,7 +28,8 @@ func ParseDependencies(deps map[string]any) ([]DependencyItem, error) {
 	keys := maps.Keys(deps)
 	slices.SortStableFunc(keys, strings.Compare)
 
-	for name, depValue := range deps {
+	for _, name := range keys {
+		depValue := deps[name]
 		record := DependencyItem{
 			Name: name,
 		}
@@ -37,8 +44,137 @@ func ParseDependencies(deps map[string]any) ([]DependencyItem, error) {
 		default:
 			return nil, errors.Wrapf(tclerrors.ErrValidation, "failed to parse dependency")
 		}
+
+		if _, err := ParseVersionConstraint(record.Version); err != nil {
+			return nil, errors.WithMessagef(err, "dependency '%s'", name)
+		}
 		dependencies = append(dependencies, record)
 	}
 
 	return dependencies, nil
 }
+
+// ParseVersionConstraint accepts an exact version, a range like `^1.2.0`, `~1.4` or `>=2.0.0 <3`, and `latest`.
+func ParseVersionConstraint(constraint string) (*semver.Constraints, error) {
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == VersionLatest {
+		constraint = "*"
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, errors.Wrapf(tclerrors.ErrValidation, "invalid version constraint '%s': %v", constraint, err)
+	}
+
+	return c, nil
+}
+
+func isResolvable(rel domain.VapiRelease) bool {
+	return rel.Published && !rel.Deprecated && !rel.Suspended && !rel.Yanked
+}
+
+// SelectRelease picks the highest resolvable release whose version satisfies the constraint.
+func SelectRelease(
+	constraint string,
+	releases []domain.VapiRelease,
+) (*domain.VapiRelease, error) {
+	c, err := ParseVersionConstraint(constraint)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		selected        *domain.VapiRelease
+		selectedVersion *semver.Version
+	)
+	for i, rel := range releases {
+		if !isResolvable(rel) {
+			continue
+		}
+
+		v, err := semver.NewVersion(rel.Version)
+		if err != nil {
+			logger.Warn("skip release having invalid version", "version", rel.Version, "err", err)
+			continue
+		}
+
+		if !c.Check(v) {
+			continue
+		}
+
+		if selectedVersion == nil || v.GreaterThan(selectedVersion) {
+			selected = &releases[i]
+			selectedVersion = v
+		}
+	}
+
+	if selected == nil {
+		return nil, errors.Wrapf(
+			tclerrors.ErrNotFound,
+			"no release satisfies '%s'. candidates: [%s]",
+			constraint,
+			strings.Join(describeCandidates(releases), ", "),
+		)
+	}
+
+	return selected, nil
+}
+
+func describeCandidates(releases []domain.VapiRelease) []string {
+	releases = slices.Clone(releases)
+	slices.SortStableFunc(releases, func(lhs, rhs domain.VapiRelease) int {
+		return compareVersions(lhs.Version, rhs.Version)
+	})
+
+	candidates := make([]string, 0, len(releases))
+	for _, rel := range releases {
+		var flags []string
+		if !rel.Published {
+			flags = append(flags, "unpublished")
+		}
+		if rel.Deprecated {
+			flags = append(flags, "deprecated")
+		}
+		if rel.Suspended {
+			flags = append(flags, "suspended")
+		}
+		if rel.Yanked {
+			flags = append(flags, "yanked")
+		}
+
+		if len(flags) == 0 {
+			candidates = append(candidates, rel.Version)
+		} else {
+			candidates = append(candidates, fmt.Sprintf("%s (%s)", rel.Version, strings.Join(flags, ", ")))
+		}
+	}
+
+	return candidates
+}
+
+// resolveDependency resolves the dependency among the releases of the package, which must be granted to the user.
+func resolveDependency(
+	tx *gorm.DB,
+	dep DependencyItem,
+	user *domain.User,
+) (*domain.VapiRelease, error) {
+	releases, err := domain.FindVapiReleasesByPackageName(tx, dep.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(releases) == 0 {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "dependency vapi '%s' is not found", dep.Name)
+	}
+
+	if err := releases[0].Package.IsGrantedTo(tx, nil, user); err != nil {
+		return nil, errors.WithMessagef(err, "dependency vapi '%s' is not granted", dep.Name)
+	}
+
+	rel, err := SelectRelease(dep.Version, releases)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to resolve dependency vapi '%s'", dep.Name)
+	}
+
+	return rel, nil
+}
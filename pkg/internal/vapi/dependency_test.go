@@ -1,6 +1,8 @@
 package vapi_test
 
 import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/vapi"
 	"gopkg.in/yaml.v3"
 	"strings"
@@ -43,3 +45,47 @@ dependencies:
 		s.Truef(v, "%s is not visited", dep)
 	}
 }
+
+func (s *VapiTestSuite) TestSelectRelease() {
+	releases := []domain.VapiRelease{
+		{Version: "1.2.0", Published: true},
+		{Version: "1.4.1", Published: true},
+		{Version: "1.4.7", Published: true, Deprecated: true},
+		{Version: "1.9.0", Published: true},
+		{Version: "2.0.0", Published: true},
+		{Version: "2.3.0", Published: true, Suspended: true},
+		{Version: "3.0.0", Published: false},
+	}
+
+	for constraint, expected := range map[string]string{
+		"1.2.0":       "1.2.0",
+		"^1.2.0":      "1.9.0",
+		"~1.4":        "1.4.1",
+		">=2.0.0 <3":  "2.0.0",
+		"latest":      "2.0.0",
+		"":            "2.0.0",
+		">=1.0, <1.5": "1.4.1",
+	} {
+		rel, err := vapi.SelectRelease(constraint, releases)
+		s.Require().NoErrorf(err, "constraint: %s", constraint)
+		s.Equalf(expected, rel.Version, "constraint: %s", constraint)
+	}
+}
+
+func (s *VapiTestSuite) TestGivenNoMatchingReleaseWhenSelectReleaseThenShouldListCandidates() {
+	releases := []domain.VapiRelease{
+		{Version: "1.0.0", Published: true},
+		{Version: "2.0.0", Published: true, Deprecated: true},
+	}
+
+	_, err := vapi.SelectRelease("^2.0.0", releases)
+	s.Require().ErrorIs(err, tclerrors.ErrNotFound)
+	s.Contains(err.Error(), "1.0.0, 2.0.0 (deprecated)")
+}
+
+func (s *VapiTestSuite) TestGivenInvalidConstraintWhenParseDependenciesThenShouldFail() {
+	_, err := vapi.ParseDependencies(map[string]any{
+		"dep1": ">>1.0",
+	})
+	s.Require().ErrorIs(err, tclerrors.ErrValidation)
+}
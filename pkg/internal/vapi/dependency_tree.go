@@ -0,0 +1,303 @@
+package vapi
+
+import (
+	"context"
+	"fmt"
+	"github.com/Masterminds/semver/v3"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"slices"
+	"strings"
+)
+
+type (
+	DependencyTreeNode struct {
+		Release      domain.VapiRelease
+		Constraint   string // constraint requested by the parent, empty for the vapis installed on the stack
+		Dependencies []*DependencyTreeNode
+	}
+
+	DependencyResolution struct {
+		Roots []*DependencyTreeNode
+		// Releases is the resolved set ordered so that dependencies come before their dependents.
+		Releases []domain.VapiRelease
+	}
+
+	dependencyRequirement struct {
+		release    domain.VapiRelease
+		requiredBy *domain.VapiRelease
+		constraint string
+	}
+)
+
+func dependencyGroupKey(rel domain.VapiRelease) string {
+	return fmt.Sprintf("%s/%s", rel.Package.Name, rel.MajorVersion())
+}
+
+func dependencyConstraint(parent domain.VapiRelease, dep domain.VapiRelease) string {
+	for _, resolved := range parent.ResolvedDependencies {
+		if resolved.Name == dep.Package.Name {
+			return resolved.Constraint
+		}
+	}
+
+	// releases registered before semver ranges were supported depend on an exact version
+	return dep.Version
+}
+
+func (s *service) ResolveDependencies(
+	ctx context.Context,
+	vapiReleases []domain.VapiRelease,
+) (*DependencyResolution, error) {
+	tx := helpers.GetTx(ctx)
+
+	var (
+		requirements = map[string][]dependencyRequirement{}
+		dependencies = map[uint][]domain.VapiRelease{}
+		onPath       = map[uint]bool{}
+		path         []domain.VapiRelease
+	)
+
+	var walk func(rel domain.VapiRelease) error
+	walk = func(rel domain.VapiRelease) error {
+		if onPath[rel.ID] {
+			cycle := make([]string, 0, len(path)+1)
+			for _, r := range path[slices.IndexFunc(path, func(r domain.VapiRelease) bool { return r.ID == rel.ID }):] {
+				cycle = append(cycle, fmt.Sprintf("%s@%s", r.Package.Name, r.Version))
+			}
+			cycle = append(cycle, fmt.Sprintf("%s@%s", rel.Package.Name, rel.Version))
+			return errors.Wrapf(tclerrors.ErrValidation, "dependency cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+
+		if _, ok := dependencies[rel.ID]; ok {
+			return nil
+		}
+
+		var deps []domain.VapiRelease
+		if err := tx.Model(&rel).Preload("Package").Association("Dependencies").Find(&deps); err != nil {
+			return errors.Wrapf(err, "failed to find dependencies")
+		}
+
+		onPath[rel.ID] = true
+		path = append(path, rel)
+		for _, dep := range deps {
+			key := dependencyGroupKey(dep)
+			requirements[key] = append(requirements[key], dependencyRequirement{
+				release:    dep,
+				requiredBy: &rel,
+				constraint: dependencyConstraint(rel, dep),
+			})
+
+			if err := walk(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		onPath[rel.ID] = false
+		dependencies[rel.ID] = deps
+
+		return nil
+	}
+
+	for _, rel := range vapiReleases {
+		key := dependencyGroupKey(rel)
+		requirements[key] = append(requirements[key], dependencyRequirement{
+			release:    rel,
+			constraint: rel.Version,
+		})
+
+		if err := walk(rel); err != nil {
+			return nil, err
+		}
+	}
+
+	selected := make(map[string]domain.VapiRelease, len(requirements))
+	for key, reqs := range requirements {
+		rel, err := selectRequiredRelease(reqs)
+		if err != nil {
+			return nil, err
+		}
+		selected[key] = *rel
+	}
+
+	var (
+		result   DependencyResolution
+		building = map[uint]bool{}
+		added    = map[uint]bool{}
+	)
+
+	var build func(rel domain.VapiRelease, constraint string) (*DependencyTreeNode, error)
+	build = func(rel domain.VapiRelease, constraint string) (*DependencyTreeNode, error) {
+		if building[rel.ID] {
+			return nil, errors.Wrapf(tclerrors.ErrValidation, "dependency cycle detected at %s@%s", rel.Package.Name, rel.Version)
+		}
+		building[rel.ID] = true
+		defer delete(building, rel.ID)
+
+		node := &DependencyTreeNode{
+			Release:    rel,
+			Constraint: constraint,
+		}
+		for _, dep := range dependencies[rel.ID] {
+			child, err := build(selected[dependencyGroupKey(dep)], dependencyConstraint(rel, dep))
+			if err != nil {
+				return nil, err
+			}
+			node.Dependencies = append(node.Dependencies, child)
+		}
+
+		if !added[rel.ID] {
+			added[rel.ID] = true
+			result.Releases = append(result.Releases, rel)
+		}
+
+		return node, nil
+	}
+
+	for _, rel := range vapiReleases {
+		root, err := build(selected[dependencyGroupKey(rel)], "")
+		if err != nil {
+			return nil, err
+		}
+		result.Roots = append(result.Roots, root)
+	}
+
+	return &result, nil
+}
+
+// BuildLockedDependencyTree builds the dependency tree of the locked releases of a stack. The dependencies of a release
+// are the locked releases of the same packages and major versions, and the roots are the releases installed on the
+// stack directly, so that the tree shows what is deployed.
+func (s *service) BuildLockedDependencyTree(
+	ctx context.Context,
+	locks []domain.StackVapiLock,
+) (*DependencyResolution, error) {
+	tx := helpers.GetTx(ctx)
+
+	var (
+		result       = DependencyResolution{Releases: make([]domain.VapiRelease, 0, len(locks))}
+		locked       = make(map[string]domain.VapiRelease, len(locks))
+		dependencies = map[uint][]domain.VapiRelease{}
+		building     = map[uint]bool{}
+	)
+	for _, lock := range locks {
+		locked[dependencyGroupKey(lock.Vapi)] = lock.Vapi
+		result.Releases = append(result.Releases, lock.Vapi)
+	}
+
+	var build func(rel domain.VapiRelease, constraint string) (*DependencyTreeNode, error)
+	build = func(rel domain.VapiRelease, constraint string) (*DependencyTreeNode, error) {
+		if building[rel.ID] {
+			return nil, errors.Wrapf(tclerrors.ErrValidation, "dependency cycle detected at %s@%s", rel.Package.Name, rel.Version)
+		}
+		building[rel.ID] = true
+		defer delete(building, rel.ID)
+
+		deps, ok := dependencies[rel.ID]
+		if !ok {
+			if err := tx.Model(&rel).Preload("Package").Association("Dependencies").Find(&deps); err != nil {
+				return nil, errors.Wrapf(err, "failed to find dependencies")
+			}
+			dependencies[rel.ID] = deps
+		}
+
+		node := &DependencyTreeNode{
+			Release:    rel,
+			Constraint: constraint,
+		}
+		for _, dep := range deps {
+			lockedDep, ok := locked[dependencyGroupKey(dep)]
+			if !ok {
+				return nil, errors.Wrapf(
+					tclerrors.ErrPreconditionFailed,
+					"dependency %s of %s@%s is not locked",
+					dep.Package.Name,
+					rel.Package.Name,
+					rel.Version,
+				)
+			}
+
+			child, err := build(lockedDep, dependencyConstraint(rel, dep))
+			if err != nil {
+				return nil, err
+			}
+			node.Dependencies = append(node.Dependencies, child)
+		}
+
+		return node, nil
+	}
+
+	for _, lock := range locks {
+		if !lock.Direct {
+			continue
+		}
+
+		root, err := build(lock.Vapi, "")
+		if err != nil {
+			return nil, err
+		}
+		result.Roots = append(result.Roots, root)
+	}
+
+	return &result, nil
+}
+
+// selectRequiredRelease picks the highest release among the required ones that satisfies every constraint on it.
+func selectRequiredRelease(reqs []dependencyRequirement) (*domain.VapiRelease, error) {
+	candidates := make([]domain.VapiRelease, 0, len(reqs))
+	for _, req := range reqs {
+		if !slices.ContainsFunc(candidates, func(c domain.VapiRelease) bool { return c.ID == req.release.ID }) {
+			candidates = append(candidates, req.release)
+		}
+	}
+	slices.SortStableFunc(candidates, func(lhs, rhs domain.VapiRelease) int {
+		return compareVersions(rhs.Version, lhs.Version)
+	})
+
+	constraints := make([]*semver.Constraints, 0, len(reqs))
+	for _, req := range reqs {
+		c, err := ParseVersionConstraint(req.constraint)
+		if err != nil {
+			return nil, err
+		}
+		constraints = append(constraints, c)
+	}
+
+	for i, candidate := range candidates {
+		v, err := semver.NewVersion(candidate.Version)
+		if err != nil {
+			continue
+		}
+
+		if !slices.ContainsFunc(constraints, func(c *semver.Constraints) bool { return !c.Check(v) }) {
+			return &candidates[i], nil
+		}
+	}
+
+	requiredBy := make([]string, 0, len(reqs))
+	for _, req := range reqs {
+		if req.requiredBy == nil {
+			requiredBy = append(requiredBy, fmt.Sprintf("stack requires %s", req.constraint))
+		} else {
+			requiredBy = append(requiredBy, fmt.Sprintf("%s@%s requires %s", req.requiredBy.Package.Name, req.requiredBy.Version, req.constraint))
+		}
+	}
+
+	return nil, errors.Wrapf(
+		tclerrors.ErrValidation,
+		"incompatible constraints for '%s': %s",
+		reqs[0].release.Package.Name,
+		strings.Join(requiredBy, ", "),
+	)
+}
+
+func compareVersions(lhs, rhs string) int {
+	l, lErr := semver.NewVersion(lhs)
+	r, rErr := semver.NewVersion(rhs)
+	if lErr != nil || rErr != nil {
+		return strings.Compare(lhs, rhs)
+	}
+	return l.Compare(r)
+}
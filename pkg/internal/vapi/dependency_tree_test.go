@@ -0,0 +1,109 @@
+package vapi_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"gorm.io/datatypes"
+)
+
+func (s *VapiTestSuite) saveRelease(name string, version string, deps ...domain.VapiRelease) domain.VapiRelease {
+	var pkg domain.VapiPackage
+	s.Require().NoError(s.db.FirstOrCreate(&pkg, domain.VapiPackage{Name: name, OwnerId: s.user.ID}).Error)
+
+	rel := domain.VapiRelease{
+		Version:      version,
+		PackageID:    pkg.ID,
+		Package:      pkg,
+		Published:    true,
+		Dependencies: deps,
+	}
+	s.Require().NoError(rel.Save(s.db))
+
+	return rel
+}
+
+func (s *VapiTestSuite) TestGivenCompatibleConstraintsWhenResolveDependenciesThenShouldPickHighestSatisfyingAll() {
+	// Given
+	common120 := s.saveRelease("common", "1.2.5")
+	common140 := s.saveRelease("common", "1.4.0")
+
+	a := s.saveRelease("a", "1.0.0", common120)
+	a.ResolvedDependencies = datatypes.NewJSONSlice([]domain.VapiReleaseDependency{
+		{Name: "common", Constraint: "~1.2", Version: "1.2.5"},
+	})
+	s.Require().NoError(s.db.Model(&a).Update("resolved_dependencies", a.ResolvedDependencies).Error)
+
+	b := s.saveRelease("b", "1.0.0", common140)
+	b.ResolvedDependencies = datatypes.NewJSONSlice([]domain.VapiReleaseDependency{
+		{Name: "common", Constraint: "^1.2", Version: "1.4.0"},
+	})
+	s.Require().NoError(s.db.Model(&b).Update("resolved_dependencies", b.ResolvedDependencies).Error)
+
+	// When
+	resolution, err := s.vapiService.ResolveDependencies(s.Context(), []domain.VapiRelease{a, b})
+	s.Require().NoError(err)
+
+	// Then
+	s.Require().Len(resolution.Releases, 3)
+	s.Equal(common120.ID, resolution.Releases[0].ID)
+	s.Require().Len(resolution.Roots, 2)
+	s.Equal("~1.2", resolution.Roots[0].Dependencies[0].Constraint)
+	s.Equal(common120.ID, resolution.Roots[1].Dependencies[0].Release.ID)
+}
+
+func (s *VapiTestSuite) TestGivenIncompatibleConstraintsWhenResolveDependenciesThenShouldFail() {
+	// Given
+	common120 := s.saveRelease("common", "1.2.0")
+	common140 := s.saveRelease("common", "1.4.0")
+	a := s.saveRelease("a", "1.0.0", common120)
+	b := s.saveRelease("b", "1.0.0", common140)
+
+	// When
+	_, err := s.vapiService.ResolveDependencies(s.Context(), []domain.VapiRelease{a, b})
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrValidation)
+	s.Contains(err.Error(), "incompatible constraints for 'common'")
+}
+
+func (s *VapiTestSuite) TestGivenDependencyCycleWhenResolveDependenciesThenShouldFail() {
+	// Given
+	a := s.saveRelease("a", "1.0.0")
+	b := s.saveRelease("b", "1.0.0", a)
+	s.Require().NoError(s.db.Model(&a).Association("Dependencies").Append(&b))
+
+	// When
+	_, err := s.vapiService.ResolveDependencies(s.Context(), []domain.VapiRelease{a})
+
+	// Then
+	s.Require().ErrorIs(err, tclerrors.ErrValidation)
+	s.Contains(err.Error(), "dependency cycle detected: a@1.0.0 -> b@1.0.0 -> a@1.0.0")
+}
+
+func (s *VapiTestSuite) TestGivenLocksWhenBuildLockedDependencyTreeThenShouldShowLockedReleases() {
+	// Given
+	common120 := s.saveRelease("common", "1.2.0")
+	common140 := s.saveRelease("common", "1.4.0")
+	a := s.saveRelease("a", "1.0.0", common120)
+	a.ResolvedDependencies = datatypes.NewJSONSlice([]domain.VapiReleaseDependency{
+		{Name: "common", Constraint: "^1.2", Version: "1.2.0"},
+	})
+	s.Require().NoError(s.db.Model(&a).Update("resolved_dependencies", a.ResolvedDependencies).Error)
+
+	locks := []domain.StackVapiLock{
+		{VapiID: common140.ID, Vapi: common140, Seq: 0},
+		{VapiID: a.ID, Vapi: a, Seq: 1, Direct: true},
+	}
+
+	// When
+	tree, err := s.vapiService.BuildLockedDependencyTree(s.Context(), locks)
+	s.Require().NoError(err)
+
+	// Then
+	s.Require().Len(tree.Releases, 2)
+	s.Require().Len(tree.Roots, 1)
+	s.Equal(a.ID, tree.Roots[0].Release.ID)
+	s.Require().Len(tree.Roots[0].Dependencies, 1)
+	s.Equal(common140.ID, tree.Roots[0].Dependencies[0].Release.ID)
+	s.Equal("^1.2", tree.Roots[0].Dependencies[0].Constraint)
+}
@@ -3,6 +3,7 @@ package vapi
 import (
 	"context"
 	"fmt"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
 	"github.com/habiliai/apidepot/pkg/internal/domain"
 	"github.com/habiliai/apidepot/pkg/internal/helpers"
 	"github.com/mokiat/gog"
@@ -25,36 +26,27 @@ type SearchVapisOutput struct {
 	NextPage *int                 `json:"next_page"`
 }
 
+// FindVapiReleaseOnStack finds the published release of the package granted to the stack.
 func (s *service) FindVapiReleaseOnStack(
 	ctx context.Context,
 	stackId uint,
 	name string,
 	version string,
-	projectId uint,
 ) (*domain.VapiRelease, error) {
 	tx := helpers.GetTx(ctx)
 
-	var vapi domain.VapiRelease
-	if err := tx.
-		Table(
-			"(?) as p, (?) as r",
-			tx.Model(&domain.VapiPackage{}).Where("name = ? AND project_id = ?", name, projectId),
-			tx.Model(&domain.VapiRelease{}).Where("version = ?", version),
-		).
-		Where(
-			"p.id = r.package_id AND ((r.access = 'public' AND r.published = true) OR (? IN (?)))",
-			stackId,
-			tx.Table("vapi_packages_borrowers as b").Select("b.stack_id").Where("b.vapi_package_id = p.id"),
-		).
-		Select("r.*").
-		Limit(1).
-		Preload("Package").
-		First(&vapi).
-		Error; err != nil {
-		return nil, errors.Wrapf(err, "failed to find vapi by name and version")
+	rel, err := domain.FindVapiReleaseByPackageNameAndVersion(tx, name, version)
+	if err != nil {
+		return nil, err
+	} else if !rel.Published {
+		return nil, errors.Wrapf(tclerrors.ErrNotFound, "vapi release is not published")
 	}
 
-	return &vapi, nil
+	if err := s.IsGrantedVapi(ctx, stackId, rel); err != nil {
+		return nil, err
+	}
+
+	return rel, nil
 }
 
 func (s *service) SearchVapis(
@@ -63,7 +55,12 @@ func (s *service) SearchVapis(
 ) (output SearchVapisOutput, err error) {
 	tx := helpers.GetTx(ctx)
 
-	stmt := tx.Model(&domain.VapiPackage{})
+	me, err := s.getUserIfAuthorized(ctx)
+	if err != nil {
+		return output, err
+	}
+
+	stmt := tx.Model(&domain.VapiPackage{}).Scopes(domain.ListedVapiPackages(me))
 
 	if input.Name != nil {
 		stmt = stmt.
@@ -140,8 +137,13 @@ func (s *service) SearchVapis(
 func (s *service) GetPackagesByOwnerId(ctx context.Context, ownerId uint) ([]domain.VapiPackage, error) {
 	tx := helpers.GetTx(ctx)
 
+	me, err := s.getUserIfAuthorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	var pkgs []domain.VapiPackage
-	err := errors.WithStack(tx.Find(&pkgs, "owner_id = ?", ownerId).Error)
+	err = errors.WithStack(tx.Scopes(domain.ListedVapiPackages(me)).Find(&pkgs, "owner_id = ?", ownerId).Error)
 
 	return pkgs, err
 }
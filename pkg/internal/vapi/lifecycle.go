@@ -0,0 +1,152 @@
+package vapi
+
+import (
+	"context"
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// DeprecateRelease marks the release deprecated with the message shown on installing or searching it.
+// Deprecated releases are no longer resolved for dependency constraints, but can still be installed and deployed.
+func (s *service) DeprecateRelease(ctx context.Context, id uint, message string) (*domain.VapiRelease, error) {
+	if message == "" {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "deprecation message is required")
+	}
+
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventDeprecated, message, func(rel *domain.VapiRelease) {
+		rel.Deprecated = true
+		rel.DeprecationMessage = message
+	})
+}
+
+// SuspendRelease blocks new installs of the release and deployments of the stacks having it.
+func (s *service) SuspendRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error) {
+	if reason == "" {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "suspension reason is required")
+	}
+
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventSuspended, reason, func(rel *domain.VapiRelease) {
+		rel.Suspended = true
+		rel.SuspensionReason = reason
+	})
+}
+
+// YankRelease hides the release from resolving dependencies and new installs. Stacks having locked it keep working.
+func (s *service) YankRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error) {
+	if reason == "" {
+		return nil, errors.Wrapf(tclerrors.ErrBadRequest, "yank reason is required")
+	}
+
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventYanked, reason, func(rel *domain.VapiRelease) {
+		rel.Yanked = true
+		rel.YankReason = reason
+	})
+}
+
+// UnpublishRelease hides the release from new installs and resolving dependencies. Its tarball is kept for the stacks having it.
+func (s *service) UnpublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventUnpublished, "", func(rel *domain.VapiRelease) {
+		rel.Published = false
+	})
+}
+
+func (s *service) PublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventPublished, "", func(rel *domain.VapiRelease) {
+		rel.Published = true
+	})
+}
+
+// RestoreRelease takes back the deprecation, the suspension and the yank of the release.
+func (s *service) RestoreRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	return s.changeReleaseState(ctx, id, domain.VapiReleaseEventRestored, "", func(rel *domain.VapiRelease) {
+		rel.Deprecated, rel.DeprecationMessage = false, ""
+		rel.Suspended, rel.SuspensionReason = false, ""
+		rel.Yanked, rel.YankReason = false, ""
+	})
+}
+
+// changeReleaseState applies the change to the release and warns the stacks having locked it by a notice.
+func (s *service) changeReleaseState(
+	ctx context.Context,
+	id uint,
+	event domain.VapiReleaseEvent,
+	message string,
+	change func(rel *domain.VapiRelease),
+) (*domain.VapiRelease, error) {
+	tx := helpers.GetTx(ctx)
+	rel, err := domain.GetVapiReleaseByID(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rel.Package.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return nil, err
+	}
+
+	change(rel)
+	if err := tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select(
+			"Published",
+			"Deprecated",
+			"DeprecationMessage",
+			"Suspended",
+			"SuspensionReason",
+			"Yanked",
+			"YankReason",
+		).Updates(rel).Error; err != nil {
+			return errors.Wrapf(err, "failed to update vapi release")
+		}
+
+		stackIds, err := domain.CreateStackVapiNotices(tx, rel, event, message)
+		if err != nil {
+			return err
+		}
+
+		logger.Info(
+			"changed vapi release state",
+			"name", rel.Package.Name,
+			"version", rel.Version,
+			"event", event,
+			"stackIds", stackIds,
+		)
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return rel, nil
+}
+
+// checkReleasesNotLocked keeps the tarballs of the releases which stacks still depend on from being deleted.
+func checkReleasesNotLocked(tx *gorm.DB, rels []domain.VapiRelease) error {
+	ids := make([]uint, 0, len(rels))
+	for _, rel := range rels {
+		ids = append(ids, rel.ID)
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	count, err := domain.CountStacksLockingVapiReleases(tx, ids)
+	if err != nil {
+		return err
+	} else if count > 0 {
+		return errors.Wrapf(
+			tclerrors.ErrPreconditionFailed,
+			"%d stacks depend on the releases. yank or unpublish them instead",
+			count,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+package vapi_test
+
+import (
+	tclerrors "github.com/habiliai/apidepot/pkg/errors"
+	"github.com/habiliai/apidepot/pkg/internal/domain"
+	"github.com/habiliai/apidepot/pkg/internal/helpers"
+	"github.com/habiliai/apidepot/pkg/internal/vapi"
+	"github.com/stretchr/testify/mock"
+)
+
+func (s *VapiTestSuite) TestGivenYankedReleaseWhenSelectReleaseThenSkipped() {
+	releases := []domain.VapiRelease{
+		{Version: "1.2.0", Published: true},
+		{Version: "1.3.0", Published: true, Yanked: true},
+	}
+
+	rel, err := vapi.SelectRelease("^1.2.0", releases)
+	s.Require().NoError(err)
+	s.Equal("1.2.0", rel.Version)
+
+	_, err = vapi.SelectRelease("1.3.0", releases)
+	s.Require().ErrorIs(err, tclerrors.ErrNotFound)
+	s.Contains(err.Error(), "1.3.0 (yanked)")
+}
+
+func (s *VapiTestSuite) TestGivenReleaseLockedByStackWhenYankThenStackNoticedAndReleaseKept() {
+	// given
+	rel := s.createReleaseOfPackage("yanked-vapi", domain.VapiPackageAccessPublic)
+
+	other := domain.User{AuthUserId: "locking-owner-auth-id"}
+	s.Require().NoError(other.Save(s.db))
+	stack := domain.Stack{
+		Name:   "locking",
+		Hash:   "locking",
+		Domain: "locking.shaple.io",
+		Project: domain.Project{
+			Name:    "locking",
+			OwnerID: other.ID,
+		},
+	}
+	s.Require().NoError(stack.Save(s.db))
+	s.Require().NoError(s.db.Create(&domain.StackVapiLock{StackID: stack.ID, VapiID: rel.ID, Direct: true}).Error)
+
+	ctx := helpers.WithAuthToken(s.Context(), "token")
+	s.users.On("GetUser", mock.Anything).Return(&s.user, nil).Twice()
+	defer s.users.AssertExpectations(s.T())
+
+	// when
+	yanked, err := s.vapiService.YankRelease(ctx, rel.ID, "broken migration")
+	s.Require().NoError(err)
+
+	// then
+	s.True(yanked.Yanked)
+	s.Equal("broken migration", yanked.YankReason)
+
+	notices, err := domain.FindStackVapiNoticesByStackId(s.db, stack.ID)
+	s.Require().NoError(err)
+	s.Require().Len(notices, 1)
+	s.Equal(domain.VapiReleaseEventYanked, notices[0].Event)
+	s.Equal("broken migration", notices[0].Message)
+	s.Equal(rel.ID, notices[0].VapiReleaseID)
+
+	err = s.vapiService.DeleteRelease(ctx, rel.ID)
+	s.Require().ErrorIs(err, tclerrors.ErrPreconditionFailed)
+}
@@ -25,6 +25,15 @@ func (s *service) GetPackage(
 		return nil, err
 	}
 
+	me, err := s.getUserIfAuthorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pkg.IsGrantedTo(tx, nil, me); err != nil {
+		return nil, err
+	}
+
 	return pkg, nil
 }
 
@@ -32,10 +41,17 @@ func (s *service) GetPackages(
 	ctx context.Context,
 	input GetPackagesInput,
 ) ([]domain.VapiPackage, error) {
-	stmt := helpers.GetTx(ctx)
+	me, err := s.getUserIfAuthorized(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	// unlisted packages are found only by their name
+	stmt := helpers.GetTx(ctx).Model(&domain.VapiPackage{})
 	if input.Name != nil {
-		stmt = stmt.Where("name = ?", *input.Name)
+		stmt = stmt.Scopes(domain.GrantedVapiPackages(me)).Where("name = ?", *input.Name)
+	} else {
+		stmt = stmt.Scopes(domain.ListedVapiPackages(me))
 	}
 
 	var pkgs []domain.VapiPackage
@@ -62,7 +78,7 @@ func (s *service) DeletePackage(
 		return err
 	}
 
-	if err := pkg.IsPermittedToEdit(me); err != nil {
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
 		return err
 	}
 
@@ -75,6 +91,42 @@ func (s *service) DeletePackage(
 	})
 }
 
+// TransferPackage moves the package to the organization, or back to its owner if organizationId is nil.
+func (s *service) TransferPackage(
+	ctx context.Context,
+	id uint,
+	organizationId *uint,
+) error {
+	tx := helpers.GetTx(ctx)
+
+	pkg, err := domain.FindVapiPackageByID(tx, id)
+	if err != nil {
+		return err
+	}
+
+	me, err := s.users.GetUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	if organizationId != nil {
+		if err := domain.CheckOrganizationRole(tx, *organizationId, me, domain.OrganizationRoleAdmin); err != nil {
+			return err
+		}
+	}
+
+	return tx.Transaction(func(tx *gorm.DB) error {
+		return errors.Wrapf(
+			tx.Model(pkg).Update("organization_id", organizationId).Error,
+			"failed to transfer package",
+		)
+	})
+}
+
 func (s *service) DeleteAllPackages(
 	ctx context.Context,
 	projectId uint,
@@ -101,7 +153,7 @@ func (s *service) DeleteAllPackages(
 
 	return tx.Transaction(func(tx *gorm.DB) error {
 		for _, pkg := range pkgs {
-			if err := pkg.IsPermittedToEdit(me); err != nil {
+			if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
 				return err
 			}
 
@@ -13,6 +13,7 @@ import (
 	"github.com/mokiat/gog"
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v3"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 	"strings"
@@ -23,9 +24,20 @@ type (
 	DeployStatus string
 
 	VapiPackageYaml struct {
-		Name         string         `yaml:"name"`
-		Version      string         `yaml:"version"`
-		Dependencies map[string]any `yaml:"dependencies"`
+		Name         string               `yaml:"name"`
+		Version      string               `yaml:"version"`
+		Dependencies map[string]any       `yaml:"dependencies"`
+		Resources    domain.VapiResources `yaml:"resources"`
+	}
+
+	registerInput struct {
+		GitRepo     string
+		GitBranch   string
+		Name        string
+		Description string
+		Domains     []string
+		VapiPoolId  string
+		Homepage    string
 	}
 )
 
@@ -39,8 +51,6 @@ func (s *service) Register(
 	vapiPoolId string,
 	homepage string,
 ) (*domain.VapiRelease, error) {
-	tx := helpers.GetTx(ctx)
-
 	user, err := s.users.GetUser(ctx)
 	if err != nil {
 		return nil, err
@@ -51,6 +61,57 @@ func (s *service) Register(
 		return nil, errors.New("installationId is empty")
 	}
 
+	accessToken := user.GithubAccessToken
+	if accessToken == "" {
+		accessToken = helpers.GetGithubToken(ctx)
+	}
+
+	return s.register(ctx, user, accessToken, gitBranch, registerInput{
+		GitRepo:     gitRepo,
+		GitBranch:   gitBranch,
+		Name:        name,
+		Description: description,
+		Domains:     domains,
+		VapiPoolId:  vapiPoolId,
+		Homepage:    homepage,
+	})
+}
+
+// RegisterPushedRef registers the release of the package at the pushed branch or tag like refs/tags/v1.0.0 on behalf
+// of the owner of the package. The repository is cloned with accessToken, e.g. of the github app installation.
+func (s *service) RegisterPushedRef(
+	ctx context.Context,
+	packageId uint,
+	ref string,
+	accessToken string,
+) (*domain.VapiRelease, error) {
+	pkg, err := domain.FindVapiPackageByID(helpers.GetTx(ctx), packageId)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.register(ctx, &pkg.Owner, accessToken, ref, registerInput{
+		GitRepo:     pkg.GitRepo,
+		GitBranch:   pkg.GitBranch,
+		Name:        pkg.Name,
+		Description: pkg.Description,
+		Domains:     pkg.Domains,
+		VapiPoolId:  pkg.VapiPoolId,
+		Homepage:    pkg.Homepage,
+	})
+}
+
+// register clones the repository at ref, a branch or a full reference, and creates the release of the package by user.
+func (s *service) register(
+	ctx context.Context,
+	user *domain.User,
+	accessToken string,
+	ref string,
+	input registerInput,
+) (*domain.VapiRelease, error) {
+	tx := helpers.GetTx(ctx)
+	gitRepo := input.GitRepo
+
 	// validate input parameters
 	if gitRepo == "" {
 		return nil, errors.New("gitRepo is empty. must be like 'go-git/go-git'")
@@ -69,20 +130,15 @@ func (s *service) Register(
 		return nil, errors.Errorf("gitRepo('%s') is invalid. must be like 'go-git/go-git'", gitRepo)
 	}
 
-	accessToken := user.GithubAccessToken
-	if accessToken == "" {
-		accessToken = helpers.GetGithubToken(ctx)
-	}
-
 	var gitUrl string
 	if accessToken == "" {
 		gitUrl = fmt.Sprintf("https://github.com/%s", gitRepo)
 	} else {
 		gitUrl = fmt.Sprintf("https://%s@github.com/%s", accessToken, gitRepo)
 	}
-	logger.Debug("check", "gitUrl", gitUrl, "gitBranch", gitBranch)
+	logger.Debug("check", "gitUrl", gitUrl, "ref", ref)
 
-	repo, err := s.git.Clone(ctx, gitUrl, gitBranch)
+	repo, err := s.git.Clone(ctx, gitUrl, ref)
 	if err != nil {
 		return nil, err
 	}
@@ -104,6 +160,13 @@ func (s *service) Register(
 		return nil, errors.New("package.json is invalid. version is empty")
 	}
 
+	resources := domain.DefaultVapiResources().Override(vapiPackageYaml.Resources)
+	if err := resources.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "invalid resources in %s", constants.VapiYamlFileName)
+	} else if err := resources.CheckQuota(user); err != nil {
+		return nil, err
+	}
+
 	// make tar file from git repo
 	dirTree, err := s.git.OpenDir(repo, "")
 	if err != nil {
@@ -117,35 +180,35 @@ func (s *service) Register(
 
 	// make up vapiRelease and dependencies, then upload tar file to storage
 	var rel *domain.VapiRelease
-	tarObjectPath := fmt.Sprintf("%s/v%s.tar", name, vapiPackageYaml.Version)
+	tarObjectPath := fmt.Sprintf("%s/v%s.tar", input.Name, vapiPackageYaml.Version)
 	if err := tx.Transaction(func(tx *gorm.DB) error {
 		var vapiPackage domain.VapiPackage
-		if r := tx.Find(&vapiPackage, "name = ?", name); r.Error != nil {
+		if r := tx.Find(&vapiPackage, "name = ?", input.Name); r.Error != nil {
 			return errors.Wrapf(r.Error, "failed to find vapi by name")
 		} else {
-			vapiPackage.Description = description
-			vapiPackage.Domains = domains
+			vapiPackage.Description = input.Description
+			vapiPackage.Domains = input.Domains
 			vapiPackage.GitRepo = gitRepo
-			vapiPackage.GitBranch = gitBranch
-			vapiPackage.Homepage = homepage
+			vapiPackage.GitBranch = input.GitBranch
+			vapiPackage.Homepage = input.Homepage
 			if r.RowsAffected == 0 {
-				logger.Debug("not found vapi", "pkgName", name)
-				vapiPackage.Name = name
+				logger.Debug("not found vapi", "pkgName", input.Name)
+				vapiPackage.Name = input.Name
 				vapiPackage.OwnerId = user.ID
 				vapiPackage.Owner = *user
-				vapiPackage.VapiPoolId = vapiPoolId
+				vapiPackage.VapiPoolId = input.VapiPoolId
 			}
 			if err := vapiPackage.Save(tx); err != nil {
 				return err
 			}
 		}
 
-		if err := vapiPackage.IsPermittedToEdit(user); err != nil {
+		if err := vapiPackage.IsPermittedToEdit(tx, user); err != nil {
 			return errors.Wrapf(tclerrors.ErrForbidden, "failed to edit package")
 		}
 
 		var skip bool
-		rel, skip, err = s.createVapiRelease(tx, vapiPackage, tarObjectPath, gitHash, vapiPackageYaml.Version)
+		rel, skip, err = s.createVapiRelease(tx, vapiPackage, tarObjectPath, gitHash, vapiPackageYaml)
 		if err != nil {
 			return err
 		}
@@ -158,19 +221,28 @@ func (s *service) Register(
 		if err != nil {
 			return err
 		}
+		resolvedDependencies := make([]domain.VapiReleaseDependency, 0, len(dependencies))
 		for _, dep := range dependencies {
-			dep, err := domain.FindVapiReleaseByPackageNameAndVersion(tx, dep.Name, dep.Version)
+			depRel, err := resolveDependency(tx, dep, user)
 			if err != nil {
 				return err
 			}
+			logger.Info("resolved dependency", "name", dep.Name, "constraint", dep.Version, "version", depRel.Version)
 
-			if !dep.Published {
-				return errors.Errorf("dependency vapi '%s' is not used", dep.Package.Name)
-			}
-
-			if err := tx.Model(rel).Association("Dependencies").Append(dep); err != nil {
+			if err := tx.Model(rel).Association("Dependencies").Append(depRel); err != nil {
 				return errors.Wrapf(err, "failed to append dependency")
 			}
+
+			resolvedDependencies = append(resolvedDependencies, domain.VapiReleaseDependency{
+				Name:       dep.Name,
+				Constraint: dep.Version,
+				Version:    depRel.Version,
+			})
+		}
+
+		rel.ResolvedDependencies = resolvedDependencies
+		if err := tx.Model(rel).Update("resolved_dependencies", rel.ResolvedDependencies).Error; err != nil {
+			return errors.Wrapf(err, "failed to save resolved dependencies")
 		}
 
 		{
@@ -204,17 +276,19 @@ func (s *service) createVapiRelease(
 	vapi domain.VapiPackage,
 	tarPath string,
 	gitHash string,
-	version string,
+	packageYaml VapiPackageYaml,
 ) (*domain.VapiRelease, bool, error) {
+	version := packageYaml.Version
 	release := domain.VapiRelease{
 		Version:     version,
 		TarFilePath: tarPath,
 		Deprecated:  false,
 		Suspended:   false,
-		Published:   true, // TODO: it is needed to be changed by auto-preview process
+		Published:   true, // releases are published on registration, and can be unpublished later
 		PackageID:   vapi.ID,
 		Package:     vapi,
 		GitHash:     gitHash,
+		Resources:   datatypes.NewJSONType(packageYaml.Resources),
 	}
 
 	if r := tx.Clauses(clause.OnConflict{
@@ -16,8 +16,9 @@ import (
 )
 
 type Migration struct {
-	Version time.Time `json:"version"` // format: yymmddHHMMSS
-	Query   string    `json:"query"`
+	Version   time.Time `json:"version"` // format: yymmddHHMMSS
+	Query     string    `json:"query"`
+	DownQuery string    `json:"down_query,omitempty"` // read from the paired '*_down.sql' file
 }
 
 func (s *service) GetDBMigrations(
@@ -29,6 +30,22 @@ func (s *service) GetDBMigrations(
 		return nil, errors.Wrapf(err, "failed to download tar file")
 	}
 
+	return readDBMigrations(tarFile)
+}
+
+func (s *service) GetCustomVapiDBMigrations(
+	ctx context.Context,
+	customVapi domain.CustomVapi,
+) ([]Migration, error) {
+	tarFile, err := s.storage.DownloadFile(ctx, constants.CustomVapiBucketId, customVapi.TarFilePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to download custom vapi tar file")
+	}
+
+	return readDBMigrations(tarFile)
+}
+
+func readDBMigrations(tarFile []byte) ([]Migration, error) {
 	tfs := tarfs.New(tar.NewReader(bytes.NewBuffer(tarFile)))
 	if ok, err := afero.DirExists(tfs, "/migrations"); err != nil {
 		return nil, errors.Wrapf(err, "failed to check migrations directory")
@@ -42,6 +59,7 @@ func (s *service) GetDBMigrations(
 	}
 
 	migrations := make([]Migration, 0, len(files))
+	downQueries := map[string]string{}
 	for _, file := range files {
 		if file.IsDir() {
 			continue
@@ -70,12 +88,33 @@ func (s *service) GetDBMigrations(
 			return nil, errors.Wrapf(err, "failed to read migration file")
 		}
 
+		// '<version>_<name>_down.sql' reverts the migration of the same version
+		if strings.HasSuffix(fileBaseName, "_down") {
+			downQueries[versionStr] = string(migration)
+			continue
+		}
+
 		migrations = append(migrations, Migration{
 			Version: version,
 			Query:   string(migration),
 		})
 	}
 
+	for i := range migrations {
+		versionStr := migrations[i].Version.Format("060102150405")
+		downQuery, ok := downQueries[versionStr]
+		if !ok {
+			continue
+		}
+
+		migrations[i].DownQuery = downQuery
+		delete(downQueries, versionStr)
+	}
+
+	for versionStr := range downQueries {
+		return nil, errors.Errorf("down migration has no paired migration. version=%s", versionStr)
+	}
+
 	return migrations, nil
 }
 
@@ -118,7 +157,11 @@ func (s *service) DeleteRelease(
 		return err
 	}
 
-	if err := rel.Package.IsPermittedToEdit(me); err != nil {
+	if err := rel.Package.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
+		return err
+	}
+
+	if err := checkReleasesNotLocked(tx, []domain.VapiRelease{*rel}); err != nil {
 		return err
 	}
 
@@ -143,6 +186,10 @@ func (s *service) deleteReleases(
 	tx *gorm.DB,
 	rels []domain.VapiRelease,
 ) error {
+	if err := checkReleasesNotLocked(tx, rels); err != nil {
+		return err
+	}
+
 	return tx.Transaction(func(tx *gorm.DB) error {
 		tarFilePaths := make([]string, 0, len(rels))
 		for _, rel := range rels {
@@ -177,7 +224,7 @@ func (s *service) DeleteReleasesByPackageId(
 		return err
 	}
 
-	if err := pkg.IsPermittedToEdit(me); err != nil {
+	if err := pkg.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
 		return err
 	}
 
@@ -200,7 +247,7 @@ func (s *service) DeleteAllReleases(
 	}
 
 	for _, rel := range rels {
-		if err := rel.Package.IsPermittedToEdit(me); err != nil {
+		if err := rel.Package.CheckPermission(tx, me, domain.OrganizationRoleAdmin); err != nil {
 			return err
 		}
 	}
@@ -22,10 +22,20 @@ type Service interface {
 		vapiPoolId string,
 		homepage string,
 	) (*domain.VapiRelease, error)
+	RegisterPushedRef(
+		ctx context.Context,
+		packageId uint,
+		ref string,
+		accessToken string,
+	) (*domain.VapiRelease, error)
 	GetDBMigrations(
 		ctx context.Context,
 		vapiRel domain.VapiRelease,
 	) ([]Migration, error)
+	GetCustomVapiDBMigrations(
+		ctx context.Context,
+		customVapi domain.CustomVapi,
+	) ([]Migration, error)
 	SearchVapis(
 		ctx context.Context,
 		input SearchVapisInput,
@@ -38,6 +48,11 @@ type Service interface {
 		ctx context.Context,
 		id uint,
 	) error
+	TransferPackage(
+		ctx context.Context,
+		id uint,
+		organizationId *uint,
+	) error
 	GetRelease(
 		ctx context.Context,
 		id uint,
@@ -68,6 +83,44 @@ type Service interface {
 		ctx context.Context,
 		vapiReleases []domain.VapiRelease,
 	) ([]domain.VapiRelease, error)
+	ResolveDependencies(
+		ctx context.Context,
+		vapiReleases []domain.VapiRelease,
+	) (*DependencyResolution, error)
+	BuildLockedDependencyTree(
+		ctx context.Context,
+		locks []domain.StackVapiLock,
+	) (*DependencyResolution, error)
+	SetPackageAccess(
+		ctx context.Context,
+		id uint,
+		access domain.VapiPackageAccess,
+	) error
+	GrantPackage(
+		ctx context.Context,
+		id uint,
+		input GrantPackageInput,
+	) (*domain.VapiPackageBorrower, error)
+	RevokePackageGrant(
+		ctx context.Context,
+		id uint,
+		borrowerId uint,
+	) error
+	GetPackageBorrowers(
+		ctx context.Context,
+		id uint,
+	) ([]domain.VapiPackageBorrower, error)
+	IsGrantedVapi(
+		ctx context.Context,
+		stackId uint,
+		rel *domain.VapiRelease,
+	) error
+	DeprecateRelease(ctx context.Context, id uint, message string) (*domain.VapiRelease, error)
+	SuspendRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error)
+	YankRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error)
+	UnpublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error)
+	PublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error)
+	RestoreRelease(ctx context.Context, id uint) (*domain.VapiRelease, error)
 }
 
 type service struct {
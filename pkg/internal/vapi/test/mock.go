@@ -16,11 +16,26 @@ func (s *ServiceMock) GetDBMigrations(ctx context.Context, vapiRel domain.VapiRe
 	return args.Get(0).([]vapi.Migration), args.Error(1)
 }
 
+func (s *ServiceMock) GetCustomVapiDBMigrations(ctx context.Context, customVapi domain.CustomVapi) ([]vapi.Migration, error) {
+	args := s.Called(ctx, customVapi)
+	return args.Get(0).([]vapi.Migration), args.Error(1)
+}
+
 func (s *ServiceMock) GetAllDependenciesOfVapiReleases(ctx context.Context, vapiReleases []domain.VapiRelease) ([]domain.VapiRelease, error) {
 	args := s.Called(ctx, vapiReleases)
 	return args.Get(0).([]domain.VapiRelease), args.Error(1)
 }
 
+func (s *ServiceMock) ResolveDependencies(ctx context.Context, vapiReleases []domain.VapiRelease) (*vapi.DependencyResolution, error) {
+	args := s.Called(ctx, vapiReleases)
+	return args.Get(0).(*vapi.DependencyResolution), args.Error(1)
+}
+
+func (s *ServiceMock) BuildLockedDependencyTree(ctx context.Context, locks []domain.StackVapiLock) (*vapi.DependencyResolution, error) {
+	args := s.Called(ctx, locks)
+	return args.Get(0).(*vapi.DependencyResolution), args.Error(1)
+}
+
 func (s *ServiceMock) IsGrantedVapi(ctx context.Context, stackId uint, rel *domain.VapiRelease) error {
 	args := s.Called(ctx, stackId, rel)
 	return args.Error(0)
@@ -31,6 +46,11 @@ func (s *ServiceMock) Register(ctx context.Context, gitRepo string, gitBranch st
 	return args.Get(0).(*domain.VapiRelease), args.Error(1)
 }
 
+func (s *ServiceMock) RegisterPushedRef(ctx context.Context, packageId uint, ref string, accessToken string) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, packageId, ref, accessToken)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
 func (s *ServiceMock) GetPackagesByOwnerId(ctx context.Context, ownerId uint) ([]domain.VapiPackage, error) {
 	args := s.Called(ctx, ownerId)
 	return args.Get(0).([]domain.VapiPackage), args.Error(1)
@@ -81,11 +101,66 @@ func (s *ServiceMock) DeletePackage(ctx context.Context, id uint) error {
 	return args.Error(0)
 }
 
+func (s *ServiceMock) TransferPackage(ctx context.Context, id uint, organizationId *uint) error {
+	args := s.Called(ctx, id, organizationId)
+	return args.Error(0)
+}
+
 func (s *ServiceMock) SearchVapis(ctx context.Context, input vapi.SearchVapisInput) (vapi.SearchVapisOutput, error) {
 	args := s.Called(ctx, input)
 	return args.Get(0).(vapi.SearchVapisOutput), args.Error(1)
 }
 
+func (s *ServiceMock) SetPackageAccess(ctx context.Context, id uint, access domain.VapiPackageAccess) error {
+	args := s.Called(ctx, id, access)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) GrantPackage(ctx context.Context, id uint, input vapi.GrantPackageInput) (*domain.VapiPackageBorrower, error) {
+	args := s.Called(ctx, id, input)
+	return args.Get(0).(*domain.VapiPackageBorrower), args.Error(1)
+}
+
+func (s *ServiceMock) RevokePackageGrant(ctx context.Context, id uint, borrowerId uint) error {
+	args := s.Called(ctx, id, borrowerId)
+	return args.Error(0)
+}
+
+func (s *ServiceMock) GetPackageBorrowers(ctx context.Context, id uint) ([]domain.VapiPackageBorrower, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).([]domain.VapiPackageBorrower), args.Error(1)
+}
+
+func (s *ServiceMock) DeprecateRelease(ctx context.Context, id uint, message string) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id, message)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) SuspendRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id, reason)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) YankRelease(ctx context.Context, id uint, reason string) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id, reason)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) UnpublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) PublishRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
+func (s *ServiceMock) RestoreRelease(ctx context.Context, id uint) (*domain.VapiRelease, error) {
+	args := s.Called(ctx, id)
+	return args.Get(0).(*domain.VapiRelease), args.Error(1)
+}
+
 func NewService() *ServiceMock {
 	return &ServiceMock{}
 }